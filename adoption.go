@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+// adoptExisting reports whether dbResource may take over a database or role
+// that already exists on the server, with spec.allowAdoption or
+// spec.adoptExisting. Tenants only get to ask for it, the controller must be
+// started with --allow-adoption.
+func adoptExisting(dbResource *v1.Database) bool {
+	return allowAdoption && (dbResource.Spec.AllowAdoption || dbResource.Spec.AdoptExisting)
+}
+
+// fingerprintAdoption returns what dbResource is about to take over on inst,
+// read before the database is handed over to its owner role.
+func fingerprintAdoption(dbResource *v1.Database, inst *instance, exists, roleExisted bool) (*v1.DatabaseAdoption, error) {
+	adoption := &v1.DatabaseAdoption{RoleExisted: roleExisted, AdoptedTime: metav1.Now()}
+	if !exists || schemaMode(dbResource) {
+		return adoption, nil
+	}
+	err := inst.DB.QueryRow(`SELECT pg_get_userbyid(datdba), pg_encoding_to_char(encoding), datcollate
+		FROM pg_database WHERE datname = $1`, databaseName(dbResource)).Scan(&adoption.Owner, &adoption.Encoding, &adoption.Collation)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the database to adopt: %s", err.Error())
+	}
+	return adoption, nil
+}
+
+// adoptionMessage describes adoption in the event of the Database taking it
+// over.
+func adoptionMessage(dbResource *v1.Database, adoption *v1.DatabaseAdoption) string {
+	if adoption.Owner == "" {
+		return fmt.Sprintf("Adopted existing role %s", roleName(dbResource))
+	}
+	return fmt.Sprintf("Adopted existing database %s owned by %s, encoding %s", databaseName(dbResource), adoption.Owner, adoption.Encoding)
+}
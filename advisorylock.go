@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+// The reconciles and deletions of a Database hold a session level advisory
+// lock keyed by the name of its database, or <database>.<schema> in schema
+// mode, taken on the database of the admin connection, so two replicas of the
+// controller, or a script following the same convention, never run DDL on the
+// same database at once:
+//
+//	SELECT pg_advisory_lock(hashtext('<database>'));
+const (
+	lockDatabaseStatement   = "SELECT pg_try_advisory_lock(hashtext($1))"
+	unlockDatabaseStatement = "SELECT pg_advisory_unlock(hashtext($1))"
+)
+
+// databaseLockTimeout is how long a deletion waits for the lock of its
+// database.
+const databaseLockTimeout = time.Minute
+
+// databaseLocked is returned by lockDatabase while another session holds the
+// lock of the database.
+type databaseLocked string
+
+func (e databaseLocked) Error() string {
+	return string(e)
+}
+
+// lockName returns the name the advisory lock of dbResource is keyed by: its
+// database, or its schema qualified by the shared database in schema mode so
+// the Databases sharing it don't wait for each other.
+func lockName(dbResource *v1.Database) string {
+	if schemaMode(dbResource) {
+		return databaseName(dbResource) + "." + schemaName(dbResource)
+	}
+	return databaseName(dbResource)
+}
+
+// lockDatabase takes the advisory lock called name, see lockName, on a
+// session of inst set aside until the returned function releases it, failing
+// with a databaseLocked error rather than waiting when another session holds
+// it.
+// The dialects without advisory locks are not locked.
+func lockDatabase(ctx context.Context, logger zerolog.Logger, inst *instance, name string) (func(), error) {
+	if !inst.dialect.advisoryLocks {
+		return func() {}, nil
+	}
+	conn, err := inst.DB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var locked bool
+	if err := conn.QueryRowContext(ctx, lockDatabaseStatement, name).Scan(&locked); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error taking lock %s: %s", name, err.Error())
+	}
+	if !locked {
+		conn.Close()
+		return nil, databaseLocked(fmt.Sprintf("lock %s is held by another session", name))
+	}
+	return func() { unlockDatabase(logger, conn, name) }, nil
+}
+
+// unlockDatabase releases the lock called name before conn goes back to the
+// pool, where it would otherwise keep it.
+func unlockDatabase(logger zerolog.Logger, conn *sql.Conn, name string) {
+	defer conn.Close()
+	var unlocked bool
+	if err := conn.QueryRowContext(context.Background(), unlockDatabaseStatement, name).Scan(&unlocked); err != nil {
+		logger.Error().Err(err).Str("lock", name).Msg("error releasing database lock")
+	} else if !unlocked {
+		logger.Warn().Str("lock", name).Msg("database lock was not held")
+	}
+}
+
+// waitDatabaseLock takes the lock called name like lockDatabase, waiting up
+// to databaseLockTimeout for another session to release it.
+func waitDatabaseLock(ctx context.Context, logger zerolog.Logger, inst *instance, name string) (func(), error) {
+	var unlock func()
+	var lockErr error
+	err := wait.PollImmediate(time.Second, databaseLockTimeout, func() (bool, error) {
+		unlock, lockErr = lockDatabase(ctx, logger, inst, name)
+		if _, locked := lockErr.(databaseLocked); locked {
+			return false, nil
+		}
+		return true, lockErr
+	})
+	if err == wait.ErrWaitTimeout {
+		return nil, lockErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return unlock, nil
+}
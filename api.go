@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+	clientset "github.com/joshrendek/k8s-external-postgres/pkg/client/clientset/versioned"
+)
+
+// apiPermissions are needed on top of requiredPermissions by --api-addr, to
+// authenticate and authorize the API clients and create their Databases.
+var apiPermissions = []permission{
+	{"authentication.k8s.io", "tokenreviews", []string{"create"}},
+	{"authorization.k8s.io", "subjectaccessreviews", []string{"create"}},
+	{"postgresql.org", "databases", []string{"create"}},
+}
+
+// apiServer serves the provisioning API for clients that can't create
+// Databases themselves. Clients authenticate with a Kubernetes bearer token
+// and need the same RBAC permissions on Databases as through the API server.
+type apiServer struct {
+	kubeclientset     kubernetes.Interface
+	databaseClientset clientset.Interface
+}
+
+// apiCreateRequest is the body of a provisioning request.
+type apiCreateRequest struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Spec   v1.DatabaseConfig `json:"spec"`
+}
+
+// apiDatabase is the view of a Database returned to clients, without the
+// spec holding the password.
+type apiDatabase struct {
+	Namespace    string                 `json:"namespace"`
+	Name         string                 `json:"name"`
+	State        string                 `json:"state,omitempty"`
+	Message      string                 `json:"message,omitempty"`
+	Reason       string                 `json:"reason,omitempty"`
+	DatabaseName string                 `json:"databaseName,omitempty"`
+	RoleName     string                 `json:"roleName,omitempty"`
+	Secret       string                 `json:"secret,omitempty"`
+	Conditions   []v1.DatabaseCondition `json:"conditions,omitempty"`
+}
+
+// apiEvent is a line of the status stream of a Database.
+type apiEvent struct {
+	Type     watch.EventType `json:"type"`
+	Database apiDatabase     `json:"database"`
+}
+
+func newAPIDatabase(dbResource *v1.Database) apiDatabase {
+	view := apiDatabase{
+		Namespace:    dbResource.Namespace,
+		Name:         dbResource.Name,
+		State:        dbResource.Status.State,
+		Message:      dbResource.Status.Message,
+		Reason:       dbResource.Status.Reason,
+		DatabaseName: dbResource.Status.DatabaseName,
+		RoleName:     dbResource.Status.RoleName,
+		Conditions:   dbResource.Status.Conditions,
+	}
+	if dbResource.Status.State == "provisioned" && hasCredentialsSecret(dbResource) {
+		view.Secret = dbResource.Name
+	}
+	return view
+}
+
+// runAPIServer serves the provisioning API on --api-addr until stopCh is
+// closed, with TLS when --api-tls-cert is set.
+func runAPIServer(kubeclientset kubernetes.Interface, databaseClientset clientset.Interface, stopCh <-chan struct{}) error {
+	s := &apiServer{kubeclientset: kubeclientset, databaseClientset: databaseClientset}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/namespaces/", s.serveDatabases)
+	server := &http.Server{Addr: apiAddr, Handler: mux}
+
+	go func() {
+		<-stopCh
+		server.Close()
+	}()
+
+	log.Info().Str("addr", apiAddr).Msg("Starting provisioning API")
+	var err error
+	if apiCertFile != "" {
+		err = server.ListenAndServeTLS(apiCertFile, apiKeyFile)
+	} else {
+		log.Warn().Msg("The provisioning API is served without TLS, bearer tokens are sent in clear")
+		err = server.ListenAndServe()
+	}
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// serveDatabases routes
+//
+//	POST /api/v1/namespaces/<namespace>/databases
+//	GET  /api/v1/namespaces/<namespace>/databases/<name>
+//	GET  /api/v1/namespaces/<namespace>/databases/<name>/watch
+func (s *apiServer) serveDatabases(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/namespaces/"), "/"), "/")
+	if len(parts) < 2 || parts[1] != "databases" || len(parts) > 4 || (len(parts) == 4 && parts[3] != "watch") {
+		apiError(w, http.StatusNotFound, "not found")
+		return
+	}
+	namespace := parts[0]
+
+	switch {
+	case len(parts) == 2 && r.Method == http.MethodPost:
+		if user := s.authorize(w, r, namespace, "create"); user != nil {
+			s.createDatabase(w, r, namespace, *user)
+		}
+	case len(parts) == 3 && r.Method == http.MethodGet:
+		if s.authorize(w, r, namespace, "get") != nil {
+			s.getDatabase(w, namespace, parts[2])
+		}
+	case len(parts) == 4 && r.Method == http.MethodGet:
+		if s.authorize(w, r, namespace, "watch") != nil {
+			s.watchDatabase(w, r, namespace, parts[2])
+		}
+	default:
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// authorize authenticates the bearer token of r with a TokenReview and checks
+// that its user may verb Databases in namespace, returning the user, or
+// answering the request with an error and returning nil.
+func (s *apiServer) authorize(w http.ResponseWriter, r *http.Request, namespace, verb string) *authenticationv1.UserInfo {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" || token == r.Header.Get("Authorization") {
+		apiError(w, http.StatusUnauthorized, "missing bearer token")
+		return nil
+	}
+	review, err := s.kubeclientset.AuthenticationV1().TokenReviews().Create(&authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	})
+	if err != nil {
+		apiError(w, http.StatusInternalServerError, err.Error())
+		return nil
+	}
+	if !review.Status.Authenticated {
+		apiError(w, http.StatusUnauthorized, "invalid bearer token")
+		return nil
+	}
+
+	user := review.Status.User
+	if !s.reviewAccess(w, user, &authorizationv1.ResourceAttributes{
+		Namespace: namespace,
+		Group:     v1.CRDGroup,
+		Resource:  v1.CRDPlural,
+		Verb:      verb,
+	}) {
+		return nil
+	}
+	return &user
+}
+
+// reviewAccess checks with a SubjectAccessReview that user may act on the
+// resources of attributes, answering the request with an error otherwise.
+func (s *apiServer) reviewAccess(w http.ResponseWriter, user authenticationv1.UserInfo, attributes *authorizationv1.ResourceAttributes) bool {
+	extra := map[string]authorizationv1.ExtraValue{}
+	for key, values := range user.Extra {
+		extra[key] = authorizationv1.ExtraValue(values)
+	}
+	access, err := s.kubeclientset.AuthorizationV1().SubjectAccessReviews().Create(&authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: attributes,
+			User:               user.Username,
+			Groups:             user.Groups,
+			UID:                user.UID,
+			Extra:              extra,
+		},
+	})
+	if err != nil {
+		apiError(w, http.StatusInternalServerError, err.Error())
+		return false
+	}
+	if !access.Status.Allowed {
+		apiError(w, http.StatusForbidden, fmt.Sprintf("%s can't %s %s in namespace %s", user.Username, attributes.Verb, attributes.Resource, attributes.Namespace))
+		return false
+	}
+	return true
+}
+
+func (s *apiServer) createDatabase(w http.ResponseWriter, r *http.Request, namespace string, user authenticationv1.UserInfo) {
+	req := apiCreateRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+		apiError(w, http.StatusBadRequest, "invalid provisioning request, name and spec are required")
+		return
+	}
+	if instanceNamespace := req.Spec.InstanceNamespace; instanceNamespace != "" && instanceNamespace != namespace && req.Spec.Instance != "" {
+		// The admission webhook sees the Database created by the
+		// controller, check that the client may use the instance itself.
+		if !s.reviewAccess(w, user, &authorizationv1.ResourceAttributes{
+			Namespace: instanceNamespace,
+			Group:     v1.CRDGroup,
+			Resource:  v1.InstanceCRDPlural,
+			Name:      req.Spec.Instance,
+			Verb:      "use",
+		}) {
+			return
+		}
+	}
+	dbResource := &v1.Database{
+		ObjectMeta: metav1.ObjectMeta{Name: req.Name, Namespace: namespace, Labels: req.Labels},
+		Spec:       req.Spec,
+	}
+	created, err := s.databaseClientset.DatabasesV1().Databases(namespace).Create(dbResource)
+	if err != nil {
+		apiStatusError(w, err)
+		return
+	}
+	apiJSON(w, http.StatusCreated, newAPIDatabase(created))
+}
+
+func (s *apiServer) getDatabase(w http.ResponseWriter, namespace, name string) {
+	dbResource, err := s.databaseClientset.DatabasesV1().Databases(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		apiStatusError(w, err)
+		return
+	}
+	apiJSON(w, http.StatusOK, newAPIDatabase(dbResource))
+}
+
+// watchDatabase streams the status of the Database as JSON lines until it is
+// provisioned, fails, is deleted or the client goes away.
+func (s *apiServer) watchDatabase(w http.ResponseWriter, r *http.Request, namespace, name string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		apiError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+	watcher, err := s.databaseClientset.DatabasesV1().Databases(namespace).Watch(metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("metadata.name", name).String(),
+	})
+	if err != nil {
+		apiStatusError(w, err)
+		return
+	}
+	defer watcher.Stop()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(w)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return
+			}
+			dbResource, ok := event.Object.(*v1.Database)
+			if !ok {
+				continue
+			}
+			if err := encoder.Encode(apiEvent{Type: event.Type, Database: newAPIDatabase(dbResource)}); err != nil {
+				return
+			}
+			flusher.Flush()
+			switch dbResource.Status.State {
+			case "provisioned", "error", "conflict":
+				return
+			}
+			if event.Type == watch.Deleted {
+				return
+			}
+		}
+	}
+}
+
+func apiJSON(w http.ResponseWriter, status int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
+		log.Error().Err(err).Msg("error writing API response")
+	}
+}
+
+func apiError(w http.ResponseWriter, status int, message string) {
+	apiJSON(w, status, map[string]string{"error": message})
+}
+
+// apiStatusError answers with the status code of an API server error.
+func apiStatusError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if statusErr, ok := err.(*errors.StatusError); ok {
+		status = int(statusErr.Status().Code)
+	}
+	apiError(w, status, err.Error())
+}
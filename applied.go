@@ -0,0 +1,124 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+// appliedField is a group of spec fields rendered to SQL by one step of the
+// reconcile of a provisioned Database, named after the spec field.
+type appliedField struct {
+	name  string
+	value func(dbResource *v1.Database) interface{}
+}
+
+// appliedFields are the steps skipped while the fields they apply are those
+// last applied, per status.appliedSpec. The steps reading other sources than
+// the spec, e.g. Secrets, ConfigMaps, Tablespaces or the time, always run.
+var appliedFields = []appliedField{
+	{"connectionLimit", func(d *v1.Database) interface{} {
+		return []interface{}{d.Spec.ConnectionLimit, d.Spec.RoleConnectionLimit}
+	}},
+	{"roleAttributes", func(d *v1.Database) interface{} {
+		return []interface{}{d.Spec.RoleAttributes, allowPrivilegedRoles}
+	}},
+	{"revokePublic", func(d *v1.Database) interface{} { return revokePublic(d) }},
+	{"ownership", func(d *v1.Database) interface{} {
+		return []interface{}{controllerID, d.UID}
+	}},
+	{"memberOf", func(d *v1.Database) interface{} { return d.Spec.MemberOf }},
+	{"defaultPrivileges", func(d *v1.Database) interface{} { return d.Spec.DefaultPrivileges }},
+	{"extensions", func(d *v1.Database) interface{} { return d.Spec.Extensions }},
+	{"roleSettings", func(d *v1.Database) interface{} { return d.Spec.RoleSettings }},
+}
+
+// appliedSpec are the hashes of the appliedFields of a Database, and which
+// differ from those last applied.
+type appliedSpec struct {
+	fields  map[string]string
+	changed map[string]bool
+	// full runs every step, whether its fields changed or not.
+	full bool
+}
+
+// specHash returns the SHA-256 of the JSON of values.
+func specHash(values ...interface{}) string {
+	b, err := json.Marshal(values)
+	if err != nil {
+		// the spec was unmarshalled from JSON in the first place
+		panic(err)
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// planApplied hashes the appliedFields of a provisioned dbResource, along
+// with the database, roles and instance they are applied to, and compares
+// them to status.appliedSpec. Every step runs in dry-run mode, on the
+// postgresql.org/reconcile annotation, and once --spec-drift-interval has
+// passed since they last all ran, correcting the changes made on the server
+// behind the back of the controller.
+func planApplied(dbResource *v1.Database, dryRun bool, now time.Time) *appliedSpec {
+	target := []interface{}{
+		databaseName(dbResource), ownedRoles(dbResource), schemaName(dbResource), dbResource.Spec.Mode,
+		instanceNamespace(dbResource), instanceName(dbResource),
+	}
+	a := &appliedSpec{fields: map[string]string{}, changed: map[string]bool{}}
+	applied := dbResource.Status.AppliedSpec
+	for _, field := range appliedFields {
+		hash := specHash(target, field.value(dbResource))
+		a.fields[field.name] = hash
+		if applied == nil || applied.Fields[field.name] != hash {
+			a.changed[field.name] = true
+		}
+	}
+	a.full = dryRun || applied == nil || specDriftInterval <= 0 ||
+		now.Sub(applied.VerifiedTime.Time) >= specDriftInterval ||
+		dbResource.Annotations[reconcileAnnotation] != dbResource.Status.ReconcileRequest
+	return a
+}
+
+// run reports whether the step applying the named field must run.
+func (a *appliedSpec) run(name string) bool {
+	return a.full || a.changed[name]
+}
+
+// changedFields returns the names of the fields differing from those last
+// applied, sorted.
+func (a *appliedSpec) changedFields() []string {
+	var names []string
+	for name := range a.changed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// record sets status.appliedSpec to the hashes of a, once every step
+// succeeded, moving its verifiedTime when every step ran. It returns false
+// when status was already up to date.
+func (a *appliedSpec) record(status *v1.DatabaseStatus, reconcileRequest string, now time.Time) bool {
+	if !a.full && len(a.changed) == 0 {
+		return false
+	}
+	hashes := make([]string, 0, len(appliedFields))
+	for _, field := range appliedFields {
+		hashes = append(hashes, a.fields[field.name])
+	}
+	recorded := &v1.AppliedSpec{Hash: specHash(hashes), Fields: a.fields}
+	if a.full || status.AppliedSpec == nil {
+		recorded.VerifiedTime = metav1.NewTime(now)
+	} else {
+		recorded.VerifiedTime = status.AppliedSpec.VerifiedTime
+	}
+	status.AppliedSpec = recorded
+	status.ReconcileRequest = reconcileRequest
+	return true
+}
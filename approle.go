@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+const (
+	// roleLayoutOwner provisions the owner role only, which the application
+	// uses for both migrations and queries.
+	roleLayoutOwner = "owner"
+	// roleLayoutOwnerApp provisions an application role with DML rights
+	// next to the owner role, which keeps DDL to migrations.
+	roleLayoutOwnerApp = "owner-app"
+
+	// appSecretSuffix is appended to the Database name to build the name of
+	// the Secret holding the application role credentials.
+	appSecretSuffix = "-app"
+)
+
+// appRole reports whether dbResource has an application role besides its
+// owner role.
+func appRole(dbResource *v1.Database) bool {
+	return dbResource.Spec.RoleLayout == roleLayoutOwnerApp
+}
+
+// appUsername returns the name of the application role of username.
+func appUsername(username string) string {
+	return username + "_app"
+}
+
+// provisionAppUser creates the <username>_app role for dbResource, grants it
+// SELECT, INSERT, UPDATE and DELETE on every existing and future table, and
+// the use of the sequences, of the public schema, or its own schema in schema
+// mode, and stores its credentials in the <name>-app Secret. The tables stay
+// owned by the owner role, so the application role can't alter or drop them.
+// An existing role is given a new password, so it can be run again after a
+// failed attempt.
+func (c *Controller) provisionAppUser(dbResource *v1.Database, inst *instance, exec *sqlExecutor) error {
+	username := appUsername(roleName(dbResource))
+	database := databaseName(dbResource)
+
+	password, err := generatePassword()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := upsertRoleStatement(inst.DB, username, password, passwordEncryptionFor(dbResource))
+	if err != nil {
+		return err
+	}
+	roleStmts := []string{stmt, fmt.Sprintf("GRANT CONNECT ON DATABASE %s TO %s", database, username)}
+	if err := exec.ExecDDL(inst, roleStmts); err != nil {
+		return fmt.Errorf("error creating application user: %s", err.Error())
+	}
+
+	db, err := inst.openDatabase(database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	schema := tenantSchema(dbResource)
+	stmts := []string{
+		fmt.Sprintf("GRANT USAGE ON SCHEMA %s TO %s", schema, username),
+		fmt.Sprintf("GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA %s TO %s", schema, username),
+		fmt.Sprintf("GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA %s TO %s", schema, username),
+	}
+	if inst.dialect.defaultPrivileges {
+		owner := roleName(dbResource)
+		stmts = append(stmts,
+			fmt.Sprintf("ALTER DEFAULT PRIVILEGES FOR ROLE %s IN SCHEMA %s GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO %s", owner, schema, username),
+			fmt.Sprintf("ALTER DEFAULT PRIVILEGES FOR ROLE %s IN SCHEMA %s GRANT USAGE, SELECT ON SEQUENCES TO %s", owner, schema, username),
+		)
+	}
+	for _, stmt := range stmts {
+		if err := exec.Exec(db, stmt); err != nil {
+			return fmt.Errorf("error granting application privileges: %s", err.Error())
+		}
+	}
+
+	if exec.dryRun {
+		return nil
+	}
+	return c.storeCredentials(dbResource, inst, dbResource.Name+appSecretSuffix, username, password)
+}
+
+// syncAppUser provisions the application role of a provisioned dbResource
+// switched to the owner-app layout. The role of a Database switched back is
+// left in place.
+func (c *Controller) syncAppUser(dbResource *v1.Database, inst *instance, exec *sqlExecutor) error {
+	if !appRole(dbResource) {
+		return nil
+	}
+	exists, err := roleExists(inst.DB, appUsername(roleName(dbResource)))
+	if err != nil || exists {
+		return err
+	}
+	return c.provisionAppUser(dbResource, inst, exec)
+}
+
+// rotateAppPassword sets a new generated password on the application role of
+// dbResource and stores it in the <name>-app Secret.
+func (c *Controller) rotateAppPassword(dbResource *v1.Database, inst *instance, exec *sqlExecutor) error {
+	username := appUsername(roleName(dbResource))
+	password, err := generatePassword()
+	if err != nil {
+		return err
+	}
+	stmt, err := rolePasswordStatement("ALTER ROLE", username, password, passwordEncryptionFor(dbResource))
+	if err != nil {
+		return err
+	}
+	if err := exec.Exec(inst.DB, stmt); err != nil {
+		return fmt.Errorf("error rotating application password: %s", err.Error())
+	}
+
+	if exec.dryRun {
+		return nil
+	}
+	return c.storeCredentials(dbResource, inst, dbResource.Name+appSecretSuffix, username, password)
+}
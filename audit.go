@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/syslog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// auditRecord describes one statement executed by the controller and the
+// resource whose reconcile triggered it.
+type auditRecord struct {
+	Time      time.Time `json:"time"`
+	Kind      string    `json:"kind"`
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	UID       string    `json:"uid"`
+	Statement string    `json:"statement"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// auditSink stores audit records.
+type auditSink interface {
+	Record(rec auditRecord) error
+}
+
+// auditSinks are configured from the --audit-* flags by setupAudit.
+var auditSinks []auditSink
+
+// setupAudit configures the audit sinks enabled by the --audit-* flags, the
+// audit table living on inst.
+func setupAudit(inst *instance) error {
+	if auditTable != "" {
+		sink, err := newTableAuditSink(inst, auditDatabase, auditTable)
+		if err != nil {
+			return fmt.Errorf("error setting up audit table: %s", err.Error())
+		}
+		auditSinks = append(auditSinks, sink)
+	}
+	if auditWebhookURL != "" {
+		auditSinks = append(auditSinks, &webhookAuditSink{
+			url:    auditWebhookURL,
+			client: &http.Client{Timeout: 5 * time.Second},
+		})
+	}
+	if auditSyslog != "" {
+		u, err := url.Parse(auditSyslog)
+		if err != nil {
+			return fmt.Errorf("invalid audit syslog address %q: %s", auditSyslog, err.Error())
+		}
+		writer, err := syslog.Dial(u.Scheme, u.Host, syslog.LOG_INFO|syslog.LOG_AUTHPRIV, "k8s-external-postgres")
+		if err != nil {
+			return fmt.Errorf("error connecting to audit syslog: %s", err.Error())
+		}
+		auditSinks = append(auditSinks, &syslogAuditSink{writer: writer})
+	}
+	return nil
+}
+
+// closeAudit closes the connections of the audit sinks holding one.
+func closeAudit() {
+	for _, sink := range auditSinks {
+		if closer, ok := sink.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+}
+
+// recordAudit hands rec to every audit sink. Failing sinks are logged but
+// never fail the reconcile.
+func recordAudit(logger zerolog.Logger, rec auditRecord) {
+	for _, sink := range auditSinks {
+		if err := sink.Record(rec); err != nil {
+			logger.Error().Err(err).Msg("error recording audit record")
+		}
+	}
+}
+
+// tableAuditSink inserts audit records into a table of an admin database.
+type tableAuditSink struct {
+	db    *sql.DB
+	table string
+}
+
+func newTableAuditSink(inst *instance, database, table string) (*tableAuditSink, error) {
+	db, err := inst.openDatabase(database)
+	if err != nil {
+		return nil, err
+	}
+	stmt := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id bigserial PRIMARY KEY,
+	executed_at timestamptz NOT NULL,
+	kind text NOT NULL,
+	namespace text NOT NULL,
+	name text NOT NULL,
+	uid text NOT NULL,
+	statement text NOT NULL,
+	error text
+)`, table)
+	if _, err := db.Exec(stmt); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &tableAuditSink{db: db, table: table}, nil
+}
+
+func (s *tableAuditSink) Record(rec auditRecord) error {
+	var recErr *string
+	if rec.Error != "" {
+		recErr = &rec.Error
+	}
+	_, err := s.db.Exec(fmt.Sprintf("INSERT INTO %s (executed_at, kind, namespace, name, uid, statement, error) VALUES ($1, $2, $3, $4, $5, $6, $7)", s.table),
+		rec.Time, rec.Kind, rec.Namespace, rec.Name, rec.UID, rec.Statement, recErr)
+	return err
+}
+
+func (s *tableAuditSink) Close() error {
+	return s.db.Close()
+}
+
+// webhookAuditSink POSTs every audit record as JSON to an URL.
+type webhookAuditSink struct {
+	url    string
+	client *http.Client
+}
+
+func (s *webhookAuditSink) Record(rec auditRecord) error {
+	body, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("audit webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// syslogAuditSink writes every audit record as a JSON syslog message.
+type syslogAuditSink struct {
+	writer *syslog.Writer
+}
+
+func (s *syslogAuditSink) Record(rec auditRecord) error {
+	body, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if rec.Error != "" {
+		return s.writer.Err(string(body))
+	}
+	return s.writer.Info(string(body))
+}
+
+func (s *syslogAuditSink) Close() error {
+	return s.writer.Close()
+}
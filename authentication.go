@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+// Authentication methods of spec.authentication.
+const (
+	authPassword = "password"
+	authCert     = "cert"
+	authLDAP     = "ldap"
+	authIdent    = "ident"
+)
+
+// defaultIdentMap is the pg_ident.conf map of the suggested entries.
+const defaultIdentMap = "k8s"
+
+// authMethod returns the authentication method of the owner role of
+// dbResource.
+func authMethod(dbResource *v1.Database) string {
+	if dbResource.Spec.Authentication == nil || dbResource.Spec.Authentication.Method == "" {
+		return authPassword
+	}
+	return dbResource.Spec.Authentication.Method
+}
+
+// passwordless reports whether the owner role of dbResource logs in without
+// a password, spec.password being ignored.
+func passwordless(dbResource *v1.Database) bool {
+	switch authMethod(dbResource) {
+	case authCert, authLDAP, authIdent:
+		return true
+	}
+	return false
+}
+
+// checkAuthentication validates the spec.authentication of dbResource.
+func checkAuthentication(dbResource *v1.Database) error {
+	switch authMethod(dbResource) {
+	case authPassword, authCert, authLDAP, authIdent:
+		return nil
+	}
+	return fmt.Errorf("invalid authentication method %q, must be password, cert, ldap or ident", authMethod(dbResource))
+}
+
+// pgHbaName returns the name of the ConfigMap suggesting the pg_hba.conf and
+// pg_ident.conf entries of the Database name.
+func pgHbaName(name string) string {
+	return name + "-pg-hba"
+}
+
+// pgHbaEntries returns the pg_hba.conf and pg_ident.conf entries letting the
+// owner role of the passwordless dbResource log in, for DBAs to add to the
+// configuration of the server.
+func pgHbaEntries(dbResource *v1.Database) map[string]string {
+	auth := dbResource.Spec.Authentication
+	identMap := auth.IdentMap
+	if identMap == "" {
+		identMap = defaultIdentMap
+	}
+	database, role := databaseName(dbResource), roleName(dbResource)
+	hba := fmt.Sprintf("# Database %s/%s\n", dbResource.Namespace, dbResource.Name)
+	switch auth.Method {
+	case authCert:
+		hba += fmt.Sprintf("hostssl %s %s all cert map=%s\n", database, role, identMap)
+	case authIdent:
+		hba += fmt.Sprintf("host %s %s all ident map=%s\n", database, role, identMap)
+	case authLDAP:
+		hba += "# set the options of the LDAP server\n"
+		hba += fmt.Sprintf("host %s %s all ldap ldapserver=ldap.example.com ldapprefix=\"cn=\" ldapsuffix=\", dc=example, dc=com\"\n", database, role)
+		return map[string]string{"pg_hba.conf": hba}
+	}
+
+	users := auth.SystemUsers
+	if len(users) == 0 {
+		users = []string{role}
+	}
+	ident := fmt.Sprintf("# Database %s/%s\n", dbResource.Namespace, dbResource.Name)
+	for _, user := range users {
+		if strings.ContainsAny(user, " \t") {
+			user = `"` + user + `"`
+		}
+		ident += fmt.Sprintf("%s %s %s\n", identMap, user, role)
+	}
+	return map[string]string{"pg_hba.conf": hba, "pg_ident.conf": ident}
+}
+
+// syncAuthentication writes the pg_hba.conf and pg_ident.conf entries the
+// passwordless owner role of dbResource needs to a ConfigMap owned by it,
+// deleting the ConfigMap once the role is back to password authentication.
+// The server configuration is left to the DBAs.
+func (c *Controller) syncAuthentication(dbResource *v1.Database) error {
+	name := pgHbaName(dbResource.Name)
+	configMaps := c.kubeclientset.CoreV1().ConfigMaps(dbResource.Namespace)
+	existing, err := c.ConfigMapsLister.ConfigMaps(dbResource.Namespace).Get(name)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	owned := existing != nil && metav1.IsControlledBy(existing, dbResource)
+
+	if !passwordless(dbResource) {
+		if !owned {
+			return nil
+		}
+		err := configMaps.Delete(name, &metav1.DeleteOptions{})
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	data := pgHbaEntries(dbResource)
+	if owned && reflect.DeepEqual(existing.Data, data) {
+		return nil
+	}
+	if existing != nil && !owned {
+		return fmt.Errorf("configmap %q exists and is not owned by the Database", name)
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: dbResource.Namespace,
+			Labels:    map[string]string{databaseLabel: dbResource.Name},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(dbResource, v1.SchemeGroupVersion.WithKind("Database")),
+			},
+		},
+		Data: data,
+	}
+	if existing == nil {
+		_, err = configMaps.Create(configMap)
+	} else {
+		configMap.ResourceVersion = existing.ResourceVersion
+		_, err = configMaps.Update(configMap)
+	}
+	if err == nil {
+		c.recorder.Event(dbResource, corev1.EventTypeNormal, "PgHbaEntriesUpdated",
+			fmt.Sprintf("pg_hba.conf entries for %s authentication written to configmap %s", authMethod(dbResource), name))
+	}
+	return err
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+const (
+	// azureDatabaseScope is the scope of the Azure AD tokens Azure Database
+	// for PostgreSQL accepts as passwords.
+	azureDatabaseScope = "https://ossrdbms-aad.database.windows.net/.default"
+	// azureDefaultAuthority is the Azure AD endpoint of the public cloud,
+	// AZURE_AUTHORITY_HOST overriding it.
+	azureDefaultAuthority = "https://login.microsoftonline.com/"
+	// azureTokenRefreshMargin is how long before its expiry a token is
+	// replaced, so connections opened meanwhile don't present an expired one.
+	azureTokenRefreshMargin = 5 * time.Minute
+)
+
+// azureADToken obtains the Azure AD access tokens the admin connections to
+// an Azure Database for PostgreSQL flexible server log in with, through
+// workload identity: the service account token projected by the workload
+// identity webhook is exchanged for an access token of the managed identity
+// federated with it. The user of the admin URI is the name of the Azure AD
+// role of the identity on the server.
+type azureADToken struct {
+	tenantID  string
+	clientID  string
+	authority string
+	tokenFile string
+	client    *http.Client
+
+	// mu guards the token, shared by the connections of the instance.
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// newAzureADToken returns the token source of the identity spec, whose
+// empty fields default to the AZURE_TENANT_ID and AZURE_CLIENT_ID set by the
+// workload identity webhook.
+func newAzureADToken(spec *v1.AzureADAuthentication) (*azureADToken, error) {
+	a := &azureADToken{
+		tenantID:  os.Getenv("AZURE_TENANT_ID"),
+		clientID:  os.Getenv("AZURE_CLIENT_ID"),
+		authority: os.Getenv("AZURE_AUTHORITY_HOST"),
+		tokenFile: os.Getenv("AZURE_FEDERATED_TOKEN_FILE"),
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}
+	if spec.TenantID != "" {
+		a.tenantID = spec.TenantID
+	}
+	if spec.ClientID != "" {
+		a.clientID = spec.ClientID
+	}
+	if a.authority == "" {
+		a.authority = azureDefaultAuthority
+	}
+	switch {
+	case a.tenantID == "":
+		return nil, fmt.Errorf("azure AD authentication requires a tenant ID, AZURE_TENANT_ID is not set")
+	case a.clientID == "":
+		return nil, fmt.Errorf("azure AD authentication requires a client ID, AZURE_CLIENT_ID is not set")
+	case a.tokenFile == "":
+		return nil, fmt.Errorf("azure AD authentication requires workload identity, AZURE_FEDERATED_TOKEN_FILE is not set")
+	}
+	return a, nil
+}
+
+// flagAzureADToken returns the token source of the default instance with
+// --azure-ad-auth, nil without.
+func flagAzureADToken() (*azureADToken, error) {
+	if !azureADAuth {
+		return nil, nil
+	}
+	return newAzureADToken(&v1.AzureADAuthentication{})
+}
+
+// id identifies the identity of a, empty when a is nil.
+func (a *azureADToken) id() string {
+	if a == nil {
+		return ""
+	}
+	return a.tenantID + "/" + a.clientID
+}
+
+// password returns the access token a new connection logs in with, the
+// cached one until it is about to expire.
+func (a *azureADToken) password() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token != "" && time.Now().Add(azureTokenRefreshMargin).Before(a.expiry) {
+		return a.token, nil
+	}
+
+	// the projected token is rotated by the kubelet, read it every time
+	assertion, err := ioutil.ReadFile(a.tokenFile)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"client_id":             {a.clientID},
+		"scope":                 {azureDatabaseScope},
+		"grant_type":            {"client_credentials"},
+		"client_assertion_type": {"urn:ietf:params:oauth:client-assertion-type:jwt-bearer"},
+		"client_assertion":      {strings.TrimSpace(string(assertion))},
+	}
+	endpoint := fmt.Sprintf("%s/%s/oauth2/v2.0/token", strings.TrimSuffix(a.authority, "/"), url.PathEscape(a.tenantID))
+	resp, err := a.client.PostForm(endpoint, form)
+	if err != nil {
+		return "", fmt.Errorf("error requesting azure AD token: %s", err.Error())
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		AccessToken      string `json:"access_token"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("error decoding azure AD token: %s", err.Error())
+	}
+	if resp.StatusCode != http.StatusOK || body.AccessToken == "" {
+		return "", fmt.Errorf("azure AD returned %s: %s %s", resp.Status, body.Error, body.ErrorDescription)
+	}
+	a.token = body.AccessToken
+	a.expiry = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	return a.token, nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/joshrendek/k8s-external-postgres/pkg/fakepg"
+)
+
+const (
+	// backendPostgres connects to the servers of the admin URIs.
+	backendPostgres = "postgres"
+	// backendFake provisions on in-memory fake servers instead, told apart by
+	// the host of their admin URI, so the reconciles can be tested without a
+	// server.
+	backendFake = "fake"
+)
+
+// setupBackend checks the --backend flag and injects the --fake-failures,
+// semicolon separated regexp=SQLSTATE pairs, into the fake servers.
+func setupBackend() error {
+	switch backend {
+	case backendPostgres:
+		if fakeFailures != "" {
+			return fmt.Errorf("--fake-failures requires --backend=%s", backendFake)
+		}
+		return nil
+	case backendFake:
+	default:
+		return fmt.Errorf("unknown backend %q, must be %s or %s", backend, backendPostgres, backendFake)
+	}
+	for _, item := range strings.Split(fakeFailures, ";") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		i := strings.LastIndex(item, "=")
+		if i < 0 {
+			return fmt.Errorf("invalid fake failure %q, must be regexp=SQLSTATE", item)
+		}
+		pattern, err := regexp.Compile(item[:i])
+		if err != nil {
+			return fmt.Errorf("invalid fake failure %q: %s", item, err.Error())
+		}
+		fakepg.FailOn(pattern, item[i+1:])
+	}
+	return nil
+}
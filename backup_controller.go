@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	batchlisters "k8s.io/client-go/listers/batch/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+	clientset "github.com/joshrendek/k8s-external-postgres/pkg/client/clientset/versioned"
+	informers "github.com/joshrendek/k8s-external-postgres/pkg/client/informers/externalversions"
+	listers "github.com/joshrendek/k8s-external-postgres/pkg/client/listers/postgresql/v1"
+)
+
+const (
+	backupStateRunning   = "running"
+	backupStateCompleted = "completed"
+	backupStateFailed    = "failed"
+
+	// backupScript dumps the database, uploads the dump with rclone and
+	// reports its checksum through the container termination message.
+	backupScript = `set -e
+pg_dump --format=custom --file=/tmp/backup.dump "$DATABASE_URL"
+rclone copyto /tmp/backup.dump "$BACKUP_LOCATION"
+sha256sum /tmp/backup.dump | cut -d ' ' -f 1 > /dev/termination-log
+`
+)
+
+// BackupController runs a pg_dump Job for every DatabaseBackup resource and
+// records the outcome in its status.
+type BackupController struct {
+	kubeclientset     kubernetes.Interface
+	databaseClientset clientset.Interface
+
+	DatabasesLister listers.DatabaseLister
+	DatabasesSynced cache.InformerSynced
+	BackupsLister   listers.DatabaseBackupLister
+	BackupsSynced   cache.InformerSynced
+	JobsLister      batchlisters.JobLister
+	JobsSynced      cache.InformerSynced
+
+	workqueue workqueue.RateLimitingInterface
+	recorder  record.EventRecorder
+}
+
+// NewBackupController returns a new backup controller
+func NewBackupController(
+	kubeclientset kubernetes.Interface,
+	databaseClientset clientset.Interface,
+	kubeInformerFactory kubeinformers.SharedInformerFactory,
+	databaseInformerFactory informers.SharedInformerFactory) *BackupController {
+
+	databaseInformer := databaseInformerFactory.Databases().V1().Databases()
+	backupInformer := databaseInformerFactory.Databases().V1().DatabaseBackups()
+	jobInformer := kubeInformerFactory.Batch().V1().Jobs()
+
+	controller := &BackupController{
+		kubeclientset:     kubeclientset,
+		databaseClientset: databaseClientset,
+		DatabasesLister:   databaseInformer.Lister(),
+		DatabasesSynced:   databaseInformer.Informer().HasSynced,
+		BackupsLister:     backupInformer.Lister(),
+		BackupsSynced:     backupInformer.Informer().HasSynced,
+		JobsLister:        jobInformer.Lister(),
+		JobsSynced:        jobInformer.Informer().HasSynced,
+		workqueue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "DatabaseBackups"),
+		recorder:          newEventRecorder(kubeclientset),
+	}
+
+	log.Info().Msg("Setting up backup event handlers")
+	backupInformer.Informer().AddEventHandler(instrumentHandler("databasebackups", "backup", cache.ResourceEventHandlerFuncs{
+		AddFunc: controller.enqueueBackup,
+		UpdateFunc: func(old, new interface{}) {
+			controller.enqueueBackup(new)
+		},
+	}))
+	// Jobs are owned by the DatabaseBackup that created them, re-sync the
+	// owner whenever one of them changes.
+	jobInformer.Informer().AddEventHandler(instrumentHandler("jobs", "backup", cache.ResourceEventHandlerFuncs{
+		AddFunc: controller.handleJob,
+		UpdateFunc: func(old, new interface{}) {
+			controller.handleJob(new)
+		},
+	}))
+	return controller
+}
+
+// Run waits for the informer caches to sync and starts the backup workers. It
+// blocks until stopCh is closed.
+func (c *BackupController) Run(threadiness int, stopCh <-chan struct{}) error {
+	defer runtime.HandleCrash()
+	defer c.workqueue.ShutDown()
+
+	log.Info().Msg("Starting DatabaseBackup controller")
+	if ok := cache.WaitForCacheSync(stopCh, c.DatabasesSynced, c.BackupsSynced, c.JobsSynced); !ok {
+		return fmt.Errorf("failed to wait for caches to sync")
+	}
+
+	workersDone := make(chan struct{})
+	go func() {
+		runQueueWorkers(c.workqueue, threadiness, c.syncHandler, stopCh)
+		close(workersDone)
+	}()
+	go wait.Until(c.runSchedules, time.Minute, stopCh)
+
+	<-stopCh
+	log.Info().Msg("Shutting down backup workers")
+	<-workersDone
+
+	return nil
+}
+
+// syncHandler starts the backup Job of a DatabaseBackup if needed and, once the
+// Job has finished, records the artifact location and checksum in its status.
+func (c *BackupController) syncHandler(ctx context.Context, logger zerolog.Logger, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		runtime.HandleError(fmt.Errorf("invalid resource key: %s", key))
+		return nil
+	}
+
+	backup, err := c.BackupsLister.DatabaseBackups(namespace).Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			runtime.HandleError(fmt.Errorf("backup '%s' in work queue no longer exists", key))
+			return nil
+		}
+		return err
+	}
+
+	switch backup.Status.State {
+	case backupStateCompleted, backupStateFailed:
+		return nil
+	}
+
+	dbResource, err := c.DatabasesLister.Databases(namespace).Get(backup.Spec.Database)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return c.updateBackupStatus(backup, func(status *v1.DatabaseBackupStatus) {
+				status.State = backupStateFailed
+				status.Message = fmt.Sprintf("database %q not found", backup.Spec.Database)
+			})
+		}
+		return err
+	}
+	if dbResource.Status.State != "provisioned" {
+		return fmt.Errorf("database %q is not provisioned yet", backup.Spec.Database)
+	}
+	if !hasCredentialsSecret(dbResource) {
+		return c.updateBackupStatus(backup, func(status *v1.DatabaseBackupStatus) {
+			status.State = backupStateFailed
+			status.Message = fmt.Sprintf("database %q stores its credentials in Vault only, the backup job needs its Secret", backup.Spec.Database)
+		})
+	}
+
+	jobName := backup.Name + "-backup"
+	job, err := c.JobsLister.Jobs(namespace).Get(jobName)
+	if errors.IsNotFound(err) {
+		if backupImage == "" {
+			return c.updateBackupStatus(backup, func(status *v1.DatabaseBackupStatus) {
+				status.State = backupStateFailed
+				status.Message = "Backups require --backup-image to dump the database"
+			})
+		}
+		logger.Info().Str("database", databaseName(dbResource)).Msg("starting backup job")
+		job, err = c.kubeclientset.BatchV1().Jobs(namespace).Create(newBackupJob(backup, dbResource, jobName))
+	}
+	if err != nil {
+		return err
+	}
+	if !metav1.IsControlledBy(job, backup) {
+		msg := fmt.Sprintf(MessageResourceExists, job.Name)
+		c.recorder.Event(backup, corev1.EventTypeWarning, ErrResourceExists, msg)
+		return fmt.Errorf("%s", msg)
+	}
+	// the location the Job was started with, the database may have been
+	// renamed since
+	location := jobEnv(job, "BACKUP_LOCATION")
+
+	if cond := finishedJobCondition(job); cond != nil {
+		if cond.Type == batchv1.JobFailed {
+			c.recorder.Event(backup, corev1.EventTypeWarning, "BackupFailed", cond.Message)
+			return c.updateBackupStatus(backup, func(status *v1.DatabaseBackupStatus) {
+				status.State = backupStateFailed
+				status.Message = fmt.Sprintf("backup job failed: %s", cond.Message)
+			})
+		}
+		checksum, err := jobTerminationMessage(c.kubeclientset, job)
+		if err != nil {
+			return err
+		}
+		c.recorder.Event(backup, corev1.EventTypeNormal, SuccessSynced, "Backup completed successfully")
+		return c.updateBackupStatus(backup, func(status *v1.DatabaseBackupStatus) {
+			now := metav1.Now()
+			status.State = backupStateCompleted
+			status.Message = "successful"
+			status.Job = job.Name
+			status.Location = location
+			status.Checksum = checksum
+			status.CompletionTime = &now
+		})
+	}
+
+	if backup.Status.State == backupStateRunning {
+		return nil
+	}
+	return c.updateBackupStatus(backup, func(status *v1.DatabaseBackupStatus) {
+		status.State = backupStateRunning
+		status.Message = "backup in progress"
+		status.Job = job.Name
+		status.Location = location
+	})
+}
+
+func (c *BackupController) updateBackupStatus(backup *v1.DatabaseBackup, mutate func(status *v1.DatabaseBackupStatus)) error {
+	// NEVER modify objects from the store. It's a read-only, local cache.
+	backupCopy := backup.DeepCopy()
+	mutate(&backupCopy.Status)
+	_, err := c.databaseClientset.DatabasesV1().DatabaseBackups(backup.Namespace).Update(backupCopy)
+	return err
+}
+
+// backupLocation is the rclone path the dump of dbResource is uploaded to,
+// computed when its Job is created and read back from the Job afterwards.
+func backupLocation(backup *v1.DatabaseBackup, dbResource *v1.Database) string {
+	return fmt.Sprintf("%s/%s/%s/%s.dump", strings.TrimSuffix(backup.Spec.Destination, "/"),
+		backup.Namespace, databaseName(dbResource), backup.Name)
+}
+
+// jobEnv returns the value of the environment variable called name of the
+// first container of job, empty when it has none.
+func jobEnv(job *batchv1.Job, name string) string {
+	containers := job.Spec.Template.Spec.Containers
+	if len(containers) == 0 {
+		return ""
+	}
+	for _, env := range containers[0].Env {
+		if env.Name == name {
+			return env.Value
+		}
+	}
+	return ""
+}
+
+// newBackupJob builds the Job dumping dbResource with the credentials from its
+// Secret and uploading the result to the backup storage.
+func newBackupJob(backup *v1.DatabaseBackup, dbResource *v1.Database, name string) *batchv1.Job {
+	var backoffLimit int32 = 2
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: backup.Namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(backup, v1.SchemeGroupVersion.WithKind("DatabaseBackup")),
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{
+						{
+							Name:    "backup",
+							Image:   backupImage,
+							Command: []string{"/bin/sh", "-c", backupScript},
+							Env: []corev1.EnvVar{
+								databaseURLEnv(dbResource.Name),
+								{Name: "BACKUP_LOCATION", Value: backupLocation(backup, dbResource)},
+							},
+							EnvFrom: storageEnvFrom(backup.Spec.StorageSecret),
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// enqueueBackup takes a DatabaseBackup resource and puts its namespace/name
+// key onto the work queue.
+func (c *BackupController) enqueueBackup(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	c.workqueue.AddRateLimited(key)
+}
+
+// handleJob enqueues the DatabaseBackup owning a Job, ignoring Jobs that are
+// not managed by this controller.
+func (c *BackupController) handleJob(obj interface{}) {
+	job, ok := obj.(*batchv1.Job)
+	if !ok {
+		return
+	}
+	ownerRef := metav1.GetControllerOf(job)
+	if ownerRef == nil || ownerRef.Kind != "DatabaseBackup" {
+		return
+	}
+	backup, err := c.BackupsLister.DatabaseBackups(job.Namespace).Get(ownerRef.Name)
+	if err != nil {
+		return
+	}
+	c.enqueueBackup(backup)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/robfig/cron"
+	"github.com/rs/zerolog"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/runtime"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+const (
+	// scheduledBackupLabel is set to the Database name on DatabaseBackups
+	// created from its backup schedule.
+	scheduledBackupLabel = "postgresql.org/scheduled-backup"
+)
+
+// runSchedules creates the DatabaseBackups that are due according to the
+// backup schedule of every Database and prunes the ones past retention.
+func (c *BackupController) runSchedules() {
+	dbResources, err := c.DatabasesLister.List(labels.Everything())
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	now := time.Now()
+	for _, dbResource := range dbResources {
+		if dbResource.Spec.Backup == nil || dbResource.Status.State != "provisioned" {
+			continue
+		}
+		logger := resourceLogger(dbResource.Namespace, dbResource.Name)
+		if err := c.syncSchedule(logger, dbResource, now); err != nil {
+			logger.Error().Err(err).Msg("error scheduling backup")
+		}
+	}
+}
+
+func (c *BackupController) syncSchedule(logger zerolog.Logger, dbResource *v1.Database, now time.Time) error {
+	schedule, err := cron.ParseStandard(dbResource.Spec.Backup.Schedule)
+	if err != nil {
+		c.recorder.Event(dbResource, corev1.EventTypeWarning, "InvalidSchedule", err.Error())
+		return err
+	}
+
+	selector := labels.SelectorFromSet(labels.Set{scheduledBackupLabel: dbResource.Name})
+	backups, err := c.BackupsLister.DatabaseBackups(dbResource.Namespace).List(selector)
+	if err != nil {
+		return err
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreationTimestamp.Before(&backups[j].CreationTimestamp)
+	})
+
+	last := dbResource.CreationTimestamp.Time
+	if len(backups) > 0 {
+		last = backups[len(backups)-1].CreationTimestamp.Time
+	}
+	if !schedule.Next(last).After(now) {
+		backup, err := c.createScheduledBackup(logger, dbResource, now)
+		if err != nil {
+			return err
+		}
+		backups = append(backups, backup)
+	}
+
+	retention := dbResource.Spec.Backup.Retention
+	if retention <= 0 {
+		return nil
+	}
+	// Only completed backups count, running and failed ones don't push them
+	// out. Whatever is older than the last completed backup kept goes.
+	var prune []*v1.DatabaseBackup
+	kept := 0
+	for i := len(backups) - 1; i >= 0; i-- {
+		if kept < retention {
+			if backups[i].Status.State == backupStateCompleted {
+				kept++
+			}
+			continue
+		}
+		prune = append(prune, backups[i])
+	}
+	for _, backup := range prune {
+		logger.Info().Str("backup", backup.Name).Msg("pruning scheduled backup")
+		err := c.databaseClientset.DatabasesV1().DatabaseBackups(backup.Namespace).Delete(backup.Name, &metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// createScheduledBackup creates the DatabaseBackup of dbResource for the
+// schedule slot containing now. It is owned by the Database so it is garbage
+// collected with it, the uploaded dumps are left in place.
+func (c *BackupController) createScheduledBackup(logger zerolog.Logger, dbResource *v1.Database, now time.Time) (*v1.DatabaseBackup, error) {
+	backup := &v1.DatabaseBackup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%d", dbResource.Name, now.Truncate(time.Minute).Unix()),
+			Namespace: dbResource.Namespace,
+			Labels:    map[string]string{scheduledBackupLabel: dbResource.Name},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(dbResource, v1.SchemeGroupVersion.WithKind("Database")),
+			},
+		},
+		Spec: v1.DatabaseBackupSpec{
+			Database:      dbResource.Name,
+			StorageSecret: dbResource.Spec.Backup.StorageSecret,
+			Destination:   dbResource.Spec.Backup.Destination,
+		},
+	}
+	logger.Info().Str("backup", backup.Name).Msg("creating scheduled backup")
+	created, err := c.databaseClientset.DatabasesV1().DatabaseBackups(backup.Namespace).Create(backup)
+	if errors.IsAlreadyExists(err) {
+		return c.databaseClientset.DatabasesV1().DatabaseBackups(backup.Namespace).Get(backup.Name, metav1.GetOptions{})
+	}
+	return created, err
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+	"github.com/joshrendek/k8s-external-postgres/pkg/provisioner"
+)
+
+// canaryPending reports whether the credentials of dbResource are to be
+// tried before it is marked Ready: until the Ready condition is True for its
+// current generation.
+func canaryPending(dbResource *v1.Database) bool {
+	if !canaryConnect {
+		return false
+	}
+	ready := findCondition(&dbResource.Status, readyCondition)
+	return ready == nil || ready.Status != conditionTrue || dbResource.Status.ObservedGeneration != dbResource.Generation
+}
+
+// checkCredentials connects to the database of dbResource as each of its
+// roles, with the credentials written for them rather than the admin
+// connection, and runs SELECT 1. It catches the pg_hba.conf entries,
+// password encodings and grants the applications would trip on. Roles whose
+// credentials only hold a password verifier can't be tried.
+func (c *Controller) checkCredentials(dbResource *v1.Database, inst *instance) error {
+	store, err := c.credentialStore(dbResource)
+	if err != nil {
+		return err
+	}
+	for _, name := range credentialNames(dbResource) {
+		credentials, err := c.liveCredentials(store, dbResource, name)
+		if err != nil {
+			return err
+		}
+		if credentials == nil {
+			return fmt.Errorf("credentials %s are not stored", name)
+		}
+		password := appliedPassword(credentials)
+		if password == "" || provisioner.IsPasswordVerifier(password) {
+			continue
+		}
+		if err := canaryQuery(inst, databaseName(dbResource), credentials["USERNAME"], password); err != nil {
+			return fmt.Errorf("error connecting as %s: %s", credentials["USERNAME"], err.Error())
+		}
+	}
+	return nil
+}
+
+// liveCredentials returns the credentials called name from store. Secrets
+// are read from the API server, those just written may not be in the
+// informer cache yet.
+func (c *Controller) liveCredentials(store credentialStore, dbResource *v1.Database, name string) (map[string]string, error) {
+	if _, ok := store.(*secretStore); !ok {
+		return store.Get(dbResource, name)
+	}
+	secret, err := c.kubeclientset.CoreV1().Secrets(dbResource.Namespace).Get(name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	data := make(map[string]string, len(secret.Data))
+	for key, value := range secret.Data {
+		data[key] = string(value)
+	}
+	return data, nil
+}
+
+// canaryQuery runs SELECT 1 on database of the server of inst, logged in as
+// username.
+func canaryQuery(inst *instance, database, username, password string) error {
+	dsn, err := inst.databaseURL(database, username, password)
+	if err != nil {
+		return err
+	}
+	db, err := openDSN(dsn, inst.tunnel, nil)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+	var one int
+	err = db.QueryRowContext(ctx, "SELECT 1").Scan(&one)
+	if pgErr, ok := err.(pgx.PgError); ok && pgErr.Code == sqlStateInvalidAuthorization {
+		// invalid_authorization_specification, pg_hba.conf has no entry
+		return fmt.Errorf("%s, check pg_hba.conf", pgErr.Message)
+	}
+	return err
+}
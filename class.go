@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/runtime"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+// classDefaults returns spec with the fields it leaves empty set to those of
+// class, and whether any was.
+func classDefaults(spec v1.DatabaseConfig, class *v1.DatabaseClass) (v1.DatabaseConfig, bool) {
+	defaults := class.Spec.DeepCopy()
+	changed := false
+	if spec.Instance == "" && spec.Placement == nil {
+		// a Database naming either keeps its pick
+		if defaults.Instance != "" {
+			spec.Instance, changed = defaults.Instance, true
+		} else if defaults.Placement != nil {
+			spec.Placement, changed = defaults.Placement, true
+		}
+	}
+	if len(spec.Extensions) == 0 && len(defaults.Extensions) > 0 {
+		spec.Extensions, changed = defaults.Extensions, true
+	}
+	if spec.RoleLayout == "" && defaults.RoleLayout != "" {
+		spec.RoleLayout, changed = defaults.RoleLayout, true
+	}
+	if spec.DeletionPolicy == nil && defaults.DeletionPolicy != nil {
+		spec.DeletionPolicy, changed = defaults.DeletionPolicy, true
+	}
+	if spec.Backup == nil && defaults.Backup != nil {
+		spec.Backup, changed = defaults.Backup, true
+	}
+	return spec, changed
+}
+
+// applyClass copies the defaults of the DatabaseClass of dbResource to its
+// spec, before it is provisioned, so every later reconcile, sweep and
+// deletion sees them, and edits to the class only reach new Databases. It
+// reports whether the Database was updated, the reconcile then waiting for
+// the update to come back from the informer.
+func (c *Controller) applyClass(dbResource *v1.Database) (bool, error) {
+	if dbResource.Spec.ClassName == "" {
+		return false, nil
+	}
+	class, err := c.ClassesLister.Get(dbResource.Spec.ClassName)
+	if err != nil {
+		return false, fmt.Errorf("error getting DatabaseClass %q: %s", dbResource.Spec.ClassName, err.Error())
+	}
+	spec, changed := classDefaults(dbResource.Spec, class)
+	if !changed {
+		return false, nil
+	}
+	dbCopy := dbResource.DeepCopy()
+	dbCopy.Spec = spec
+	if _, err := c.databaseClientset.DatabasesV1().Databases(dbCopy.Namespace).Update(dbCopy); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// handleClass enqueues the Databases of a DatabaseClass that is created or
+// changes and that are not provisioned yet, e.g. waiting for it.
+func (c *Controller) handleClass(obj interface{}) {
+	class, ok := obj.(*v1.DatabaseClass)
+	if !ok {
+		return
+	}
+	dbResources, err := c.DatabasesLister.List(labels.Everything())
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	for _, dbResource := range dbResources {
+		if dbResource.Spec.ClassName == class.Name && dbResource.Status.State == "" {
+			c.enqueueDatabase(dbResource)
+		}
+	}
+}
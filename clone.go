@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/rs/zerolog"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+	"github.com/joshrendek/k8s-external-postgres/pkg/provisioner"
+)
+
+// cloneScript dumps the source database with the credentials of its owner
+// and restores it with the ones of the clone, so the restored objects belong
+// to the owner of the clone.
+const cloneScript = `set -e
+pg_dump --format=custom --no-owner --no-privileges --dbname="$SOURCE_DATABASE_URL" --file=/tmp/clone.dump
+pg_restore --no-owner --no-privileges --exit-on-error --dbname="$DATABASE_URL" /tmp/clone.dump
+`
+
+// cloneSource returns the Database dbResource is cloned from, once it is
+// provisioned.
+func (c *Controller) cloneSource(dbResource *v1.Database) (*v1.Database, error) {
+	source, err := c.DatabasesLister.Databases(dbResource.Namespace).Get(dbResource.Spec.CloneFrom)
+	if err != nil {
+		return nil, fmt.Errorf("error getting clone source %q: %s", dbResource.Spec.CloneFrom, err.Error())
+	}
+	if source.Status.State != "provisioned" {
+		return nil, fmt.Errorf("clone source %q is not provisioned yet", dbResource.Spec.CloneFrom)
+	}
+	return source, nil
+}
+
+// cloneJobName returns the name of the Job dumping and restoring the clone
+// source of dbResource.
+func cloneJobName(dbResource *v1.Database) string {
+	return dbResource.Name + "-clone"
+}
+
+// reassignStatement returns the statement handing the schemas, relations,
+// routines and types owned by from in the current database over to to.
+// REASSIGN OWNED can't be used as it would hand over the source database
+// itself too. Sequences owned by a column follow their table.
+func reassignStatement(from, to string) string {
+	return fmt.Sprintf(`DO $clone$
+DECLARE
+	r record;
+BEGIN
+	FOR r IN SELECT nspname FROM pg_namespace WHERE nspowner = %[1]s::regrole LOOP
+		EXECUTE format('ALTER SCHEMA %%I OWNER TO %%I', r.nspname, %[2]s);
+	END LOOP;
+	FOR r IN SELECT c.oid::regclass AS rel, c.relkind FROM pg_class c
+		WHERE c.relowner = %[1]s::regrole AND c.relkind IN ('r', 'p', 'v', 'm', 'f', 'S')
+		AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.classid = 'pg_class'::regclass
+			AND d.objid = c.oid AND d.refclassid = 'pg_class'::regclass AND d.deptype IN ('a', 'i')) LOOP
+		EXECUTE format('ALTER %%s %%s OWNER TO %%I', CASE r.relkind WHEN 'v' THEN 'VIEW'
+			WHEN 'm' THEN 'MATERIALIZED VIEW' WHEN 'f' THEN 'FOREIGN TABLE'
+			WHEN 'S' THEN 'SEQUENCE' ELSE 'TABLE' END, r.rel, %[2]s);
+	END LOOP;
+	FOR r IN SELECT p.oid::regprocedure AS routine FROM pg_proc p WHERE p.proowner = %[1]s::regrole LOOP
+		EXECUTE format('ALTER ROUTINE %%s OWNER TO %%I', r.routine, %[2]s);
+	END LOOP;
+	FOR r IN SELECT t.oid::regtype AS typ, t.typtype FROM pg_type t
+		WHERE t.typowner = %[1]s::regrole AND t.typtype IN ('c', 'd', 'e', 'r')
+		AND (t.typrelid = 0 OR (SELECT relkind FROM pg_class WHERE oid = t.typrelid) = 'c') LOOP
+		EXECUTE format('ALTER %%s %%s OWNER TO %%I', CASE r.typtype WHEN 'd' THEN 'DOMAIN' ELSE 'TYPE' END, r.typ, %[2]s);
+	END LOOP;
+END
+$clone$`, provisioner.QuoteLiteral(from), provisioner.QuoteLiteral(to))
+}
+
+// cloneWithTemplate creates the database of dbResource as a copy of the one
+// of source on the same server, and hands the copied objects over to the
+// owner of dbResource. It returns false, without error, when sessions are
+// connected to the source database, which CREATE DATABASE ... TEMPLATE
+// refuses.
+func cloneWithTemplate(dbResource, source *v1.Database, inst *instance, exec *sqlExecutor) (bool, error) {
+	database := databaseName(dbResource)
+	stmt := fmt.Sprintf("CREATE DATABASE %s TEMPLATE %s OWNER %s", database, databaseName(source), roleName(dbResource))
+	if err := exec.Exec(inst.DB, stmt); err != nil {
+		if sqlState(err) == sqlStateObjectInUse {
+			// object_in_use: sessions are connected to the source
+			return false, nil
+		}
+		return false, err
+	}
+
+	db, err := inst.openDatabase(database)
+	if err != nil {
+		return false, err
+	}
+	err = exec.Exec(db, reassignStatement(roleName(source), roleName(dbResource)))
+	db.Close()
+	if err != nil {
+		// the copy still belongs to the owner of the source, don't leave it
+		// behind
+		if dropErr := exec.Exec(inst.DB, fmt.Sprintf("DROP DATABASE %s", database)); dropErr != nil {
+			return false, fmt.Errorf("error handing the copied objects over: %s, and dropping the copy: %s", err.Error(), dropErr.Error())
+		}
+		return false, fmt.Errorf("error handing the copied objects over: %s", err.Error())
+	}
+	return true, nil
+}
+
+// startCloneJob starts the Job restoring a dump of source into the database
+// of dbResource, unless it is already running.
+func (c *Controller) startCloneJob(logger zerolog.Logger, dbResource, source *v1.Database) error {
+	name := cloneJobName(dbResource)
+	job, err := c.JobsLister.Jobs(dbResource.Namespace).Get(name)
+	if errors.IsNotFound(err) {
+		logger.Info().Str("source", databaseName(source)).Msg("starting clone job")
+		job, err = c.kubeclientset.BatchV1().Jobs(dbResource.Namespace).Create(newCloneJob(dbResource, source, name))
+	}
+	if err != nil {
+		return err
+	}
+	if !metav1.IsControlledBy(job, dbResource) {
+		msg := fmt.Sprintf(MessageResourceExists, job.Name)
+		c.recorder.Event(dbResource, corev1.EventTypeWarning, ErrResourceExists, msg)
+		return fmt.Errorf("%s", msg)
+	}
+	return nil
+}
+
+// syncClone completes the provisioning of a dbResource in the cloning state
+// once its clone Job has finished.
+func (c *Controller) syncClone(dbResource *v1.Database) error {
+	job, err := c.JobsLister.Jobs(dbResource.Namespace).Get(cloneJobName(dbResource))
+	if errors.IsNotFound(err) {
+		return c.updateFooStatus(dbResource, fmt.Sprintf("clone job %s not found", cloneJobName(dbResource)), "error")
+	}
+	if err != nil {
+		return err
+	}
+	cond := finishedJobCondition(job)
+	if cond == nil {
+		return nil
+	}
+	if cond.Type == batchv1.JobFailed {
+		c.recorder.Event(dbResource, corev1.EventTypeWarning, "CloneFailed", cond.Message)
+		return c.updateFooStatus(dbResource, fmt.Sprintf("clone job failed: %s", cond.Message), "error")
+	}
+	if err := c.updateFooStatus(dbResource, "successful", "provisioned"); err != nil {
+		return err
+	}
+	c.recorder.Event(dbResource, corev1.EventTypeNormal, SuccessSynced, fmt.Sprintf("Cloned from %s", dbResource.Spec.CloneFrom))
+	notify(notifyDatabaseCreated, dbResource, fmt.Sprintf("cloned from %s", dbResource.Spec.CloneFrom))
+	return nil
+}
+
+// newCloneJob builds the Job dumping the database of source with the
+// credentials from its Secret and restoring it into dbResource with the ones
+// from its own.
+func newCloneJob(dbResource, source *v1.Database, name string) *batchv1.Job {
+	var backoffLimit int32 = 0
+	sourceURL := databaseURLEnv(source.Name)
+	sourceURL.Name = "SOURCE_DATABASE_URL"
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: dbResource.Namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(dbResource, v1.SchemeGroupVersion.WithKind("Database")),
+			},
+		},
+		Spec: batchv1.JobSpec{
+			// pg_restore is not idempotent, a failed clone is not retried
+			// into a half restored database.
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{
+						{
+							Name:    "clone",
+							Image:   backupImage,
+							Command: []string{"/bin/sh", "-c", cloneScript},
+							Env: []corev1.EnvVar{
+								databaseURLEnv(dbResource.Name),
+								sourceURL,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// handleJob enqueues the Database owning a clone Job, ignoring the other
+// Jobs.
+func (c *Controller) handleJob(obj interface{}) {
+	job, ok := obj.(*batchv1.Job)
+	if !ok {
+		return
+	}
+	ownerRef := metav1.GetControllerOf(job)
+	if ownerRef == nil || ownerRef.Kind != "Database" {
+		return
+	}
+	dbResource, err := c.DatabasesLister.Databases(job.Namespace).Get(ownerRef.Name)
+	if err != nil {
+		return
+	}
+	c.enqueueDatabase(dbResource)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+// The annotations understood by the controller, their value only needs to
+// change to trigger the action again.
+const (
+	reconcileAnnotation = "postgresql.org/reconcile"
+	rotateAnnotation    = "postgresql.org/rotate"
+)
+
+// runReconcile has the controller retry the provisioning of a Database.
+func runReconcile(c *clients, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: kubectl pgdb reconcile NAME")
+	}
+	return c.updateDatabase(args[0], func(dbResource *v1.Database) error {
+		setAnnotation(dbResource, reconcileAnnotation, time.Now().UTC().Format(time.RFC3339))
+		return nil
+	})
+}
+
+// runRotate sets a new random password on the owner role, applied by the
+// controller like any password edit, and has the controller generate a new
+// password for the read-only role.
+func runRotate(c *clients, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: kubectl pgdb rotate NAME")
+	}
+	return c.updateDatabase(args[0], func(dbResource *v1.Database) error {
+		if dbResource.Spec.PasswordVerifierSecret != nil {
+			return fmt.Errorf("the password of %q is a verifier from secret %q, update the secret instead",
+				dbResource.Name, dbResource.Spec.PasswordVerifierSecret.Name)
+		}
+		b := make([]byte, 24)
+		if _, err := rand.Read(b); err != nil {
+			return err
+		}
+		dbResource.Spec.Password = base64.RawURLEncoding.EncodeToString(b)
+		setAnnotation(dbResource, rotateAnnotation, time.Now().UTC().Format(time.RFC3339))
+		return nil
+	})
+}
+
+// updateDatabase applies mutate to the Database name and saves it.
+func (c *clients) updateDatabase(name string, mutate func(*v1.Database) error) error {
+	databases := c.databases.DatabasesV1().Databases(c.namespace)
+	dbResource, err := databases.Get(name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if err := mutate(dbResource); err != nil {
+		return err
+	}
+	if _, err := databases.Update(dbResource); err != nil {
+		return err
+	}
+	fmt.Printf("database.postgresql.org/%s updated\n", name)
+	return nil
+}
+
+func setAnnotation(dbResource *v1.Database, key, value string) {
+	if dbResource.Annotations == nil {
+		dbResource.Annotations = map[string]string{}
+	}
+	dbResource.Annotations[key] = value
+}
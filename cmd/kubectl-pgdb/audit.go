@@ -0,0 +1,110 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+var (
+	auditDatabase string
+	auditTable    string
+	auditLimit    int
+	auditFollow   bool
+)
+
+// auditEntry is a row of the audit table.
+type auditEntry struct {
+	id         int64
+	executedAt time.Time
+	kind       string
+	namespace  string
+	name       string
+	statement  string
+	err        string
+}
+
+// runAudit prints the last audit entries, of the Database NAME when given,
+// and keeps printing new ones with -f.
+func runAudit(c *clients, args []string) error {
+	if postgresURI == "" {
+		return fmt.Errorf("--postgres-uri or PGDB_POSTGRES_URI is required")
+	}
+	u, err := url.Parse(postgresURI)
+	if err != nil {
+		return err
+	}
+	u.Path = "/" + auditDatabase
+	db, err := sql.Open("pgx", u.String())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	namespace, name := "", ""
+	if len(args) > 0 {
+		namespace, name = c.namespace, args[0]
+	}
+
+	entries, err := queryAudit(db, namespace, name, 0, auditLimit)
+	if err != nil {
+		return err
+	}
+	var last int64
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tKIND\tNAMESPACE\tNAME\tSTATEMENT\tERROR")
+	// entries are newest first
+	for i := len(entries) - 1; i >= 0; i-- {
+		printAuditEntry(w, entries[i])
+		last = entries[i].id
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	for auditFollow {
+		time.Sleep(2 * time.Second)
+		entries, err := queryAudit(db, namespace, name, last, 1000)
+		if err != nil {
+			return err
+		}
+		for i := len(entries) - 1; i >= 0; i-- {
+			printAuditEntry(w, entries[i])
+			last = entries[i].id
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// queryAudit returns at most limit entries newer than after, newest first,
+// only the ones of namespace/name when name is set.
+func queryAudit(db *sql.DB, namespace, name string, after int64, limit int) ([]auditEntry, error) {
+	rows, err := db.Query(fmt.Sprintf(`SELECT id, executed_at, kind, namespace, name, statement, coalesce(error, '')
+		FROM %s WHERE id > $1 AND ($2 = '' OR (namespace = $3 AND name = $2))
+		ORDER BY id DESC LIMIT $4`, auditTable), after, name, namespace, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []auditEntry
+	for rows.Next() {
+		var e auditEntry
+		if err := rows.Scan(&e.id, &e.executedAt, &e.kind, &e.namespace, &e.name, &e.statement, &e.err); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func printAuditEntry(w *tabwriter.Writer, e auditEntry) {
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", e.executedAt.Local().Format("2006-01-02 15:04:05"),
+		e.kind, e.namespace, e.name, e.statement, e.err)
+}
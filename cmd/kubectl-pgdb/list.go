@@ -0,0 +1,156 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	_ "github.com/jackc/pgx/stdlib"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+var (
+	allNamespaces bool
+	postgresURI   string
+)
+
+// listNamespace returns the namespace to list resources in, all of them with -A.
+func (c *clients) listNamespace() string {
+	if allNamespaces {
+		return metav1.NamespaceAll
+	}
+	return c.namespace
+}
+
+// runList prints the Databases with the state of their database on the
+// server, when the admin URI of the server is known.
+func runList(c *clients, args []string) error {
+	dbResources, err := c.databases.DatabasesV1().Databases(c.listNamespace()).List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	servers := map[string]*sql.DB{}
+	defer func() {
+		for _, db := range servers {
+			db.Close()
+		}
+	}()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tNAME\tDATABASE\tINSTANCE\tSTATE\tSERVER\tOWNER\tSIZE\tCONNECTIONS")
+	for _, dbResource := range dbResources.Items {
+		database := dbResource.Status.DatabaseName
+		if database == "" {
+			database = dbResource.Spec.Database
+		}
+		server, owner, size, connections := "-", "-", "-", "-"
+		if db, err := c.serverOf(&dbResource, servers); err != nil {
+			server = "unknown: " + err.Error()
+		} else if db != nil {
+			server, owner, size, connections = liveState(db, database)
+		}
+		instance := dbResource.Spec.Instance
+		if instance == "" {
+			instance = "default"
+		} else if dbResource.Spec.InstanceNamespace != "" {
+			instance = dbResource.Spec.InstanceNamespace + "/" + instance
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", dbResource.Namespace, dbResource.Name,
+			database, instance, dbResource.Status.State, server, owner, size, connections)
+	}
+	return w.Flush()
+}
+
+// serverOf returns an admin connection to the server dbResource is
+// provisioned on, or nil when its admin URI is not known. Connections are
+// shared through servers.
+func (c *clients) serverOf(dbResource *v1.Database, servers map[string]*sql.DB) (*sql.DB, error) {
+	uri := postgresURI
+	if dbResource.Spec.Instance != "" {
+		namespace := dbResource.Namespace
+		if dbResource.Spec.InstanceNamespace != "" {
+			namespace = dbResource.Spec.InstanceNamespace
+		}
+		pgInstance, err := c.databases.DatabasesV1().PostgresInstances(namespace).Get(dbResource.Spec.Instance, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		secret, err := c.kube.CoreV1().Secrets(namespace).Get(pgInstance.Spec.AdminSecret, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		uri = string(secret.Data["DATABASE_URL"])
+	}
+	if uri == "" {
+		return nil, nil
+	}
+	if db, ok := servers[uri]; ok {
+		return db, nil
+	}
+	db, err := sql.Open("pgx", uri)
+	if err != nil {
+		return nil, err
+	}
+	servers[uri] = db
+	return db, nil
+}
+
+// liveState returns whether database exists on the server, its owner, size
+// and number of open connections.
+func liveState(db *sql.DB, database string) (string, string, string, string) {
+	var owner, size string
+	var connections int
+	err := db.QueryRow(`SELECT pg_get_userbyid(datdba), pg_size_pretty(pg_database_size(datname)),
+		(SELECT count(*) FROM pg_stat_activity WHERE pg_stat_activity.datname = pg_database.datname)
+		FROM pg_database WHERE datname = $1`, database).Scan(&owner, &size, &connections)
+	if err == sql.ErrNoRows {
+		return "missing", "-", "-", "-"
+	}
+	if err != nil {
+		return "unknown: " + err.Error(), "-", "-", "-"
+	}
+	return "present", owner, size, fmt.Sprint(connections)
+}
+
+// runErrors prints the Databases that failed to provision and the warning
+// events recorded for Databases.
+func runErrors(c *clients, args []string) error {
+	dbResources, err := c.databases.DatabasesV1().Databases(c.listNamespace()).List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tNAME\tSTATE\tREASON\tMESSAGE")
+	for _, dbResource := range dbResources.Items {
+		switch dbResource.Status.State {
+		case "error", "conflict":
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", dbResource.Namespace, dbResource.Name, dbResource.Status.State, dbResource.Status.Reason, dbResource.Status.Message)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	selector := fields.Set{"involvedObject.kind": "Database", "type": corev1.EventTypeWarning}.AsSelector()
+	events, err := c.kube.CoreV1().Events(c.listNamespace()).List(metav1.ListOptions{FieldSelector: selector.String()})
+	if err != nil {
+		return err
+	}
+	if len(events.Items) == 0 {
+		return nil
+	}
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "LAST SEEN\tNAMESPACE\tNAME\tREASON\tCOUNT\tMESSAGE")
+	for _, event := range events.Items {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n", event.LastTimestamp.Format("2006-01-02 15:04:05"), event.Namespace,
+			event.InvolvedObject.Name, event.Reason, event.Count, event.Message)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,115 @@
+// kubectl-pgdb inspects the Databases managed by k8s-external-postgres.
+// Installed on the PATH it is available as "kubectl pgdb".
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	clientset "github.com/joshrendek/k8s-external-postgres/pkg/client/clientset/versioned"
+)
+
+const usage = `Inspect the Databases managed by k8s-external-postgres.
+
+Usage:
+  kubectl pgdb list [-A] [--postgres-uri URI]   list Databases with their server side state
+  kubectl pgdb errors [-A]                      show Databases failing to reconcile and their warnings
+  kubectl pgdb audit [NAME] [-f]                tail the audit log of executed statements
+  kubectl pgdb reconcile NAME                   retry the provisioning of a Database in error
+  kubectl pgdb rotate NAME                      rotate the passwords of a Database
+
+Global flags:
+  --kubeconfig PATH    path to the kubeconfig
+  -n, --namespace NS   namespace of the Databases, defaults to the one of the context
+`
+
+// clients holds what every command needs to talk to the cluster.
+type clients struct {
+	kube      kubernetes.Interface
+	databases clientset.Interface
+	namespace string
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	commands := map[string]func(*clients, []string) error{
+		"list":      runList,
+		"errors":    runErrors,
+		"audit":     runAudit,
+		"reconcile": runReconcile,
+		"rotate":    runRotate,
+	}
+	command, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet("kubectl pgdb "+os.Args[1], flag.ExitOnError)
+	kubeconfig := flags.String("kubeconfig", "", "Path to the kubeconfig")
+	namespace := flags.String("namespace", "", "Namespace of the Databases")
+	flags.StringVar(namespace, "n", "", "Namespace of the Databases")
+	registerFlags(os.Args[1], flags)
+	flags.Parse(os.Args[2:])
+
+	c, err := newClients(*kubeconfig, *namespace)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := command(c, flags.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// registerFlags adds the flags specific to command.
+func registerFlags(command string, flags *flag.FlagSet) {
+	switch command {
+	case "list":
+		flags.BoolVar(&allNamespaces, "A", false, "List the Databases of every namespace")
+		flags.StringVar(&postgresURI, "postgres-uri", os.Getenv("PGDB_POSTGRES_URI"), "Admin URI of the default server, to report the state of its databases")
+	case "errors":
+		flags.BoolVar(&allNamespaces, "A", false, "Show the Databases of every namespace")
+	case "audit":
+		flags.StringVar(&postgresURI, "postgres-uri", os.Getenv("PGDB_POSTGRES_URI"), "Admin URI of the server holding the audit table")
+		flags.StringVar(&auditDatabase, "audit-database", "postgres", "Database holding the audit table")
+		flags.StringVar(&auditTable, "audit-table", "public.controller_audit", "Audit table the controller records statements in")
+		flags.IntVar(&auditLimit, "limit", 20, "Number of entries shown")
+		flags.BoolVar(&auditFollow, "f", false, "Keep printing new entries")
+	}
+}
+
+func newClients(kubeconfig, namespace string) (*clients, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	overrides := &clientcmd.ConfigOverrides{}
+	overrides.Context.Namespace = namespace
+	config := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+
+	ns, _, err := config.Namespace()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := config.ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error building kubeconfig: %s", err.Error())
+	}
+	kube, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	databases, err := clientset.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &clients{kube: kube, databases: databases, namespace: ns}, nil
+}
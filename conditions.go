@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/runtime"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+const (
+	conditionTrue  = "True"
+	conditionFalse = "False"
+)
+
+// findCondition returns the condition of status of the given type, nil when
+// it is not set.
+func findCondition(status *v1.DatabaseStatus, condType string) *v1.DatabaseCondition {
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == condType {
+			return &status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// setCondition sets the condition of the given type on status, its transition
+// time only moving when its status changes. It returns false when the
+// condition was already set as requested.
+func setCondition(status *v1.DatabaseStatus, condType, condStatus, reason, message string) bool {
+	cond := findCondition(status, condType)
+	if cond == nil {
+		status.Conditions = append(status.Conditions, v1.DatabaseCondition{Type: condType})
+		cond = &status.Conditions[len(status.Conditions)-1]
+	}
+	if cond.Status == condStatus && cond.Reason == reason && cond.Message == message {
+		return false
+	}
+	if cond.Status != condStatus {
+		cond.LastTransitionTime = metav1.Now()
+	}
+	cond.Status = condStatus
+	cond.Reason = reason
+	cond.Message = message
+	return true
+}
+
+// updateCondition sets a condition on the status of dbResource, only
+// updating the resource when it changed.
+func (c *Controller) updateCondition(dbResource *v1.Database, condType, condStatus, reason, message string) error {
+	dbCopy := dbResource.DeepCopy()
+	if !setCondition(&dbCopy.Status, condType, condStatus, reason, message) {
+		return nil
+	}
+	return c.updateStatus(dbCopy)
+}
+
+// readyCondition is True once the current generation of a Database has been
+// applied on the server, for GitOps tools to wait on.
+const readyCondition = "Ready"
+
+// notReadyReasons are the reasons of the Ready condition of the states other
+// than provisioned.
+var notReadyReasons = map[string]string{
+	"error":    "ProvisioningFailed",
+	"conflict": "Conflict",
+	"cloning":  "Cloning",
+}
+
+// statusSubresourceMissing is set once the Database CRD was found without
+// the status subresource, as installed by older versions and not updated
+// with --install-crds. The status is then written with Update, which moves
+// the generation too, so observedGeneration can't be tracked.
+var statusSubresourceMissing int32
+
+// updateStatus writes the status of dbCopy through the status subresource,
+// or with Update when the CRD predates it.
+func (c *Controller) updateStatus(dbCopy *v1.Database) error {
+	_, err := c.writeStatus(dbCopy)
+	return err
+}
+
+// writeStatus is updateStatus returning the updated Database, for the
+// reconciles writing their status more than once. The write is tracked until
+// the informer delivers it, see databaseWrites.
+func (c *Controller) writeStatus(dbCopy *v1.Database) (updated *v1.Database, err error) {
+	span := startAPISpan(dbCopy.Namespace, dbCopy.Name, "UpdateStatus Database", attribute.String("state", dbCopy.Status.State))
+	defer func() {
+		endSpan(span, err)
+		if err == nil {
+			databaseWrites.written(updated)
+		}
+	}()
+	databases := c.databaseClientset.DatabasesV1().Databases(dbCopy.Namespace)
+	if atomic.LoadInt32(&statusSubresourceMissing) == 0 {
+		updated, err = databases.UpdateStatus(dbCopy)
+		if !errors.IsNotFound(err) {
+			return updated, err
+		}
+		if _, getErr := databases.Get(dbCopy.Name, metav1.GetOptions{}); getErr != nil {
+			// the Database itself is gone
+			return nil, err
+		}
+		log.Warn().Msg("The Database CRD has no status subresource, update it with --install-crds")
+		atomic.StoreInt32(&statusSubresourceMissing, 1)
+	}
+	return databases.Update(dbCopy)
+}
+
+// syncFailed records the failure of a step reconciling a provisioned
+// dbResource as a warning event and on its Ready condition, or its
+// NetworkAccessDenied one when the server refused the connection. It returns
+// err so the Database is retried.
+func (c *Controller) syncFailed(dbResource *v1.Database, reason string, err error) error {
+	if denial := detectNetworkDenial(err.Error()); denial != nil {
+		if updateErr := c.reportNetworkDenial(dbResource, denial); updateErr != nil {
+			runtime.HandleError(updateErr)
+		}
+		return err
+	}
+	c.recorder.Event(dbResource, corev1.EventTypeWarning, reason, err.Error())
+	if updateErr := c.updateCondition(dbResource, readyCondition, conditionFalse, reason, err.Error()); updateErr != nil {
+		runtime.HandleError(updateErr)
+	}
+	return err
+}
+
+// markReconciling sets the Ready condition of dbResource, in state, to False
+// while the controller applies a generation it hasn't yet: a new Database,
+// or a provisioned one whose spec changed, so waiting on Ready doesn't return
+// before the change is applied. A Ready condition already False, e.g. on a
+// failure, is kept. It returns dbResource as written.
+func (c *Controller) markReconciling(dbResource *v1.Database, state string) (*v1.Database, error) {
+	var reason, message string
+	switch {
+	case state == "":
+		reason, message = "Provisioning", "Provisioning the database and roles"
+	case state == "provisioned" && dbResource.Generation != dbResource.Status.ObservedGeneration &&
+		len(dbResource.Status.PendingActions) == 0 && atomic.LoadInt32(&statusSubresourceMissing) == 0:
+		reason, message = "Reconciling", fmt.Sprintf("Applying generation %d", dbResource.Generation)
+	default:
+		return dbResource, nil
+	}
+	if ready := findCondition(&dbResource.Status, readyCondition); ready != nil && ready.Status == conditionFalse {
+		return dbResource, nil
+	}
+	dbCopy := dbResource.DeepCopy()
+	setCondition(&dbCopy.Status, readyCondition, conditionFalse, reason, message)
+	return c.writeStatus(dbCopy)
+}
+
+// markApplied sets the Ready condition of a provisioned dbResource and
+// records its generation as observed once every step of its reconcile
+// succeeded, unless some were deferred until the maintenance window of m,
+// along with the hashes of the spec fields applied.
+func (c *Controller) markApplied(dbResource *v1.Database, m *maintenance, applied *appliedSpec) error {
+	dbCopy := dbResource.DeepCopy()
+	changed := setCondition(&dbCopy.Status, readyCondition, conditionTrue, "Provisioned", "")
+	changed = clearNetworkDenial(&dbCopy.Status) || changed
+	changed = m.record(&dbCopy.Status) || changed
+	changed = applied.record(&dbCopy.Status, dbResource.Annotations[reconcileAnnotation], time.Now()) || changed
+	if atomic.LoadInt32(&statusSubresourceMissing) == 0 && len(m.deferred) == 0 && dbCopy.Status.ObservedGeneration != dbResource.Generation {
+		dbCopy.Status.ObservedGeneration = dbResource.Generation
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return c.updateStatus(dbCopy)
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// settings are the controller settings that can be changed while it runs
+// through the --config ConfigMap. Keys missing from the ConfigMap keep the
+// value of the flag of the same name.
+type settings struct {
+	PostgresURI        string
+	Dialect            string
+	Workers            int
+	DryRun             bool
+	PasswordEncryption string
+	LogLevel           string
+	PgBouncerConfigMap string
+
+	DatabaseNameTemplate string
+	RoleNameTemplate     string
+
+	MaxDatabases int
+}
+
+var (
+	settingsMu      sync.RWMutex
+	currentSettings settings
+)
+
+// getSettings returns the settings in effect.
+func getSettings() settings {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	return currentSettings
+}
+
+func setSettings(s settings) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	currentSettings = s
+}
+
+// flagSettings returns the settings given on the command line.
+func flagSettings() settings {
+	return settings{
+		PostgresURI:        postgresURL,
+		Dialect:            dialectName,
+		Workers:            workers,
+		DryRun:             dryRun,
+		PasswordEncryption: passwordEncryption,
+		LogLevel:           logLevel,
+		PgBouncerConfigMap: pgBouncerConfigMap,
+
+		DatabaseNameTemplate: databaseNameTemplate,
+		RoleNameTemplate:     roleNameTemplate,
+
+		MaxDatabases: maxDatabases,
+	}
+}
+
+// parseSettings overrides base with the keys of configMap. The admin URI may
+// be read from the DATABASE_URL key of the Secret named by
+// postgres-uri-secret, looked up with getSecret.
+func parseSettings(base settings, configMap *corev1.ConfigMap, getSecret func(namespace, name string) (*corev1.Secret, error)) (settings, error) {
+	s := base
+	keys := make([]string, 0, len(configMap.Data))
+	for key := range configMap.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := strings.TrimSpace(configMap.Data[key])
+		switch key {
+		case "postgres-uri":
+			s.PostgresURI = value
+		case "postgres-uri-secret":
+			secret, err := getSecret(configMap.Namespace, value)
+			if err != nil {
+				return s, fmt.Errorf("error reading postgres-uri-secret %q: %s", value, err.Error())
+			}
+			uri, ok := secret.Data["DATABASE_URL"]
+			if !ok {
+				return s, fmt.Errorf("secret %q has no DATABASE_URL key", value)
+			}
+			s.PostgresURI = string(uri)
+		case "dialect":
+			if _, err := lookupDialect(value); err != nil {
+				return s, err
+			}
+			s.Dialect = value
+		case "workers":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return s, fmt.Errorf("invalid workers %q, must be a positive number", value)
+			}
+			s.Workers = n
+		case "max-databases":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return s, fmt.Errorf("invalid max-databases %q, must be a number", value)
+			}
+			s.MaxDatabases = n
+		case "dry-run":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return s, fmt.Errorf("invalid dry-run %q: %s", value, err.Error())
+			}
+			s.DryRun = b
+		case "password-encryption":
+			switch value {
+			case "", passwordEncryptionMD5, passwordEncryptionScram:
+			default:
+				return s, fmt.Errorf("unknown password-encryption %q, must be md5 or scram-sha-256", value)
+			}
+			s.PasswordEncryption = value
+		case "log-level":
+			if _, err := zerolog.ParseLevel(value); err != nil {
+				return s, fmt.Errorf("invalid log-level %q: %s", value, err.Error())
+			}
+			s.LogLevel = value
+		case "pgbouncer-configmap":
+			s.PgBouncerConfigMap = value
+		case "database-name-template", "role-name-template":
+			if _, err := template.New(key).Parse(value); err != nil {
+				return s, fmt.Errorf("invalid %s %q: %s", key, value, err.Error())
+			}
+			if key == "database-name-template" {
+				s.DatabaseNameTemplate = value
+			} else {
+				s.RoleNameTemplate = value
+			}
+		default:
+			return s, fmt.Errorf("unknown setting %q", key)
+		}
+	}
+	return s, nil
+}
+
+// applySettings switches the controller from the settings in effect to s.
+// Nothing is changed when s can't be applied, e.g. when the new admin URI
+// does not connect.
+func (c *Controller) applySettings(s settings) error {
+	old := getSettings()
+	if s == old {
+		return nil
+	}
+
+	var inst *instance
+	if s.PostgresURI != old.PostgresURI || s.Dialect != old.Dialect {
+		d, err := lookupDialect(s.Dialect)
+		if err != nil {
+			return err
+		}
+		inst, err = openInstance(s.PostgresURI, d, flagTimeouts(), nil, c.instances.getDefault().azureAD)
+		if err != nil {
+			return fmt.Errorf("error connecting to postgres: %s", err.Error())
+		}
+	}
+	checkInst := inst
+	if checkInst == nil {
+		checkInst = c.instances.getDefault()
+	}
+	if err := checkPasswordEncryption(s.PasswordEncryption, checkInst); err != nil {
+		if inst != nil {
+			inst.DB.Close()
+		}
+		return err
+	}
+
+	if inst != nil {
+		previous := c.instances.setDefault(inst)
+		// Close lets the statements in flight finish.
+		previous.DB.Close()
+		log.Info().Str("dialect", inst.dialect.name).Str("serverVersion", inst.version.String()).Msg("Connected to postgres")
+	}
+	if lvl, err := zerolog.ParseLevel(s.LogLevel); err == nil {
+		zerolog.SetGlobalLevel(lvl)
+	}
+	setSettings(s)
+	c.setWorkers(s.Workers)
+	log.Info().Int("workers", s.Workers).Bool("dryRun", s.DryRun).Str("logLevel", s.LogLevel).Msg("Settings reloaded")
+	return nil
+}
+
+// reloadSettings applies the settings of configMap when it is the --config
+// ConfigMap.
+func (c *Controller) reloadSettings(obj interface{}) {
+	configMap, ok := obj.(*corev1.ConfigMap)
+	if !ok {
+		return
+	}
+	key, err := cache.MetaNamespaceKeyFunc(configMap)
+	if err != nil || key != configMapName {
+		return
+	}
+	s, err := parseSettings(flagSettings(), configMap, func(namespace, name string) (*corev1.Secret, error) {
+		return c.SecretsLister.Secrets(namespace).Get(name)
+	})
+	if err == nil {
+		err = c.applySettings(s)
+	}
+	if err != nil {
+		log.Error().Err(err).Str("configMap", key).Msg("error reloading settings, keeping the current ones")
+	}
+}
+
+// loadSettings reads the settings of the ConfigMap namespace/name at startup,
+// before the informers are running.
+func loadSettings(kubeClient kubernetes.Interface, key string, base settings) (settings, error) {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return base, fmt.Errorf("invalid --config %q: %s", key, err.Error())
+	}
+	configMap, err := kubeClient.CoreV1().ConfigMaps(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return base, fmt.Errorf("error reading config %q: %s", key, err.Error())
+	}
+	return parseSettings(base, configMap, func(namespace, name string) (*corev1.Secret, error) {
+		return kubeClient.CoreV1().Secrets(namespace).Get(name, metav1.GetOptions{})
+	})
+}
@@ -1,20 +1,22 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"reflect"
+	"sync"
 	"time"
 
-	"database/sql"
-
-	"github.com/golang/glog"
-	_ "github.com/lib/pq"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
+	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	batchlisters "k8s.io/client-go/listers/batch/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
@@ -25,6 +27,7 @@ import (
 	samplescheme "github.com/joshrendek/k8s-external-postgres/pkg/client/clientset/versioned/scheme"
 	informers "github.com/joshrendek/k8s-external-postgres/pkg/client/informers/externalversions"
 	listers "github.com/joshrendek/k8s-external-postgres/pkg/client/listers/postgresql/v1"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
@@ -39,10 +42,18 @@ const (
 
 	// MessageResourceExists is the message used for Events when a resource
 	// fails to sync due to a Deployment already existing
-	MessageResourceExists = "Resource %q already exists and is not managed by Foo"
+	MessageResourceExists = "Resource %q already exists and is not managed by the controller"
 	// MessageResourceSynced is the message used for an Event fired when a Foo
 	// is synced successfully
 	MessageResourceSynced = "Foo synced successfully"
+
+	// reconcileAnnotation retries the provisioning of a Database in the error
+	// state whenever its value changes, and runs every statement of a
+	// provisioned one, see planApplied.
+	reconcileAnnotation = "postgresql.org/reconcile"
+	// rotateAnnotation generates a new password for the read-only and
+	// application roles of a Database whenever its value changes.
+	rotateAnnotation = "postgresql.org/rotate"
 )
 
 // Controller is the controller implementation for Foo resources
@@ -52,8 +63,20 @@ type Controller struct {
 	// databaseClientset is a clientset for our own API group
 	databaseClientset clientset.Interface
 
-	DatabasesLister listers.DatabaseLister
-	DatabasesSynced cache.InformerSynced
+	DatabasesLister   listers.DatabaseLister
+	DatabasesSynced   cache.InformerSynced
+	SecretsLister     corelisters.SecretLister
+	SecretsSynced     cache.InformerSynced
+	ConfigMapsLister  corelisters.ConfigMapLister
+	ConfigMapsSynced  cache.InformerSynced
+	QuotasLister      listers.DatabaseQuotaLister
+	QuotasSynced      cache.InformerSynced
+	JobsLister        batchlisters.JobLister
+	JobsSynced        cache.InformerSynced
+	TablespacesLister listers.TablespaceLister
+	TablespacesSynced cache.InformerSynced
+	ClassesLister     listers.DatabaseClassLister
+	ClassesSynced     cache.InformerSynced
 
 	// workqueue is a rate limited work queue. This is used to queue work to be
 	// processed instead of performing it as soon as a change happens. This
@@ -61,78 +84,157 @@ type Controller struct {
 	// time, and makes it easy to ensure we are never processing the same item
 	// simultaneously in two different workers.
 	workqueue workqueue.RateLimitingInterface
+	// priorityQueue holds the Databases whose reconcile goes ahead of the
+	// bulk of provisioning, served by their own workers. keyLocks keeps a
+	// Database queued on both from being reconciled twice at once.
+	priorityQueue workqueue.RateLimitingInterface
+	keyLocks      keyLocks
 	// recorder is an event recorder for recording Event resources to the
 	// Kubernetes API.
 	recorder record.EventRecorder
-	DB       *sql.DB
+	// instances connects to the servers the databases are provisioned on.
+	instances *instanceRegistry
+
+	// workerStops has a channel per running worker, closed to stop it, so
+	// the number of workers can be changed at runtime.
+	workersMu   sync.Mutex
+	workerStops []chan struct{}
+	stopCh      <-chan struct{}
+	// workers tracks the running workers so shutdown can wait for them.
+	workers sync.WaitGroup
+	// ctx is passed to the reconciles and cancelled when a shutdown outlasts
+	// --shutdown-timeout.
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 // NewController returns a new sample controller
 func NewController(
 	kubeclientset kubernetes.Interface,
 	databaseClientset clientset.Interface,
-	databaseInformerFactory informers.SharedInformerFactory) *Controller {
+	kubeInformerFactory kubeinformers.SharedInformerFactory,
+	databaseInformerFactory informers.SharedInformerFactory,
+	instances *instanceRegistry) *Controller {
 
 	// obtain references to shared index informers for the Deployment and Foo
 	// types.
 	databaseInformer := databaseInformerFactory.Databases().V1().Databases()
+	secretInformer := kubeInformerFactory.Core().V1().Secrets()
+	configMapInformer := kubeInformerFactory.Core().V1().ConfigMaps()
+	quotaInformer := databaseInformerFactory.Databases().V1().DatabaseQuotas()
+	jobInformer := kubeInformerFactory.Batch().V1().Jobs()
+	tablespaceInformer := databaseInformerFactory.Databases().V1().Tablespaces()
+	classInformer := databaseInformerFactory.Databases().V1().DatabaseClasses()
 
-	// Create event broadcaster
-	// Add sample-controller types to the default Kubernetes Scheme so Events can be
-	// logged for sample-controller types.
-	samplescheme.AddToScheme(scheme.Scheme)
-	glog.V(4).Info("Creating event broadcaster")
-	eventBroadcaster := record.NewBroadcaster()
-	eventBroadcaster.StartLogging(glog.Infof)
-	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubeclientset.CoreV1().Events("")})
-	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: controllerAgentName})
-
-	db, err := sql.Open("postgres", postgresURL)
-	if err != nil {
-		panic(err)
-	}
-
-	if err := db.Ping(); err != nil {
-		panic(err)
-	}
+	recorder := newEventRecorder(kubeclientset)
 
 	controller := &Controller{
 		kubeclientset:     kubeclientset,
 		databaseClientset: databaseClientset,
 		DatabasesLister:   databaseInformer.Lister(),
 		DatabasesSynced:   databaseInformer.Informer().HasSynced,
+		SecretsLister:     secretInformer.Lister(),
+		SecretsSynced:     secretInformer.Informer().HasSynced,
+		ConfigMapsLister:  configMapInformer.Lister(),
+		ConfigMapsSynced:  configMapInformer.Informer().HasSynced,
+		QuotasLister:      quotaInformer.Lister(),
+		QuotasSynced:      quotaInformer.Informer().HasSynced,
+		JobsLister:        jobInformer.Lister(),
+		JobsSynced:        jobInformer.Informer().HasSynced,
+		TablespacesLister: tablespaceInformer.Lister(),
+		TablespacesSynced: tablespaceInformer.Informer().HasSynced,
+		ClassesLister:     classInformer.Lister(),
+		ClassesSynced:     classInformer.Informer().HasSynced,
 		workqueue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "Foos"),
+		priorityQueue:     workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "PriorityDatabases"),
 		recorder:          recorder,
-		DB:                db,
+		instances:         instances,
 	}
 
-	glog.Info("Setting up event handlers")
+	log.Info().Msg("Setting up event handlers")
+	if configMapName != "" {
+		configMapInformer.Informer().AddEventHandler(instrumentHandler("configmaps", "database", cache.ResourceEventHandlerFuncs{
+			AddFunc: controller.reloadSettings,
+			UpdateFunc: func(old, new interface{}) {
+				controller.reloadSettings(new)
+			},
+		}))
+	}
 	// Set up an event handler for when Foo resources change
-	databaseInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+	databaseInformer.Informer().AddEventHandler(instrumentHandler("databases", "database", cache.ResourceEventHandlerFuncs{
 		AddFunc: controller.enqueueDatabase,
 		UpdateFunc: func(old, new interface{}) {
+			databaseWrites.delivered(new.(*v1.Database))
 			controller.enqueueDatabase(new)
 		},
 		// can't call enqueueDatabase since it'll be deleted by the time the work queue gets it,
-		// handle it immediately instead
+		// handle it immediately instead. The credentials Secrets are owned by the
+		// Database and garbage collected by Kubernetes.
 		DeleteFunc: func(obj interface{}) {
 			dbResource := obj.(*v1.Database)
-
-			dbStmt := fmt.Sprintf("DROP DATABASE %s", dbResource.Spec.Database)
-			if _, err := db.Exec(dbStmt); err != nil {
-				fmt.Println("error deleting database: ", err)
+			databaseWrites.forget(dbResource.Namespace + "/" + dbResource.Name)
+			go controller.deleteDatabase(dbResource)
+		},
+	}))
+	// Databases are re-synced whenever a Secret or ConfigMap they reference
+	// changes, so edits to their password, initSQL script or migrations take
+	// effect.
+	secretInformer.Informer().AddEventHandler(instrumentHandler("secrets", "database", cache.ResourceEventHandlerFuncs{
+		AddFunc: controller.handleReferencedSecret,
+		UpdateFunc: func(old, new interface{}) {
+			if !resync(old, new) {
+				controller.handleReferencedSecret(new)
 			}
-
-			stmt := fmt.Sprintf("DROP ROLE %s", dbResource.Spec.Username)
-			if _, err := db.Exec(stmt); err != nil {
-				fmt.Println("error dropping user: ", err)
+		},
+		DeleteFunc: controller.handleReferencedSecret,
+	}))
+	configMapInformer.Informer().AddEventHandler(instrumentHandler("configmaps", "database", cache.ResourceEventHandlerFuncs{
+		AddFunc: controller.handleReferencedConfigMap,
+		UpdateFunc: func(old, new interface{}) {
+			if !resync(old, new) {
+				controller.handleReferencedConfigMap(new)
 			}
-			log.Debug().Str("database", dbResource.Spec.Database).Msg("dropping database")
 		},
-	})
+		DeleteFunc: controller.handleReferencedConfigMap,
+	}))
+	// Databases waiting for their DatabaseClass are provisioned once it is
+	// created.
+	classInformer.Informer().AddEventHandler(instrumentHandler("databaseclasses", "database", cache.ResourceEventHandlerFuncs{
+		AddFunc: controller.handleClass,
+		UpdateFunc: func(old, new interface{}) {
+			if !resync(old, new) {
+				controller.handleClass(new)
+			}
+		},
+	}))
+	// Clone Jobs are owned by the Database they restore into, re-sync it
+	// whenever one of them changes.
+	jobInformer.Informer().AddEventHandler(instrumentHandler("jobs", "database", cache.ResourceEventHandlerFuncs{
+		AddFunc: controller.handleJob,
+		UpdateFunc: func(old, new interface{}) {
+			controller.handleJob(new)
+		},
+	}))
 	return controller
 }
 
+// newEventRecorder returns an event recorder for recording Event resources
+// to the Kubernetes API on behalf of the controllers in this package, copying
+// the important ones to the --event-aggregator-namespace.
+func newEventRecorder(kubeclientset kubernetes.Interface) record.EventRecorder {
+	// Create event broadcaster
+	// Add sample-controller types to the default Kubernetes Scheme so Events can be
+	// logged for sample-controller types.
+	samplescheme.AddToScheme(scheme.Scheme)
+	log.Debug().Msg("Creating event broadcaster")
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartLogging(func(format string, args ...interface{}) {
+		log.Debug().Msgf(format, args...)
+	})
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubeclientset.CoreV1().Events("")})
+	return aggregateEvents(eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: controllerAgentName}))
+}
+
 // Run will set up the event handlers for types we are interested in, as well
 // as syncing informer caches and starting workers. It will block until stopCh
 // is closed, at which point it will shutdown the workqueue and wait for
@@ -140,47 +242,123 @@ func NewController(
 func (c *Controller) Run(threadiness int, stopCh <-chan struct{}) error {
 	defer runtime.HandleCrash()
 	defer c.workqueue.ShutDown()
+	defer c.priorityQueue.ShutDown()
 
 	// Start the informer factories to begin populating the informer caches
-	glog.Info("Starting Database controller")
+	log.Info().Msg("Starting Database controller")
 
 	// Wait for the caches to be synced before starting workers
-	glog.Info("Waiting for informer caches to sync")
-	if ok := cache.WaitForCacheSync(stopCh, c.DatabasesSynced); !ok {
+	log.Info().Msg("Waiting for informer caches to sync")
+	if ok := cache.WaitForCacheSync(stopCh, c.DatabasesSynced, c.SecretsSynced, c.ConfigMapsSynced, c.QuotasSynced, c.JobsSynced, c.TablespacesSynced, c.ClassesSynced, c.instances.HasSynced); !ok {
 		return fmt.Errorf("failed to wait for caches to sync")
 	}
 
-	glog.Info("Starting workers")
-	// Launch two workers to process Foo resources
-	for i := 0; i < threadiness; i++ {
-		go wait.Until(c.runWorker, time.Second, stopCh)
+	log.Info().Msg("Starting workers")
+	setNotReady("")
+	c.workersMu.Lock()
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.stopCh = stopCh
+	c.workersMu.Unlock()
+	defer c.cancel()
+	c.setWorkers(threadiness)
+	c.startPriorityWorkers()
+	go wait.Until(c.syncPgBouncerConfig, 10*time.Second, stopCh)
+	go wait.Until(c.syncQuotaUsage, 10*time.Second, stopCh)
+	go wait.Until(c.dropExpiredDatabases, time.Minute, stopCh)
+	if orphanAuditInterval > 0 {
+		go wait.Until(c.auditOrphans, orphanAuditInterval, stopCh)
+	}
+	if usageInterval > 0 {
+		go wait.Until(c.syncUsage, usageInterval, stopCh)
+	}
+	if slowQueryInterval > 0 {
+		go wait.Until(c.syncSlowQueries, slowQueryInterval, stopCh)
+	}
+	if idleSessionInterval > 0 {
+		go wait.Until(c.terminateIdleSessions, idleSessionInterval, stopCh)
+	}
+	if credentialsDriftInterval > 0 {
+		go wait.Until(c.syncCredentialsDrift, credentialsDriftInterval, stopCh)
+	}
+	if healthCheckInterval > 0 {
+		go wait.Until(c.instances.checkInstances, time.Second, stopCh)
 	}
 
-	glog.Info("Started workers")
+	log.Info().Msg("Started workers")
 	<-stopCh
-	glog.Info("Shutting down workers")
+	log.Info().Msg("Shutting down workers")
+	// Stop scaling the workers and let the running ones drain the queue.
+	c.workersMu.Lock()
+	c.stopCh = nil
+	c.workersMu.Unlock()
+	c.priorityQueue.ShutDown()
+	drainQueue(c.workqueue, &c.workers, c.cancel)
 
 	return nil
 }
 
+// setWorkers starts or stops workers until n are running. Stopped workers
+// finish the item they are processing first. It does nothing before Run has
+// started the initial workers, or once it is shutting down.
+func (c *Controller) setWorkers(n int) {
+	c.workersMu.Lock()
+	defer c.workersMu.Unlock()
+	if c.stopCh == nil {
+		return
+	}
+	for len(c.workerStops) < n {
+		stop := make(chan struct{})
+		c.workerStops = append(c.workerStops, stop)
+		c.workers.Add(1)
+		go func() {
+			defer c.workers.Done()
+			c.runWorker(stop)
+		}()
+	}
+	for len(c.workerStops) > n {
+		last := len(c.workerStops) - 1
+		close(c.workerStops[last])
+		c.workerStops = c.workerStops[:last]
+	}
+}
+
 // runWorker is a long-running function that will continually call the
 // processNextWorkItem function in order to read and process a message on the
-// workqueue.
-func (c *Controller) runWorker() {
-	for c.processNextWorkItem() {
+// workqueue, until stop is closed or the workqueue is drained.
+func (c *Controller) runWorker(stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		if !c.processNextWorkItem() {
+			return
+		}
 	}
 }
 
 // processNextWorkItem will read a single work item off the workqueue and
 // attempt to process it, by calling the syncHandler.
 func (c *Controller) processNextWorkItem() bool {
-	obj, shutdown := c.workqueue.Get()
+	return processNextWorkItem(c.ctx, c.workqueue, c.syncExclusive)
+}
+
+// processNextWorkItem reads a single namespace/name key off queue and hands it
+// to syncHandler, along with a logger tagged for this reconcile, re-queueing it
+// with back-off if syncing fails. It returns false once the queue is shut down
+// and drained, or ctx is cancelled.
+func processNextWorkItem(ctx context.Context, queue workqueue.RateLimitingInterface, syncHandler syncFunc) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	obj, shutdown := queue.Get()
 
 	if shutdown {
 		return false
 	}
 
-	// We wrap this block in a func so we can defer c.workqueue.Done.
+	// We wrap this block in a func so we can defer queue.Done.
 	err := func(obj interface{}) error {
 		// We call Done here so the workqueue knows we have finished
 		// processing this item. We also must remember to call Forget if we
@@ -188,7 +366,7 @@ func (c *Controller) processNextWorkItem() bool {
 		// not call Forget if a transient error occurs, instead the item is
 		// put back on the workqueue and attempted again after a back-off
 		// period.
-		defer c.workqueue.Done(obj)
+		defer queue.Done(obj)
 		var key string
 		var ok bool
 		// We expect strings to come off the workqueue. These are of the
@@ -200,19 +378,28 @@ func (c *Controller) processNextWorkItem() bool {
 			// As the item in the workqueue is actually invalid, we call
 			// Forget here else we'd go into a loop of attempting to
 			// process a work item that is invalid.
-			c.workqueue.Forget(obj)
+			queue.Forget(obj)
 			runtime.HandleError(fmt.Errorf("expected string in workqueue but got %#v", obj))
 			return nil
 		}
 		// Run the syncHandler, passing it the namespace/name string of the
 		// Foo resource to be synced.
-		if err := c.syncHandler(key); err != nil {
-			return fmt.Errorf("error syncing '%s': %s", key, err.Error())
+		ctx, logger := reconcileContext(ctx, key)
+		if err := syncHandler(ctx, logger, key); err != nil {
+			// Put the item back on the workqueue to handle any
+			// transient errors.
+			queue.AddRateLimited(key)
+			if _, locked := err.(databaseLocked); locked {
+				logger.Debug().Err(err).Msg("database locked, requeued")
+				return nil
+			}
+			logger.Error().Err(err).Msg("error syncing, requeued")
+			return nil
 		}
 		// Finally, if no error occurs we Forget this item so it does not
 		// get queued again until another change happens.
-		c.workqueue.Forget(obj)
-		glog.Infof("Successfully synced '%s'", key)
+		queue.Forget(obj)
+		logger.Debug().Msg("successfully synced")
 		return nil
 	}(obj)
 
@@ -227,7 +414,7 @@ func (c *Controller) processNextWorkItem() bool {
 // syncHandler compares the actual state with the desired, and attempts to
 // converge the two. It then updates the Status block of the Foo resource
 // with the current status of the resource.
-func (c *Controller) syncHandler(key string) error {
+func (c *Controller) syncHandler(ctx context.Context, logger zerolog.Logger, key string) error {
 	// Convert the namespace/name string into a distinct namespace and name
 	namespace, name, err := cache.SplitMetaNamespaceKey(key)
 	if err != nil {
@@ -248,62 +435,327 @@ func (c *Controller) syncHandler(key string) error {
 		return err
 	}
 
-	username := dbResource.Spec.Username
-	password := dbResource.Spec.Password
-	database := dbResource.Spec.Database
+	if databaseWrites.stale(key, dbResource) {
+		// the lister lags behind the status written by the last reconcile
+		logger.Debug().Str("resourceVersion", dbResource.ResourceVersion).Msg("reconciling stale database")
+		staleReconciles.Inc()
+	}
 
-	switch dbResource.Status.State {
-	case "provisioned":
-		log.Debug().Str("username", username).Str("database", database).Msg("already provisioned")
-	case "error":
-		log.Debug().Str("error", dbResource.Status.Message).Msg("error provisioning")
-	default:
-		log.Debug().Str("username", username).
-			Str("password", password).
-			Str("database", database).
-			Msg("provisioning")
-
-		stmt := fmt.Sprintf("CREATE USER %s WITH PASSWORD '%s'", username, password)
-		if _, err := c.DB.Exec(stmt); err != nil {
-			if err := c.updateFooStatus(dbResource, fmt.Sprintf("Error creating user: %s", err.Error()), "error"); err != nil {
-				return err
+	if done, err := c.syncProtection(logger, dbResource); done || err != nil {
+		return err
+	}
+	if paused(dbResource) {
+		logger.Debug().Msg("reconciling paused")
+		return nil
+	}
+	if expired, err := c.syncExpiry(logger, key, dbResource); expired || err != nil {
+		return err
+	}
+
+	state := dbResource.Status.State
+	if state == "conflict" && adoptExisting(dbResource) {
+		// adoption was allowed after the conflict was reported, retry
+		state = ""
+	}
+	retry := state == "error" && dbResource.Annotations[reconcileAnnotation] != dbResource.Status.ReconcileRequest
+	if retry {
+		logger.Info().Msg("retrying provisioning on request")
+		state = ""
+	}
+
+	if state == "" {
+		applied, err := c.applyClass(dbResource)
+		if err != nil {
+			c.recorder.Event(dbResource, corev1.EventTypeWarning, "InvalidClass", err.Error())
+			return err
+		}
+		if applied {
+			// the next reconcile provisions with the defaults of the class
+			return nil
+		}
+	}
+
+	if dbResource, err = c.markReconciling(dbResource, state); err != nil {
+		return err
+	}
+
+	if state == "" && needsPlacement(dbResource) {
+		if err := c.placeDatabase(logger, dbResource); err != nil {
+			c.recorder.Event(dbResource, corev1.EventTypeWarning, "PlacementFailed", err.Error())
+			return c.updateFooStatus(dbResource, err.Error(), "error")
+		}
+		// the next reconcile runs on the picked instance
+		return nil
+	}
+
+	inst, err := c.instances.forDatabase(dbResource)
+	if err != nil {
+		c.recorder.Event(dbResource, corev1.EventTypeWarning, "InstanceUnavailable", err.Error())
+		if denial := detectNetworkDenial(err.Error()); denial != nil {
+			if err := c.reportNetworkDenial(dbResource, denial); err != nil {
+				runtime.HandleError(err)
 			}
-			fmt.Println("error creating user: ", err)
 		}
+		return err
+	}
+	// The Databases of an unreachable server wait for the watchdog to find
+	// it back rather than failing on the broken connections.
+	unreachable := inst.unavailable()
+	if err := c.updateServerReachability(dbResource, unreachable); err != nil {
+		return err
+	}
+	if unreachable != nil {
+		logger.Debug().Err(unreachable).Msg("server unreachable, waiting")
+		c.workqueue.AddAfter(key, inst.retryAfter())
+		return nil
+	}
 
-		dbStmt := fmt.Sprintf("CREATE DATABASE %s OWNER %s", database, username)
-		if _, err := c.DB.Exec(dbStmt); err != nil {
-			if err := c.updateFooStatus(dbResource, fmt.Sprintf("Error creating database: %s", err.Error()), "error"); err != nil {
-				return err
+	if state == "provisioned" || state == "" {
+		updated, err := c.syncNames(dbResource)
+		if err != nil {
+			if state == "provisioned" {
+				return c.syncFailed(dbResource, "InvalidName", err)
 			}
+			c.recorder.Event(dbResource, corev1.EventTypeWarning, "InvalidName", err.Error())
+			return c.updateFooStatus(dbResource, err.Error(), "error")
+		}
+		if updated {
+			// the next reconcile runs with the recorded names
+			return nil
 		}
+	}
+	username := roleName(dbResource)
+	database := databaseName(dbResource)
+
+	// Another replica, or a script, running DDL on the database is waited
+	// for rather than raced: the reconcile is retried with back-off, from
+	// the priority queue for the Databases it serves.
+	unlock, err := lockDatabase(ctx, logger, inst, lockName(dbResource))
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
-		if err := c.updateFooStatus(dbResource, "successful", "provisioned"); err != nil {
+	switch state {
+	case "provisioned":
+		logger.Debug().Str("username", username).Str("database", database).Msg("already provisioned")
+		exec := newExecutor(ctx, dbResource, inst, logger)
+		m, err := maintenanceFor(dbResource, time.Now())
+		if err != nil {
+			return c.syncFailed(dbResource, "InvalidMaintenanceWindow", err)
+		}
+		renamed, err := c.syncDatabaseRename(logger, dbResource, inst, exec, m)
+		if err != nil {
+			return c.syncFailed(dbResource, "RenameFailed", err)
+		}
+		if renamed {
+			// the next reconcile runs with the new name
+			return nil
+		}
+		if err := checkAuthentication(dbResource); err != nil {
+			return c.syncFailed(dbResource, "InvalidSpec", err)
+		}
+		applied := planApplied(dbResource, exec.dryRun, time.Now())
+		if !applied.full {
+			logger.Debug().Strs("changed", applied.changedFields()).Msg("skipping the statements of the unchanged spec fields")
+		}
+		if err := c.syncSpecChanges(dbResource, inst, exec, m); err != nil {
+			return c.syncFailed(dbResource, "UpdateFailed", err)
+		}
+		if applied.run("connectionLimit") {
+			if err := c.syncConnectionLimits(dbResource, inst, exec); err != nil {
+				return c.syncFailed(dbResource, "ConnectionLimitFailed", err)
+			}
+		}
+		if applied.run("roleAttributes") {
+			if err := c.syncRoleAttributes(dbResource, inst, exec); err != nil {
+				return c.syncFailed(dbResource, "RoleAttributesFailed", err)
+			}
+		}
+		if err := c.syncDatabaseTablespace(dbResource, inst, exec); err != nil {
+			return c.syncFailed(dbResource, "TablespaceFailed", err)
+		}
+		if applied.run("revokePublic") {
+			if err := c.syncPublicPrivileges(dbResource, inst, exec); err != nil {
+				return c.syncFailed(dbResource, "PublicPrivilegesFailed", err)
+			}
+		}
+		if applied.run("ownership") {
+			if err := c.syncOwnershipComments(dbResource, inst, exec); err != nil {
+				return c.syncFailed(dbResource, "OwnershipCommentFailed", err)
+			}
+		}
+		if passwordExpiry > 0 && !passwordless(dbResource) {
+			if err := c.syncPasswordExpiry(dbResource, inst); err != nil {
+				return err
+			}
+		}
+		if applied.run("memberOf") {
+			if err := c.syncMemberships(dbResource, inst, exec); err != nil {
+				return c.syncFailed(dbResource, "MembershipFailed", err)
+			}
+		}
+		if applied.run("defaultPrivileges") {
+			if err := c.syncDefaultPrivileges(dbResource, inst, exec); err != nil {
+				return c.syncFailed(dbResource, "DefaultPrivilegesFailed", err)
+			}
+		}
+		if applied.run("extensions") {
+			if err := c.syncExtensions(dbResource, inst, exec); err != nil {
+				return c.syncFailed(dbResource, "ExtensionsFailed", err)
+			}
+		}
+		if !exec.dryRun {
+			if err := c.syncReplicaCredentials(dbResource); err != nil {
+				return c.syncFailed(dbResource, "CredentialsFailed", err)
+			}
+			if err := c.syncAuthentication(dbResource); err != nil {
+				return c.syncFailed(dbResource, "AuthenticationFailed", err)
+			}
+			if err := c.syncMirror(ctx, logger, dbResource, inst); err != nil {
+				return c.syncFailed(dbResource, "MirrorFailed", err)
+			}
+		}
+		if err := c.syncInitSQL(dbResource, inst, exec); err != nil {
+			return c.syncFailed(dbResource, "InitSQLFailed", err)
+		}
+		if err := c.syncMigrations(dbResource, inst, exec); err != nil {
+			return c.syncFailed(dbResource, "MigrationFailed", err)
+		}
+		if err := c.syncAppUser(dbResource, inst, exec); err != nil {
+			return c.syncFailed(dbResource, "AppUserFailed", err)
+		}
+		if applied.run("roleSettings") {
+			if err := c.syncRoleSettings(dbResource, inst, exec); err != nil {
+				return c.syncFailed(dbResource, "RoleSettingsFailed", err)
+			}
+		}
+		if rotate := dbResource.Annotations[rotateAnnotation]; rotate != dbResource.Status.RotateRequest && m.allow(actionPasswordRotation) {
+			if dbResource.Spec.ReadOnlyUser {
+				if err := c.rotateReadOnlyPassword(dbResource, inst, exec); err != nil {
+					c.recorder.Event(dbResource, corev1.EventTypeWarning, "RotationFailed", err.Error())
+					return err
+				}
+			}
+			if appRole(dbResource) {
+				if err := c.rotateAppPassword(dbResource, inst, exec); err != nil {
+					c.recorder.Event(dbResource, corev1.EventTypeWarning, "RotationFailed", err.Error())
+					return err
+				}
+			}
+			if !exec.dryRun {
+				if dbResource.Spec.ReadOnlyUser {
+					c.recorder.Event(dbResource, corev1.EventTypeNormal, "PasswordRotated", "Read-only password rotated")
+					notify(notifyPasswordRotated, dbResource, "Read-only password rotated")
+				}
+				if appRole(dbResource) {
+					c.recorder.Event(dbResource, corev1.EventTypeNormal, "PasswordRotated", "Application password rotated")
+					notify(notifyPasswordRotated, dbResource, "Application password rotated")
+				}
+				dbCopy := dbResource.DeepCopy()
+				dbCopy.Status.RotateRequest = rotate
+				return c.updateStatus(dbCopy)
+			}
+		}
+		if exec.dryRun {
+			return c.updatePlannedStatements(dbResource, exec.planned)
+		}
+		if canaryPending(dbResource) {
+			if err := c.checkCredentials(dbResource, inst); err != nil {
+				return c.syncFailed(dbResource, "CanaryFailed", err)
+			}
+		}
+		if err := c.markApplied(dbResource, m, applied); err != nil {
 			return err
 		}
+	case "cloning":
+		return c.syncClone(dbResource)
+	case "error":
+		logger.Debug().Str("error", dbResource.Status.Message).Msg("error provisioning")
+	case "conflict":
+		logger.Debug().Str("database", database).Msg("database exists and is not managed, refusing to adopt it")
+		return nil
+	default:
+		return c.provision(ctx, logger, dbResource, inst)
 	}
 	c.recorder.Event(dbResource, corev1.EventTypeNormal, SuccessSynced, MessageResourceSynced)
 	return nil
 }
 
 func (c *Controller) updateFooStatus(dbResource *dbv1alpha1.Database, message, state string) error {
+	if state == "error" && c.ctx != nil && c.ctx.Err() != nil {
+		// The reconcile was cancelled by a shutdown, leave it to the next
+		// start rather than making the error sticky.
+		return nil
+	}
+	if state == "error" && healthCheckInterval > 0 {
+		if inst, err := c.instances.forDatabase(dbResource); err == nil {
+			if err := inst.checkNow(); err != nil {
+				// The server went away mid-reconcile, retry once the
+				// watchdog finds it back rather than making the error
+				// sticky.
+				return fmt.Errorf("%s, server unreachable: %s", message, err.Error())
+			}
+		}
+	}
+	if state == "error" {
+		if denial := detectNetworkDenial(message); denial != nil {
+			// Access is granted outside of the controller, retry until it
+			// is rather than making the error sticky.
+			if err := c.reportNetworkDenial(dbResource, denial); err != nil {
+				return err
+			}
+			return fmt.Errorf("%s", message)
+		}
+	}
 	// NEVER modify objects from the store. It's a read-only, local cache.
 	// You can use DeepCopy() to make a deep copy of original object and modify this copy
 	// Or create a copy manually for better performance
 	dbCopy := dbResource.DeepCopy()
 	dbCopy.Status.Message = message
 	dbCopy.Status.State = state
-	// If the CustomResourceSubresources feature gate is not enabled,
-	// we must use Update instead of UpdateStatus to update the Status block of the Foo resource.
+	dbCopy.Status.Reason = statusReason(state, message)
+	dbCopy.Status.PlannedStatements = nil
+	dbCopy.Status.ReconcileRequest = dbResource.Annotations[reconcileAnnotation]
+	if findCondition(&dbCopy.Status, quotaExceededCondition) != nil {
+		setCondition(&dbCopy.Status, quotaExceededCondition, conditionFalse, "WithinQuota", "")
+	}
+	clearNetworkDenial(&dbCopy.Status)
+	if inst, err := c.instances.forDatabase(dbResource); err == nil {
+		dbCopy.Status.ServerVersion = inst.version.String()
+	}
+	dbCopy.Status.ObservedGeneration = dbResource.Generation
+	if state == "provisioned" {
+		dbCopy.Status.Progress = nil
+		setCondition(&dbCopy.Status, readyCondition, conditionTrue, "Provisioned", "")
+	} else {
+		setCondition(&dbCopy.Status, readyCondition, conditionFalse, notReadyReasons[state], message)
+	}
 	// UpdateStatus will not allow changes to the Spec of the resource,
 	// which is ideal for ensuring nothing other than resource status has been updated.
-	_, err := c.databaseClientset.DatabasesV1().Databases(dbResource.Namespace).Update(dbCopy)
+	err := c.updateStatus(dbCopy)
+	if err == nil && state == "error" && dbResource.Status.State != "error" {
+		c.recorder.Event(dbResource, corev1.EventTypeWarning, reasonProvisioningFailed, message)
+		notify(notifyProvisioningFailed, dbResource, message)
+	}
 	return err
 }
 
+// updatePlannedStatements records the statements a dry-run reconcile would
+// have executed, leaving the provisioning state untouched.
+func (c *Controller) updatePlannedStatements(dbResource *dbv1alpha1.Database, planned []string) error {
+	if reflect.DeepEqual(dbResource.Status.PlannedStatements, planned) {
+		return nil
+	}
+	dbCopy := dbResource.DeepCopy()
+	dbCopy.Status.PlannedStatements = planned
+	return c.updateStatus(dbCopy)
+}
+
 // enqueueDatabase takes a Foo resource and converts it into a namespace/name
-// string which is then put onto the work queue. This method should *not* be
-// passed resources of any type other than Foo.
+// string which is then put onto the work queue, or the priority queue when it
+// is a priority Database and there are --priority-workers. This method should
+// *not* be passed resources of any type other than Foo.
 func (c *Controller) enqueueDatabase(obj interface{}) {
 	var key string
 	var err error
@@ -311,5 +763,9 @@ func (c *Controller) enqueueDatabase(obj interface{}) {
 		runtime.HandleError(err)
 		return
 	}
+	if dbResource, ok := obj.(*v1.Database); ok && priorityWorkers > 0 && priorityDatabase(dbResource) {
+		c.priorityQueue.AddRateLimited(key)
+		return
+	}
 	c.workqueue.AddRateLimited(key)
 }
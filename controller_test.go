@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+	"github.com/joshrendek/k8s-external-postgres/pkg/client/clientset/versioned/fake"
+	informers "github.com/joshrendek/k8s-external-postgres/pkg/client/informers/externalversions"
+	"github.com/joshrendek/k8s-external-postgres/pkg/fakepg"
+	"github.com/joshrendek/k8s-external-postgres/pkg/provisioner"
+)
+
+// testFixture runs the Controller against a fake server, as with
+// --backend=fake, and fake clientsets.
+type testFixture struct {
+	t          *testing.T
+	server     *fakepg.Server
+	kubeclient *kubefake.Clientset
+	client     *fake.Clientset
+	controller *Controller
+	stopCh     chan struct{}
+}
+
+// newFixture starts the Controller on the fake server of host, emptied, with
+// the Databases dbs.
+func newFixture(t *testing.T, host string, dbs ...*v1.Database) *testFixture {
+	backend = backendFake
+	setSettings(flagSettings())
+	if err := setupPasswordPolicy(); err != nil {
+		t.Fatalf("setupPasswordPolicy: %s", err)
+	}
+	server := fakepg.Lookup(host)
+	server.Reset()
+	d, err := lookupDialect("postgres")
+	if err != nil {
+		t.Fatalf("lookupDialect: %s", err)
+	}
+	inst, err := openInstance("postgres://postgres:postgres@"+host+"/postgres", d, flagTimeouts(), nil, nil)
+	if err != nil {
+		t.Fatalf("openInstance: %s", err)
+	}
+
+	f := &testFixture{
+		t:          t,
+		server:     server,
+		kubeclient: kubefake.NewSimpleClientset(),
+		stopCh:     make(chan struct{}),
+	}
+	objects := []runtime.Object{}
+	for _, db := range dbs {
+		objects = append(objects, db)
+	}
+	f.client = fake.NewSimpleClientset(objects...)
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(f.kubeclient, 0)
+	databaseInformerFactory := informers.NewSharedInformerFactory(f.client, 0)
+	instances := newInstanceRegistry(inst, kubeInformerFactory, databaseInformerFactory)
+	f.controller = NewController(f.kubeclient, f.client, kubeInformerFactory, databaseInformerFactory, instances)
+	f.controller.ctx = context.Background()
+	kubeInformerFactory.Start(f.stopCh)
+	databaseInformerFactory.Start(f.stopCh)
+	for informer, synced := range kubeInformerFactory.WaitForCacheSync(f.stopCh) {
+		if !synced {
+			t.Fatalf("cache of %s not synced", informer)
+		}
+	}
+	for informer, synced := range databaseInformerFactory.WaitForCacheSync(f.stopCh) {
+		if !synced {
+			t.Fatalf("cache of %s not synced", informer)
+		}
+	}
+	return f
+}
+
+func (f *testFixture) stop() {
+	close(f.stopCh)
+	f.controller.instances.getDefault().DB.Close()
+}
+
+// get returns the Database called name as stored.
+func (f *testFixture) get(name string) *v1.Database {
+	db, err := f.client.DatabasesV1().Databases(metav1.NamespaceDefault).Get(name, metav1.GetOptions{})
+	if err != nil {
+		f.t.Fatalf("get database %s: %s", name, err)
+	}
+	return db
+}
+
+// update stores db, waiting for the lister to see it.
+func (f *testFixture) update(db *v1.Database) {
+	if _, err := f.client.DatabasesV1().Databases(db.Namespace).Update(db); err != nil {
+		f.t.Fatalf("update database %s: %s", db.Name, err)
+	}
+	f.waitSynced(db.Name)
+}
+
+// waitSynced waits for the lister to catch up with the Database called name.
+func (f *testFixture) waitSynced(name string) {
+	err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		cached, err := f.controller.DatabasesLister.Databases(metav1.NamespaceDefault).Get(name)
+		if err != nil {
+			return false, nil
+		}
+		return cached.ResourceVersion == f.get(name).ResourceVersion, nil
+	})
+	if err != nil {
+		f.t.Fatalf("lister not synced with database %s: %s", name, err)
+	}
+}
+
+// reconcileUntil reconciles the Database called name, as the workqueue does,
+// until done holds for it.
+func (f *testFixture) reconcileUntil(name string, done func(*v1.Database) bool) *v1.Database {
+	key := metav1.NamespaceDefault + "/" + name
+	for i := 0; i < 20; i++ {
+		f.waitSynced(name)
+		if db := f.get(name); done(db) {
+			return db
+		}
+		if err := f.controller.syncHandler(context.Background(), zerolog.Nop(), key); err != nil {
+			f.t.Logf("reconcile %d of %s: %s", i, key, err)
+		}
+	}
+	db := f.get(name)
+	f.t.Fatalf("database %s not reconciled, status %+v", name, db.Status)
+	return db
+}
+
+func inState(state string) func(*v1.Database) bool {
+	return func(db *v1.Database) bool { return db.Status.State == state }
+}
+
+func testDatabase(name string, annotations map[string]string) *v1.Database {
+	return &v1.Database{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   metav1.NamespaceDefault,
+			Annotations: annotations,
+		},
+		Spec: v1.DatabaseConfig{
+			Username: name,
+			Password: "Fake-Passw0rd-" + name,
+			Database: name,
+		},
+	}
+}
+
+func TestReconcileProvisions(t *testing.T) {
+	f := newFixture(t, "reconcile-provisions", testDatabase("app", nil))
+	defer f.stop()
+
+	db := f.reconcileUntil("app", inState("provisioned"))
+	if c := findCondition(&db.Status, readyCondition); c == nil || c.Status != conditionTrue {
+		t.Errorf("Ready condition = %+v, want True", c)
+	}
+	if db.Status.Progress != nil {
+		t.Errorf("progress = %+v, want cleared once provisioned", db.Status.Progress)
+	}
+	if d := f.server.Database("app"); d == nil || d.Owner != "app" {
+		t.Errorf("database app = %+v, want owned by app", d)
+	}
+	if r := f.server.Role("app"); r == nil || !r.Login {
+		t.Errorf("role app = %+v, want a login", r)
+	}
+	if _, err := f.kubeclient.CoreV1().Secrets(metav1.NamespaceDefault).Get("app", metav1.GetOptions{}); err != nil {
+		t.Errorf("credentials secret: %s", err)
+	}
+
+	f.controller.deleteDatabase(db)
+	if f.server.Database("app") != nil {
+		t.Errorf("database app kept after the Database was deleted")
+	}
+	if f.server.Role("app") != nil {
+		t.Errorf("role app kept after the Database was deleted")
+	}
+}
+
+func TestReconcileConflict(t *testing.T) {
+	f := newFixture(t, "reconcile-conflict", testDatabase("app", nil))
+	defer f.stop()
+	// created by someone else before the Database
+	other := fakepg.NewProvisioner("reconcile-conflict", provisioner.Options{DatabaseOwner: true})
+	if err := other.CreateDatabase(context.Background(), "app", "app", "other"); err != nil {
+		t.Fatalf("CreateDatabase: %s", err)
+	}
+
+	db := f.reconcileUntil("app", inState("conflict"))
+	if db.Status.Message == "" {
+		t.Errorf("conflict reported without a message")
+	}
+
+	f.controller.deleteDatabase(db)
+	if f.server.Database("app") == nil || f.server.Role("app") == nil {
+		t.Errorf("objects of the conflict dropped with the Database")
+	}
+}
+
+func TestReconcileAdoption(t *testing.T) {
+	allowAdoption = true
+	defer func() { allowAdoption = false }()
+	allow := testDatabase("app", nil)
+	allow.Spec.AllowAdoption = true
+	adopt := testDatabase("missing", nil)
+	adopt.Spec.AdoptExisting = true
+	f := newFixture(t, "reconcile-adoption", allow, adopt)
+	defer f.stop()
+	other := fakepg.NewProvisioner("reconcile-adoption", provisioner.Options{DatabaseOwner: true})
+	if err := other.CreateDatabase(context.Background(), "app", "legacy", "other"); err != nil {
+		t.Fatalf("CreateDatabase: %s", err)
+	}
+
+	db := f.reconcileUntil("app", inState("provisioned"))
+	if db.Status.Adoption == nil || db.Status.Adoption.Owner != "legacy" {
+		t.Errorf("adoption = %+v, want the database owned by legacy", db.Status.Adoption)
+	}
+	if d := f.server.Database("app"); d == nil || d.Owner != "app" {
+		t.Errorf("database app = %+v, want handed over to app", d)
+	}
+
+	// adoptExisting doesn't create what it was to adopt
+	f.reconcileUntil("missing", inState("error"))
+	if f.server.Database("missing") != nil {
+		t.Errorf("database missing created instead of adopted")
+	}
+}
+
+func TestReconcileFailure(t *testing.T) {
+	f := newFixture(t, "reconcile-failure", testDatabase("app", nil))
+	defer f.stop()
+	f.server.FailOn(regexp.MustCompile(`^CREATE DATABASE`), "53100")
+
+	db := f.reconcileUntil("app", inState("error"))
+	if db.Status.Message == "" {
+		t.Errorf("error reported without a message")
+	}
+	if c := findCondition(&db.Status, readyCondition); c == nil || c.Status != conditionFalse {
+		t.Errorf("Ready condition = %+v, want False", c)
+	}
+	if f.server.Database("app") != nil {
+		t.Errorf("database app created despite the failure")
+	}
+}
+
+func TestProtectionFinalizer(t *testing.T) {
+	f := newFixture(t, "protection", testDatabase("app", map[string]string{protectedAnnotation: "true"}))
+	defer f.stop()
+
+	db := f.reconcileUntil("app", func(db *v1.Database) bool {
+		return hasFinalizer(db, protectionFinalizer) && db.Status.State == "provisioned"
+	})
+
+	// the deletion waits for the annotation to be removed
+	now := metav1.Now()
+	db.DeletionTimestamp = &now
+	f.update(db)
+	db = f.reconcileUntil("app", func(db *v1.Database) bool {
+		c := findCondition(&db.Status, deletionBlockedCondition)
+		return c != nil && c.Status == conditionTrue
+	})
+	if !hasFinalizer(db, protectionFinalizer) {
+		t.Errorf("finalizer removed from the protected Database")
+	}
+
+	delete(db.Annotations, protectedAnnotation)
+	f.update(db)
+	f.reconcileUntil("app", func(db *v1.Database) bool {
+		return !hasFinalizer(db, protectionFinalizer)
+	})
+}
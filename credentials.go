@@ -0,0 +1,112 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+	"github.com/joshrendek/k8s-external-postgres/pkg/provisioner"
+)
+
+const (
+	credentialStoreSecret         = "secret"
+	credentialStoreVault          = "vault"
+	credentialStoreExternalSecret = "externalSecret"
+)
+
+// credentialStore persists the connection details of the roles of a
+// Database, under the name of the Secret they are exposed as.
+type credentialStore interface {
+	// Get returns the credentials stored under name, nil when there are
+	// none.
+	Get(dbResource *v1.Database, name string) (map[string]string, error)
+	// Put writes the credentials stored under name.
+	Put(dbResource *v1.Database, name string, data map[string]string) error
+	// Delete removes the credentials stored under name once dbResource is
+	// deleted.
+	Delete(dbResource *v1.Database, name string) error
+}
+
+// credentialStore returns the store the credentials of dbResource are
+// written to, as selected by spec.credentialStore.
+func (c *Controller) credentialStore(dbResource *v1.Database) (credentialStore, error) {
+	spec := dbResource.Spec.CredentialStore
+	if spec == nil {
+		return &secretStore{c: c}, nil
+	}
+	switch spec.Type {
+	case "", credentialStoreSecret:
+		return &secretStore{c: c}, nil
+	case credentialStoreVault:
+		if vault == nil {
+			return nil, fmt.Errorf("credential store vault requires --vault-addr")
+		}
+		return &vaultStore{vault: vault}, nil
+	case credentialStoreExternalSecret:
+		if vault == nil {
+			return nil, fmt.Errorf("credential store externalSecret requires --vault-addr")
+		}
+		if spec.SecretStoreRef == nil || spec.SecretStoreRef.Name == "" {
+			return nil, fmt.Errorf("credential store externalSecret requires spec.credentialStore.secretStoreRef")
+		}
+		return &externalSecretStore{vaultStore: vaultStore{vault: vault}, kubeclientset: c.kubeclientset}, nil
+	default:
+		return nil, fmt.Errorf("unknown credential store %q, must be secret, vault or externalSecret", spec.Type)
+	}
+}
+
+// credentialNames returns the names the credentials of the roles of
+// dbResource are stored under.
+func credentialNames(dbResource *v1.Database) []string {
+	names := []string{dbResource.Name}
+	if dbResource.Spec.ReadOnlyUser {
+		names = append(names, dbResource.Name+readOnlySecretSuffix)
+	}
+	if appRole(dbResource) {
+		names = append(names, dbResource.Name+appSecretSuffix)
+	}
+	return names
+}
+
+// hasCredentialsSecret reports whether the credentials of dbResource are
+// exposed as a Secret, which the backup, restore and clone Jobs read.
+func hasCredentialsSecret(dbResource *v1.Database) bool {
+	spec := dbResource.Spec.CredentialStore
+	return spec == nil || spec.Type != credentialStoreVault
+}
+
+// openAsOwner opens a connection to the database of dbResource logged in as
+// its owner role username with password, rather than as the admin role. The
+// SQL written by the authors of Databases runs on it, so it can't do more
+// than the applications of the Database. Roles logging in without a
+// password, or whose password is only known as a verifier, can't run it.
+func openAsOwner(dbResource *v1.Database, inst *instance, username, password string) (*sql.DB, error) {
+	if password == "" || provisioner.IsPasswordVerifier(password) {
+		return nil, fmt.Errorf("the SQL of Databases runs logged in as role %s, which requires its plaintext password", username)
+	}
+	dsn, err := inst.databaseURL(databaseName(dbResource), username, password)
+	if err != nil {
+		return nil, err
+	}
+	if dsn, err = sessionDSN(dsn, inst.timeouts); err != nil {
+		return nil, err
+	}
+	return openDSN(dsn, inst.tunnel, nil)
+}
+
+// openStoredOwner opens a connection like openAsOwner with the credentials
+// stored for the owner role of dbResource.
+func (c *Controller) openStoredOwner(dbResource *v1.Database, inst *instance) (*sql.DB, error) {
+	store, err := c.credentialStore(dbResource)
+	if err != nil {
+		return nil, err
+	}
+	credentials, err := c.liveCredentials(store, dbResource, dbResource.Name)
+	if err != nil {
+		return nil, err
+	}
+	if credentials == nil {
+		return nil, fmt.Errorf("credentials %s are not stored", dbResource.Name)
+	}
+	return openAsOwner(dbResource, inst, credentials["USERNAME"], credentials["PASSWORD"])
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/rs/zerolog"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+const (
+	// danglingLabel is set on the ConfigMaps reporting the Databases whose
+	// database couldn't be dropped once deleted, with --dangling-reports.
+	danglingLabel = "postgresql.org/dangling"
+	// danglingDatabaseKey holds the deleted Database as JSON.
+	danglingDatabaseKey = "database"
+	// danglingErrorKey holds the error the drop failed with.
+	danglingErrorKey = "error"
+)
+
+// Objects of a deleted Database whose cleanup can fail, as counted by the
+// object label of external_postgres_database_drop_failures_total.
+const (
+	dropObjectInstance    = "instance"
+	dropObjectHook        = "hook"
+	dropObjectDatabase    = "database"
+	dropObjectRole        = "role"
+	dropObjectCredentials = "credentials"
+)
+
+// danglingName returns the name of the ConfigMap reporting the database of
+// the deleted Database name as left behind.
+func danglingName(name string) string {
+	return name + "-dangling"
+}
+
+// deletionFailed reports that object of the deleted dbResource couldn't be
+// cleaned up: a DeletionFailed event is recorded on it, in its namespace, and
+// the failure is counted. With --dangling-reports, the failures leaving its
+// database behind are also recorded in a ConfigMap of the namespace.
+func (c *Controller) deletionFailed(logger zerolog.Logger, dbResource *v1.Database, object string, err error) {
+	databaseDropFailures.WithLabelValues(dbResource.Namespace, object).Inc()
+	c.recorder.Event(dbResource, corev1.EventTypeWarning, "DeletionFailed", fmt.Sprintf("Error deleting %s: %s", object, err.Error()))
+	if !danglingReports || object == dropObjectRole || object == dropObjectCredentials {
+		return
+	}
+	if err := c.reportDangling(dbResource, err); err != nil {
+		logger.Error().Err(err).Msg("error reporting dangling database")
+	}
+}
+
+// reportDangling records the deleted dbResource, whose database couldn't be
+// dropped with dropErr, in its dangling ConfigMap.
+func (c *Controller) reportDangling(dbResource *v1.Database, dropErr error) error {
+	dbJSON, err := json.Marshal(dbResource)
+	if err != nil {
+		return err
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      danglingName(dbResource.Name),
+			Namespace: dbResource.Namespace,
+			Labels:    map[string]string{danglingLabel: "true"},
+		},
+		Data: map[string]string{
+			danglingDatabaseKey: string(dbJSON),
+			danglingErrorKey:    dropErr.Error(),
+		},
+	}
+	configMaps := c.kubeclientset.CoreV1().ConfigMaps(dbResource.Namespace)
+	_, err = configMaps.Create(configMap)
+	if errors.IsAlreadyExists(err) {
+		_, err = configMaps.Update(configMap)
+	}
+	return err
+}
+
+// clearDangling deletes the dangling ConfigMap of the deleted dbResource once
+// its database is dropped, e.g. by a later drop of the same name.
+func (c *Controller) clearDangling(logger zerolog.Logger, dbResource *v1.Database) {
+	configMap, err := c.ConfigMapsLister.ConfigMaps(dbResource.Namespace).Get(danglingName(dbResource.Name))
+	if err != nil || configMap.Labels[danglingLabel] != "true" {
+		return
+	}
+	err = c.kubeclientset.CoreV1().ConfigMaps(configMap.Namespace).Delete(configMap.Name, &metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		logger.Error().Err(err).Msg("error deleting dangling database report")
+	}
+}
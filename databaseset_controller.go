@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"text/template"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+	clientset "github.com/joshrendek/k8s-external-postgres/pkg/client/clientset/versioned"
+	informers "github.com/joshrendek/k8s-external-postgres/pkg/client/informers/externalversions"
+	listers "github.com/joshrendek/k8s-external-postgres/pkg/client/listers/postgresql/v1"
+)
+
+const (
+	// databaseSetLabel is set to the DatabaseSet name on the Databases it
+	// creates.
+	databaseSetLabel = "postgresql.org/database-set"
+	// tenantLabel is set to the tenant on the Databases of a DatabaseSet.
+	tenantLabel = "postgresql.org/tenant"
+
+	// defaultTenantNameTemplate names the database and owner role of a tenant
+	// when the template leaves them empty.
+	defaultTenantNameTemplate = "{{ .Set }}_{{ .Tenant }}"
+)
+
+// tenantPattern is what tenant names look like, so <set>-<tenant> is a valid
+// resource name.
+var tenantPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// tenantData is what the username and database templates of a DatabaseSet
+// are executed with.
+type tenantData struct {
+	Set    string
+	Tenant string
+	Index  int
+}
+
+// DatabaseSetController fans DatabaseSets out into a Database per tenant and
+// aggregates their state.
+type DatabaseSetController struct {
+	kubeclientset     kubernetes.Interface
+	databaseClientset clientset.Interface
+
+	DatabaseSetsLister listers.DatabaseSetLister
+	DatabaseSetsSynced cache.InformerSynced
+	DatabasesLister    listers.DatabaseLister
+	DatabasesSynced    cache.InformerSynced
+
+	queue    workqueue.RateLimitingInterface
+	recorder record.EventRecorder
+}
+
+// NewDatabaseSetController returns a new database set controller
+func NewDatabaseSetController(
+	kubeclientset kubernetes.Interface,
+	databaseClientset clientset.Interface,
+	databaseInformerFactory informers.SharedInformerFactory) *DatabaseSetController {
+
+	setInformer := databaseInformerFactory.Databases().V1().DatabaseSets()
+	databaseInformer := databaseInformerFactory.Databases().V1().Databases()
+
+	controller := &DatabaseSetController{
+		kubeclientset:      kubeclientset,
+		databaseClientset:  databaseClientset,
+		DatabaseSetsLister: setInformer.Lister(),
+		DatabaseSetsSynced: setInformer.Informer().HasSynced,
+		DatabasesLister:    databaseInformer.Lister(),
+		DatabasesSynced:    databaseInformer.Informer().HasSynced,
+		queue:              workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "DatabaseSets"),
+		recorder:           newEventRecorder(kubeclientset),
+	}
+
+	log.Info().Msg("Setting up database set event handlers")
+	setInformer.Informer().AddEventHandler(instrumentHandler("databasesets", "databaseset", cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			enqueue(controller.queue, obj)
+		},
+		UpdateFunc: func(old, new interface{}) {
+			if old.(*v1.DatabaseSet).ResourceVersion == new.(*v1.DatabaseSet).ResourceVersion {
+				return
+			}
+			enqueue(controller.queue, new)
+		},
+		// the Databases of a deleted DatabaseSet are deleted by the garbage
+		// collector through their owner reference
+	}))
+	// the state of the set follows the state of its Databases
+	databaseInformer.Informer().AddEventHandler(instrumentHandler("databases", "databaseset", cache.ResourceEventHandlerFuncs{
+		AddFunc: controller.enqueueSet,
+		UpdateFunc: func(old, new interface{}) {
+			if old.(*v1.Database).ResourceVersion == new.(*v1.Database).ResourceVersion {
+				return
+			}
+			controller.enqueueSet(new)
+		},
+		DeleteFunc: controller.enqueueSet,
+	}))
+	return controller
+}
+
+// enqueueSet queues the DatabaseSet the Database obj belongs to, if any.
+func (c *DatabaseSetController) enqueueSet(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	dbResource, ok := obj.(*v1.Database)
+	if !ok {
+		return
+	}
+	ref := metav1.GetControllerOf(dbResource)
+	if ref == nil || ref.Kind != "DatabaseSet" {
+		return
+	}
+	c.queue.AddRateLimited(dbResource.Namespace + "/" + ref.Name)
+}
+
+// Run waits for the informer caches to sync and starts the workers. It blocks
+// until stopCh is closed.
+func (c *DatabaseSetController) Run(threadiness int, stopCh <-chan struct{}) error {
+	defer runtime.HandleCrash()
+
+	log.Info().Msg("Starting database set controller")
+	if ok := cache.WaitForCacheSync(stopCh, c.DatabaseSetsSynced, c.DatabasesSynced); !ok {
+		return fmt.Errorf("failed to wait for caches to sync")
+	}
+
+	runQueueWorkers(c.queue, threadiness, c.syncDatabaseSet, stopCh)
+	log.Info().Msg("Shutting down database set workers")
+	return nil
+}
+
+// tenants returns the tenants of set, from its list or count.
+func tenants(set *v1.DatabaseSet) ([]string, error) {
+	if len(set.Spec.Tenants) > 0 && set.Spec.Count > 0 {
+		return nil, fmt.Errorf("only one of tenants and count can be set")
+	}
+	if set.Spec.Count > 0 {
+		names := make([]string, set.Spec.Count)
+		for i := range names {
+			names[i] = strconv.Itoa(i)
+		}
+		return names, nil
+	}
+	seen := map[string]bool{}
+	for _, tenant := range set.Spec.Tenants {
+		if !tenantPattern.MatchString(tenant) {
+			return nil, fmt.Errorf("invalid tenant %q, must be lowercase letters, digits and dashes", tenant)
+		}
+		if seen[tenant] {
+			return nil, fmt.Errorf("duplicate tenant %q", tenant)
+		}
+		seen[tenant] = true
+	}
+	return set.Spec.Tenants, nil
+}
+
+// tenantDatabaseName returns the name of the Database of tenant.
+func tenantDatabaseName(set *v1.DatabaseSet, tenant string) string {
+	return set.Name + "-" + tenant
+}
+
+// renderTenantName executes the username or database template tmpl of a
+// DatabaseSet for a tenant.
+func renderTenantName(tmpl string, data tenantData) (string, error) {
+	if tmpl == "" {
+		tmpl = defaultTenantNameTemplate
+	}
+	t, err := template.New("tenant").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid template %q: %s", tmpl, err.Error())
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("error executing template %q: %s", tmpl, err.Error())
+	}
+	return buf.String(), nil
+}
+
+// tenantDatabase returns the Database of the index-th tenant of set, the
+// password of existing kept unless the template sets one.
+func tenantDatabase(set *v1.DatabaseSet, tenant string, index int, existing *v1.Database) (*v1.Database, error) {
+	data := tenantData{Set: set.Name, Tenant: tenant, Index: index}
+	spec := *set.Spec.Template.Spec.DeepCopy()
+	var err error
+	if spec.Username, err = renderTenantName(spec.Username, data); err != nil {
+		return nil, err
+	}
+	if spec.Database, err = renderTenantName(spec.Database, data); err != nil {
+		return nil, err
+	}
+	if spec.Password == "" && existing != nil {
+		spec.Password = existing.Spec.Password
+	} else if spec.Password == "" {
+		if spec.Password, err = generatePassword(); err != nil {
+			return nil, err
+		}
+	}
+
+	dbLabels := map[string]string{}
+	for key, value := range set.Spec.Template.Labels {
+		dbLabels[key] = value
+	}
+	dbLabels[databaseSetLabel] = set.Name
+	dbLabels[tenantLabel] = tenant
+	return &v1.Database{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            tenantDatabaseName(set, tenant),
+			Namespace:       set.Namespace,
+			Labels:          dbLabels,
+			Annotations:     set.Spec.Template.Annotations,
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(set, v1.SchemeGroupVersion.WithKind("DatabaseSet"))},
+		},
+		Spec: spec,
+	}, nil
+}
+
+// syncDatabaseSet creates or updates the Database of every tenant of a
+// DatabaseSet, deletes the ones of the tenants removed and records how many
+// are provisioned.
+func (c *DatabaseSetController) syncDatabaseSet(ctx context.Context, logger zerolog.Logger, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		runtime.HandleError(fmt.Errorf("invalid resource key: %s", key))
+		return nil
+	}
+
+	set, err := c.DatabaseSetsLister.DatabaseSets(namespace).Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			runtime.HandleError(fmt.Errorf("database set '%s' in work queue no longer exists", key))
+			return nil
+		}
+		return err
+	}
+
+	names, err := tenants(set)
+	if err != nil {
+		return c.updateSetStatus(set, v1.DatabaseSetStatus{State: "error", Message: err.Error()})
+	}
+
+	databases := c.databaseClientset.DatabasesV1().Databases(namespace)
+	status := v1.DatabaseSetStatus{Tenants: len(names)}
+	wanted := map[string]bool{}
+	for i, tenant := range names {
+		dbName := tenantDatabaseName(set, tenant)
+		wanted[dbName] = true
+		existing, err := c.DatabasesLister.Databases(namespace).Get(dbName)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		if existing != nil && !metav1.IsControlledBy(existing, set) {
+			status.Failed = append(status.Failed, tenant)
+			c.recorder.Event(set, corev1.EventTypeWarning, "TenantConflict", fmt.Sprintf("Database %s already exists and isn't part of the set", dbName))
+			continue
+		}
+		desired, err := tenantDatabase(set, tenant, i, existing)
+		if err != nil {
+			return c.updateSetStatus(set, v1.DatabaseSetStatus{State: "error", Message: err.Error(), Tenants: len(names)})
+		}
+
+		if existing == nil {
+			logger.Info().Str("tenant", tenant).Msg("creating tenant database")
+			if _, err := databases.Create(desired); err != nil && !errors.IsAlreadyExists(err) {
+				return err
+			}
+			continue
+		}
+		if !reflect.DeepEqual(existing.Spec, desired.Spec) || !reflect.DeepEqual(existing.Labels, desired.Labels) ||
+			!reflect.DeepEqual(existing.Annotations, desired.Annotations) {
+			dbCopy := existing.DeepCopy()
+			dbCopy.Spec = desired.Spec
+			dbCopy.Labels = desired.Labels
+			dbCopy.Annotations = desired.Annotations
+			if _, err := databases.Update(dbCopy); err != nil {
+				return err
+			}
+		}
+		switch existing.Status.State {
+		case "provisioned":
+			status.Provisioned++
+		case "error", "conflict":
+			status.Failed = append(status.Failed, tenant)
+		}
+	}
+
+	// the Databases of the tenants removed from the set
+	owned, err := c.DatabasesLister.Databases(namespace).List(labels.SelectorFromSet(labels.Set{databaseSetLabel: set.Name}))
+	if err != nil {
+		return err
+	}
+	for _, dbResource := range owned {
+		if wanted[dbResource.Name] || !metav1.IsControlledBy(dbResource, set) || dbResource.DeletionTimestamp != nil {
+			continue
+		}
+		logger.Info().Str("tenant", dbResource.Labels[tenantLabel]).Msg("tenant removed, deleting its database")
+		if err := databases.Delete(dbResource.Name, &metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	switch {
+	case len(status.Failed) > 0:
+		status.State = "error"
+		status.Message = fmt.Sprintf("%d of %d tenants failed", len(status.Failed), status.Tenants)
+	case status.Provisioned == status.Tenants:
+		status.State = "provisioned"
+		status.Message = fmt.Sprintf("%d tenants provisioned", status.Tenants)
+	default:
+		status.State = "provisioning"
+		status.Message = fmt.Sprintf("%d of %d tenants provisioned", status.Provisioned, status.Tenants)
+	}
+	if status.State == "provisioned" && set.Status.State != "provisioned" {
+		c.recorder.Event(set, corev1.EventTypeNormal, SuccessSynced, status.Message)
+	}
+	return c.updateSetStatus(set, status)
+}
+
+// updateSetStatus writes status unless it is already recorded.
+func (c *DatabaseSetController) updateSetStatus(set *v1.DatabaseSet, status v1.DatabaseSetStatus) error {
+	if reflect.DeepEqual(set.Status, status) {
+		return nil
+	}
+	setCopy := set.DeepCopy()
+	setCopy.Status = status
+	_, err := c.databaseClientset.DatabasesV1().DatabaseSets(set.Namespace).Update(setCopy)
+	return err
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// ddlSlots are the semaphores serializing the statements of the controller
+// per server. With many Databases applied at once, concurrent CREATE
+// DATABASE, CREATE ROLE and GRANT statements contend for the same catalog
+// locks and deadlock, so at most --max-concurrent-ddl of them run on a server
+// at a time, the others waiting in the order they arrived. Priority
+// statements, the drops and password rotations, go ahead of the others.
+var ddlSlots = struct {
+	mu      sync.Mutex
+	servers map[string]*ddlSlot
+}{servers: map[string]*ddlSlot{}}
+
+// ddlSlot is a semaphore whose free places are handed to the priority
+// waiters first.
+type ddlSlot struct {
+	mu   sync.Mutex
+	free int
+	// waiters are the channels of the waiting statements, closed when they
+	// are handed a place, the priority ones first.
+	waiters [2][]chan struct{}
+}
+
+// ddlSlot returns the semaphore of the server of inst, shared by the
+// instances reaching the same server with other credentials. It is nil when
+// the statements are not serialized.
+func (i *instance) ddlSlot() *ddlSlot {
+	if maxConcurrentDDL <= 0 {
+		return nil
+	}
+	server := net.JoinHostPort(i.hostPort())
+	ddlSlots.mu.Lock()
+	defer ddlSlots.mu.Unlock()
+	slot, ok := ddlSlots.servers[server]
+	if !ok {
+		slot = &ddlSlot{free: maxConcurrentDDL}
+		ddlSlots.servers[server] = slot
+	}
+	return slot
+}
+
+// acquire waits for a free place, or returns the error of ctx when it is
+// cancelled first.
+func (s *ddlSlot) acquire(ctx context.Context, priority bool) error {
+	s.mu.Lock()
+	if s.free > 0 {
+		s.free--
+		s.mu.Unlock()
+		return nil
+	}
+	class := 1
+	if priority {
+		class = 0
+	}
+	ready := make(chan struct{})
+	s.waiters[class] = append(s.waiters[class], ready)
+	s.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, waiter := range s.waiters[class] {
+		if waiter == ready {
+			s.waiters[class] = append(s.waiters[class][:i], s.waiters[class][i+1:]...)
+			return ctx.Err()
+		}
+	}
+	// the place was handed over meanwhile, pass it on
+	s.releaseLocked()
+	return ctx.Err()
+}
+
+// release hands the place to the next waiter, or frees it.
+func (s *ddlSlot) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.releaseLocked()
+}
+
+func (s *ddlSlot) releaseLocked() {
+	for class := range s.waiters {
+		if len(s.waiters[class]) > 0 {
+			close(s.waiters[class][0])
+			s.waiters[class] = s.waiters[class][1:]
+			return
+		}
+	}
+	s.free++
+}
+
+// ddlLimiters are the token buckets throttling the statements of the
+// controller per server to --max-ddl-rate, so a burst of Databases doesn't
+// degrade a server serving live traffic.
+var ddlLimiters = struct {
+	mu      sync.Mutex
+	servers map[string]*rate.Limiter
+}{servers: map[string]*rate.Limiter{}}
+
+// ddlLimiter returns the token bucket of the server of inst, shared by the
+// instances reaching the same server with other credentials. It is nil when
+// the statements are not throttled.
+func (i *instance) ddlLimiter() *rate.Limiter {
+	if maxDDLRate <= 0 {
+		return nil
+	}
+	server := net.JoinHostPort(i.hostPort())
+	ddlLimiters.mu.Lock()
+	defer ddlLimiters.mu.Unlock()
+	limiter, ok := ddlLimiters.servers[server]
+	if !ok {
+		burst := ddlBurst
+		if burst < 1 {
+			burst = 1
+		}
+		limiter = rate.NewLimiter(rate.Limit(maxDDLRate), burst)
+		ddlLimiters.servers[server] = limiter
+	}
+	return limiter
+}
+
+// waitForToken waits until limiter lets a statement run, or returns the
+// error of ctx when it is cancelled first. Priority statements take their
+// token without waiting, the others waiting longer for it.
+func waitForToken(ctx context.Context, limiter *rate.Limiter, priority bool, server string) error {
+	if limiter == nil {
+		return nil
+	}
+	if priority {
+		limiter.Reserve()
+		return nil
+	}
+	start := time.Now()
+	if err := limiter.Wait(ctx); err != nil {
+		return err
+	}
+	ddlThrottledSeconds.WithLabelValues(server).Observe(time.Since(start).Seconds())
+	return nil
+}
+
+// acquireSlot waits for a free place in slot, returning the function
+// releasing it, or the error of ctx when it is cancelled first.
+func acquireSlot(ctx context.Context, slot *ddlSlot, priority bool, server string) (func(), error) {
+	if slot == nil {
+		return func() {}, nil
+	}
+	start := time.Now()
+	if err := slot.acquire(ctx, priority); err != nil {
+		return nil, err
+	}
+	ddlWaitSeconds.WithLabelValues(server).Observe(time.Since(start).Seconds())
+	return slot.release, nil
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+	"github.com/joshrendek/k8s-external-postgres/pkg/provisioner"
+)
+
+// defaultPrivilegeObjects maps the kinds of objects default privileges apply
+// to, to their pg_default_acl.defaclobjtype and the privileges ALL stands for.
+var defaultPrivilegeObjects = map[string]struct {
+	objType    string
+	privileges []string
+}{
+	"tables":    {"r", []string{"SELECT", "INSERT", "UPDATE", "DELETE", "TRUNCATE", "REFERENCES", "TRIGGER"}},
+	"sequences": {"S", []string{"SELECT", "USAGE", "UPDATE"}},
+	"functions": {"f", []string{"EXECUTE"}},
+	"types":     {"T", []string{"USAGE"}},
+}
+
+// normalizeDefaultPrivilege fills in the defaults of rule, the owner of
+// dbResource as role and tables as objects, and expands its privileges so
+// rules can be compared. The role must be one of the roles of dbResource,
+// the default privileges of the other roles of the server are not its own.
+func normalizeDefaultPrivilege(dbResource *v1.Database, rule v1.DefaultPrivilege) (v1.DefaultPrivilege, error) {
+	if rule.Role == "" {
+		rule.Role = roleName(dbResource)
+	}
+	if !containsString(ownedRoles(dbResource), rule.Role) {
+		return rule, fmt.Errorf("default privileges role %q is not a role of the Database, must be one of %s", rule.Role, strings.Join(ownedRoles(dbResource), ", "))
+	}
+	rule.On = strings.ToLower(rule.On)
+	if rule.On == "" {
+		rule.On = "tables"
+	}
+	objects, ok := defaultPrivilegeObjects[rule.On]
+	if !ok {
+		return rule, fmt.Errorf("unknown default privileges objects %q, must be tables, sequences, functions or types", rule.On)
+	}
+	if rule.Grantee == "" {
+		return rule, fmt.Errorf("default privileges on %s of %s have no grantee", rule.On, rule.Role)
+	}
+
+	var privileges []string
+	for _, privilege := range rule.Privileges {
+		privilege = strings.ToUpper(privilege)
+		if privilege == "ALL" || privilege == "ALL PRIVILEGES" {
+			privileges = append([]string(nil), objects.privileges...)
+			break
+		}
+		if !containsString(objects.privileges, privilege) {
+			return rule, fmt.Errorf("privilege %q does not apply to %s", privilege, rule.On)
+		}
+		if !containsString(privileges, privilege) {
+			privileges = append(privileges, privilege)
+		}
+	}
+	if len(privileges) == 0 {
+		return rule, fmt.Errorf("default privileges on %s of %s grant no privilege", rule.On, rule.Role)
+	}
+	rule.Privileges = privileges
+	return rule, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultPrivilegeStatement returns the ALTER DEFAULT PRIVILEGES statement
+// granting, or revoking, the privileges of rule. Its role, schema and
+// grantee are quoted, but for the PUBLIC grantee.
+func defaultPrivilegeStatement(rule v1.DefaultPrivilege, grant bool) string {
+	stmt := fmt.Sprintf("ALTER DEFAULT PRIVILEGES FOR ROLE %s", provisioner.QuoteIdentifier(rule.Role))
+	if rule.Schema != "" {
+		stmt += fmt.Sprintf(" IN SCHEMA %s", provisioner.QuoteIdentifier(rule.Schema))
+	}
+	grantee := provisioner.QuoteIdentifier(rule.Grantee)
+	if strings.ToUpper(rule.Grantee) == "PUBLIC" {
+		grantee = "PUBLIC"
+	}
+	privileges := strings.Join(rule.Privileges, ", ")
+	if grant {
+		return stmt + fmt.Sprintf(" GRANT %s ON %s TO %s", privileges, strings.ToUpper(rule.On), grantee)
+	}
+	return stmt + fmt.Sprintf(" REVOKE %s ON %s FROM %s", privileges, strings.ToUpper(rule.On), grantee)
+}
+
+// defaultPrivilegeGranted tells whether every privilege of rule is already
+// in pg_default_acl.
+func defaultPrivilegeGranted(db *sql.DB, rule v1.DefaultPrivilege) (bool, error) {
+	var granted int
+	err := db.QueryRow(`SELECT count(DISTINCT a.privilege_type)
+		FROM pg_default_acl d, aclexplode(d.defaclacl) a
+		WHERE d.defaclrole = (SELECT oid FROM pg_roles WHERE rolname = $1)
+		AND d.defaclnamespace = coalesce((SELECT oid FROM pg_namespace WHERE nspname = $2), 0)
+		AND d.defaclobjtype = $3
+		AND a.grantee = CASE WHEN upper($4) = 'PUBLIC' THEN 0 ELSE (SELECT oid FROM pg_roles WHERE rolname = $4) END
+		AND a.privilege_type = ANY($5)`,
+		rule.Role, rule.Schema, defaultPrivilegeObjects[rule.On].objType, rule.Grantee, textArray(rule.Privileges)).Scan(&granted)
+	return granted == len(rule.Privileges), err
+}
+
+// applyDefaultPrivileges grants the default privileges declared by dbResource
+// that are missing from its database and revokes the ones previously applied
+// but since removed from the spec. It returns the applied rules.
+func applyDefaultPrivileges(dbResource *v1.Database, inst *instance, exec *sqlExecutor) ([]v1.DefaultPrivilege, error) {
+	if len(dbResource.Spec.DefaultPrivileges) == 0 && len(dbResource.Status.DefaultPrivileges) == 0 {
+		return nil, nil
+	}
+	if !inst.dialect.defaultPrivileges {
+		return nil, fmt.Errorf("default privileges are not supported by %s", inst.dialect.name)
+	}
+
+	var desired []v1.DefaultPrivilege
+	for _, rule := range dbResource.Spec.DefaultPrivileges {
+		rule, err := normalizeDefaultPrivilege(dbResource, rule)
+		if err != nil {
+			return nil, err
+		}
+		desired = append(desired, rule)
+	}
+
+	db, err := inst.openDatabase(databaseName(dbResource))
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	for _, rule := range desired {
+		granted, err := defaultPrivilegeGranted(db, rule)
+		if err != nil && !exec.dryRun {
+			return nil, err
+		}
+		if granted {
+			continue
+		}
+		if err := exec.Exec(db, defaultPrivilegeStatement(rule, true)); err != nil {
+			return nil, fmt.Errorf("error granting default privileges: %s", err.Error())
+		}
+	}
+	for _, rule := range dbResource.Status.DefaultPrivileges {
+		if containsDefaultPrivilege(desired, rule) {
+			continue
+		}
+		if err := exec.Exec(db, defaultPrivilegeStatement(rule, false)); err != nil {
+			return nil, fmt.Errorf("error revoking default privileges: %s", err.Error())
+		}
+	}
+
+	return desired, nil
+}
+
+// syncDefaultPrivileges applies the default privileges of a provisioned
+// dbResource and records them in its status.
+func (c *Controller) syncDefaultPrivileges(dbResource *v1.Database, inst *instance, exec *sqlExecutor) error {
+	applied, err := applyDefaultPrivileges(dbResource, inst, exec)
+	if err != nil {
+		return err
+	}
+	if exec.dryRun || reflect.DeepEqual(applied, dbResource.Status.DefaultPrivileges) {
+		return nil
+	}
+	dbCopy := dbResource.DeepCopy()
+	dbCopy.Status.DefaultPrivileges = applied
+	return c.updateStatus(dbCopy)
+}
+
+func containsDefaultPrivilege(rules []v1.DefaultPrivilege, rule v1.DefaultPrivilege) bool {
+	for _, r := range rules {
+		if reflect.DeepEqual(r, rule) {
+			return true
+		}
+	}
+	return false
+}
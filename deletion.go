@@ -0,0 +1,362 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+	"github.com/joshrendek/k8s-external-postgres/pkg/provisioner"
+)
+
+// dropBackoff paces the DROP DATABASE attempts while sessions are still
+// connected to the database.
+var dropBackoff = wait.Backoff{Duration: time.Second, Factor: 2, Steps: 5}
+
+// deleteDatabase drops the database, or schema in schema mode, and roles of
+// the deleted dbResource, or only marks them pending drop during its
+// deletion grace period, or until its maintenance window for forced drops.
+// It runs in its own goroutine as dropping may be retried for a while.
+func (c *Controller) deleteDatabase(dbResource *v1.Database) {
+	ctx, logger := resourceContext(context.Background(), dbResource.Namespace, dbResource.Name)
+	if !provisionedObjects(dbResource) {
+		logger.Info().Str("state", dbResource.Status.State).Msg("nothing was provisioned, keeping the database and roles found on the server")
+		return
+	}
+	inst, err := c.instances.forDatabase(dbResource)
+	if err != nil {
+		logger.Error().Err(err).Msg("error dropping database")
+		c.deletionFailed(logger, dbResource, dropObjectInstance, err)
+		return
+	}
+	exec := newExecutor(ctx, dbResource, inst, logger)
+	// cleanup goes ahead of the statements of provisioning
+	exec.priority = true
+	unlock, err := waitDatabaseLock(ctx, logger, inst, lockName(dbResource))
+	if err != nil {
+		logger.Error().Err(err).Msg("error locking database")
+		c.deletionFailed(logger, dbResource, dropObjectDatabase, err)
+		return
+	}
+	defer unlock()
+
+	dropAfter := time.Now().Add(deletionGracePeriod(dbResource))
+	deferred, err := deferForcedDrop(dbResource, dropAfter)
+	if err != nil {
+		logger.Error().Err(err).Msg("error checking the maintenance window, dropping now")
+	}
+	if deferred != nil {
+		dropAfter = *deferred
+	}
+	if (deletionGracePeriod(dbResource) > 0 || deferred != nil) && dbResource.Status.State == "provisioned" {
+		if err := c.markPendingDrop(logger, dbResource, inst, exec, dropAfter); err != nil {
+			logger.Error().Err(err).Msg("error marking database pending drop")
+			c.deletionFailed(logger, dbResource, dropObjectDatabase, err)
+		}
+		return
+	}
+	c.dropDeletedDatabase(logger, dbResource, inst, exec, hookPreDelete)
+}
+
+// provisionedObjects reports whether dbResource created, or adopted, the
+// database and roles named after it. One stopped at a conflict found them
+// belonging to someone else, and one failing before its first statement
+// never got any.
+func provisionedObjects(dbResource *v1.Database) bool {
+	switch dbResource.Status.State {
+	case "conflict":
+		return false
+	case "", "error":
+		return resuming(dbResource) || dbResource.Status.Adoption != nil || dbResource.Status.AppliedSpec != nil
+	}
+	return true
+}
+
+// deleteHookFailed is returned by dropDeletedDatabase when a preDelete hook
+// failed, the database and roles being kept for good.
+type deleteHookFailed string
+
+func (e deleteHookFailed) Error() string {
+	return string(e)
+}
+
+// dropDeletedDatabase drops the database, or schema, and roles of the
+// deleted dbResource and deletes its credentials, returning the error
+// dropping the database. Objects that turn out to belong to another
+// Database or controller are kept. It starts from phase, the delete hooks
+// run on, resuming a deletion requeued while the Job of a hook was running.
+func (c *Controller) dropDeletedDatabase(logger zerolog.Logger, dbResource *v1.Database, inst *instance, exec *sqlExecutor, phase string) error {
+	var dropErr error
+	if phase != hookPostDelete {
+		hooked, running, err := c.runDeleteHooks(logger, dbResource, inst, exec, hookPreDelete)
+		if running {
+			return c.requeueDeletion(logger, hooked, hookPreDelete)
+		}
+		if err != nil {
+			logger.Error().Err(err).Msg("preDelete hook failed, keeping the database and roles")
+			c.deletionFailed(logger, dbResource, dropObjectHook, err)
+			return deleteHookFailed(err.Error())
+		}
+		dropErr = c.dropObjects(logger, dbResource, inst, exec)
+	}
+
+	if dropErr == nil {
+		hooked, running, err := c.runDeleteHooks(logger, dbResource, inst, exec, hookPostDelete)
+		if running {
+			return c.requeueDeletion(logger, hooked, hookPostDelete)
+		}
+		if err != nil {
+			logger.Error().Err(err).Msg("postDelete hook failed")
+		}
+		c.deleteHookJobs(logger, dbResource)
+	}
+
+	if exec.dryRun {
+		return dropErr
+	}
+	store, err := c.credentialStore(dbResource)
+	if err != nil {
+		logger.Error().Err(err).Msg("error deleting credentials")
+		c.deletionFailed(logger, dbResource, dropObjectCredentials, err)
+		return dropErr
+	}
+	for _, name := range credentialNames(dbResource) {
+		if err := store.Delete(dbResource, name); err != nil {
+			logger.Error().Err(err).Str("name", name).Msg("error deleting credentials")
+			c.deletionFailed(logger, dbResource, dropObjectCredentials, err)
+		}
+	}
+	return dropErr
+}
+
+// dropObjects drops the database, or schema, and roles of the deleted
+// dbResource, returning the error dropping the database.
+func (c *Controller) dropObjects(logger zerolog.Logger, dbResource *v1.Database, inst *instance, exec *sqlExecutor) error {
+	drop := func() error { return dropDatabase(logger, dbResource, inst, exec) }
+	if schemaMode(dbResource) {
+		// the shared database stays, only the schema goes
+		logger.Info().Str("database", databaseName(dbResource)).Str("schema", schemaName(dbResource)).Msg("dropping schema")
+		drop = func() error { return dropSchema(dbResource, inst, exec) }
+	} else {
+		logger.Info().Str("database", databaseName(dbResource)).Msg("dropping database")
+	}
+	var dropErr error
+	if !schemaMode(dbResource) {
+		dropErr = verifyOwnership(inst, orphanKindDatabase, databaseName(dbResource), dbResource)
+	}
+	if dropErr == nil {
+		dropErr = drop()
+	}
+	if dropErr != nil {
+		logger.Error().Err(dropErr).Msg("error deleting database")
+		c.deletionFailed(logger, dbResource, dropObjectDatabase, dropErr)
+	} else if !exec.dryRun {
+		notify(notifyDatabaseDeleted, dbResource, "")
+		c.clearDangling(logger, dbResource)
+	}
+
+	if dbResource.Spec.ReadOnlyUser {
+		if err := dropOwnedRole(inst, exec, dbResource, readOnlyUsername(roleName(dbResource))); err != nil {
+			logger.Error().Err(err).Msg("error dropping read-only user")
+			c.deletionFailed(logger, dbResource, dropObjectRole, err)
+		}
+	}
+	if appRole(dbResource) {
+		if err := dropOwnedRole(inst, exec, dbResource, appUsername(roleName(dbResource))); err != nil {
+			logger.Error().Err(err).Msg("error dropping application user")
+			c.deletionFailed(logger, dbResource, dropObjectRole, err)
+		}
+	}
+
+	if err := dropOwnedRole(inst, exec, dbResource, roleName(dbResource)); err != nil {
+		logger.Error().Err(err).Msg("error dropping user")
+		c.deletionFailed(logger, dbResource, dropObjectRole, err)
+	}
+	c.dropMirror(exec.ctx, logger, dbResource)
+	return dropErr
+}
+
+// dropOwnedRole drops username once verified it belongs to dbResource,
+// releasing the objects it owns first as deletionPolicy.ownedObjects says.
+func dropOwnedRole(inst *instance, exec *sqlExecutor, dbResource *v1.Database, username string) error {
+	if err := verifyOwnership(inst, orphanKindRole, username, dbResource); err != nil {
+		return err
+	}
+	if err := releaseOwnedObjects(inst, exec, dbResource, username); err != nil {
+		return fmt.Errorf("error releasing the objects of role %s: %s", username, err.Error())
+	}
+	return exec.Exec(inst.DB, provisioner.DropRoleStatement(username))
+}
+
+const (
+	ownedObjectsReassign = "reassign"
+	ownedObjectsDrop     = "drop"
+)
+
+// reassignTarget returns the role the objects of username are reassigned to:
+// deletionPolicy.reassignTo quoted, which must be another role of
+// dbResource, or the admin role when empty. The tenant doesn't get to hand
+// its objects over to the other roles of the server.
+func reassignTarget(dbResource *v1.Database, username string) (string, error) {
+	to := dbResource.Spec.DeletionPolicy.ReassignTo
+	if to == "" {
+		return "CURRENT_USER", nil
+	}
+	if to == username || !containsString(ownedRoles(dbResource), to) {
+		return "", fmt.Errorf("deletionPolicy.reassignTo %q is not another role of the Database, must be one of %s or empty", to, strings.Join(ownedRoles(dbResource), ", "))
+	}
+	return provisioner.QuoteIdentifier(to), nil
+}
+
+// releaseOwnedObjects reassigns or drops the objects username owns, and
+// revokes its privileges, in every database it has any in, so DROP ROLE
+// doesn't fail on them. REASSIGN OWNED and DROP OWNED only reach the objects
+// of the database of the session, each database is connected to in turn.
+func releaseOwnedObjects(inst *instance, exec *sqlExecutor, dbResource *v1.Database, username string) error {
+	policy := dbResource.Spec.DeletionPolicy
+	if policy == nil || policy.OwnedObjects == "" {
+		return nil
+	}
+	var stmts []string
+	switch policy.OwnedObjects {
+	case ownedObjectsReassign:
+		to, err := reassignTarget(dbResource, username)
+		if err != nil {
+			return err
+		}
+		// the privileges are left behind by REASSIGN OWNED
+		stmts = []string{provisioner.ReassignOwnedStatement(username, to), provisioner.DropOwnedStatement(username)}
+	case ownedObjectsDrop:
+		stmts = []string{provisioner.DropOwnedStatement(username)}
+	default:
+		return fmt.Errorf("unknown deletionPolicy.ownedObjects %q", policy.OwnedObjects)
+	}
+
+	databases, err := dependentDatabases(inst, username)
+	if err != nil {
+		return err
+	}
+	for _, database := range databases {
+		exec.logger.Info().Str("role", username).Str("database", database).Str("ownedObjects", policy.OwnedObjects).Msg("releasing owned objects")
+		db, err := inst.openDatabase(database)
+		if err != nil {
+			return err
+		}
+		for _, stmt := range stmts {
+			if err = exec.Exec(db, stmt); err != nil {
+				break
+			}
+		}
+		db.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dependentDatabases returns the databases username owns objects or holds
+// privileges in, the one of the admin session standing for the shared
+// objects, e.g. tablespaces. Databases not accepting connections are left
+// out.
+func dependentDatabases(inst *instance, username string) ([]string, error) {
+	rows, err := inst.DB.Query(`SELECT DISTINCT COALESCE(d.datname, current_database())
+		FROM pg_shdepend s
+		JOIN pg_roles r ON r.oid = s.refobjid
+		LEFT JOIN pg_database d ON d.oid = s.dbid
+		WHERE r.rolname = $1 AND (s.dbid = 0 OR d.datallowconn)`, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var databases []string
+	for rows.Next() {
+		var database string
+		if err := rows.Scan(&database); err != nil {
+			return nil, err
+		}
+		databases = append(databases, database)
+	}
+	return databases, rows.Err()
+}
+
+// deferForcedDrop returns when the forced drop of the database of
+// dbResource, due at dropAfter, runs in its maintenance window. It is nil when
+// the drop isn't forced or the window is open then.
+func deferForcedDrop(dbResource *v1.Database, dropAfter time.Time) (*time.Time, error) {
+	if dbResource.Spec.DeletionPolicy == nil || !dbResource.Spec.DeletionPolicy.Force {
+		return nil, nil
+	}
+	m, err := maintenanceFor(dbResource, dropAfter)
+	if err != nil || m.allow(actionForcedDrop) {
+		return nil, err
+	}
+	return &m.next, nil
+}
+
+// dropDatabase drops the database of dbResource, retrying while connected
+// sessions block it. With deletionPolicy.force the sessions are terminated,
+// or dropped along with the database on PostgreSQL 13+.
+func dropDatabase(logger zerolog.Logger, dbResource *v1.Database, inst *instance, exec *sqlExecutor) error {
+	database := databaseName(dbResource)
+	force := dbResource.Spec.DeletionPolicy != nil && dbResource.Spec.DeletionPolicy.Force
+	if force && !inst.dialect.terminateBackends {
+		logger.Info().Str("dialect", inst.dialect.name).Msg("sessions can't be terminated, dropping without force")
+		force = false
+	}
+
+	stmt := provisioner.DropDatabaseStatement(database, force && inst.version.supportsDropForce())
+	terminate := ""
+	if force && !inst.version.supportsDropForce() {
+		terminate = provisioner.TerminateSessionsStatement(database)
+	}
+
+	var lastErr error
+	err := wait.ExponentialBackoff(dropBackoff, func() (bool, error) {
+		if terminate != "" {
+			if err := exec.Exec(inst.DB, terminate); err != nil {
+				return false, fmt.Errorf("error terminating sessions: %s", err.Error())
+			}
+		}
+		lastErr = exec.Exec(inst.DB, stmt)
+		if lastErr == nil {
+			return true, nil
+		}
+		if sqlState(lastErr) == sqlStateObjectInUse {
+			// object_in_use: sessions are still connected
+			logger.Info().Err(lastErr).Msg("database in use, retrying drop")
+			return false, nil
+		}
+		return false, lastErr
+	})
+	if err == wait.ErrWaitTimeout {
+		return lastErr
+	}
+	return err
+}
+
+// dropCreatedRole drops the owner role created by a provisioning attempt that
+// failed afterwards, revoking its access to the shared database first in
+// schema mode. It returns dbResource with the role no longer recorded as
+// created, so the next attempt creates it again.
+func dropCreatedRole(logger zerolog.Logger, dbResource *v1.Database, inst *instance, exec *sqlExecutor) *v1.Database {
+	username := roleName(dbResource)
+	var stmts []string
+	if schemaMode(dbResource) {
+		stmts = append(stmts, fmt.Sprintf("REVOKE CONNECT ON DATABASE %s FROM %s", databaseName(dbResource), username))
+	}
+	stmts = append(stmts, provisioner.DropRoleStatement(username))
+	if err := exec.ExecDDL(inst, stmts); err != nil {
+		logger.Error().Err(err).Msg("error dropping user after failed provisioning")
+	}
+	if dbResource.Status.Progress == nil {
+		return dbResource
+	}
+	dbCopy := dbResource.DeepCopy()
+	dbCopy.Status.Progress.RoleCreated = false
+	return dbCopy
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/joshrendek/k8s-external-postgres/pkg/provisioner"
+)
+
+// dialect describes how a PostgreSQL compatible server differs from
+// PostgreSQL, so statements it does not support are skipped or replaced by an
+// equivalent.
+type dialect struct {
+	name string
+	// databaseOwner is set when databases can be owned by a role, otherwise
+	// the owner is granted every privilege on the database instead.
+	databaseOwner bool
+	// connectionLimits is set when databases and roles take a CONNECTION
+	// LIMIT.
+	connectionLimits bool
+	// passwordVerifiers is set when md5 and SCRAM verifiers are accepted as
+	// passwords.
+	passwordVerifiers bool
+	// logicalReplication is set when publications and subscriptions are
+	// available.
+	logicalReplication bool
+	// defaultPrivileges is set when ALTER DEFAULT PRIVILEGES is supported and
+	// recorded in pg_default_acl.
+	defaultPrivileges bool
+	// terminateBackends is set when the sessions of a database can be
+	// terminated with pg_terminate_backend.
+	terminateBackends bool
+	// statistics is set when database sizes and sessions can be read from
+	// pg_database_size and pg_stat_activity.
+	statistics bool
+	// transactionalDDL is set when role statements can be grouped in a
+	// transaction and rolled back together.
+	transactionalDDL bool
+	// templateClone is set when a database can be created as a copy of
+	// another with CREATE DATABASE ... TEMPLATE.
+	templateClone bool
+	// alterSystem is set when server parameters can be changed with ALTER
+	// SYSTEM.
+	alterSystem bool
+	// tablespaces is set when tablespaces can be created in a directory of
+	// the server with CREATE TABLESPACE ... LOCATION.
+	tablespaces bool
+	// comments is set when databases and roles take a COMMENT, recording
+	// the Database they belong to.
+	comments bool
+	// publicPrivileges is set when PUBLIC is granted privileges on new
+	// databases and the public schema, which can be checked with
+	// has_database_privilege and has_schema_privilege and revoked.
+	publicPrivileges bool
+	// roleSettings is set when roles take parameters with ALTER ROLE ... SET,
+	// recorded in pg_db_role_setting.
+	roleSettings bool
+	// roleAttributes is set when roles take the SUPERUSER, CREATEDB,
+	// CREATEROLE, INHERIT, REPLICATION and BYPASSRLS attributes, recorded in
+	// pg_roles.
+	roleAttributes bool
+	// foreignServers is set when postgres_fdw foreign servers and user
+	// mappings can be created.
+	foreignServers bool
+	// sessionLabels is set when sessions take custom settings such as
+	// k8s.reconcile_id with set_config.
+	sessionLabels bool
+	// physicalBackups is set when the server is self-hosted, its data
+	// directory backed up by pgBackRest or WAL-G with PhysicalBackups.
+	physicalBackups bool
+	// advisoryLocks is set when sessions take advisory locks with
+	// pg_try_advisory_lock, held while a Database is reconciled.
+	advisoryLocks bool
+	// grantRoleToAdmin is set when the admin role is not a superuser and must
+	// be a member of a role to create databases owned by it.
+	grantRoleToAdmin bool
+}
+
+var dialects = map[string]dialect{
+	"postgres": {
+		name:               "postgres",
+		databaseOwner:      true,
+		connectionLimits:   true,
+		passwordVerifiers:  true,
+		logicalReplication: true,
+		defaultPrivileges:  true,
+		terminateBackends:  true,
+		statistics:         true,
+		transactionalDDL:   true,
+		templateClone:      true,
+		alterSystem:        true,
+		tablespaces:        true,
+		comments:           true,
+		publicPrivileges:   true,
+		roleSettings:       true,
+		roleAttributes:     true,
+		foreignServers:     true,
+		sessionLabels:      true,
+		physicalBackups:    true,
+		advisoryLocks:      true,
+	},
+	"alloydb": {
+		name:               "alloydb",
+		databaseOwner:      true,
+		connectionLimits:   true,
+		passwordVerifiers:  true,
+		logicalReplication: true,
+		defaultPrivileges:  true,
+		terminateBackends:  true,
+		statistics:         true,
+		transactionalDDL:   true,
+		templateClone:      true,
+		comments:           true,
+		publicPrivileges:   true,
+		roleSettings:       true,
+		roleAttributes:     true,
+		foreignServers:     true,
+		sessionLabels:      true,
+		advisoryLocks:      true,
+		grantRoleToAdmin:   true,
+	},
+	"yugabyte": {
+		name:              "yugabyte",
+		databaseOwner:     true,
+		connectionLimits:  true,
+		passwordVerifiers: true,
+		defaultPrivileges: true,
+		terminateBackends: true,
+		comments:          true,
+		publicPrivileges:  true,
+		roleSettings:      true,
+		roleAttributes:    true,
+		sessionLabels:     true,
+	},
+	"cockroachdb": {
+		name: "cockroachdb",
+	},
+}
+
+// lookupDialect returns the dialect called name, postgres when empty.
+func lookupDialect(name string) (dialect, error) {
+	if name == "" {
+		name = "postgres"
+	}
+	d, ok := dialects[name]
+	if !ok {
+		return dialect{}, fmt.Errorf("unknown dialect %q, must be postgres, alloydb, yugabyte or cockroachdb", name)
+	}
+	return d, nil
+}
+
+// createRoleStatements returns the statements run once username has been
+// created so the admin role can hand databases over to it.
+func (d dialect) createRoleStatements(username string) []string {
+	return provisioner.CreateRoleStatements(username, d.grantRoleToAdmin)
+}
+
+// createDatabaseStatements returns the statements creating database owned
+// by owner.
+func (d dialect) createDatabaseStatements(database, owner string) []string {
+	return provisioner.CreateDatabaseStatements(database, owner, d.databaseOwner)
+}
+
+// changeOwnerStatements returns the statements handing the existing database
+// over to owner.
+func (d dialect) changeOwnerStatements(database, owner string) []string {
+	return provisioner.ChangeOwnerStatements(database, owner, d.databaseOwner)
+}
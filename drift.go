@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/runtime"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+	"github.com/joshrendek/k8s-external-postgres/pkg/provisioner"
+)
+
+// credentialsDriftCondition is True while the password of a role of a
+// Database on the server is not the one of its credentials, e.g. after
+// someone ran ALTER ROLE by hand.
+const credentialsDriftCondition = "CredentialsDrift"
+
+// passwordDrifted reports whether the password of username on the server of
+// inst is no longer password. The verifier stored in pg_authid is compared
+// when the admin role may read it, only superusers can, otherwise the
+// controller tries to log in as username on database.
+func passwordDrifted(inst *instance, database, username, password string) (bool, error) {
+	if inst.dialect.passwordVerifiers {
+		var stored sql.NullString
+		err := inst.DB.QueryRow(`SELECT rolpassword FROM pg_authid WHERE rolname = $1`, username).Scan(&stored)
+		switch {
+		case err == sql.ErrNoRows:
+			// the role is gone, which the reconcile reports
+			return false, nil
+		case err == nil:
+			return !stored.Valid || !provisioner.PasswordMatches(username, password, stored.String), nil
+		case sqlState(err) != sqlStateInsufficientPrivilege:
+			return false, err
+		}
+	}
+	if provisioner.IsPasswordVerifier(password) {
+		// there is no logging in with a verifier
+		return false, nil
+	}
+	err := canaryQuery(inst, database, username, password)
+	if sqlState(err) == sqlStateInvalidPassword {
+		return true, nil
+	}
+	return false, err
+}
+
+// syncCredentialsDrift checks the passwords of the roles of every
+// provisioned Database against its credentials, setting the
+// CredentialsDrift condition when they differ. With
+// --repair-credentials-drift the password of the credentials is set back on
+// the role.
+func (c *Controller) syncCredentialsDrift() {
+	dbResources, err := c.DatabasesLister.List(labels.Everything())
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	for _, dbResource := range dbResources {
+		if dbResource.Status.State != "provisioned" || paused(dbResource) {
+			continue
+		}
+		inst, err := c.instances.forDatabase(dbResource)
+		if err != nil || inst.unavailable() != nil {
+			continue
+		}
+		if err := c.checkCredentialsDrift(dbResource, inst); err != nil {
+			runtime.HandleError(fmt.Errorf("error checking the credentials of %s/%s: %s", dbResource.Namespace, dbResource.Name, err.Error()))
+		}
+	}
+}
+
+// checkCredentialsDrift compares the passwords of the roles of dbResource on
+// inst with its credentials, repairing them with --repair-credentials-drift,
+// and records the outcome in the CredentialsDrift condition. Roles without
+// a password, e.g. logging in with a certificate, are skipped.
+func (c *Controller) checkCredentialsDrift(dbResource *v1.Database, inst *instance) error {
+	store, err := c.credentialStore(dbResource)
+	if err != nil {
+		return err
+	}
+	ctx, logger := resourceContext(context.Background(), dbResource.Namespace, dbResource.Name)
+	exec := newExecutor(ctx, dbResource, inst, logger)
+
+	var drifted, repaired []string
+	for _, name := range credentialNames(dbResource) {
+		credentials, err := store.Get(dbResource, name)
+		if err != nil {
+			return err
+		}
+		password := appliedPassword(credentials)
+		if credentials == nil || password == "" {
+			continue
+		}
+		username := credentials["USERNAME"]
+		drift, err := passwordDrifted(inst, databaseName(dbResource), username, password)
+		if err != nil {
+			return err
+		}
+		if !drift {
+			continue
+		}
+		logger.Warn().Str("role", username).Bool("repair", repairCredentialsDrift).Msg("password changed on the server")
+		if repairCredentialsDrift && !exec.dryRun {
+			stmt, err := rolePasswordStatement("ALTER ROLE", username, password, passwordEncryptionFor(dbResource))
+			if err == nil {
+				err = exec.Exec(inst.DB, stmt)
+			}
+			if err == nil {
+				repaired = append(repaired, username)
+				continue
+			}
+			logger.Error().Err(err).Str("role", username).Msg("error repairing password")
+		}
+		drifted = append(drifted, username)
+	}
+
+	for _, username := range repaired {
+		c.recorder.Event(dbResource, corev1.EventTypeNormal, "CredentialsRepaired", fmt.Sprintf("Password of role %s changed on the server, set back to the one of its credentials", username))
+	}
+	dbCopy := dbResource.DeepCopy()
+	cond := findCondition(&dbResource.Status, credentialsDriftCondition)
+	wasDrifted := cond != nil && cond.Status == conditionTrue
+	switch {
+	case len(drifted) > 0:
+		message := fmt.Sprintf("Password of role %s changed on the server, it no longer matches the credentials", strings.Join(drifted, ", "))
+		if !setCondition(&dbCopy.Status, credentialsDriftCondition, conditionTrue, "PasswordChanged", message) {
+			return nil
+		}
+		c.recorder.Event(dbResource, corev1.EventTypeWarning, "CredentialsDrift", message)
+		if !wasDrifted {
+			credentialsDriftDetected.WithLabelValues(dbResource.Namespace, dbResource.Name).Inc()
+		}
+	case len(repaired) > 0:
+		credentialsDriftDetected.WithLabelValues(dbResource.Namespace, dbResource.Name).Inc()
+		if !setCondition(&dbCopy.Status, credentialsDriftCondition, conditionFalse, "Repaired", "") {
+			return nil
+		}
+	case cond != nil:
+		if !setCondition(&dbCopy.Status, credentialsDriftCondition, conditionFalse, "InSync", "") {
+			return nil
+		}
+	default:
+		return nil
+	}
+	return c.updateStatus(dbCopy)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"github.com/jackc/pgx"
+	"github.com/jackc/pgx/pgtype"
+)
+
+// SQLSTATE codes of the server errors the controller handles.
+const (
+	sqlStateObjectInUse           = "55006"
+	sqlStateInvalidAuthorization  = "28000"
+	sqlStateInvalidPassword       = "28P01"
+	sqlStateInsufficientPrivilege = "42501"
+)
+
+// sqlState returns the SQLSTATE code of err when the server reported it, ""
+// for the errors of the client or of the connection.
+func sqlState(err error) string {
+	if pgErr, ok := err.(pgx.PgError); ok {
+		return pgErr.Code
+	}
+	return ""
+}
+
+// textArray returns values as a text[] query parameter, database/sql only
+// passing scalars to the driver.
+func textArray(values []string) *pgtype.TextArray {
+	array := &pgtype.TextArray{}
+	// Set only fails on types it doesn't convert, []string is not one.
+	array.Set(values)
+	return array
+}
+
+// scanTextArray returns the scanner of a text[] column into values.
+func scanTextArray(values *[]string) *textArrayScanner {
+	return &textArrayScanner{values: values}
+}
+
+type textArrayScanner struct {
+	values *[]string
+}
+
+func (s *textArrayScanner) Scan(src interface{}) error {
+	var array pgtype.TextArray
+	if err := array.Scan(src); err != nil {
+		return err
+	}
+	*s.values = nil
+	if array.Status != pgtype.Present {
+		return nil
+	}
+	return array.AssignTo(s.values)
+}
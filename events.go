@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/tools/reference"
+)
+
+// aggregatingRecorder records the events of the controllers, and copies the
+// warnings whose reason is one of --aggregate-event-reasons to the
+// --event-aggregator-namespace, so the team running the servers watches one
+// namespace instead of those of every tenant.
+type aggregatingRecorder struct {
+	record.EventRecorder
+	namespace string
+	reasons   map[string]bool
+}
+
+// aggregateEvents wraps recorder so it copies the important events to the
+// --event-aggregator-namespace, returning recorder as is without one.
+func aggregateEvents(recorder record.EventRecorder) record.EventRecorder {
+	if eventAggregatorNamespace == "" {
+		return recorder
+	}
+	reasons := map[string]bool{}
+	for _, reason := range splitList(aggregateEventReasons) {
+		reasons[reason] = true
+	}
+	return &aggregatingRecorder{EventRecorder: recorder, namespace: eventAggregatorNamespace, reasons: reasons}
+}
+
+func (r *aggregatingRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	r.EventRecorder.Event(object, eventtype, reason, message)
+	r.aggregate(object, eventtype, reason, message)
+}
+
+func (r *aggregatingRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.Event(object, eventtype, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+// aggregate records the copy of an event of object in the aggregator
+// namespace. Events can't involve objects of another namespace, the copy
+// involves a reference to object named <namespace>.<name> in the
+// aggregator namespace instead, with the original namespace and name in the
+// message.
+func (r *aggregatingRecorder) aggregate(object runtime.Object, eventtype, reason, message string) {
+	if eventtype != corev1.EventTypeWarning || !r.reasons[reason] {
+		return
+	}
+	ref, err := reference.GetReference(scheme.Scheme, object)
+	if err != nil || ref.Namespace == "" || ref.Namespace == r.namespace {
+		return
+	}
+	aggregated := &corev1.ObjectReference{
+		APIVersion: ref.APIVersion,
+		Kind:       ref.Kind,
+		Namespace:  r.namespace,
+		Name:       ref.Namespace + "." + ref.Name,
+		UID:        ref.UID,
+	}
+	r.EventRecorder.Event(aggregated, eventtype, reason, fmt.Sprintf("%s %s/%s: %s", ref.Kind, ref.Namespace, ref.Name, message))
+}
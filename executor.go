@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/rs/zerolog"
+	"golang.org/x/time/rate"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+// dryRunAnnotation puts a single Database in dry-run mode when set to "true".
+const dryRunAnnotation = "postgresql.org/dry-run"
+
+// sessionApplication is the application_name of the sessions of the
+// controller between statements.
+const sessionApplication = "k8s-external-postgres"
+
+const (
+	// labelSessionStatement names the object and reconcile behind the
+	// statements of a session in application_name and k8s.reconcile_id.
+	labelSessionStatement = "SELECT set_config('application_name', $1, false), set_config('k8s.reconcile_id', $2, false)"
+	// labelTransactionStatement does the same until the end of the
+	// transaction.
+	labelTransactionStatement = "SELECT set_config('application_name', $1, true), set_config('k8s.reconcile_id', $2, true)"
+	// unlabelSessionStatement restores the settings of the connection URI.
+	unlabelSessionStatement = "RESET application_name; RESET k8s.reconcile_id"
+)
+
+var passwordPattern = regexp.MustCompile(`(?i)(PASSWORD\s+)'(?:[^']|'')*'`)
+
+// connectionPattern matches the connection strings of CREATE and ALTER
+// SUBSCRIPTION, which hold a password.
+var connectionPattern = regexp.MustCompile(`(?i)(CONNECTION\s+)'(?:[^']|'')*'`)
+
+// sqlExecutor executes the statements of a reconcile or, in dry-run mode,
+// only logs and records them so they can be reviewed in the Database status.
+// Executed statements are sent to the audit sinks along with the resource
+// they were executed for, and the sessions running them are labelled with it
+// so pg_stat_activity and the server logs tell it too.
+type sqlExecutor struct {
+	ctx     context.Context
+	dryRun  bool
+	planned []string
+	logger  zerolog.Logger
+	kind    string
+	object  metav1.Object
+	// slot serializes the statements with the ones of the other reconciles
+	// on the same server, nil when they are not.
+	slot *ddlSlot
+	// limiter throttles the statements run on the server, nil when they are
+	// not.
+	limiter *rate.Limiter
+	server  string
+	// priority statements go ahead of the others waiting for the slot and
+	// limiter, for drops and password rotations.
+	priority bool
+	// reconcileID identifies the reconcile the statements are run for.
+	reconcileID string
+	// labels is set when the sessions running the statements are to be
+	// labelled with the object and reconcile ID.
+	labels bool
+}
+
+// newExecutor returns the executor for a reconcile of dbResource on inst,
+// honouring both the dry-run setting and the dry-run annotation. Statements
+// are logged to logger, serialized on the server of inst and aborted when
+// ctx is cancelled.
+func newExecutor(ctx context.Context, dbResource *v1.Database, inst *instance, logger zerolog.Logger) *sqlExecutor {
+	exec := newResourceExecutor(ctx, "Database", dbResource, logger)
+	exec.dryRun = getSettings().DryRun || dbResource.Annotations[dryRunAnnotation] == "true"
+	exec.slot = inst.ddlSlot()
+	exec.limiter = inst.ddlLimiter()
+	exec.server = inst.label()
+	exec.priority = priorityDatabase(dbResource)
+	exec.labels = inst.dialect.sessionLabels
+	return exec
+}
+
+// newResourceExecutor returns the executor for a reconcile of the object of
+// the given kind, which does not support dry-run.
+func newResourceExecutor(ctx context.Context, kind string, object metav1.Object, logger zerolog.Logger) *sqlExecutor {
+	return &sqlExecutor{ctx: ctx, logger: logger, kind: kind, object: object, reconcileID: reconcileID(ctx), labels: true}
+}
+
+// application returns the application_name of the sessions running the
+// statements of e, naming its object. The server truncates it to 63 bytes.
+func (e *sqlExecutor) application() string {
+	return fmt.Sprintf("%s/%s/%s/%s", sessionApplication, e.kind, e.object.GetNamespace(), e.object.GetName())
+}
+
+// labelledConn returns a connection of pool whose session is labelled with
+// the object and reconcile ID of e, and the function handing it back. When
+// the session can't be labelled the statements still run, unlabelled.
+func (e *sqlExecutor) labelledConn(pool *sql.DB) (execer, func()) {
+	conn, err := pool.Conn(e.ctx)
+	if err != nil {
+		// the statement reports the connection error
+		return pool, func() {}
+	}
+	if _, err := conn.ExecContext(e.ctx, labelSessionStatement, e.application(), e.reconcileID); err != nil {
+		e.logger.Debug().Err(err).Msg("error labelling session")
+		return conn, func() { conn.Close() }
+	}
+	release := func() {
+		// the connection goes back to the pool, reused by other objects
+		if _, err := conn.ExecContext(context.Background(), unlabelSessionStatement); err != nil {
+			e.logger.Debug().Err(err).Msg("error resetting session labels")
+		}
+		conn.Close()
+	}
+	return conn, release
+}
+
+// execer is satisfied by *sql.DB, *sql.Conn and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Exec runs stmt on db unless in dry-run mode.
+func (e *sqlExecutor) Exec(db *sql.DB, stmt string) error {
+	if e.dryRun {
+		return e.exec(nil, stmt)
+	}
+	release, err := acquireSlot(e.ctx, e.slot, e.priority, e.server)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return e.exec(db, stmt)
+}
+
+// ExecTx runs stmts on db in a single transaction unless in dry-run mode, so
+// either all of them or none are applied.
+func (e *sqlExecutor) ExecTx(db *sql.DB, stmts []string) error {
+	if e.dryRun {
+		for _, stmt := range stmts {
+			e.exec(nil, stmt)
+		}
+		return nil
+	}
+	// the slot is held for the whole transaction, whose locks are only
+	// released on commit
+	release, err := acquireSlot(e.ctx, e.slot, e.priority, e.server)
+	if err != nil {
+		return err
+	}
+	defer release()
+	tx, err := db.BeginTx(e.ctx, nil)
+	if err != nil {
+		return err
+	}
+	if e.labels {
+		if _, err := tx.ExecContext(e.ctx, labelTransactionStatement, e.application(), e.reconcileID); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	for _, stmt := range stmts {
+		if err := e.exec(tx, stmt); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ExecDDL runs stmts on the admin database of inst, in a single transaction
+// when its dialect supports transactional DDL.
+func (e *sqlExecutor) ExecDDL(inst *instance, stmts []string) error {
+	if inst.dialect.transactionalDDL {
+		return e.ExecTx(inst.DB, stmts)
+	}
+	for _, stmt := range stmts {
+		if err := e.Exec(inst.DB, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *sqlExecutor) exec(db execer, stmt string) error {
+	redacted := redactStatement(stmt)
+	e.logger.Info().Bool("dryRun", e.dryRun).Str("statement", redacted).Msg("executing statement")
+	if e.dryRun {
+		e.planned = append(e.planned, redacted)
+		return nil
+	}
+	span := startSQLSpan(e.ctx, e.server, redacted)
+	if err := waitForToken(e.ctx, e.limiter, e.priority, e.server); err != nil {
+		endSpan(span, err)
+		return err
+	}
+	if pool, ok := db.(*sql.DB); ok && e.labels {
+		var release func()
+		db, release = e.labelledConn(pool)
+		defer release()
+	}
+	_, err := db.ExecContext(e.ctx, stmt)
+	endSpan(span, err)
+
+	rec := auditRecord{
+		Time:      time.Now().UTC(),
+		Kind:      e.kind,
+		Namespace: e.object.GetNamespace(),
+		Name:      e.object.GetName(),
+		UID:       string(e.object.GetUID()),
+		Statement: redacted,
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	recordAudit(e.logger, rec)
+	return err
+}
+
+// redactStatement masks passwords and connection strings so statements can
+// be logged and stored.
+func redactStatement(stmt string) string {
+	stmt = passwordPattern.ReplaceAllString(stmt, "${1}'********'")
+	return connectionPattern.ReplaceAllString(stmt, "${1}'********'")
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+	"github.com/joshrendek/k8s-external-postgres/pkg/provisioner"
+)
+
+const (
+	// extensionUpdateCondition is set while an extension of a Database is
+	// older than the default version of the server, e.g. after an upgrade.
+	extensionUpdateCondition = "ExtensionUpdateAvailable"
+
+	// latestExtensionVersion follows the default version of the server.
+	latestExtensionVersion = "latest"
+)
+
+// installedExtensions returns the installed and default versions of the
+// extensions called names on the server of db, by name. Extensions the
+// server doesn't provide are missing.
+func installedExtensions(db *sql.DB, names []string) (map[string]v1.ExtensionStatus, error) {
+	rows, err := db.Query("SELECT name, COALESCE(installed_version, ''), COALESCE(default_version, '') FROM pg_available_extensions WHERE name = ANY($1)", textArray(names))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	extensions := map[string]v1.ExtensionStatus{}
+	for rows.Next() {
+		var ext v1.ExtensionStatus
+		if err := rows.Scan(&ext.Name, &ext.InstalledVersion, &ext.AvailableVersion); err != nil {
+			return nil, err
+		}
+		extensions[ext.Name] = ext
+	}
+	return extensions, rows.Err()
+}
+
+// extensionStatements returns the statements creating ext or updating it to
+// its version, found as installed.
+func extensionStatements(db *sql.DB, ext v1.Extension, installed v1.ExtensionStatus) ([]string, error) {
+	target := ext.Version
+	if target == latestExtensionVersion {
+		target = installed.AvailableVersion
+	}
+	if target != "" && target != installed.InstalledVersion {
+		var available bool
+		err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM pg_available_extension_versions WHERE name = $1 AND version = $2)", ext.Name, target).Scan(&available)
+		if err != nil {
+			return nil, err
+		}
+		if !available {
+			return nil, fmt.Errorf("version %s of extension %s is not available on the server", target, ext.Name)
+		}
+	}
+
+	if installed.InstalledVersion == "" {
+		stmt := fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS %s", provisioner.QuoteIdentifier(ext.Name))
+		if ext.Schema != "" {
+			stmt += fmt.Sprintf(" WITH SCHEMA %s", provisioner.QuoteIdentifier(ext.Schema))
+		}
+		if target != "" {
+			stmt += fmt.Sprintf(" VERSION %s", provisioner.QuoteLiteral(target))
+		}
+		return []string{stmt}, nil
+	}
+	if target != "" && target != installed.InstalledVersion {
+		return []string{fmt.Sprintf("ALTER EXTENSION %s UPDATE TO %s", provisioner.QuoteIdentifier(ext.Name), provisioner.QuoteLiteral(target))}, nil
+	}
+	return nil, nil
+}
+
+// applyExtensions creates the extensions of dbResource in its database and
+// updates them to their version, returning their versions once applied.
+func applyExtensions(dbResource *v1.Database, inst *instance, exec *sqlExecutor) ([]v1.ExtensionStatus, error) {
+	if len(dbResource.Spec.Extensions) == 0 {
+		return nil, nil
+	}
+	if schemaMode(dbResource) {
+		return nil, fmt.Errorf("spec.extensions is not supported in schema mode, the extensions of the shared database are not managed by its Databases")
+	}
+	db, err := inst.openDatabase(databaseName(dbResource))
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	names := make([]string, len(dbResource.Spec.Extensions))
+	for i, ext := range dbResource.Spec.Extensions {
+		names[i] = ext.Name
+	}
+	installed, err := installedExtensions(db, names)
+	if err != nil {
+		return nil, err
+	}
+	for _, ext := range dbResource.Spec.Extensions {
+		current, ok := installed[ext.Name]
+		if !ok {
+			return nil, fmt.Errorf("extension %s is not available on the server", ext.Name)
+		}
+		stmts, err := extensionStatements(db, ext, current)
+		if err != nil {
+			return nil, err
+		}
+		for _, stmt := range stmts {
+			if err := exec.Exec(db, stmt); err != nil {
+				return nil, fmt.Errorf("error applying extension %s: %s", ext.Name, err.Error())
+			}
+		}
+	}
+	if exec.dryRun {
+		return dbResource.Status.Extensions, nil
+	}
+
+	if installed, err = installedExtensions(db, names); err != nil {
+		return nil, err
+	}
+	statuses := make([]v1.ExtensionStatus, 0, len(names))
+	for _, name := range names {
+		statuses = append(statuses, installed[name])
+	}
+	return statuses, nil
+}
+
+// setExtensionStatus records the extension versions applied in status,
+// along with whether newer ones are available, returning the updates now
+// available.
+func setExtensionStatus(status *v1.DatabaseStatus, applied []v1.ExtensionStatus) string {
+	status.Extensions = applied
+	var updates []string
+	for _, ext := range applied {
+		if ext.AvailableVersion != "" && ext.InstalledVersion != ext.AvailableVersion {
+			updates = append(updates, fmt.Sprintf("%s %s to %s", ext.Name, ext.InstalledVersion, ext.AvailableVersion))
+		}
+	}
+	if len(updates) == 0 {
+		if findCondition(status, extensionUpdateCondition) != nil {
+			setCondition(status, extensionUpdateCondition, conditionFalse, "UpToDate", "")
+		}
+		return ""
+	}
+	message := strings.Join(updates, ", ")
+	setCondition(status, extensionUpdateCondition, conditionTrue, "UpdateAvailable", message)
+	return message
+}
+
+// syncExtensions applies the extensions of a provisioned dbResource and
+// records their versions, raising an event when the server provides newer
+// ones, e.g. after an upgrade.
+func (c *Controller) syncExtensions(dbResource *v1.Database, inst *instance, exec *sqlExecutor) error {
+	if len(dbResource.Spec.Extensions) == 0 && len(dbResource.Status.Extensions) == 0 {
+		return nil
+	}
+	applied, err := applyExtensions(dbResource, inst, exec)
+	if err != nil {
+		return err
+	}
+	if exec.dryRun {
+		return nil
+	}
+	dbCopy := dbResource.DeepCopy()
+	updates := setExtensionStatus(&dbCopy.Status, applied)
+	if reflect.DeepEqual(dbCopy.Status, dbResource.Status) {
+		return nil
+	}
+	if cond := findCondition(&dbResource.Status, extensionUpdateCondition); updates != "" && (cond == nil || cond.Message != updates) {
+		c.recorder.Event(dbResource, corev1.EventTypeNormal, "ExtensionUpdateAvailable", "Newer extension versions are available: "+updates)
+	}
+	return c.updateStatus(dbCopy)
+}
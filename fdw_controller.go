@@ -0,0 +1,640 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+	clientset "github.com/joshrendek/k8s-external-postgres/pkg/client/clientset/versioned"
+	informers "github.com/joshrendek/k8s-external-postgres/pkg/client/informers/externalversions"
+	listers "github.com/joshrendek/k8s-external-postgres/pkg/client/listers/postgresql/v1"
+	"github.com/joshrendek/k8s-external-postgres/pkg/provisioner"
+)
+
+// optionNameRegexp matches the names of the options of foreign servers and
+// user mappings, which are written unquoted in the statements.
+var optionNameRegexp = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// FDWController creates the postgres_fdw foreign servers of ForeignServer
+// resources in their managed databases, and the user mappings of
+// UserMapping resources with the credentials of their Secret. Both are
+// checked for drift every --fdw-drift-interval.
+type FDWController struct {
+	kubeclientset     kubernetes.Interface
+	databaseClientset clientset.Interface
+
+	DatabasesLister      listers.DatabaseLister
+	DatabasesSynced      cache.InformerSynced
+	ForeignServersLister listers.ForeignServerLister
+	ForeignServersSynced cache.InformerSynced
+	UserMappingsLister   listers.UserMappingLister
+	UserMappingsSynced   cache.InformerSynced
+
+	serverQueue  workqueue.RateLimitingInterface
+	mappingQueue workqueue.RateLimitingInterface
+	recorder     record.EventRecorder
+	instances    *instanceRegistry
+}
+
+// NewFDWController returns a new foreign data wrapper controller
+func NewFDWController(
+	kubeclientset kubernetes.Interface,
+	databaseClientset clientset.Interface,
+	databaseInformerFactory informers.SharedInformerFactory,
+	instances *instanceRegistry) *FDWController {
+
+	databaseInformer := databaseInformerFactory.Databases().V1().Databases()
+	serverInformer := databaseInformerFactory.Databases().V1().ForeignServers()
+	mappingInformer := databaseInformerFactory.Databases().V1().UserMappings()
+
+	controller := &FDWController{
+		kubeclientset:        kubeclientset,
+		databaseClientset:    databaseClientset,
+		DatabasesLister:      databaseInformer.Lister(),
+		DatabasesSynced:      databaseInformer.Informer().HasSynced,
+		ForeignServersLister: serverInformer.Lister(),
+		ForeignServersSynced: serverInformer.Informer().HasSynced,
+		UserMappingsLister:   mappingInformer.Lister(),
+		UserMappingsSynced:   mappingInformer.Informer().HasSynced,
+		serverQueue:          workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "ForeignServers"),
+		mappingQueue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "UserMappings"),
+		recorder:             newEventRecorder(kubeclientset),
+		instances:            instances,
+	}
+
+	log.Info().Msg("Setting up foreign data wrapper event handlers")
+	serverInformer.Informer().AddEventHandler(instrumentHandler("foreignservers", "fdw", cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			enqueue(controller.serverQueue, obj)
+		},
+		UpdateFunc: func(old, new interface{}) {
+			// drift is checked every --fdw-drift-interval rather than on
+			// every resync of the informer
+			if old.(*v1.ForeignServer).ResourceVersion == new.(*v1.ForeignServer).ResourceVersion {
+				return
+			}
+			enqueue(controller.serverQueue, new)
+		},
+		// handled immediately, the resource is gone by the time the work
+		// queue would get it
+		DeleteFunc: controller.dropForeignServer,
+	}))
+	mappingInformer.Informer().AddEventHandler(instrumentHandler("usermappings", "fdw", cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			enqueue(controller.mappingQueue, obj)
+		},
+		UpdateFunc: func(old, new interface{}) {
+			if old.(*v1.UserMapping).ResourceVersion == new.(*v1.UserMapping).ResourceVersion {
+				return
+			}
+			enqueue(controller.mappingQueue, new)
+		},
+		DeleteFunc: controller.dropUserMapping,
+	}))
+	return controller
+}
+
+// Run waits for the informer caches to sync and starts the foreign server
+// and user mapping workers. It blocks until stopCh is closed.
+func (c *FDWController) Run(threadiness int, stopCh <-chan struct{}) error {
+	defer runtime.HandleCrash()
+
+	log.Info().Msg("Starting foreign data wrapper controller")
+	if ok := cache.WaitForCacheSync(stopCh, c.DatabasesSynced, c.ForeignServersSynced, c.UserMappingsSynced, c.instances.HasSynced); !ok {
+		return fmt.Errorf("failed to wait for caches to sync")
+	}
+
+	var queues sync.WaitGroup
+	queues.Add(2)
+	go func() {
+		defer queues.Done()
+		runQueueWorkers(c.serverQueue, threadiness, c.syncForeignServer, stopCh)
+	}()
+	go func() {
+		defer queues.Done()
+		runQueueWorkers(c.mappingQueue, threadiness, c.syncUserMapping, stopCh)
+	}()
+
+	<-stopCh
+	log.Info().Msg("Shutting down foreign data wrapper workers")
+	queues.Wait()
+
+	return nil
+}
+
+// foreignServerName returns the name of the foreign server of fs.
+func foreignServerName(fs *v1.ForeignServer) string {
+	if fs.Spec.Name != "" {
+		return fs.Spec.Name
+	}
+	return strings.Replace(fs.Name, "-", "_", -1)
+}
+
+// provisionedDatabase returns the Database called name in namespace once it
+// is provisioned.
+func (c *FDWController) provisionedDatabase(namespace, name string) (*v1.Database, error) {
+	dbResource, err := c.DatabasesLister.Databases(namespace).Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, fmt.Errorf("database %q does not exist yet", name)
+		}
+		return nil, err
+	}
+	if dbResource.Status.State != "provisioned" {
+		return nil, fmt.Errorf("database %q is not provisioned yet", name)
+	}
+	return dbResource, nil
+}
+
+// foreignServerOptions returns the options of the foreign server of fs: the
+// host, port and dbname of its target Database overridden by its spec.
+func (c *FDWController) foreignServerOptions(fs *v1.ForeignServer) (map[string]string, error) {
+	options := map[string]string{}
+	if fs.Spec.Target != "" {
+		target, err := c.provisionedDatabase(fs.Namespace, fs.Spec.Target)
+		if err != nil {
+			return nil, err
+		}
+		targetInst, err := c.instances.forDatabase(target)
+		if err != nil {
+			return nil, err
+		}
+		options["host"], options["port"] = targetInst.hostPort()
+		options["dbname"] = databaseName(target)
+	}
+	for name, value := range fs.Spec.Options {
+		if !optionNameRegexp.MatchString(name) {
+			return nil, fmt.Errorf("invalid option name %q", name)
+		}
+		options[name] = value
+	}
+	if options["host"] == "" || options["dbname"] == "" {
+		return nil, fmt.Errorf("the host and dbname options are required without a target")
+	}
+	return options, nil
+}
+
+// syncForeignServer creates the foreign server of a ForeignServer resource in
+// the database of its Database, and sets its options back to the spec when
+// they drifted. The owner role of the Database is granted USAGE on it.
+func (c *FDWController) syncForeignServer(ctx context.Context, logger zerolog.Logger, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		runtime.HandleError(fmt.Errorf("invalid resource key: %s", key))
+		return nil
+	}
+
+	fs, err := c.ForeignServersLister.ForeignServers(namespace).Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			runtime.HandleError(fmt.Errorf("foreign server '%s' in work queue no longer exists", key))
+			return nil
+		}
+		return err
+	}
+
+	serverName := foreignServerName(fs)
+	if fs.Status.ServerName != "" && fs.Status.ServerName != serverName {
+		return c.updateForeignServerStatus(fs, "error", fmt.Sprintf("the foreign server %q can't be renamed to %q", fs.Status.ServerName, serverName))
+	}
+	dbResource, err := c.provisionedDatabase(namespace, fs.Spec.Database)
+	if err != nil {
+		return err
+	}
+	inst, err := c.instances.forDatabase(dbResource)
+	if err != nil {
+		return err
+	}
+	if !inst.dialect.foreignServers {
+		return c.updateForeignServerStatus(fs, "error", fmt.Sprintf("foreign servers are not supported by %s", inst.dialect.name))
+	}
+	options, err := c.foreignServerOptions(fs)
+	if err != nil {
+		return c.updateForeignServerStatus(fs, "error", err.Error())
+	}
+
+	db, err := inst.openDatabase(databaseName(dbResource))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	exec := newResourceExecutor(ctx, "ForeignServer", fs, logger)
+	changed, err := ensureForeignServer(exec, db, fs, serverName, options, roleName(dbResource))
+	if err != nil {
+		if _, conflict := err.(foreignServerConflict); conflict {
+			return c.updateForeignServerStatus(fs, "conflict", err.Error())
+		}
+		c.recorder.Event(fs, corev1.EventTypeWarning, "ForeignServerFailed", err.Error())
+		if statusErr := c.updateForeignServerStatus(fs, "error", err.Error()); statusErr != nil {
+			return statusErr
+		}
+		return err
+	}
+	if len(changed) > 0 && fs.Status.State == "provisioned" {
+		logger.Info().Strs("options", changed).Msg("foreign server options differed from the spec, setting them again")
+		c.recorder.Event(fs, corev1.EventTypeNormal, "OptionsUpdated", fmt.Sprintf("Set foreign server options: %s", strings.Join(changed, ", ")))
+	}
+
+	if fs.Status.State != "provisioned" {
+		c.recorder.Event(fs, corev1.EventTypeNormal, SuccessSynced, "Foreign server synced successfully")
+	}
+	fsCopy := fs.DeepCopy()
+	fsCopy.Status.ServerName = serverName
+	if err := c.setForeignServerStatus(fs, fsCopy, "provisioned", "successful"); err != nil {
+		return err
+	}
+	c.serverQueue.AddAfter(key, fdwDriftInterval)
+	return nil
+}
+
+// foreignServerConflict is returned for a foreign server of the same name
+// that isn't a postgres_fdw one.
+type foreignServerConflict string
+
+func (e foreignServerConflict) Error() string {
+	return string(e)
+}
+
+// ensureForeignServer creates the foreign server called name with options
+// when it is missing, otherwise sets, adds and drops its options so they
+// match. It returns the names of the options changed on an existing server.
+func ensureForeignServer(exec *sqlExecutor, db *sql.DB, fs *v1.ForeignServer, name string, options map[string]string, owner string) ([]string, error) {
+	var wrapper string
+	var current []string
+	err := db.QueryRow("SELECT w.fdwname, COALESCE(s.srvoptions, '{}') FROM pg_foreign_server s JOIN pg_foreign_data_wrapper w ON w.oid = s.srvfdw WHERE s.srvname = $1",
+		name).Scan(&wrapper, scanTextArray(&current))
+	var changed []string
+	switch {
+	case err == sql.ErrNoRows:
+		if err := exec.Exec(db, "CREATE EXTENSION IF NOT EXISTS postgres_fdw"); err != nil {
+			return nil, err
+		}
+		stmt := fmt.Sprintf("CREATE SERVER %s FOREIGN DATA WRAPPER postgres_fdw", provisioner.QuoteIdentifier(name))
+		if clause := optionsClause(nil, options); clause != "" {
+			stmt += " OPTIONS (" + clause + ")"
+		}
+		if err := exec.Exec(db, stmt); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	case wrapper != "postgres_fdw":
+		return nil, foreignServerConflict(fmt.Sprintf("Foreign server %q already exists for the %s wrapper", name, wrapper))
+	default:
+		if fs.Status.ServerName == "" {
+			exec.logger.Info().Str("server", name).Msg("adopting existing foreign server")
+		}
+		existing := parseOptions(current)
+		if clause := optionsClause(existing, options); clause != "" {
+			if err := exec.Exec(db, fmt.Sprintf("ALTER SERVER %s OPTIONS (%s)", provisioner.QuoteIdentifier(name), clause)); err != nil {
+				return nil, err
+			}
+			changed = changedOptions(existing, options)
+		}
+	}
+
+	var usage bool
+	if err := db.QueryRow("SELECT has_server_privilege($1, $2, 'USAGE')", owner, name).Scan(&usage); err != nil {
+		return nil, err
+	}
+	if !usage {
+		if err := exec.Exec(db, fmt.Sprintf("GRANT USAGE ON FOREIGN SERVER %s TO %s", provisioner.QuoteIdentifier(name), provisioner.QuoteIdentifier(owner))); err != nil {
+			return nil, err
+		}
+	}
+	return changed, nil
+}
+
+// parseOptions returns the options of a srvoptions or umoptions array, by
+// name.
+func parseOptions(options []string) map[string]string {
+	parsed := make(map[string]string, len(options))
+	for _, option := range options {
+		parts := strings.SplitN(option, "=", 2)
+		if len(parts) == 2 {
+			parsed[parts[0]] = parts[1]
+		}
+	}
+	return parsed
+}
+
+// optionsClause returns the OPTIONS clause turning the current options into
+// the desired ones, empty when they match. Without current options it lists
+// the desired ones, as CREATE takes them.
+func optionsClause(current, desired map[string]string) string {
+	var clauses []string
+	for _, name := range changedOptions(current, desired) {
+		value, ok := desired[name]
+		literal := "'" + strings.Replace(value, "'", "''", -1) + "'"
+		switch _, exists := current[name]; {
+		case !ok:
+			clauses = append(clauses, "DROP "+name)
+		case current == nil:
+			clauses = append(clauses, name+" "+literal)
+		case exists:
+			clauses = append(clauses, "SET "+name+" "+literal)
+		default:
+			clauses = append(clauses, "ADD "+name+" "+literal)
+		}
+	}
+	return strings.Join(clauses, ", ")
+}
+
+// changedOptions returns the sorted names of the options differing between
+// current and desired.
+func changedOptions(current, desired map[string]string) []string {
+	var names []string
+	for name, value := range desired {
+		if existing, ok := current[name]; !ok || existing != value {
+			names = append(names, name)
+		}
+	}
+	for name := range current {
+		if _, ok := desired[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// userMappingRole returns the local role of the user mapping of um, whose
+// foreign server is in the database of dbResource.
+func userMappingRole(um *v1.UserMapping, dbResource *v1.Database) string {
+	if um.Spec.User != "" {
+		return um.Spec.User
+	}
+	return roleName(dbResource)
+}
+
+// quoteMappingRole quotes the role of a user mapping, PUBLIC being a keyword.
+func quoteMappingRole(role string) string {
+	if strings.ToUpper(role) == "PUBLIC" {
+		return "PUBLIC"
+	}
+	return provisioner.QuoteIdentifier(role)
+}
+
+// syncUserMapping creates the user mapping of a UserMapping resource on the
+// foreign server of its ForeignServer, with the username and password of its
+// Secret, and sets them again when they drifted or the Secret changed.
+func (c *FDWController) syncUserMapping(ctx context.Context, logger zerolog.Logger, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		runtime.HandleError(fmt.Errorf("invalid resource key: %s", key))
+		return nil
+	}
+
+	um, err := c.UserMappingsLister.UserMappings(namespace).Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			runtime.HandleError(fmt.Errorf("user mapping '%s' in work queue no longer exists", key))
+			return nil
+		}
+		return err
+	}
+
+	fs, err := c.ForeignServersLister.ForeignServers(namespace).Get(um.Spec.Server)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return fmt.Errorf("foreign server %q does not exist yet", um.Spec.Server)
+		}
+		return err
+	}
+	if fs.Status.State != "provisioned" {
+		return fmt.Errorf("foreign server %q is not provisioned yet", um.Spec.Server)
+	}
+	dbResource, err := c.provisionedDatabase(namespace, fs.Spec.Database)
+	if err != nil {
+		return err
+	}
+	inst, err := c.instances.forDatabase(dbResource)
+	if err != nil {
+		return err
+	}
+	options, err := c.userMappingOptions(um)
+	if err != nil {
+		return c.updateUserMappingStatus(um, um.DeepCopy(), "error", err.Error())
+	}
+
+	db, err := inst.openDatabase(databaseName(dbResource))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	exec := newResourceExecutor(ctx, "UserMapping", um, logger)
+	serverName := fs.Status.ServerName
+	role := userMappingRole(um, dbResource)
+	if um.Status.User != "" && (um.Status.User != role || um.Status.ServerName != serverName) {
+		// the mapping moved to another role or server
+		stmt := fmt.Sprintf("DROP USER MAPPING IF EXISTS FOR %s SERVER %s", quoteMappingRole(um.Status.User), provisioner.QuoteIdentifier(um.Status.ServerName))
+		if err := exec.Exec(db, stmt); err != nil {
+			logger.Error().Err(err).Msg("error dropping previous user mapping")
+		}
+	}
+	if err := ensureUserMapping(exec, db, serverName, role, options); err != nil {
+		c.recorder.Event(um, corev1.EventTypeWarning, "UserMappingFailed", err.Error())
+		if statusErr := c.updateUserMappingStatus(um, um.DeepCopy(), "error", err.Error()); statusErr != nil {
+			return statusErr
+		}
+		return err
+	}
+
+	if um.Status.State != "provisioned" {
+		c.recorder.Event(um, corev1.EventTypeNormal, SuccessSynced, "User mapping synced successfully")
+	}
+	umCopy := um.DeepCopy()
+	umCopy.Status.ServerName = serverName
+	umCopy.Status.User = role
+	if err := c.updateUserMappingStatus(um, umCopy, "provisioned", "successful"); err != nil {
+		return err
+	}
+	c.mappingQueue.AddAfter(key, fdwDriftInterval)
+	return nil
+}
+
+// userMappingOptions returns the user and password options of the user
+// mapping of um, read from its Secret.
+func (c *FDWController) userMappingOptions(um *v1.UserMapping) (map[string]string, error) {
+	secret, err := c.kubeclientset.CoreV1().Secrets(um.Namespace).Get(um.Spec.CredentialsSecret, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	usernameKey, passwordKey := um.Spec.UsernameKey, um.Spec.PasswordKey
+	if usernameKey == "" {
+		usernameKey = "USERNAME"
+	}
+	if passwordKey == "" {
+		passwordKey = "PASSWORD"
+	}
+	options := map[string]string{}
+	for option, key := range map[string]string{"user": usernameKey, "password": passwordKey} {
+		value, ok := secret.Data[key]
+		if !ok {
+			return nil, fmt.Errorf("secret %q has no %s key", um.Spec.CredentialsSecret, key)
+		}
+		options[option] = string(value)
+	}
+	return options, nil
+}
+
+// ensureUserMapping creates the user mapping of role on the foreign server
+// called server with options when it is missing, otherwise sets them when
+// they differ.
+func ensureUserMapping(exec *sqlExecutor, db *sql.DB, server, role string, options map[string]string) error {
+	usename := role
+	if strings.ToUpper(role) == "PUBLIC" {
+		usename = "public"
+	}
+	var current []string
+	err := db.QueryRow("SELECT COALESCE(umoptions, '{}') FROM pg_user_mappings WHERE srvname = $1 AND usename = $2", server, usename).Scan(scanTextArray(&current))
+	switch {
+	case err == sql.ErrNoRows:
+		return exec.Exec(db, fmt.Sprintf("CREATE USER MAPPING FOR %s SERVER %s OPTIONS (%s)",
+			quoteMappingRole(role), provisioner.QuoteIdentifier(server), optionsClause(nil, options)))
+	case err != nil:
+		return err
+	}
+	clause := optionsClause(parseOptions(current), options)
+	if clause == "" {
+		return nil
+	}
+	return exec.Exec(db, fmt.Sprintf("ALTER USER MAPPING FOR %s SERVER %s OPTIONS (%s)", quoteMappingRole(role), provisioner.QuoteIdentifier(server), clause))
+}
+
+// dropForeignServer drops the foreign server of a deleted ForeignServer,
+// along with its user mappings. It fails, leaving the server in place,
+// while foreign tables still use it.
+func (c *FDWController) dropForeignServer(obj interface{}) {
+	fs, ok := obj.(*v1.ForeignServer)
+	if !ok || fs.Status.ServerName == "" {
+		return
+	}
+	dbResource, err := c.DatabasesLister.Databases(fs.Namespace).Get(fs.Spec.Database)
+	if err != nil {
+		// the database, and the foreign server with it, is already gone
+		return
+	}
+	inst, err := c.instances.forDatabase(dbResource)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	db, err := inst.openDatabase(databaseName(dbResource))
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	defer db.Close()
+
+	ctx, logger := resourceContext(context.Background(), fs.Namespace, fs.Name)
+	logger.Info().Str("server", fs.Status.ServerName).Msg("dropping foreign server")
+	exec := newResourceExecutor(ctx, "ForeignServer", fs, logger)
+	rows, err := db.Query("SELECT usename FROM pg_user_mappings WHERE srvname = $1", fs.Status.ServerName)
+	if err != nil {
+		logger.Error().Err(err).Msg("error dropping foreign server")
+		return
+	}
+	var roles []string
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			rows.Close()
+			logger.Error().Err(err).Msg("error dropping foreign server")
+			return
+		}
+		roles = append(roles, role)
+	}
+	rows.Close()
+	for _, role := range roles {
+		stmt := fmt.Sprintf("DROP USER MAPPING IF EXISTS FOR %s SERVER %s", quoteMappingRole(role), provisioner.QuoteIdentifier(fs.Status.ServerName))
+		if err := exec.Exec(db, stmt); err != nil {
+			logger.Error().Err(err).Str("user", role).Msg("error dropping user mapping")
+		}
+	}
+	if err := exec.Exec(db, fmt.Sprintf("DROP SERVER IF EXISTS %s", provisioner.QuoteIdentifier(fs.Status.ServerName))); err != nil {
+		logger.Error().Err(err).Msg("error dropping foreign server")
+	}
+}
+
+// dropUserMapping drops the user mapping of a deleted UserMapping.
+func (c *FDWController) dropUserMapping(obj interface{}) {
+	um, ok := obj.(*v1.UserMapping)
+	if !ok || um.Status.User == "" {
+		return
+	}
+	fs, err := c.ForeignServersLister.ForeignServers(um.Namespace).Get(um.Spec.Server)
+	if err != nil {
+		// dropping the foreign server drops its user mappings
+		return
+	}
+	dbResource, err := c.DatabasesLister.Databases(fs.Namespace).Get(fs.Spec.Database)
+	if err != nil {
+		return
+	}
+	inst, err := c.instances.forDatabase(dbResource)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	db, err := inst.openDatabase(databaseName(dbResource))
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	defer db.Close()
+
+	ctx, logger := resourceContext(context.Background(), um.Namespace, um.Name)
+	logger.Info().Str("server", um.Status.ServerName).Str("user", um.Status.User).Msg("dropping user mapping")
+	exec := newResourceExecutor(ctx, "UserMapping", um, logger)
+	stmt := fmt.Sprintf("DROP USER MAPPING IF EXISTS FOR %s SERVER %s", quoteMappingRole(um.Status.User), provisioner.QuoteIdentifier(um.Status.ServerName))
+	if err := exec.Exec(db, stmt); err != nil {
+		logger.Error().Err(err).Msg("error dropping user mapping")
+	}
+}
+
+func (c *FDWController) updateForeignServerStatus(fs *v1.ForeignServer, state, message string) error {
+	return c.setForeignServerStatus(fs, fs.DeepCopy(), state, message)
+}
+
+// setForeignServerStatus writes fsCopy with state and message unless nothing
+// changed since fs.
+func (c *FDWController) setForeignServerStatus(fs, fsCopy *v1.ForeignServer, state, message string) error {
+	fsCopy.Status.State = state
+	fsCopy.Status.Message = message
+	if fsCopy.Status == fs.Status {
+		return nil
+	}
+	_, err := c.databaseClientset.DatabasesV1().ForeignServers(fs.Namespace).Update(fsCopy)
+	return err
+}
+
+// updateUserMappingStatus writes umCopy with state and message unless
+// nothing changed since um.
+func (c *FDWController) updateUserMappingStatus(um, umCopy *v1.UserMapping, state, message string) error {
+	umCopy.Status.State = state
+	umCopy.Status.Message = message
+	if umCopy.Status == um.Status {
+		return nil
+	}
+	_, err := c.databaseClientset.DatabasesV1().UserMappings(um.Namespace).Update(umCopy)
+	return err
+}
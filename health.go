@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// readiness is reported on /readyz: a replica is ready once its informer
+// caches are synced, or while it is a standby waiting for leadership.
+var readiness = struct {
+	mu     sync.Mutex
+	reason string
+}{reason: "waiting for informer caches to sync"}
+
+// setNotReady makes /readyz fail with reason, or succeed when it is empty.
+func setNotReady(reason string) {
+	readiness.mu.Lock()
+	defer readiness.mu.Unlock()
+	readiness.reason = reason
+}
+
+// runHealthServer serves the liveness probe on /healthz and the readiness
+// one on /readyz on --health-probe-addr until stopCh is closed.
+func runHealthServer(stopCh <-chan struct{}) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		readiness.mu.Lock()
+		reason := readiness.reason
+		readiness.mu.Unlock()
+		if reason != "" {
+			http.Error(w, reason, http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	server := &http.Server{Addr: healthProbeAddr, Handler: mux}
+
+	go func() {
+		<-stopCh
+		server.Close()
+	}()
+
+	log.Info().Str("addr", healthProbeAddr).Msg("Starting health probes")
+	err := server.ListenAndServe()
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+const (
+	hookPreCreate  = "preCreate"
+	hookPostCreate = "postCreate"
+	hookPreDelete  = "preDelete"
+	hookPostDelete = "postDelete"
+
+	hookFailurePolicyFail   = "Fail"
+	hookFailurePolicyIgnore = "Ignore"
+
+	// hookDatabaseUIDAnnotation records on the Jobs of delete hooks the UID
+	// of the deleted Database they run for, telling them from the Jobs left
+	// by an earlier Database of the same name.
+	hookDatabaseUIDAnnotation = "postgresql.org/database-uid"
+)
+
+// hookClient calls the hook webhooks. Redirects are not followed, they could
+// lead off the --hook-webhook-hosts.
+var hookClient = &http.Client{
+	Timeout: 30 * time.Second,
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// hookPayload is the body POSTed to the webhook of a hook.
+type hookPayload struct {
+	Hook      string `json:"hook"`
+	On        string `json:"on"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	UID       string `json:"uid"`
+	Database  string `json:"database"`
+	Role      string `json:"role"`
+	Instance  string `json:"instance,omitempty"`
+}
+
+// validateHooks checks the hooks of dbResource have a unique name, a known
+// lifecycle point and failure policy, and exactly one step.
+func validateHooks(dbResource *v1.Database) error {
+	names := map[string]bool{}
+	for _, hook := range dbResource.Spec.Hooks {
+		if hook.Name == "" || names[hook.Name] {
+			return fmt.Errorf("hooks must have a unique name, got %q", hook.Name)
+		}
+		names[hook.Name] = true
+		switch hook.On {
+		case hookPreCreate, hookPostCreate, hookPreDelete, hookPostDelete:
+		default:
+			return fmt.Errorf("unknown on %q of hook %s, must be preCreate, postCreate, preDelete or postDelete", hook.On, hook.Name)
+		}
+		switch hook.FailurePolicy {
+		case "", hookFailurePolicyFail, hookFailurePolicyIgnore:
+		default:
+			return fmt.Errorf("unknown failurePolicy %q of hook %s, must be Fail or Ignore", hook.FailurePolicy, hook.Name)
+		}
+		steps := 0
+		if hook.SQL != "" {
+			if hook.On == hookPreCreate || hook.On == hookPostDelete {
+				return fmt.Errorf("the sql of hook %s runs as the owner role, only on postCreate or preDelete", hook.Name)
+			}
+			steps++
+		}
+		if hook.Webhook != "" {
+			if err := checkHookWebhook(hook.Webhook); err != nil {
+				return err
+			}
+			steps++
+		}
+		if hook.Job != nil {
+			if hook.Job.Image == "" {
+				return fmt.Errorf("the job of hook %s has no image", hook.Name)
+			}
+			steps++
+		}
+		if steps != 1 {
+			return fmt.Errorf("hook %s must have exactly one of sql, webhook or job", hook.Name)
+		}
+	}
+	return nil
+}
+
+// runCreateHooks runs the hooks of dbResource on the preCreate or postCreate
+// point, in order, recording each one completed in the provisioning progress
+// so a resumed provisioning doesn't run it again. It returns the updated
+// Database, and whether a hook Job is still running, in which case the
+// Database is synced again once the Job changes.
+func (c *Controller) runCreateHooks(logger zerolog.Logger, dbResource *v1.Database, inst *instance, exec *sqlExecutor, on string) (*v1.Database, bool, error) {
+	for _, hook := range dbResource.Spec.Hooks {
+		if hook.On != on || containsString(progress(dbResource).HooksRun, hook.Name) {
+			continue
+		}
+		running, err := c.runHook(logger, dbResource, inst, exec, hook)
+		if running {
+			logger.Info().Str("hook", hook.Name).Msg("waiting for hook job")
+			return dbResource, true, nil
+		}
+		if err != nil {
+			if hook.FailurePolicy != hookFailurePolicyIgnore {
+				return dbResource, false, fmt.Errorf("hook %s failed: %s", hook.Name, err.Error())
+			}
+			c.recorder.Event(dbResource, corev1.EventTypeWarning, "HookFailed", fmt.Sprintf("Hook %s failed, ignored: %s", hook.Name, err.Error()))
+		}
+		name := hook.Name
+		if dbResource, err = c.recordProgress(dbResource, exec, func(p *v1.ProvisioningProgress) { p.HooksRun = append(p.HooksRun, name) }); err != nil {
+			return dbResource, false, err
+		}
+	}
+	return dbResource, false, nil
+}
+
+// runDeleteHooks runs the hooks of the deleted dbResource on the preDelete
+// or postDelete point, in order, recording each one completed in the
+// progress of the returned copy of dbResource, which a requeued deletion
+// resumes from. It returns whether a hook Job is still running, in which
+// case the deletion is requeued, and the error of the first failed hook
+// whose failure policy is Fail.
+func (c *Controller) runDeleteHooks(logger zerolog.Logger, dbResource *v1.Database, inst *instance, exec *sqlExecutor, on string) (*v1.Database, bool, error) {
+	for _, hook := range dbResource.Spec.Hooks {
+		if hook.On != on || containsString(progress(dbResource).HooksRun, hook.Name) {
+			continue
+		}
+		logger.Info().Str("hook", hook.Name).Str("on", on).Msg("running hook")
+		running, err := c.runHook(logger, dbResource, inst, exec, hook)
+		if running {
+			logger.Info().Str("hook", hook.Name).Msg("waiting for hook job")
+			return dbResource, true, nil
+		}
+		if err != nil {
+			c.recorder.Event(dbResource, corev1.EventTypeWarning, "HookFailed", fmt.Sprintf("Hook %s failed: %s", hook.Name, err.Error()))
+			if hook.FailurePolicy != hookFailurePolicyIgnore {
+				return dbResource, false, fmt.Errorf("hook %s failed: %s", hook.Name, err.Error())
+			}
+			logger.Error().Err(err).Str("hook", hook.Name).Msg("hook failed, ignored")
+		}
+		dbCopy := dbResource.DeepCopy()
+		p := progress(dbCopy)
+		p.HooksRun = append(p.HooksRun, hook.Name)
+		dbCopy.Status.Progress = &p
+		dbResource = dbCopy
+	}
+	return dbResource, false, nil
+}
+
+// runHook runs the step of hook for dbResource. SQL runs in the database
+// logged in as the owner role. Hook Jobs are only started, reporting whether
+// they are still running. Webhooks and Jobs are skipped in dry run.
+func (c *Controller) runHook(logger zerolog.Logger, dbResource *v1.Database, inst *instance, exec *sqlExecutor, hook v1.Hook) (bool, error) {
+	switch {
+	case hook.SQL != "":
+		if hook.On == hookPreCreate || hook.On == hookPostDelete {
+			return false, fmt.Errorf("the sql of hook %s can't run on %s, the owner role doesn't exist then", hook.Name, hook.On)
+		}
+		db, err := c.openStoredOwner(dbResource, inst)
+		if err != nil {
+			return false, err
+		}
+		defer db.Close()
+		return false, exec.Exec(db, hook.SQL)
+	case exec.dryRun:
+		logger.Info().Str("hook", hook.Name).Msg("dry run, skipping hook")
+		return false, nil
+	case hook.Webhook != "":
+		if err := checkHookWebhook(hook.Webhook); err != nil {
+			return false, err
+		}
+		return false, postJSON(hookClient, hook.Webhook, hookPayload{
+			Hook:      hook.Name,
+			On:        hook.On,
+			Namespace: dbResource.Namespace,
+			Name:      dbResource.Name,
+			UID:       string(dbResource.UID),
+			Database:  databaseName(dbResource),
+			Role:      roleName(dbResource),
+			Instance:  instanceName(dbResource),
+		})
+	default:
+		if hook.On == hookPreDelete || hook.On == hookPostDelete {
+			return c.runDeleteHookJob(dbResource, hook)
+		}
+		return c.runCreateHookJob(dbResource, hook)
+	}
+}
+
+// checkHookWebhook checks the webhook of a hook is an http or https URL of
+// one of the --hook-webhook-hosts, so Databases can't make the controller
+// call any address it reaches.
+func checkHookWebhook(webhook string) error {
+	u, err := url.Parse(webhook)
+	if err != nil {
+		return fmt.Errorf("invalid hook webhook %q: %s", webhook, err.Error())
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("hook webhook %q must be an http or https URL", webhook)
+	}
+	for _, host := range splitList(hookWebhookHosts) {
+		if strings.EqualFold(u.Host, host) {
+			return nil
+		}
+	}
+	return fmt.Errorf("the host of hook webhook %q is not one of --hook-webhook-hosts", webhook)
+}
+
+// hookJobName returns the name of the Job of hook.
+func hookJobName(dbResource *v1.Database, hook v1.Hook) string {
+	return fmt.Sprintf("%s-hook-%s", dbResource.Name, hook.Name)
+}
+
+// runCreateHookJob starts the Job of a create hook, owned by dbResource, and
+// reports whether it is still running or the error it failed with.
+func (c *Controller) runCreateHookJob(dbResource *v1.Database, hook v1.Hook) (bool, error) {
+	name := hookJobName(dbResource, hook)
+	job, err := c.kubeclientset.BatchV1().Jobs(dbResource.Namespace).Get(name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		job, err = c.kubeclientset.BatchV1().Jobs(dbResource.Namespace).Create(newHookJob(dbResource, hook, name))
+	}
+	if err != nil {
+		return false, err
+	}
+	if !metav1.IsControlledBy(job, dbResource) {
+		return false, fmt.Errorf(MessageResourceExists, job.Name)
+	}
+	return hookJobResult(job)
+}
+
+// runDeleteHookJob starts the Job of a delete hook and reports whether it
+// is still running or the error it failed with, a Job running for longer
+// than --hook-timeout failing. A Job left by an earlier Database of the same
+// name is deleted first, rather than its result reported, the new one being
+// created once it is gone.
+func (c *Controller) runDeleteHookJob(dbResource *v1.Database, hook v1.Hook) (bool, error) {
+	name := hookJobName(dbResource, hook)
+	jobs := c.kubeclientset.BatchV1().Jobs(dbResource.Namespace)
+	job, err := jobs.Get(name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = jobs.Create(newHookJob(dbResource, hook, name))
+		return err == nil, err
+	}
+	if err != nil {
+		return false, err
+	}
+	if job.Annotations[hookDatabaseUIDAnnotation] != string(dbResource.UID) {
+		if job.DeletionTimestamp == nil {
+			propagation := metav1.DeletePropagationBackground
+			err := jobs.Delete(name, &metav1.DeleteOptions{PropagationPolicy: &propagation})
+			if err != nil && !errors.IsNotFound(err) {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	running, err := hookJobResult(job)
+	if running && time.Since(job.CreationTimestamp.Time) > hookTimeout {
+		return false, fmt.Errorf("job %s did not finish within %s", name, hookTimeout)
+	}
+	return running, err
+}
+
+// deleteHookJobs deletes the Jobs the delete hooks of dbResource ran once
+// its deletion is over.
+func (c *Controller) deleteHookJobs(logger zerolog.Logger, dbResource *v1.Database) {
+	jobs := c.kubeclientset.BatchV1().Jobs(dbResource.Namespace)
+	for _, hook := range dbResource.Spec.Hooks {
+		if hook.Job == nil || (hook.On != hookPreDelete && hook.On != hookPostDelete) {
+			continue
+		}
+		name := hookJobName(dbResource, hook)
+		job, err := jobs.Get(name, metav1.GetOptions{})
+		if err != nil || job.Annotations[hookDatabaseUIDAnnotation] != string(dbResource.UID) {
+			continue
+		}
+		propagation := metav1.DeletePropagationBackground
+		if err := jobs.Delete(name, &metav1.DeleteOptions{PropagationPolicy: &propagation}); err != nil && !errors.IsNotFound(err) {
+			logger.Error().Err(err).Str("hook", hook.Name).Msg("error deleting hook job")
+		}
+	}
+}
+
+// hookJobResult reports whether job is still running, or the error it
+// failed with.
+func hookJobResult(job *batchv1.Job) (bool, error) {
+	cond := finishedJobCondition(job)
+	if cond == nil {
+		return true, nil
+	}
+	if cond.Type == batchv1.JobFailed {
+		return false, fmt.Errorf("job %s failed: %s", job.Name, cond.Message)
+	}
+	return false, nil
+}
+
+// newHookJob builds the Job of hook. Create hook Jobs are owned by
+// dbResource, the ones of delete hooks outlive it.
+func newHookJob(dbResource *v1.Database, hook v1.Hook, name string) *batchv1.Job {
+	var backoffLimit int32 = 0
+	env := []corev1.EnvVar{
+		{Name: "HOOK", Value: hook.On},
+		{Name: "DATABASE_NAMESPACE", Value: dbResource.Namespace},
+		{Name: "DATABASE_NAME", Value: dbResource.Name},
+		{Name: "DATABASE", Value: databaseName(dbResource)},
+		{Name: "ROLE", Value: roleName(dbResource)},
+	}
+	if (hook.On == hookPostCreate || hook.On == hookPreDelete) && hasCredentialsSecret(dbResource) {
+		databaseURL := databaseURLEnv(dbResource.Name)
+		// the Secret of a deleted Database may be garbage collected first
+		optional := hook.On == hookPreDelete
+		databaseURL.ValueFrom.SecretKeyRef.Optional = &optional
+		env = append(env, databaseURL)
+	}
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: dbResource.Namespace,
+		},
+		Spec: batchv1.JobSpec{
+			// hooks are not assumed idempotent, a failed one is reported
+			// rather than retried
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{
+						{
+							Name:    "hook",
+							Image:   hook.Job.Image,
+							Command: hook.Job.Command,
+							Args:    hook.Job.Args,
+							Env:     env,
+						},
+					},
+				},
+			},
+		},
+	}
+	if hook.On == hookPreCreate || hook.On == hookPostCreate {
+		job.OwnerReferences = []metav1.OwnerReference{
+			*metav1.NewControllerRef(dbResource, v1.SchemeGroupVersion.WithKind("Database")),
+		}
+	} else {
+		job.Annotations = map[string]string{hookDatabaseUIDAnnotation: string(dbResource.UID)}
+	}
+	return job
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+// waitPendingDrop waits for the lister to see the pending drop record of the
+// Database called name in the given phase, nil once it is deleted.
+func (f *testFixture) waitPendingDrop(name string, phase *string) {
+	err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		configMap, err := f.controller.ConfigMapsLister.ConfigMaps(metav1.NamespaceDefault).Get(pendingDropName(name))
+		if phase == nil {
+			return errors.IsNotFound(err), nil
+		}
+		return err == nil && configMap.Data[pendingDropPhaseKey] == *phase, nil
+	})
+	if err != nil {
+		f.t.Fatalf("pending drop of %s not in phase %v: %s", name, phase, err)
+	}
+}
+
+func TestDeleteHookJob(t *testing.T) {
+	db := testDatabase("app", nil)
+	db.UID = types.UID("current")
+	db.Spec.Hooks = []v1.Hook{{Name: "dump", On: hookPreDelete, Job: &v1.HookJob{Image: "dump"}}}
+	f := newFixture(t, "delete-hook-job", db)
+	defer f.stop()
+	db = f.reconcileUntil("app", inState("provisioned"))
+
+	// left by an earlier Database of the same name
+	jobs := f.kubeclient.BatchV1().Jobs(metav1.NamespaceDefault)
+	name := hookJobName(db, db.Spec.Hooks[0])
+	stale := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+		Name:        name,
+		Namespace:   metav1.NamespaceDefault,
+		Annotations: map[string]string{hookDatabaseUIDAnnotation: "earlier"},
+	}}
+	stale.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+	if _, err := jobs.Create(stale); err != nil {
+		t.Fatalf("create job: %s", err)
+	}
+
+	f.controller.deleteDatabase(db)
+	if _, err := jobs.Get(name, metav1.GetOptions{}); !errors.IsNotFound(err) {
+		t.Errorf("stale hook job kept, err %v", err)
+	}
+	if f.server.Database("app") == nil {
+		t.Fatalf("database app dropped before the preDelete hook ran")
+	}
+	preDelete := hookPreDelete
+	f.waitPendingDrop("app", &preDelete)
+
+	f.controller.dropExpiredDatabases()
+	job, err := jobs.Get(name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("hook job not created: %s", err)
+	}
+	if job.Annotations[hookDatabaseUIDAnnotation] != "current" {
+		t.Errorf("hook job annotations = %v, want the UID of the deleted Database", job.Annotations)
+	}
+	if f.server.Database("app") == nil {
+		t.Fatalf("database app dropped while the preDelete hook runs")
+	}
+
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+	if _, err := jobs.Update(job); err != nil {
+		t.Fatalf("update job: %s", err)
+	}
+	f.controller.dropExpiredDatabases()
+	if f.server.Database("app") != nil {
+		t.Errorf("database app kept once the preDelete hook completed")
+	}
+	if _, err := jobs.Get(name, metav1.GetOptions{}); !errors.IsNotFound(err) {
+		t.Errorf("hook job kept once the deletion is over, err %v", err)
+	}
+	f.waitPendingDrop("app", nil)
+}
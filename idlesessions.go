@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/runtime"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+	"github.com/joshrendek/k8s-external-postgres/pkg/provisioner"
+)
+
+// idleSessions returns the pids of the sessions of the roles of dbResource
+// that have been idle, or idle in a transaction, for longer than its
+// spec.idleSessionTimeout.
+func idleSessions(dbResource *v1.Database, inst *instance) ([]string, error) {
+	var roles []string
+	for _, role := range ownedRoles(dbResource) {
+		roles = append(roles, provisioner.QuoteLiteral(role))
+	}
+	rows, err := inst.DB.Query(fmt.Sprintf(`SELECT pid FROM pg_stat_activity
+		WHERE usename IN (%s) AND state LIKE 'idle%%' AND pid <> pg_backend_pid()
+		AND state_change < now() - $1 * interval '1 second'`, strings.Join(roles, ", ")),
+		dbResource.Spec.IdleSessionTimeout.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pids []string
+	for rows.Next() {
+		var pid int64
+		if err := rows.Scan(&pid); err != nil {
+			return nil, err
+		}
+		pids = append(pids, strconv.FormatInt(pid, 10))
+	}
+	return pids, rows.Err()
+}
+
+// terminateIdleSessions terminates the sessions idle past the
+// spec.idleSessionTimeout of every provisioned Database setting one, for
+// tenants leaking connections on servers whose own timeouts can't be
+// changed. Sessions running a statement are left alone.
+func (c *Controller) terminateIdleSessions() {
+	dbResources, err := c.DatabasesLister.List(labels.Everything())
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	for _, dbResource := range dbResources {
+		timeout := dbResource.Spec.IdleSessionTimeout
+		if timeout == nil || timeout.Duration <= 0 || dbResource.Status.State != "provisioned" || paused(dbResource) {
+			continue
+		}
+		inst, err := c.instances.forDatabase(dbResource)
+		if err != nil || !inst.dialect.terminateBackends || !inst.dialect.statistics || inst.unavailable() != nil {
+			continue
+		}
+		pids, err := idleSessions(dbResource, inst)
+		if err != nil {
+			runtime.HandleError(fmt.Errorf("error listing idle sessions of %s/%s: %s", dbResource.Namespace, dbResource.Name, err.Error()))
+			continue
+		}
+		if len(pids) == 0 {
+			continue
+		}
+
+		ctx, logger := resourceContext(context.Background(), dbResource.Namespace, dbResource.Name)
+		exec := newExecutor(ctx, dbResource, inst, logger)
+		logger.Info().Int("sessions", len(pids)).Dur("idleSessionTimeout", timeout.Duration).Msg("terminating idle sessions")
+		stmt := fmt.Sprintf("SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE pid IN (%s) AND state LIKE 'idle%%'", strings.Join(pids, ", "))
+		if err := exec.Exec(inst.DB, stmt); err != nil {
+			runtime.HandleError(fmt.Errorf("error terminating idle sessions of %s/%s: %s", dbResource.Namespace, dbResource.Name, err.Error()))
+			continue
+		}
+		if exec.dryRun {
+			continue
+		}
+		idleSessionsTerminated.WithLabelValues(dbResource.Namespace, dbResource.Name).Add(float64(len(pids)))
+		c.recorder.Event(dbResource, corev1.EventTypeNormal, "IdleSessionsTerminated",
+			fmt.Sprintf("Terminated %d sessions idle for longer than spec.idleSessionTimeout of %s", len(pids), timeout.Duration))
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+	informers "github.com/joshrendek/k8s-external-postgres/pkg/client/informers/externalversions"
+)
+
+var (
+	// informerEvents counts the add, update and delete events the event
+	// handlers of the controllers received, resyncs excluded.
+	informerEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "external_postgres_informer_events_total",
+		Help: "Number of add, update and delete events handled by the controller, resyncs excluded.",
+	}, []string{"resource", "controller", "event"})
+	// informerResyncs counts the periodic resyncs of the informer caches, as
+	// handled by the controllers: updates whose object is unchanged.
+	informerResyncs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "external_postgres_informer_resyncs_total",
+		Help: "Number of objects redelivered unchanged to the controller by the periodic resync of the informer.",
+	}, []string{"resource", "controller"})
+	// informerHandlerSeconds measures how long the event handlers take,
+	// holding up the delivery of the next events of their informer.
+	informerHandlerSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "external_postgres_informer_handler_duration_seconds",
+		Help:    "Time the event handlers of the controller took to handle an event.",
+		Buckets: prometheus.ExponentialBuckets(0.0001, 4, 10),
+	}, []string{"resource", "controller"})
+	// informerLastEvent is the time of the last event received from the
+	// watch of each informer, resyncs excluded.
+	informerLastEvent = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "external_postgres_informer_last_event_timestamp_seconds",
+		Help: "Unix time of the last event received from the watch of the informer, resyncs excluded.",
+	}, []string{"resource"})
+	// informerLagSeconds measures how long the Database informer takes to
+	// deliver the status written by a reconcile back to the lister.
+	informerLagSeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "external_postgres_informer_lag_seconds",
+		Help:    "Time between the write of the status of a Database and its delivery to the lister.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 4, 10),
+	})
+	// staleReconciles counts the reconciles of Databases whose lister had not
+	// yet delivered the status last written by the controller.
+	staleReconciles = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "external_postgres_stale_reconciles_total",
+		Help: "Number of reconciles run on a Database older than the status last written by the controller.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(informerEvents, informerResyncs, informerHandlerSeconds, informerLastEvent, informerLagSeconds, staleReconciles)
+}
+
+// instrumentHandler wraps the event handlers controller sets on the informer
+// of resource to count their events, telling resyncs apart, and time them.
+func instrumentHandler(resource, controller string, handler cache.ResourceEventHandlerFuncs) cache.ResourceEventHandlerFuncs {
+	observe := func(event string, start time.Time) {
+		informerHandlerSeconds.WithLabelValues(resource, controller).Observe(time.Since(start).Seconds())
+		if event == "" {
+			informerResyncs.WithLabelValues(resource, controller).Inc()
+			return
+		}
+		informerEvents.WithLabelValues(resource, controller, event).Inc()
+		informerLastEvent.WithLabelValues(resource).Set(float64(start.Unix()))
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			defer observe("add", time.Now())
+			if handler.AddFunc != nil {
+				handler.AddFunc(obj)
+			}
+		},
+		UpdateFunc: func(old, new interface{}) {
+			event := "update"
+			if resync(old, new) {
+				event = ""
+			}
+			defer observe(event, time.Now())
+			if handler.UpdateFunc != nil {
+				handler.UpdateFunc(old, new)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			defer observe("delete", time.Now())
+			if handler.DeleteFunc != nil {
+				handler.DeleteFunc(obj)
+			}
+		},
+	}
+}
+
+// resync reports whether the update of old to new is a resync of the
+// informer, the object being unchanged.
+func resync(old, new interface{}) bool {
+	oldMeta, err := meta.Accessor(old)
+	if err != nil {
+		return false
+	}
+	newMeta, err := meta.Accessor(new)
+	if err != nil {
+		return false
+	}
+	return oldMeta.GetResourceVersion() == newMeta.GetResourceVersion()
+}
+
+// informerCollector reports the number of objects in the caches of the
+// informers of the controllers.
+type informerCollector struct {
+	informers map[string]cache.SharedIndexInformer
+	desc      *prometheus.Desc
+}
+
+// newInformerCollector returns the collector of the informers the
+// controllers use, which must have been set up with kubeFactory and
+// databaseFactory beforehand.
+func newInformerCollector(kubeFactory kubeinformers.SharedInformerFactory, databaseFactory informers.SharedInformerFactory) *informerCollector {
+	databases := databaseFactory.Databases().V1()
+	return &informerCollector{
+		informers: map[string]cache.SharedIndexInformer{
+			"configmaps":         kubeFactory.Core().V1().ConfigMaps().Informer(),
+			"secrets":            kubeFactory.Core().V1().Secrets().Informer(),
+			"jobs":               kubeFactory.Batch().V1().Jobs().Informer(),
+			"databases":          databases.Databases().Informer(),
+			"databasebackups":    databases.DatabaseBackups().Informer(),
+			"databaserestores":   databases.DatabaseRestores().Informer(),
+			"databasesets":       databases.DatabaseSets().Informer(),
+			"databasequotas":     databases.DatabaseQuotas().Informer(),
+			"postgresinstances":  databases.PostgresInstances().Informer(),
+			"postgresparameters": databases.PostgresParameters().Informer(),
+			"publications":       databases.Publications().Informer(),
+			"subscriptions":      databases.Subscriptions().Informer(),
+			"tablespaces":        databases.Tablespaces().Informer(),
+			"foreignservers":     databases.ForeignServers().Informer(),
+			"usermappings":       databases.UserMappings().Informer(),
+		},
+		desc: prometheus.NewDesc("external_postgres_informer_cache_objects",
+			"Number of objects in the cache of the informer.", []string{"resource"}, nil),
+	}
+}
+
+func (c *informerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *informerCollector) Collect(ch chan<- prometheus.Metric) {
+	for resource, informer := range c.informers {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(len(informer.GetStore().ListKeys())), resource)
+	}
+}
+
+// writeTracker remembers the resource version of the last status the
+// controller wrote for each Database until the informer delivers it, so
+// reconciles running on an older object can be told apart.
+type writeTracker struct {
+	mu     sync.Mutex
+	writes map[string]trackedWrite
+}
+
+type trackedWrite struct {
+	resourceVersion string
+	at              time.Time
+}
+
+// writeTrackerExpiry is how long a write is waited for before it is assumed
+// missed, e.g. superseded while the watch was re-established.
+const writeTrackerExpiry = 10 * time.Minute
+
+var databaseWrites = &writeTracker{writes: map[string]trackedWrite{}}
+
+// written records that dbResource was just written.
+func (t *writeTracker) written(dbResource *v1.Database) {
+	key, err := cache.MetaNamespaceKeyFunc(dbResource)
+	if err != nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.writes[key] = trackedWrite{resourceVersion: dbResource.ResourceVersion, at: time.Now()}
+}
+
+// delivered records that the informer delivered dbResource, observing the
+// lag of the write it is the result of.
+func (t *writeTracker) delivered(dbResource *v1.Database) {
+	key, err := cache.MetaNamespaceKeyFunc(dbResource)
+	if err != nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if w, ok := t.writes[key]; ok && w.resourceVersion == dbResource.ResourceVersion {
+		informerLagSeconds.Observe(time.Since(w.at).Seconds())
+		delete(t.writes, key)
+	}
+}
+
+// forget drops the write of the Database of key, once deleted.
+func (t *writeTracker) forget(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.writes, key)
+}
+
+// stale reports whether the write of the Database of key wasn't delivered
+// yet, dbResource as read from the lister being older than it.
+func (t *writeTracker) stale(key string, dbResource *v1.Database) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	w, ok := t.writes[key]
+	if !ok || w.resourceVersion == dbResource.ResourceVersion {
+		return false
+	}
+	if time.Since(w.at) > writeTrackerExpiry {
+		delete(t.writes, key)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+const (
+	initSQLReject  = "reject"
+	initSQLReapply = "reapply"
+)
+
+// hasInitSQL reports whether a script is run against the database of
+// dbResource once created: its initSQL, or the row level security bootstrap.
+func hasInitSQL(dbResource *v1.Database) bool {
+	return dbResource.Spec.InitSQL != nil || rowLevelSecurity(dbResource)
+}
+
+// initSQLOnChange returns the onChange policy of the initSQL of dbResource.
+// The row level security bootstrap alone is idempotent and always reapplied.
+func initSQLOnChange(dbResource *v1.Database) string {
+	if dbResource.Spec.InitSQL == nil {
+		return initSQLReapply
+	}
+	return dbResource.Spec.InitSQL.OnChange
+}
+
+// initSQLScript returns the initSQL script of dbResource, read from its spec
+// or from the referenced ConfigMap, followed by the row level security
+// bootstrap when enabled.
+func (c *Controller) initSQLScript(dbResource *v1.Database) (string, error) {
+	script := ""
+	if init := dbResource.Spec.InitSQL; init != nil {
+		script = init.SQL
+		if script == "" && init.ConfigMap != nil {
+			ref := init.ConfigMap
+			configMap, err := c.ConfigMapsLister.ConfigMaps(dbResource.Namespace).Get(ref.Name)
+			if err != nil {
+				return "", fmt.Errorf("error reading initSQL configmap %q: %s", ref.Name, err.Error())
+			}
+			var ok bool
+			if script, ok = configMap.Data[ref.Key]; !ok {
+				return "", fmt.Errorf("configmap %q has no key %q", ref.Name, ref.Key)
+			}
+		}
+	}
+	if rowLevelSecurity(dbResource) {
+		rls, err := rowLevelSecurityScript(dbResource)
+		if err != nil {
+			return "", err
+		}
+		if script != "" {
+			script += ";\n"
+		}
+		script += rls
+	}
+	return script, nil
+}
+
+// initSQLChecksum returns the checksum recorded in the status for script.
+func initSQLChecksum(script string) string {
+	sum := sha256.Sum256([]byte(script))
+	return hex.EncodeToString(sum[:])
+}
+
+// applyInitSQL runs the initSQL script of dbResource on db, a connection to
+// its database logged in as its owner role, see openAsOwner, and returns the
+// checksum of the applied script. The script is sent as a single query so it
+// runs in one transaction, with the schema of dbResource as search_path in
+// schema mode.
+func (c *Controller) applyInitSQL(dbResource *v1.Database, db *sql.DB, exec *sqlExecutor) (string, error) {
+	script, err := c.initSQLScript(dbResource)
+	if err != nil {
+		return "", err
+	}
+	switch onChange := initSQLOnChange(dbResource); onChange {
+	case "", initSQLReject, initSQLReapply:
+	default:
+		return "", fmt.Errorf("unknown initSQL onChange policy %q", onChange)
+	}
+
+	stmt := script
+	if schemaMode(dbResource) {
+		stmt = fmt.Sprintf("SET search_path TO %s;\n%s;\nRESET search_path", schemaName(dbResource), stmt)
+	}
+	if err := exec.Exec(db, stmt); err != nil {
+		return "", fmt.Errorf("error running initSQL: %s", err.Error())
+	}
+	return initSQLChecksum(script), nil
+}
+
+// syncInitSQL handles initSQL scripts edited, or added, after the database
+// was provisioned according to their onChange policy.
+func (c *Controller) syncInitSQL(dbResource *v1.Database, inst *instance, exec *sqlExecutor) error {
+	if !hasInitSQL(dbResource) {
+		return nil
+	}
+	script, err := c.initSQLScript(dbResource)
+	if err != nil {
+		return err
+	}
+	if initSQLChecksum(script) == dbResource.Status.InitSQLChecksum {
+		return nil
+	}
+
+	if initSQLOnChange(dbResource) != initSQLReapply {
+		exec.logger.Debug().Msg("initSQL changed since it was applied, not running it again")
+		c.recorder.Event(dbResource, corev1.EventTypeWarning, "InitSQLChanged",
+			"initSQL changed since it was applied, set onChange to reapply to run it again")
+		return nil
+	}
+
+	db, err := c.openStoredOwner(dbResource, inst)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	checksum, err := c.applyInitSQL(dbResource, db, exec)
+	if err != nil {
+		return err
+	}
+	if exec.dryRun {
+		return nil
+	}
+	dbCopy := dbResource.DeepCopy()
+	dbCopy.Status.InitSQLChecksum = checksum
+	return c.updateStatus(dbCopy)
+}
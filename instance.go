@@ -0,0 +1,409 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	kubeinformers "k8s.io/client-go/informers"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+	informers "github.com/joshrendek/k8s-external-postgres/pkg/client/informers/externalversions"
+	listers "github.com/joshrendek/k8s-external-postgres/pkg/client/listers/postgresql/v1"
+)
+
+// instance is a server databases are provisioned on, with an open admin
+// connection.
+type instance struct {
+	// name is the registry key of the instance, empty for the default one.
+	name     string
+	url      string
+	dialect  dialect
+	timeouts v1.SessionTimeouts
+	version  serverVersion
+	DB       *sql.DB
+	health   instanceHealth
+	// tunnel forwards the connections through an SSH bastion, nil when the
+	// server is reached directly.
+	tunnel *sshTunnel
+	// readWrite is set when the admin URI asks for the read-write host of
+	// several, checked again by the watchdog.
+	readWrite bool
+	// azureAD logs the admin connections in with Azure AD access tokens, nil
+	// when they use the password of the admin URI.
+	azureAD *azureADToken
+}
+
+// flagTimeouts returns the session timeouts of the --*-timeout flags.
+func flagTimeouts() v1.SessionTimeouts {
+	return v1.SessionTimeouts{
+		Statement:         metav1.Duration{Duration: statementTimeout},
+		Lock:              metav1.Duration{Duration: lockTimeout},
+		IdleInTransaction: metav1.Duration{Duration: idleInTransactionTimeout},
+	}
+}
+
+// sessionDSN adds the timeouts t to the connection URI uri, along with the
+// application_name of the controller unless uri sets one. pgx sends the
+// parameters it does not know as settings of the session.
+func sessionDSN(uri string, t v1.SessionTimeouts) (string, error) {
+	u, err := parseURI(uri)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	for name, d := range map[string]time.Duration{
+		"statement_timeout":                   t.Statement.Duration,
+		"lock_timeout":                        t.Lock.Duration,
+		"idle_in_transaction_session_timeout": t.IdleInTransaction.Duration,
+	} {
+		if d > 0 && q.Get(name) == "" {
+			q.Set(name, fmt.Sprint(int64(d/time.Millisecond)))
+		}
+	}
+	if q.Get("application_name") == "" {
+		q.Set("application_name", sessionApplication)
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// openInstance connects to the server behind the admin URI adminURL, with
+// the session timeouts t, through tunnel and logging in with the tokens of
+// azureAD when they are not nil, and detects its version. The URI may be in
+// the keyword/value form, and list several hosts.
+func openInstance(adminURL string, d dialect, t v1.SessionTimeouts, tunnel *sshTunnel, azureAD *azureADToken) (*instance, error) {
+	adminURL, err := normalizeDSN(adminURL)
+	if err != nil {
+		return nil, err
+	}
+	_, readWrite, err := hostDSNs(adminURL)
+	if err != nil {
+		return nil, err
+	}
+	dsn, err := sessionDSN(adminURL, t)
+	if err != nil {
+		return nil, err
+	}
+	db, err := openDSN(dsn, tunnel, azureAD)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	version, err := detectServerVersion(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &instance{url: adminURL, dialect: d, timeouts: t, version: version, DB: db, tunnel: tunnel, readWrite: readWrite, azureAD: azureAD}, nil
+}
+
+// close closes the connection pool of i and its SSH tunnel.
+func (i *instance) close() {
+	i.DB.Close()
+	if i.tunnel != nil {
+		i.tunnel.Close()
+	}
+}
+
+// tunnelID identifies the SSH tunnel of i, empty when it has none.
+func (i *instance) tunnelID() string {
+	if i.tunnel == nil {
+		return ""
+	}
+	return i.tunnel.id
+}
+
+// databaseURL rewrites the admin URI so it points at the given database,
+// optionally authenticating as a different user.
+func (i *instance) databaseURL(database, username, password string) (string, error) {
+	u, err := parseURI(i.url)
+	if err != nil {
+		return "", err
+	}
+	u.Path = "/" + database
+	if username != "" && password != "" {
+		u.User = url.UserPassword(username, password)
+	} else if username != "" {
+		u.User = url.User(username)
+	}
+	return u.String(), nil
+}
+
+// openDatabase opens an admin connection to the given database. Some
+// statements (GRANT ... ON ALL TABLES, ALTER DEFAULT PRIVILEGES) only apply
+// to the database of the current session.
+func (i *instance) openDatabase(database string) (*sql.DB, error) {
+	dsn, err := i.databaseURL(database, "", "")
+	if err != nil {
+		return nil, err
+	}
+	if dsn, err = sessionDSN(dsn, i.timeouts); err != nil {
+		return nil, err
+	}
+	return openDSN(dsn, i.tunnel, i.azureAD)
+}
+
+// hostPort returns the host and port of the admin URI, the first one of a
+// multi-host URI.
+func (i *instance) hostPort() (string, string) {
+	first, _ := splitHosts(i.url)
+	u, err := url.Parse(first)
+	if err != nil {
+		return "", ""
+	}
+	port := u.Port()
+	if port == "" {
+		port = "5432"
+	}
+	return u.Hostname(), port
+}
+
+// instanceRegistry hands out the instance a Database is provisioned on: the
+// PostgresInstance named by its spec, or the default instance the controller
+// was started with, possibly reached with the admin credentials of its
+// namespace. Connections other than the default one are opened on first use
+// and re-opened when their admin URI or dialect changes.
+type instanceRegistry struct {
+	defaultInstance *instance
+
+	InstancesLister  listers.PostgresInstanceLister
+	InstancesSynced  cache.InformerSynced
+	SecretsLister    corelisters.SecretLister
+	SecretsSynced    cache.InformerSynced
+	NamespacesLister corelisters.NamespaceLister
+	NamespacesSynced cache.InformerSynced
+
+	mu        sync.Mutex
+	instances map[string]*instance
+}
+
+func newInstanceRegistry(
+	defaultInstance *instance,
+	kubeInformerFactory kubeinformers.SharedInformerFactory,
+	databaseInformerFactory informers.SharedInformerFactory) *instanceRegistry {
+
+	instanceInformer := databaseInformerFactory.Databases().V1().PostgresInstances()
+	secretInformer := kubeInformerFactory.Core().V1().Secrets()
+	namespaceInformer := kubeInformerFactory.Core().V1().Namespaces()
+	return &instanceRegistry{
+		defaultInstance:  defaultInstance,
+		InstancesLister:  instanceInformer.Lister(),
+		InstancesSynced:  instanceInformer.Informer().HasSynced,
+		SecretsLister:    secretInformer.Lister(),
+		SecretsSynced:    secretInformer.Informer().HasSynced,
+		NamespacesLister: namespaceInformer.Lister(),
+		NamespacesSynced: namespaceInformer.Informer().HasSynced,
+		instances:        map[string]*instance{},
+	}
+}
+
+// HasSynced reports whether the informer caches of the registry have synced.
+func (r *instanceRegistry) HasSynced() bool {
+	return r.InstancesSynced() && r.SecretsSynced() && r.NamespacesSynced()
+}
+
+// getDefault returns the instance of the server the controller is configured
+// with.
+func (r *instanceRegistry) getDefault() *instance {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.defaultInstance
+}
+
+// setDefault replaces the default instance, returning the previous one.
+func (r *instanceRegistry) setDefault(inst *instance) *instance {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	previous := r.defaultInstance
+	r.defaultInstance = inst
+	return previous
+}
+
+// instanceName returns the name of the PostgresInstance dbResource is
+// provisioned on, named by its spec or else picked by its placement, empty
+// for the default server.
+func instanceName(dbResource *v1.Database) string {
+	if dbResource.Spec.Instance != "" {
+		return dbResource.Spec.Instance
+	}
+	return dbResource.Status.Instance
+}
+
+// instanceNamespace returns the namespace of the PostgresInstance of
+// dbResource, its own unless spec.instanceNamespace names another.
+func instanceNamespace(dbResource *v1.Database) string {
+	if dbResource.Spec.InstanceNamespace != "" {
+		return dbResource.Spec.InstanceNamespace
+	}
+	return dbResource.Namespace
+}
+
+// forDatabase returns the instance dbResource is provisioned on, once
+// verified a PostgresInstance of another namespace allows its namespace.
+func (r *instanceRegistry) forDatabase(dbResource *v1.Database) (*instance, error) {
+	name := instanceName(dbResource)
+	if name == "" {
+		return r.forNamespace(dbResource.Namespace)
+	}
+	namespace := instanceNamespace(dbResource)
+	if namespace != dbResource.Namespace {
+		if err := r.checkAccess(namespace, name, dbResource.Namespace); err != nil {
+			return nil, err
+		}
+	}
+	return r.get(namespace, name)
+}
+
+// checkAccess returns an error unless the PostgresInstance namespace/name
+// allows the Databases of namespace dbNamespace, listed in its
+// allowedNamespaces or matching its namespaceSelector.
+func (r *instanceRegistry) checkAccess(namespace, name, dbNamespace string) error {
+	pgInstance, err := r.InstancesLister.PostgresInstances(namespace).Get(name)
+	if err != nil {
+		return fmt.Errorf("error getting instance %s/%s: %s", namespace, name, err.Error())
+	}
+	for _, allowed := range pgInstance.Spec.AllowedNamespaces {
+		if allowed == dbNamespace {
+			return nil
+		}
+	}
+	if pgInstance.Spec.NamespaceSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(pgInstance.Spec.NamespaceSelector)
+		if err != nil {
+			return fmt.Errorf("invalid namespaceSelector of instance %s/%s: %s", namespace, name, err.Error())
+		}
+		ns, err := r.NamespacesLister.Get(dbNamespace)
+		if err != nil {
+			return fmt.Errorf("error getting namespace %s: %s", dbNamespace, err.Error())
+		}
+		if selector.Matches(labels.Set(ns.Labels)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("instance %s/%s does not allow the Databases of namespace %s", namespace, name, dbNamespace)
+}
+
+// forNamespace returns the default server as reached with the admin
+// credentials of the --namespace-admin-secret Secret of namespace, or with
+// the --postgres-uri ones when the namespace has none.
+func (r *instanceRegistry) forNamespace(namespace string) (*instance, error) {
+	defaultInstance := r.getDefault()
+	if namespaceAdminSecret == "" {
+		return defaultInstance, nil
+	}
+	secret, err := r.SecretsLister.Secrets(namespace).Get(namespaceAdminSecret)
+	if errors.IsNotFound(err) {
+		return defaultInstance, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting namespace admin secret %q: %s", namespaceAdminSecret, err.Error())
+	}
+	adminURL, ok := secret.Data["DATABASE_URL"]
+	if !ok {
+		return nil, fmt.Errorf("secret %q has no DATABASE_URL key", namespaceAdminSecret)
+	}
+	return r.connect(namespace+"/secret:"+namespaceAdminSecret, string(adminURL), defaultInstance.dialect, defaultInstance.timeouts, nil, nil)
+}
+
+// get returns the instance of the PostgresInstance namespace/name.
+func (r *instanceRegistry) get(namespace, name string) (*instance, error) {
+	pgInstance, err := r.InstancesLister.PostgresInstances(namespace).Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("error getting instance %q: %s", name, err.Error())
+	}
+	d, err := lookupDialect(pgInstance.Spec.Dialect)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := r.SecretsLister.Secrets(namespace).Get(pgInstance.Spec.AdminSecret)
+	if err != nil {
+		return nil, fmt.Errorf("error getting admin secret of instance %q: %s", name, err.Error())
+	}
+	adminURL, ok := secret.Data["DATABASE_URL"]
+	if !ok {
+		return nil, fmt.Errorf("secret %q has no DATABASE_URL key", pgInstance.Spec.AdminSecret)
+	}
+
+	timeouts := flagTimeouts()
+	if pgInstance.Spec.Timeouts != nil {
+		timeouts = *pgInstance.Spec.Timeouts
+	}
+	var tunnel *sshTunnel
+	if spec := pgInstance.Spec.SSHTunnel; spec != nil {
+		tunnelSecret, err := r.SecretsLister.Secrets(namespace).Get(spec.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("error getting SSH tunnel secret of instance %q: %s", name, err.Error())
+		}
+		if tunnel, err = newSSHTunnel(spec, tunnelSecret); err != nil {
+			return nil, err
+		}
+	}
+	var azureAD *azureADToken
+	if spec := pgInstance.Spec.AzureAD; spec != nil {
+		if azureAD, err = newAzureADToken(spec); err != nil {
+			if tunnel != nil {
+				tunnel.Close()
+			}
+			return nil, fmt.Errorf("error setting up azure AD authentication of instance %q: %s", name, err.Error())
+		}
+	}
+	return r.connect(namespace+"/"+name, string(adminURL), d, timeouts, tunnel, azureAD)
+}
+
+// connect returns the instance cached under key, connecting to adminURL when
+// there is none or when it was opened with another URI, dialect, timeouts,
+// SSH tunnel or Azure AD identity.
+func (r *instanceRegistry) connect(key, adminURL string, d dialect, t v1.SessionTimeouts, tunnel *sshTunnel, azureAD *azureADToken) (*instance, error) {
+	tunnelID := ""
+	if tunnel != nil {
+		tunnelID = tunnel.id
+	}
+	if normalized, err := normalizeDSN(adminURL); err == nil {
+		adminURL = normalized
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if inst, ok := r.instances[key]; ok {
+		if inst.url == adminURL && inst.dialect.name == d.name && inst.timeouts == t && inst.tunnelID() == tunnelID && inst.azureAD.id() == azureAD.id() {
+			return inst, nil
+		}
+		inst.close()
+		delete(r.instances, key)
+	}
+
+	inst, err := openInstance(adminURL, d, t, tunnel, azureAD)
+	if err != nil {
+		if tunnel != nil {
+			tunnel.Close()
+		}
+		return nil, fmt.Errorf("error connecting to instance %q: %s", key, err.Error())
+	}
+	log.Info().Str("instance", key).Str("dialect", d.name).Str("serverVersion", inst.version.String()).Msg("Connected to instance")
+	inst.name = key
+	r.instances[key] = inst
+	return inst, nil
+}
+
+// Close closes the connection pools of the default instance and of every
+// PostgresInstance connected to.
+func (r *instanceRegistry) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.defaultInstance.close()
+	for key, inst := range r.instances {
+		inst.close()
+		delete(r.instances, key)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// finishedJobCondition returns the Complete or Failed condition of job, or nil
+// while it is still running.
+func finishedJobCondition(job *batchv1.Job) *batchv1.JobCondition {
+	for i, cond := range job.Status.Conditions {
+		if cond.Status != corev1.ConditionTrue {
+			continue
+		}
+		if cond.Type == batchv1.JobComplete || cond.Type == batchv1.JobFailed {
+			return &job.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// jobTerminationMessage returns the termination message of the succeeded pod
+// of job, which the backup and restore scripts use to report their results.
+func jobTerminationMessage(kubeclientset kubernetes.Interface, job *batchv1.Job) (string, error) {
+	selector := labels.SelectorFromSet(labels.Set{"job-name": job.Name})
+	pods, err := kubeclientset.CoreV1().Pods(job.Namespace).List(metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return "", err
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodSucceeded {
+			continue
+		}
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.State.Terminated != nil {
+				return strings.TrimSpace(status.State.Terminated.Message), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no succeeded pod found for job %s", job.Name)
+}
+
+// databaseURLEnv exposes the DATABASE_URL of the credentials Secret of
+// dbResource to a Job container.
+func databaseURLEnv(dbResource string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: "DATABASE_URL",
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: dbResource},
+				Key:                  "DATABASE_URL",
+			},
+		},
+	}
+}
+
+// storageEnvFrom exposes every key of the storage Secret to a Job container.
+func storageEnvFrom(secret string) []corev1.EnvFromSource {
+	return []corev1.EnvFromSource{
+		{SecretRef: &corev1.SecretEnvSource{
+			LocalObjectReference: corev1.LocalObjectReference{Name: secret},
+		}},
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+const (
+	leaseDuration = 15 * time.Second
+	renewDeadline = 10 * time.Second
+	retryPeriod   = 2 * time.Second
+)
+
+// leaderElectionNamespace returns the namespace of the lock of the leader
+// election, that of the controller pod unless --leader-election-namespace is
+// set.
+func leaderElectionNamespace() string {
+	if leaderElectionNS != "" {
+		return leaderElectionNS
+	}
+	if ns := os.Getenv("POD_NAMESPACE"); ns != "" {
+		return ns
+	}
+	if ns, err := ioutil.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace"); err == nil {
+		return strings.TrimSpace(string(ns))
+	}
+	return "default"
+}
+
+// waitForLeadership blocks until this replica holds the --leader-election-id
+// lock, returning false when stopCh is closed first. The process exits when
+// the lock is lost afterwards, as another replica may be reconciling by then.
+func waitForLeadership(kubeClient kubernetes.Interface, stopCh <-chan struct{}) bool {
+	identity, err := os.Hostname()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error getting the leader election identity")
+	}
+	lock, err := resourcelock.New(resourcelock.ConfigMapsResourceLock, leaderElectionNamespace(), leaderElectionID,
+		kubeClient.CoreV1(), resourcelock.ResourceLockConfig{
+			Identity:      identity,
+			EventRecorder: newEventRecorder(kubeClient),
+		})
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error creating the leader election lock")
+	}
+
+	elected := make(chan struct{})
+	go leaderelection.RunOrDie(leaderelection.LeaderElectionConfig{
+		Lock:          lock,
+		LeaseDuration: leaseDuration,
+		RenewDeadline: renewDeadline,
+		RetryPeriod:   retryPeriod,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(<-chan struct{}) {
+				close(elected)
+			},
+			OnStoppedLeading: func() {
+				log.Fatal().Str("identity", identity).Msg("Lost leadership, exiting")
+			},
+		},
+	})
+
+	log.Info().Str("identity", identity).Str("lock", leaderElectionNamespace()+"/"+leaderElectionID).Msg("Waiting for leadership")
+	select {
+	case <-elected:
+		log.Info().Str("identity", identity).Msg("Elected leader")
+		return true
+	case <-stopCh:
+		return false
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+// connectionLimit returns the CONNECTION LIMIT value for limit, -1 meaning
+// no limit.
+func connectionLimit(limit *int32) int32 {
+	if limit == nil {
+		return -1
+	}
+	return *limit
+}
+
+// syncConnectionLimits makes the connection limits of the database and owner
+// role of dbResource match its spec, only altering the ones that differ.
+func (c *Controller) syncConnectionLimits(dbResource *v1.Database, inst *instance, exec *sqlExecutor) error {
+	if !inst.dialect.connectionLimits {
+		return nil
+	}
+	database := databaseName(dbResource)
+	username := roleName(dbResource)
+
+	// the limit of a shared database is not up to a single tenant
+	if !schemaMode(dbResource) {
+		current, err := currentConnectionLimit(inst.DB, "SELECT datconnlimit FROM pg_database WHERE datname = $1", database)
+		if err != nil {
+			return err
+		}
+		if desired := connectionLimit(dbResource.Spec.ConnectionLimit); desired != current {
+			stmt := fmt.Sprintf("ALTER DATABASE %s CONNECTION LIMIT %d", database, desired)
+			if err := exec.Exec(inst.DB, stmt); err != nil {
+				return fmt.Errorf("error setting database connection limit: %s", err.Error())
+			}
+		}
+	}
+
+	current, err := currentConnectionLimit(inst.DB, "SELECT rolconnlimit FROM pg_roles WHERE rolname = $1", username)
+	if err != nil {
+		return err
+	}
+	if desired := connectionLimit(dbResource.Spec.RoleConnectionLimit); desired != current {
+		stmt := fmt.Sprintf("ALTER ROLE %s CONNECTION LIMIT %d", username, desired)
+		if err := exec.Exec(inst.DB, stmt); err != nil {
+			return fmt.Errorf("error setting role connection limit: %s", err.Error())
+		}
+	}
+	return nil
+}
+
+// currentConnectionLimit runs query for name, reporting no limit for objects
+// that do not exist yet, as happens in dry-run mode.
+func currentConnectionLimit(db *sql.DB, query, name string) (int32, error) {
+	var limit int32
+	err := db.QueryRow(query, name).Scan(&limit)
+	if err == sql.ErrNoRows {
+		return -1, nil
+	}
+	return limit, err
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+)
+
+// setupLogging configures the global logger from the --log-level and
+// --log-format flags. Errors reported through runtime.HandleError are routed
+// to it as well so every line of the controller goes through one logger.
+func setupLogging(level, format string) error {
+	lvl, err := zerolog.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("invalid log level %q: %s", level, err.Error())
+	}
+	zerolog.SetGlobalLevel(lvl)
+
+	var out io.Writer
+	switch format {
+	case "json":
+		out = os.Stderr
+	case "console":
+		out = zerolog.ConsoleWriter{Out: os.Stderr}
+	default:
+		return fmt.Errorf("invalid log format %q, must be json or console", format)
+	}
+	log.Logger = zerolog.New(out).With().Timestamp().Logger()
+
+	// keep the rate limiting error handler, replace the glog one
+	runtime.ErrorHandlers[0] = func(err error) {
+		log.Error().Err(err).Msg("unhandled error")
+	}
+	return nil
+}
+
+// reconcileIDKey is the context key of the reconcile ID.
+type reconcileIDKey struct{}
+
+// reconcileContext returns ctx carrying a fresh reconcile ID, along with a
+// logger tagged with it and the namespace and name of the resource behind
+// the work queue key.
+func reconcileContext(ctx context.Context, key string) (context.Context, zerolog.Logger) {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		id := newReconcileID()
+		return context.WithValue(ctx, reconcileIDKey{}, id), log.With().Str("key", key).Str("reconcileID", id).Logger()
+	}
+	return resourceContext(ctx, namespace, name)
+}
+
+// resourceContext returns ctx carrying a fresh reconcile ID, along with a
+// logger tagged with it, namespace and name, for work done outside the work
+// queues.
+func resourceContext(ctx context.Context, namespace, name string) (context.Context, zerolog.Logger) {
+	id := newReconcileID()
+	logger := log.With().
+		Str("namespace", namespace).
+		Str("name", name).
+		Str("reconcileID", id).
+		Logger()
+	return context.WithValue(ctx, reconcileIDKey{}, id), logger
+}
+
+// resourceLogger returns a logger tagged with namespace, name and a fresh
+// reconcile ID, for work done outside the work queues that runs no
+// statements.
+func resourceLogger(namespace, name string) zerolog.Logger {
+	_, logger := resourceContext(context.Background(), namespace, name)
+	return logger
+}
+
+// reconcileID returns the reconcile ID carried by ctx, empty when it has
+// none.
+func reconcileID(ctx context.Context) string {
+	id, _ := ctx.Value(reconcileIDKey{}).(string)
+	return id
+}
+
+// newReconcileID returns a random identifier grouping the log lines of one
+// reconcile.
+func newReconcileID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
@@ -2,10 +2,11 @@ package main
 
 import (
 	"flag"
+	"sync"
 	"time"
 
-	"github.com/golang/glog"
 	apiextcs "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 
@@ -20,8 +21,7 @@ import (
 	"github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
 	clientset "github.com/joshrendek/k8s-external-postgres/pkg/client/clientset/versioned"
 	informers "github.com/joshrendek/k8s-external-postgres/pkg/client/informers/externalversions"
-	_ "github.com/lib/pq"
-	"github.com/rs/zerolog"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/zerolog/log"
 	"k8s.io/sample-controller/pkg/signals"
 )
@@ -30,14 +30,138 @@ var (
 	masterURL   string
 	kubeconfig  string
 	postgresURL string
+	dialectName string
 	isConsole   bool
+	logLevel    string
+	logFormat   string
+	backupImage string
+	dryRun      bool
+
+	passwordEncryption string
+
+	auditDatabase   string
+	auditTable      string
+	auditWebhookURL string
+	auditSyslog     string
+
+	webhookAddr     string
+	webhookCertFile string
+	webhookKeyFile  string
+
+	pgBouncerConfigMap string
+
+	configMapName string
+	workers       int
+
+	databaseNameTemplate string
+	roleNameTemplate     string
+
+	maxDatabases int
+
+	shutdownTimeout time.Duration
+	usageInterval   time.Duration
+	installCRDs     bool
+
+	slowQueryInterval time.Duration
+	slowQueryTop      int
+
+	idleSessionInterval time.Duration
+
+	otlpEndpoint     string
+	otlpInsecure     bool
+	traceSampleRatio float64
+
+	credentialsDriftInterval time.Duration
+	repairCredentialsDrift   bool
+
+	notifyWebhookURLs string
+	notifySlackURLs   string
+
+	eventAggregatorNamespace string
+	aggregateEventReasons    string
+
+	namespaceAdminSecret string
+
+	azureADAuth bool
+
+	passwordMinLength          int
+	passwordMinEntropy         float64
+	passwordDisallowedPatterns string
+	passwordExpiry             time.Duration
+	passwordExpiryWarning      time.Duration
+
+	statementTimeout         time.Duration
+	lockTimeout              time.Duration
+	idleInTransactionTimeout time.Duration
+
+	vaultAddr      string
+	vaultMount     string
+	vaultAuthMount string
+	vaultRole      string
+
+	metricsAddr         string
+	usageLabels         string
+	healthCheckInterval time.Duration
+
+	readReplicaEndpoints string
+
+	controllerID        string
+	orphanAuditInterval time.Duration
+	gcOrphans           bool
+	danglingReports     bool
+
+	maxConcurrentDDL int
+	maxDDLRate       float64
+	ddlBurst         int
+	priorityWorkers  int
+
+	revokePublicDefault bool
+	canaryConnect       bool
+
+	hookTimeout      time.Duration
+	hookWebhookHosts string
+
+	backend      string
+	fakeFailures string
+
+	allowAlterSystem        bool
+	allowPrivilegedRoles    bool
+	allowAdoption           bool
+	allowPodExec            bool
+	parametersDriftInterval time.Duration
+	fdwDriftInterval        time.Duration
+	specDriftInterval       time.Duration
+
+	renderManifests   bool
+	manifestNamespace string
+	manifestImage     string
+	manifestWebhookCA string
+
+	apiAddr     string
+	apiCertFile string
+	apiKeyFile  string
+
+	healthProbeAddr  string
+	leaderElect      bool
+	leaderElectionNS string
+	leaderElectionID string
 )
 
 func main() {
 	flag.Parse()
 
 	if isConsole {
-		log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+		logFormat = "console"
+	}
+	if err := setupLogging(logLevel, logFormat); err != nil {
+		log.Fatal().Err(err).Msg("Error setting up logging")
+	}
+
+	if renderManifests {
+		if err := writeManifests(os.Stdout); err != nil {
+			log.Fatal().Err(err).Msg("Error rendering manifests")
+		}
+		return
 	}
 
 	// set up signals so we handle the first shutdown signal gracefully
@@ -50,33 +174,212 @@ func main() {
 	cfg, err := clientcmd.BuildConfigFromFlags(masterURL, kubeconfig)
 
 	if err != nil {
-		glog.Fatalf("Error building kubeconfig: %s", err.Error())
+		log.Fatal().Err(err).Msg("Error building kubeconfig")
 	}
 
 	kubeClient, err := kubernetes.NewForConfig(cfg)
 	if err != nil {
-		glog.Fatalf("Error building kubernetes clientset: %s", err.Error())
+		log.Fatal().Err(err).Msg("Error building kubernetes clientset")
 	}
 
 	exampleClient, err := clientset.NewForConfig(cfg)
 	if err != nil {
-		glog.Fatalf("Error building example clientset: %s", err.Error())
+		log.Fatal().Err(err).Msg("Error building example clientset")
+	}
+
+	if err := checkPermissions(kubeClient, installCRDs); err != nil {
+		log.Fatal().Err(err).Msg("Error checking RBAC")
 	}
 
 	crdConfig, _ := GetClientConfig(kubeconfig)
 	crdClient, err := apiextcs.NewForConfig(crdConfig)
 
-	v1.CreateCRD(crdClient)
+	if installCRDs {
+		if err := v1.InstallCRDs(crdClient); err != nil {
+			log.Fatal().Err(err).Msg("Error installing CRDs")
+		}
+	} else {
+		v1.CreateCRD(crdClient)
+	}
+
+	s := flagSettings()
+	if configMapName != "" {
+		s, err = loadSettings(kubeClient, configMapName, s)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Error loading settings")
+		}
+		if err := setupLogging(s.LogLevel, logFormat); err != nil {
+			log.Fatal().Err(err).Msg("Error setting up logging")
+		}
+	}
+	setSettings(s)
 
+	if err := setupBackend(); err != nil {
+		log.Fatal().Err(err).Msg("Error setting up backend")
+	}
+	d, err := lookupDialect(s.Dialect)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error setting up dialect")
+	}
+	azureAD, err := flagAzureADToken()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error setting up azure AD authentication")
+	}
+	defaultInstance, err := openInstance(s.PostgresURI, d, flagTimeouts(), nil, azureAD)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error connecting to postgres")
+	}
+	log.Info().Str("dialect", d.name).Str("serverVersion", defaultInstance.version.String()).Msg("Connected to postgres")
+	if err := checkPasswordEncryption(s.PasswordEncryption, defaultInstance); err != nil {
+		log.Fatal().Err(err).Msg("Error checking password encryption")
+	}
+	if err := setupAudit(defaultInstance); err != nil {
+		log.Fatal().Err(err).Msg("Error setting up audit log")
+	}
+	setupNotifications()
+	setupVault()
+	flushTraces, err := setupTracing()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Error setting up tracing")
+	}
+	if err := setupPasswordPolicy(); err != nil {
+		log.Fatal().Err(err).Msg("Error setting up password policy")
+	}
+
+	if healthProbeAddr != "" {
+		go func() {
+			if err := runHealthServer(stopCh); err != nil {
+				log.Fatal().Err(err).Msg("Error running health probes")
+			}
+		}()
+	}
+
+	if apiAddr != "" {
+		go func() {
+			if err := runAPIServer(kubeClient, exampleClient, stopCh); err != nil {
+				log.Fatal().Err(err).Msg("Error running provisioning API")
+			}
+		}()
+	}
+
+	reporter := newUsageReporter(exampleClient, parseUsageLabels(usageLabels))
+	if metricsAddr != "" {
+		prometheus.MustRegister(reporter)
+		go func() {
+			if err := runMetricsServer(reporter, stopCh); err != nil {
+				log.Fatal().Err(err).Msg("Error running metrics server")
+			}
+		}()
+	}
+
+	if webhookAddr != "" {
+		go func() {
+			if err := runWebhookServer(kubeClient, stopCh); err != nil {
+				log.Fatal().Err(err).Msg("Error running admission webhook")
+			}
+		}()
+	}
+
+	// Standby replicas serve the webhook, API and probes but don't reconcile.
+	if leaderElect {
+		setNotReady("")
+		if !waitForLeadership(kubeClient, stopCh) {
+			defaultInstance.DB.Close()
+			closeAudit()
+			flushTraces()
+			log.Info().Msg("Shut down")
+			return
+		}
+		setNotReady("waiting for informer caches to sync")
+	}
+
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubeClient, time.Second*30)
 	exampleInformerFactory := informers.NewSharedInformerFactory(exampleClient, time.Second*1)
 
-	controller := NewController(kubeClient, exampleClient, exampleInformerFactory)
+	instances := newInstanceRegistry(defaultInstance, kubeInformerFactory, exampleInformerFactory)
+	controller := NewController(kubeClient, exampleClient, kubeInformerFactory, exampleInformerFactory, instances)
+	backupController := NewBackupController(kubeClient, exampleClient, kubeInformerFactory, exampleInformerFactory)
+	restoreController := NewRestoreController(kubeClient, exampleClient, kubeInformerFactory, exampleInformerFactory, instances)
+	replicationController := NewReplicationController(kubeClient, exampleClient, exampleInformerFactory, instances)
+	parametersController := NewParametersController(kubeClient, exampleClient, exampleInformerFactory, instances)
+	tablespaceController := NewTablespaceController(kubeClient, exampleClient, exampleInformerFactory, instances)
+	databaseSetController := NewDatabaseSetController(kubeClient, exampleClient, exampleInformerFactory)
+	fdwController := NewFDWController(kubeClient, exampleClient, exampleInformerFactory, instances)
+	physicalBackupController := NewPhysicalBackupController(kubeClient, exampleClient, cfg, exampleInformerFactory, instances)
+
+	reporter.setLister(exampleInformerFactory.Databases().V1().Databases().Lister())
+	if metricsAddr != "" {
+		prometheus.MustRegister(newInformerCollector(kubeInformerFactory, exampleInformerFactory))
+	}
 
+	go kubeInformerFactory.Start(stopCh)
 	go exampleInformerFactory.Start(stopCh)
 
-	if err = controller.Run(2, stopCh); err != nil {
-		glog.Fatalf("Error running controller: %s", err.Error())
-	}
+	// The controllers return once their in-flight reconciles are done, the
+	// connection pools are only closed after that.
+	var controllers sync.WaitGroup
+	controllers.Add(9)
+	go func() {
+		defer controllers.Done()
+		if err := backupController.Run(2, stopCh); err != nil {
+			log.Fatal().Err(err).Msg("Error running backup controller")
+		}
+	}()
+	go func() {
+		defer controllers.Done()
+		if err := restoreController.Run(2, stopCh); err != nil {
+			log.Fatal().Err(err).Msg("Error running restore controller")
+		}
+	}()
+	go func() {
+		defer controllers.Done()
+		if err := replicationController.Run(2, stopCh); err != nil {
+			log.Fatal().Err(err).Msg("Error running replication controller")
+		}
+	}()
+	go func() {
+		defer controllers.Done()
+		if err := parametersController.Run(2, stopCh); err != nil {
+			log.Fatal().Err(err).Msg("Error running parameters controller")
+		}
+	}()
+	go func() {
+		defer controllers.Done()
+		if err := tablespaceController.Run(2, stopCh); err != nil {
+			log.Fatal().Err(err).Msg("Error running tablespace controller")
+		}
+	}()
+	go func() {
+		defer controllers.Done()
+		if err := databaseSetController.Run(2, stopCh); err != nil {
+			log.Fatal().Err(err).Msg("Error running database set controller")
+		}
+	}()
+	go func() {
+		defer controllers.Done()
+		if err := fdwController.Run(2, stopCh); err != nil {
+			log.Fatal().Err(err).Msg("Error running foreign data wrapper controller")
+		}
+	}()
+	go func() {
+		defer controllers.Done()
+		if err := physicalBackupController.Run(2, stopCh); err != nil {
+			log.Fatal().Err(err).Msg("Error running physical backup controller")
+		}
+	}()
+
+	go func() {
+		defer controllers.Done()
+		if err := controller.Run(s.Workers, stopCh); err != nil {
+			log.Fatal().Err(err).Msg("Error running controller")
+		}
+	}()
+
+	controllers.Wait()
+	instances.Close()
+	closeAudit()
+	flushTraces()
+	log.Info().Msg("Shut down")
 }
 
 func GetClientConfig(kubeconfig string) (*rest.Config, error) {
@@ -90,7 +393,91 @@ func init() {
 	flag.StringVar(&kubeconfig, "kubeconfig", "", "Path to a kubeconfig. Only required if out-of-cluster.")
 	flag.StringVar(&masterURL, "master", "", "The address of the Kubernetes API server. Overrides any value in kubeconfig. Only required if out-of-cluster.")
 	flag.StringVar(&postgresURL, "postgres-uri", "postgres://localhost/template1?sslmode=disable", "URI to connect to postgres")
-	flag.BoolVar(&isConsole, "console", false, "whether to console log or json log")
+	flag.StringVar(&backend, "backend", backendPostgres, "Servers the Databases are provisioned on: postgres, or fake for in-memory fakes told apart by the host of their admin URI, to test the controller without a server")
+	flag.StringVar(&fakeFailures, "fake-failures", "", "Semicolon separated regexp=SQLSTATE pairs: the statements and queries matching a regexp fail with its SQLSTATE on the fake servers, e.g. ^DROP DATABASE=55006")
+	flag.StringVar(&dialectName, "dialect", "postgres", "Dialect of the --postgres-uri server: postgres, alloydb, yugabyte or cockroachdb")
+	flag.BoolVar(&isConsole, "console", false, "Deprecated: use --log-format=console")
+	flag.StringVar(&logLevel, "log-level", "info", "Minimum level of the log lines: debug, info, warn or error")
+	flag.StringVar(&logFormat, "log-format", "json", "Format of the log lines: json or console")
+	flag.BoolVar(&dryRun, "dry-run", false, "Log and record the SQL statements of every Database reconcile in its status instead of executing them")
+	flag.StringVar(&passwordEncryption, "password-encryption", "", "Method used to store role passwords: md5 or scram-sha-256 (PostgreSQL 10+). Defaults to the server password_encryption setting")
+	flag.StringVar(&auditDatabase, "audit-database", "postgres", "Database holding the --audit-table")
+	flag.StringVar(&auditTable, "audit-table", "", "Table every executed statement is recorded in, created if missing. Disabled when empty")
+	flag.StringVar(&auditWebhookURL, "audit-webhook-url", "", "URL every executed statement is POSTed to as JSON. Disabled when empty")
+	flag.StringVar(&auditSyslog, "audit-syslog", "", "Syslog server every executed statement is sent to, e.g. udp://localhost:514. Disabled when empty")
+	flag.BoolVar(&renderManifests, "render-manifests", false, "Print the manifests installing the controller, its CRDs, RBAC and admission webhook, and exit")
+	flag.StringVar(&manifestNamespace, "manifest-namespace", "k8s-external-postgres", "Namespace of the controller in the manifests printed by --render-manifests")
+	flag.StringVar(&manifestImage, "manifest-image", "joshrendek/k8s-external-postgres:latest", "Image of the controller in the manifests printed by --render-manifests")
+	flag.StringVar(&manifestWebhookCA, "manifest-webhook-ca", "", "PEM file of the CA of the webhook certificate. The manifests printed by --render-manifests register the admission webhook with it when set")
+	flag.StringVar(&webhookAddr, "webhook-addr", "", "Address the validating admission webhook listens on, e.g. :8443. Disabled when empty")
+	flag.StringVar(&webhookCertFile, "webhook-tls-cert", "", "TLS certificate of the admission webhook")
+	flag.StringVar(&webhookKeyFile, "webhook-tls-key", "", "TLS private key of the admission webhook")
+	flag.StringVar(&pgBouncerConfigMap, "pgbouncer-configmap", "", "ConfigMap, as namespace/name, the pgBouncer [databases] section of every provisioned Database is written to. Disabled when empty")
+	flag.StringVar(&configMapName, "config", "", "ConfigMap, as namespace/name, overriding the settings of the flags of the same name. It is reloaded on changes")
+	flag.IntVar(&workers, "workers", 2, "Number of Databases reconciled concurrently")
+	flag.StringVar(&databaseNameTemplate, "database-name-template", defaultDatabaseNameTemplate, "Template of the database names on the server, executed with .Namespace, .Name, .Database and .Username")
+	flag.StringVar(&roleNameTemplate, "role-name-template", defaultRoleNameTemplate, "Template of the owner role names on the server, executed like --database-name-template")
+	flag.IntVar(&maxDatabases, "max-databases", 0, "Maximum number of Databases provisioned on the --postgres-uri server. Unlimited when 0")
+	flag.StringVar(&notifyWebhookURLs, "notify-webhook-url", "", "Comma separated URLs lifecycle notifications are POSTed to as JSON. Disabled when empty")
+	flag.StringVar(&notifySlackURLs, "notify-slack-url", "", "Comma separated Slack incoming webhook URLs lifecycle notifications are posted to. Disabled when empty")
+	flag.StringVar(&eventAggregatorNamespace, "event-aggregator-namespace", "", "Namespace the warning events of --aggregate-event-reasons are copied to, so they can be watched in one place. Disabled when empty")
+	flag.StringVar(&aggregateEventReasons, "aggregate-event-reasons", "ProvisioningFailed,DeletionFailed,RotationFailed", "Comma separated reasons of the warning events copied to --event-aggregator-namespace")
+	flag.StringVar(&namespaceAdminSecret, "namespace-admin-secret", "", "Name of the Secret whose DATABASE_URL key holds the admin URI used for the Databases of its namespace on the default server, instead of --postgres-uri. Disabled when empty")
+	flag.BoolVar(&azureADAuth, "azure-ad-auth", false, "Log in to the --postgres-uri server with Azure AD access tokens obtained through workload identity instead of the password of the URI")
+	flag.DurationVar(&statementTimeout, "statement-timeout", 0, "statement_timeout of the sessions the controller opens, e.g. 5m. The server setting when 0")
+	flag.DurationVar(&lockTimeout, "lock-timeout", 0, "lock_timeout of the sessions the controller opens. The server setting when 0")
+	flag.DurationVar(&idleInTransactionTimeout, "idle-in-transaction-timeout", 0, "idle_in_transaction_session_timeout of the sessions the controller opens. The server setting when 0")
+	flag.IntVar(&passwordMinLength, "password-min-length", 0, "Minimum length of the passwords of Databases and generated passwords")
+	flag.Float64Var(&passwordMinEntropy, "password-min-entropy", 0, "Minimum estimated entropy, in bits, of the passwords of Databases and generated passwords")
+	flag.StringVar(&passwordDisallowedPatterns, "password-disallowed-patterns", "", "Comma separated regular expressions passwords must not match")
+	flag.DurationVar(&passwordExpiry, "password-expiry", 0, "Time passwords are valid for once set, through VALID UNTIL. Passwords don't expire when 0")
+	flag.DurationVar(&passwordExpiryWarning, "password-expiry-warning", 7*24*time.Hour, "How long before a password expires the PasswordExpiring condition and event are raised")
+	flag.StringVar(&vaultAddr, "vault-addr", "", "Address of the Vault server the vault and externalSecret credential stores write to, e.g. https://vault:8200. Disabled when empty")
+	flag.StringVar(&vaultMount, "vault-mount", "secret", "Mount path of the Vault KV v2 engine credentials are written to")
+	flag.StringVar(&vaultAuthMount, "vault-auth-mount", "kubernetes", "Mount path of the Vault Kubernetes auth method")
+	flag.StringVar(&vaultRole, "vault-role", "", "Vault role the controller logs in as with its service account token. VAULT_TOKEN is used when empty")
+	flag.Float64Var(&maxDDLRate, "max-ddl-rate", 0, "Number of statements per second run on a server, the others waiting for the rate limit so bursts of Databases don't degrade the server. Not limited when 0")
+	flag.IntVar(&ddlBurst, "ddl-burst", 5, "Number of statements run on a server at once before --max-ddl-rate applies")
+	flag.IntVar(&priorityWorkers, "priority-workers", 2, "Number of workers reconciling only the Databases with a pending password rotation, ahead of the bulk of provisioning. They share the --workers queue when 0")
+	flag.StringVar(&hookWebhookHosts, "hook-webhook-hosts", "", "Comma separated hosts, as host or host:port, the webhooks of Database hooks may be called on. Webhook hooks are refused when empty")
+	flag.DurationVar(&hookTimeout, "hook-timeout", 10*time.Minute, "How long the Jobs of the preDelete and postDelete hooks of deleted Databases may run before they fail")
+	flag.BoolVar(&canaryConnect, "canary-connect", true, "Connect to the databases with the credentials of their roles, and run SELECT 1, before marking them Ready")
+	flag.BoolVar(&revokePublicDefault, "revoke-public", false, "Revoke the privileges of PUBLIC on the databases and their public schema, unless spec.revokePublic of the Database says otherwise")
+	flag.IntVar(&maxConcurrentDDL, "max-concurrent-ddl", 1, "Number of statements run at the same time on a server, the others waiting their turn so concurrent reconciles don't deadlock on catalog locks. Not limited when 0")
+	flag.BoolVar(&allowPrivilegedRoles, "allow-privileged-roles", false, "Let Databases give their owner role the SUPERUSER, CREATEROLE, REPLICATION and BYPASSRLS attributes with spec.roleAttributes")
+	flag.BoolVar(&allowAdoption, "allow-adoption", false, "Let Databases take over the database and owner role already on the server with spec.allowAdoption or spec.adoptExisting, resetting the password of the role")
+	flag.BoolVar(&allowPodExec, "allow-pod-exec", false, "Let PhysicalBackups run pgBackRest or WAL-G with exec in a pod of their namespace, which requires the pods/exec permission")
+	flag.BoolVar(&allowAlterSystem, "allow-alter-system", false, "Let PostgresParameters set server wide parameters with ALTER SYSTEM, which affects every database of the server")
+	flag.DurationVar(&parametersDriftInterval, "parameters-drift-interval", time.Minute, "Interval at which the parameters set by PostgresParameters are checked for drift")
+	flag.DurationVar(&fdwDriftInterval, "fdw-drift-interval", time.Minute, "Interval at which the foreign servers and user mappings of ForeignServers and UserMappings are checked for drift")
+	flag.DurationVar(&specDriftInterval, "spec-drift-interval", 10*time.Minute, "Interval past which the statements of the unchanged spec fields of a provisioned Database, otherwise skipped, run again to correct the changes made on the server. Always run when 0")
+	flag.StringVar(&apiAddr, "api-addr", "", "Address the provisioning API for clients that can't create Databases listens on, e.g. :8444. Disabled when empty")
+	flag.StringVar(&apiCertFile, "api-tls-cert", "", "TLS certificate of the provisioning API, served without TLS when empty")
+	flag.StringVar(&apiKeyFile, "api-tls-key", "", "TLS private key of the provisioning API")
+	flag.StringVar(&healthProbeAddr, "health-probe-addr", "", "Address the liveness and readiness probes are served on at /healthz and /readyz, e.g. :8081. Disabled when empty")
+	flag.BoolVar(&leaderElect, "leader-elect", false, "Elect a leader among the replicas of the controller, only the leader reconciling")
+	flag.StringVar(&leaderElectionNS, "leader-election-namespace", "", "Namespace of the leader election ConfigMap. Defaults to the namespace of the controller pod")
+	flag.StringVar(&leaderElectionID, "leader-election-id", "external-postgres-controller", "Name of the leader election ConfigMap")
+	flag.StringVar(&metricsAddr, "metrics-addr", "", "Address the Prometheus metrics are served on at /metrics, e.g. :9102. Disabled when empty")
+	flag.StringVar(&usageLabels, "usage-labels", "", "Comma separated labels of the Databases copied on the usage metrics and reports, e.g. team,cost-center")
+	flag.StringVar(&readReplicaEndpoints, "read-replicas", "", "Comma separated host:port endpoints of the read replicas of the default server, published as HOST_RO, PORT_RO and DATABASE_URL_RO in the credentials of its Databases")
+	flag.DurationVar(&healthCheckInterval, "health-check-interval", 10*time.Second, "Interval at which the admin connection of every instance is checked, backing off while it is unreachable. Disabled when 0")
+	flag.StringVar(&controllerID, "controller-id", "external-postgres-controller", "Identity of the controller recorded in the comments of the databases and roles it creates. Controllers sharing a server must have different ones, so they never drop each other's objects")
+	flag.DurationVar(&orphanAuditInterval, "orphan-audit-interval", 10*time.Minute, "Interval at which the servers are audited for the databases and roles of deleted Databases. Disabled when 0")
+	flag.BoolVar(&gcOrphans, "gc-orphans", false, "Drop the orphaned databases and roles found by the orphan audit")
+	flag.BoolVar(&danglingReports, "dangling-reports", false, "Record the deleted Databases whose database couldn't be dropped in <name>-dangling ConfigMaps labelled postgresql.org/dangling")
+	flag.BoolVar(&installCRDs, "install-crds", false, "Create or update the CRDs to the definitions of this version at startup, failing when they can't be")
+	flag.DurationVar(&usageInterval, "usage-interval", time.Minute, "Interval at which the size, connections and last activity of every Database are recorded in its status. Disabled when 0")
+	flag.DurationVar(&slowQueryInterval, "slow-query-interval", 0, "Interval at which the slowest statements of every Database are collected from pg_stat_statements into its status. Disabled when 0")
+	flag.IntVar(&slowQueryTop, "slow-query-top", 5, "Number of statements with the highest mean execution time recorded per Database by --slow-query-interval")
+	flag.DurationVar(&idleSessionInterval, "idle-session-interval", 30*time.Second, "Interval at which the sessions idle past the spec.idleSessionTimeout of their Database are terminated. Disabled when 0")
+	flag.DurationVar(&credentialsDriftInterval, "credentials-drift-interval", 0, "Interval at which the passwords of the roles are checked against their credentials, setting the CredentialsDrift condition of their Database. Disabled when 0")
+	flag.BoolVar(&repairCredentialsDrift, "repair-credentials-drift", false, "Set the password of the credentials back on the roles whose password was changed on the server")
+	flag.StringVar(&otlpEndpoint, "otlp-endpoint", "", "host:port of the OTLP gRPC collector the traces of the Database reconciles are exported to. Disabled when empty")
+	flag.BoolVar(&otlpInsecure, "otlp-insecure", false, "Connect to --otlp-endpoint without TLS")
+	flag.Float64Var(&traceSampleRatio, "trace-sample-ratio", 1, "Fraction of the reconciles traced with --otlp-endpoint")
+	flag.DurationVar(&shutdownTimeout, "shutdown-timeout", 30*time.Second, "How long to wait on shutdown for the reconciles in progress before cancelling their statements")
+	flag.StringVar(&backupImage, "backup-image", "", "Image used by DatabaseBackup, DatabaseRestore and clone jobs, it must provide pg_dump, pg_restore, psql, rclone and sha256sum")
 }
 
 func homeDir() string {
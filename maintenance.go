@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+// maintenancePendingCondition is set on the Databases with disruptive
+// actions deferred until their maintenance window.
+const maintenancePendingCondition = "MaintenancePending"
+
+// The disruptive actions deferred until the maintenance window, as listed in
+// status.pendingActions.
+const (
+	actionOwnerChange      = "ownerChange"
+	actionPasswordChange   = "passwordChange"
+	actionPasswordRotation = "passwordRotation"
+	actionForcedDrop       = "forcedDrop"
+	actionRename           = "rename"
+)
+
+// maintenance gates the disruptive actions of a reconcile on the maintenance
+// window of its Database, collecting the ones deferred until it opens.
+type maintenance struct {
+	open     bool
+	next     time.Time
+	deferred []string
+}
+
+// maintenanceFor returns the maintenance gate of dbResource at now. Every
+// action is allowed when it has no maintenance window.
+func maintenanceFor(dbResource *v1.Database, now time.Time) (*maintenance, error) {
+	window := dbResource.Spec.MaintenanceWindow
+	if window == nil {
+		return &maintenance{open: true}, nil
+	}
+	schedule, err := cron.ParseStandard(window.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("invalid maintenance window schedule %q: %s", window.Schedule, err.Error())
+	}
+	if window.Duration.Duration <= 0 {
+		return nil, fmt.Errorf("the maintenance window duration must be positive")
+	}
+	// the window is open when it started less than its duration ago,
+	// otherwise the next start is the next window
+	start := schedule.Next(now.Add(-window.Duration.Duration))
+	return &maintenance{open: !start.After(now), next: start}, nil
+}
+
+// allow reports whether action can run now, recording it as deferred
+// otherwise.
+func (m *maintenance) allow(action string) bool {
+	if m.open {
+		return true
+	}
+	for _, deferred := range m.deferred {
+		if deferred == action {
+			return false
+		}
+	}
+	m.deferred = append(m.deferred, action)
+	return false
+}
+
+// record sets the deferred actions in status along with the
+// MaintenancePending condition, returning whether it changed.
+func (m *maintenance) record(status *v1.DatabaseStatus) bool {
+	if len(m.deferred) == 0 {
+		changed := len(status.PendingActions) > 0 || status.NextMaintenanceTime != nil
+		status.PendingActions = nil
+		status.NextMaintenanceTime = nil
+		if findCondition(status, maintenancePendingCondition) != nil {
+			changed = setCondition(status, maintenancePendingCondition, conditionFalse, "NoPendingActions", "") || changed
+		}
+		return changed
+	}
+
+	next := metav1.NewTime(m.next.UTC())
+	changed := strings.Join(status.PendingActions, ",") != strings.Join(m.deferred, ",") ||
+		status.NextMaintenanceTime == nil || !status.NextMaintenanceTime.Equal(&next)
+	status.PendingActions = m.deferred
+	status.NextMaintenanceTime = &next
+	message := fmt.Sprintf("%s deferred until the maintenance window at %s", strings.Join(m.deferred, ", "), next.Format(time.RFC3339))
+	return setCondition(status, maintenancePendingCondition, conditionTrue, "AwaitingMaintenanceWindow", message) || changed
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	admissionregistrationv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+const (
+	// manifestName names the objects rendered by --render-manifests.
+	manifestName = "k8s-external-postgres"
+	// manifestPostgresURIKey is the key of the Secret, named after the
+	// controller, holding the admin URI of the server.
+	manifestPostgresURIKey = "postgres-uri"
+	// manifestWebhookPort is the port the rendered webhook listens on.
+	manifestWebhookPort = 8443
+)
+
+// writeManifests renders the CRDs, RBAC and Deployment of the controller in
+// --manifest-namespace to w as YAML documents, along with the Service and
+// ValidatingWebhookConfiguration of the admission webhook when
+// --manifest-webhook-ca is set. The Deployment reads the admin URI from the
+// postgres-uri key of the Secret named after the controller, and the webhook
+// its certificate from the kubernetes.io/tls Secret with the -webhook-tls
+// suffix, both left to be created.
+func writeManifests(w io.Writer) error {
+	if manifestNamespace == "" || manifestImage == "" {
+		return fmt.Errorf("--manifest-namespace and --manifest-image are required")
+	}
+	var caBundle []byte
+	if manifestWebhookCA != "" {
+		var err error
+		if caBundle, err = ioutil.ReadFile(manifestWebhookCA); err != nil {
+			return fmt.Errorf("error reading the webhook CA: %s", err.Error())
+		}
+	}
+
+	objects := []interface{}{
+		&corev1.Namespace{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+			ObjectMeta: metav1.ObjectMeta{Name: manifestNamespace},
+		},
+	}
+	objects = append(objects, v1.CRDs()...)
+	objects = append(objects, rbacManifests(caBundle != nil)...)
+	objects = append(objects, deploymentManifest(caBundle != nil))
+	if caBundle != nil {
+		objects = append(objects, webhookManifests(caBundle)...)
+	}
+
+	for i, object := range objects {
+		body, err := yaml.Marshal(object)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			if _, err := io.WriteString(w, "---\n"); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// manifestMeta returns the metadata of a rendered object called name, in
+// --manifest-namespace unless cluster scoped.
+func manifestMeta(name string, namespaced bool) metav1.ObjectMeta {
+	meta := metav1.ObjectMeta{Name: name, Labels: map[string]string{"app": manifestName}}
+	if namespaced {
+		meta.Namespace = manifestNamespace
+	}
+	return meta
+}
+
+// rbacManifests returns the ServiceAccount of the controller, bound to a
+// ClusterRole granting the permissions checked at startup, including those
+// of the admission webhook with webhook.
+func rbacManifests(webhook bool) []interface{} {
+	role := &rbacv1.ClusterRole{
+		TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRole"},
+		ObjectMeta: manifestMeta(manifestName, false),
+	}
+	permissions := requiredPermissions
+	if webhook {
+		permissions = append(permissions, webhookPermissions...)
+	}
+	if allowPodExec {
+		permissions = append(permissions, podExecPermissions...)
+	}
+	for _, p := range permissions {
+		role.Rules = append(role.Rules, rbacv1.PolicyRule{
+			APIGroups: []string{p.group},
+			Resources: []string{p.resource},
+			Verbs:     p.verbs,
+		})
+	}
+	return []interface{}{
+		&corev1.ServiceAccount{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ServiceAccount"},
+			ObjectMeta: manifestMeta(manifestName, true),
+		},
+		role,
+		&rbacv1.ClusterRoleBinding{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRoleBinding"},
+			ObjectMeta: manifestMeta(manifestName, false),
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: manifestName},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: manifestName, Namespace: manifestNamespace}},
+		},
+	}
+}
+
+// deploymentManifest returns the Deployment running the controller, serving
+// the admission webhook when webhook is set.
+func deploymentManifest(webhook bool) *appsv1.Deployment {
+	replicas := int32(1)
+	labels := map[string]string{"app": manifestName}
+	container := corev1.Container{
+		Name:  "controller",
+		Image: manifestImage,
+		Args: []string{
+			"--postgres-uri=$(POSTGRES_URI)",
+			"--health-probe-addr=:8081",
+			"--metrics-addr=:9102",
+		},
+		Env: []corev1.EnvVar{
+			{
+				Name: "POSTGRES_URI",
+				ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: manifestName},
+					Key:                  manifestPostgresURIKey,
+				}},
+			},
+			{
+				Name:      "POD_NAMESPACE",
+				ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.namespace"}},
+			},
+		},
+		Ports: []corev1.ContainerPort{
+			{Name: "probes", ContainerPort: 8081},
+			{Name: "metrics", ContainerPort: 9102},
+		},
+		LivenessProbe: &corev1.Probe{Handler: corev1.Handler{
+			HTTPGet: &corev1.HTTPGetAction{Path: "/healthz", Port: intstr.FromString("probes")},
+		}},
+		ReadinessProbe: &corev1.Probe{Handler: corev1.Handler{
+			HTTPGet: &corev1.HTTPGetAction{Path: "/readyz", Port: intstr.FromString("probes")},
+		}},
+	}
+	pod := corev1.PodSpec{ServiceAccountName: manifestName}
+	if webhook {
+		container.Args = append(container.Args,
+			fmt.Sprintf("--webhook-addr=:%d", manifestWebhookPort),
+			"--webhook-tls-cert=/etc/webhook/tls.crt",
+			"--webhook-tls-key=/etc/webhook/tls.key",
+		)
+		container.Ports = append(container.Ports, corev1.ContainerPort{Name: "webhook", ContainerPort: manifestWebhookPort})
+		container.VolumeMounts = []corev1.VolumeMount{{Name: "webhook-tls", MountPath: "/etc/webhook", ReadOnly: true}}
+		pod.Volumes = []corev1.Volume{{
+			Name:         "webhook-tls",
+			VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: manifestName + "-webhook-tls"}},
+		}}
+	}
+	pod.Containers = []corev1.Container{container}
+
+	return &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: appsv1.SchemeGroupVersion.String(), Kind: "Deployment"},
+		ObjectMeta: manifestMeta(manifestName, true),
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec:       pod,
+			},
+		},
+	}
+}
+
+// webhookManifests returns the Service of the admission webhook and its
+// registration, trusting caBundle.
+func webhookManifests(caBundle []byte) []interface{} {
+	path := "/validate-database"
+	failurePolicy := admissionregistrationv1beta1.Fail
+	return []interface{}{
+		&corev1.Service{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+			ObjectMeta: manifestMeta(manifestName, true),
+			Spec: corev1.ServiceSpec{
+				Selector: map[string]string{"app": manifestName},
+				Ports:    []corev1.ServicePort{{Name: "webhook", Port: 443, TargetPort: intstr.FromString("webhook")}},
+			},
+		},
+		&admissionregistrationv1beta1.ValidatingWebhookConfiguration{
+			TypeMeta:   metav1.TypeMeta{APIVersion: admissionregistrationv1beta1.SchemeGroupVersion.String(), Kind: "ValidatingWebhookConfiguration"},
+			ObjectMeta: manifestMeta(manifestName, false),
+			Webhooks: []admissionregistrationv1beta1.Webhook{{
+				Name: strings.Join([]string{v1.CRDPlural, v1.CRDGroup}, "."),
+				Rules: []admissionregistrationv1beta1.RuleWithOperations{{
+					Operations: []admissionregistrationv1beta1.OperationType{admissionregistrationv1beta1.Create, admissionregistrationv1beta1.Update},
+					Rule: admissionregistrationv1beta1.Rule{
+						APIGroups:   []string{v1.CRDGroup},
+						APIVersions: []string{v1.CRDVersion},
+						Resources:   []string{v1.CRDPlural},
+					},
+				}},
+				FailurePolicy: &failurePolicy,
+				ClientConfig: admissionregistrationv1beta1.WebhookClientConfig{
+					Service:  &admissionregistrationv1beta1.ServiceReference{Namespace: manifestNamespace, Name: manifestName, Path: &path},
+					CABundle: caBundle,
+				},
+			}},
+		},
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+	"github.com/joshrendek/k8s-external-postgres/pkg/provisioner"
+)
+
+// normalizeMemberOf lowercases and deduplicates the group roles of
+// dbResource.
+func normalizeMemberOf(dbResource *v1.Database) []string {
+	var groups []string
+	for _, group := range dbResource.Spec.MemberOf {
+		group = strings.ToLower(strings.TrimSpace(group))
+		if group != "" && !containsString(groups, group) {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// isMember reports whether role is a direct member of group.
+func isMember(db *sql.DB, role, group string) (bool, error) {
+	var member bool
+	err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM pg_auth_members m
+		JOIN pg_roles r ON r.oid = m.roleid JOIN pg_roles u ON u.oid = m.member
+		WHERE r.rolname = $1 AND u.rolname = $2)`, group, role).Scan(&member)
+	return member, err
+}
+
+// applyMemberships makes the owner role of dbResource a member of the group
+// roles of its spec, creating the missing ones, and revokes the groups
+// removed since the status was recorded. It returns the groups applied.
+func applyMemberships(dbResource *v1.Database, inst *instance, exec *sqlExecutor) ([]string, error) {
+	desired := normalizeMemberOf(dbResource)
+	username := roleName(dbResource)
+
+	for _, group := range desired {
+		exists, err := roleExists(inst.DB, group)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			stmt := fmt.Sprintf("CREATE ROLE %s NOLOGIN", provisioner.QuoteIdentifier(group))
+			if err := exec.Exec(inst.DB, stmt); err != nil {
+				return nil, fmt.Errorf("error creating group role %q: %s", group, err.Error())
+			}
+		}
+		member, err := isMember(inst.DB, username, group)
+		if err != nil {
+			return nil, err
+		}
+		if member {
+			continue
+		}
+		stmt := fmt.Sprintf("GRANT %s TO %s", provisioner.QuoteIdentifier(group), username)
+		if err := exec.Exec(inst.DB, stmt); err != nil {
+			return nil, fmt.Errorf("error granting group role %q: %s", group, err.Error())
+		}
+	}
+
+	for _, group := range dbResource.Status.MemberOf {
+		if containsString(desired, group) {
+			continue
+		}
+		member, err := isMember(inst.DB, username, group)
+		if err != nil {
+			return nil, err
+		}
+		if !member {
+			continue
+		}
+		stmt := fmt.Sprintf("REVOKE %s FROM %s", provisioner.QuoteIdentifier(group), username)
+		if err := exec.Exec(inst.DB, stmt); err != nil {
+			return nil, fmt.Errorf("error revoking group role %q: %s", group, err.Error())
+		}
+	}
+
+	return desired, nil
+}
+
+// syncMemberships applies the group roles of a provisioned dbResource and
+// records them in its status.
+func (c *Controller) syncMemberships(dbResource *v1.Database, inst *instance, exec *sqlExecutor) error {
+	applied, err := applyMemberships(dbResource, inst, exec)
+	if err != nil {
+		return err
+	}
+	if exec.dryRun || reflect.DeepEqual(applied, dbResource.Status.MemberOf) {
+		return nil
+	}
+	dbCopy := dbResource.DeepCopy()
+	dbCopy.Status.MemberOf = applied
+	return c.updateStatus(dbCopy)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	// instanceUp is 1 while the admin connection of an instance answers the
+	// health checks of the watchdog, 0 while it is unreachable.
+	instanceUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "external_postgres_instance_up",
+		Help: "Whether the admin connection of the instance answers health checks.",
+	}, []string{"instance"})
+	// instanceReconnects counts the times the connection pool of an instance
+	// was reset after the server became reachable again.
+	instanceReconnects = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "external_postgres_instance_reconnects_total",
+		Help: "Number of times the connection pool of the instance was reset after an outage.",
+	}, []string{"instance"})
+	// ddlWaitSeconds measures how long the statements of reconciles wait for
+	// the ones on the same server with --max-concurrent-ddl.
+	ddlWaitSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "external_postgres_ddl_wait_seconds",
+		Help:    "Time statements waited for the other statements on the server of the instance.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 4, 10),
+	}, []string{"instance"})
+	// ddlThrottledSeconds measures how long the statements of reconciles
+	// wait for a token of the --max-ddl-rate of their server.
+	ddlThrottledSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "external_postgres_ddl_throttled_seconds",
+		Help:    "Time statements waited for the rate limit of the server of the instance.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 4, 10),
+	}, []string{"instance"})
+	// orphanedObjects is the number of databases and roles managed by the
+	// controller whose Database is gone, as found by the last orphan audit.
+	orphanedObjects = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "external_postgres_orphaned_objects",
+		Help: "Number of databases and roles managed by the controller whose Database is gone.",
+	}, []string{"instance", "kind"})
+	// slowQueryMeanSeconds is the mean execution time of the slowest
+	// statements of every Database, as last collected from
+	// pg_stat_statements.
+	slowQueryMeanSeconds = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "external_postgres_slow_query_mean_seconds",
+		Help: "Mean execution time of the slowest statements of the database, as last collected.",
+	}, []string{"namespace", "name", "queryid"})
+	// databaseSizeExceeded counts the times the database of a Database
+	// went past its spec.maxSizeBytes.
+	databaseSizeExceeded = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "external_postgres_database_size_exceeded_total",
+		Help: "Number of times the database went past the maxSizeBytes of its Database.",
+	}, []string{"namespace", "name"})
+	// databaseDropFailures counts the failures cleaning up the objects of
+	// deleted Databases.
+	databaseDropFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "external_postgres_database_drop_failures_total",
+		Help: "Number of failures cleaning up the database, roles or credentials of deleted Databases.",
+	}, []string{"namespace", "object"})
+	// idleSessionsTerminated counts the sessions of the roles of a Database
+	// terminated for being idle past its spec.idleSessionTimeout.
+	idleSessionsTerminated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "external_postgres_idle_sessions_terminated_total",
+		Help: "Number of sessions of the roles of the Database terminated for being idle past its idleSessionTimeout.",
+	}, []string{"namespace", "name"})
+	// credentialsDriftDetected counts the times the password of a role of
+	// a Database was found changed on the server.
+	credentialsDriftDetected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "external_postgres_credentials_drift_total",
+		Help: "Number of times the password of a role of the Database was found changed on the server.",
+	}, []string{"namespace", "name"})
+)
+
+func init() {
+	prometheus.MustRegister(instanceUp, instanceReconnects, ddlWaitSeconds, ddlThrottledSeconds, orphanedObjects, slowQueryMeanSeconds, databaseSizeExceeded,
+		databaseDropFailures, idleSessionsTerminated, credentialsDriftDetected)
+}
+
+// runMetricsServer serves the Prometheus metrics on --metrics-addr until
+// stopCh is closed, along with the usage reports of reporter and the
+// orphaned objects report.
+func runMetricsServer(reporter *usageReporter, stopCh <-chan struct{}) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/usage", reporter)
+	mux.Handle("/orphans", orphans)
+	server := &http.Server{Addr: metricsAddr, Handler: mux}
+
+	go func() {
+		<-stopCh
+		server.Close()
+	}()
+
+	log.Info().Str("addr", metricsAddr).Msg("Starting metrics server")
+	err := server.ListenAndServe()
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+	"github.com/joshrendek/k8s-external-postgres/pkg/provisioner"
+)
+
+// defaultMigrationsTable is the table golang-migrate records the version in.
+const defaultMigrationsTable = "schema_migrations"
+
+// migrationsTableRegexp matches the names accepted for spec.migrations.table.
+var migrationsTableRegexp = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+
+// migrationFileRegexp matches the up migrations of golang-migrate, e.g.
+// 0003_add_orders.up.sql.
+var migrationFileRegexp = regexp.MustCompile(`^([0-9]+)_.*\.up\.sql$`)
+
+// migration is an up migration read from the migrations ConfigMap.
+type migration struct {
+	version int64
+	name    string
+	script  string
+}
+
+// migrationsTable returns the quoted name of the table the migrations of
+// dbResource are recorded in, qualified with the schema of dbResource in
+// schema mode and with public otherwise.
+func migrationsTable(dbResource *v1.Database) (string, error) {
+	table := dbResource.Spec.Migrations.Table
+	if table == "" {
+		table = defaultMigrationsTable
+	}
+	if !migrationsTableRegexp.MatchString(table) {
+		return "", fmt.Errorf("invalid migrations table %q, must be at most 63 lowercase letters, digits or underscores", table)
+	}
+	return tenantSchema(dbResource) + "." + provisioner.QuoteIdentifier(table), nil
+}
+
+// readMigrations returns the up migrations of the ConfigMap referenced by
+// dbResource, in the order of their versions.
+func (c *Controller) readMigrations(dbResource *v1.Database) ([]migration, error) {
+	name := dbResource.Spec.Migrations.ConfigMapRef.Name
+	configMap, err := c.ConfigMapsLister.ConfigMaps(dbResource.Namespace).Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("error reading migrations configmap %q: %s", name, err.Error())
+	}
+	var migrations []migration
+	versions := map[int64]string{}
+	for key, script := range configMap.Data {
+		m := migrationFileRegexp.FindStringSubmatch(key)
+		if m == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version of migration %q: %s", key, err.Error())
+		}
+		if other, ok := versions[version]; ok {
+			return nil, fmt.Errorf("migrations %q and %q have the same version %d", other, key, version)
+		}
+		versions[version] = key
+		migrations = append(migrations, migration{version: version, name: key, script: script})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+	return migrations, nil
+}
+
+// migrationVersion returns the version recorded in table on db, 0 when the
+// table is missing or empty. Migrations left dirty, by golang-migrate, are an
+// error: they were interrupted and must be fixed by hand.
+func migrationVersion(db *sql.DB, table string) (int64, error) {
+	var exists bool
+	if err := db.QueryRow("SELECT to_regclass($1) IS NOT NULL", table).Scan(&exists); err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, nil
+	}
+	var version int64
+	var dirty bool
+	err := db.QueryRow(fmt.Sprintf("SELECT version, dirty FROM %s LIMIT 1", table)).Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if dirty {
+		return 0, fmt.Errorf("migration %d is dirty in %s, fix the database and clear the dirty flag", version, table)
+	}
+	return version, nil
+}
+
+// syncMigrations runs the migrations of dbResource newer than the version
+// recorded in its migrations table, in order. Each one runs in its own
+// transaction, along with the update of the table, logged in as the owner
+// role, see openAsOwner, and in the schema of dbResource in schema mode. The
+// version reached is recorded in status.migrationVersion, a failed migration
+// stopping the run.
+func (c *Controller) syncMigrations(dbResource *v1.Database, inst *instance, exec *sqlExecutor) error {
+	if dbResource.Spec.Migrations == nil {
+		return nil
+	}
+	table, err := migrationsTable(dbResource)
+	if err != nil {
+		return err
+	}
+	migrations, err := c.readMigrations(dbResource)
+	if err != nil {
+		return err
+	}
+	db, err := c.openStoredOwner(dbResource, inst)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	version, err := migrationVersion(db, table)
+	if err != nil {
+		return err
+	}
+	var preamble []string
+	if schemaMode(dbResource) {
+		preamble = append(preamble, fmt.Sprintf("SET LOCAL search_path TO %s", schemaName(dbResource)))
+	}
+
+	applied := version
+	var runErr error
+	for _, m := range migrations {
+		if m.version <= version {
+			continue
+		}
+		exec.logger.Info().Int64("version", m.version).Str("migration", m.name).Msg("running migration")
+		stmts := append(append([]string{}, preamble...),
+			fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)", table),
+			m.script,
+			fmt.Sprintf("TRUNCATE %s", table),
+			fmt.Sprintf("INSERT INTO %s (version, dirty) VALUES (%d, false)", table, m.version),
+		)
+		if err := exec.ExecTx(db, stmts); err != nil {
+			runErr = fmt.Errorf("error running migration %s: %s", m.name, err.Error())
+			break
+		}
+		applied = m.version
+	}
+	if exec.dryRun || applied == dbResource.Status.MigrationVersion {
+		return runErr
+	}
+	if applied > version {
+		c.recorder.Event(dbResource, corev1.EventTypeNormal, "Migrated", fmt.Sprintf("Database migrated to version %d", applied))
+	}
+	dbCopy := dbResource.DeepCopy()
+	dbCopy.Status.MigrationVersion = applied
+	if err := c.updateStatus(dbCopy); err != nil {
+		return err
+	}
+	return runErr
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net"
+	"reflect"
+
+	"github.com/rs/zerolog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+// mirrorKeys are the credential keys pointing at the mirror instance of the
+// Database, removed once it has none.
+var mirrorKeys = []string{"HOST_MIRROR", "PORT_MIRROR", "DATABASE_URL_MIRROR"}
+
+// mirrorInstance returns the spec.mirrorTo instance of dbResource, nil when
+// it isn't mirrored.
+func (c *Controller) mirrorInstance(dbResource *v1.Database) (*instance, error) {
+	if dbResource.Spec.MirrorTo == "" {
+		return nil, nil
+	}
+	return c.instances.get(dbResource.Namespace, dbResource.Spec.MirrorTo)
+}
+
+// setMirrorCredentials points the mirror keys of the credentials data at
+// mirror, or removes them when it is nil. It returns whether data changed.
+func setMirrorCredentials(data map[string]string, mirror *instance) bool {
+	want := map[string]string{}
+	if mirror != nil {
+		host, port := mirror.hostPort()
+		want["HOST_MIRROR"] = host
+		want["PORT_MIRROR"] = port
+		if dsn, err := parseURI(data["DATABASE_URL"]); err == nil && data["DATABASE_URL"] != "" {
+			dsn.Host = net.JoinHostPort(host, port)
+			want["DATABASE_URL_MIRROR"] = dsn.String()
+		}
+	}
+	changed := false
+	for _, key := range mirrorKeys {
+		value, ok := want[key]
+		if current, exists := data[key]; exists == ok && current == value {
+			continue
+		}
+		changed = true
+		if ok {
+			data[key] = value
+		} else {
+			delete(data, key)
+		}
+	}
+	return changed
+}
+
+// mirroredRole is a role of a Database along with the password last written
+// to its credentials.
+type mirroredRole struct {
+	username string
+	password string
+	owner    bool
+}
+
+// mirroredRoles returns the roles of dbResource, the owner first, with the
+// password of their credentials. Roles whose credentials are not stored yet
+// are left out.
+func (c *Controller) mirroredRoles(dbResource *v1.Database) ([]mirroredRole, error) {
+	store, err := c.credentialStore(dbResource)
+	if err != nil {
+		return nil, err
+	}
+	// credentialNames and ownedRoles both list the owner, read-only and
+	// application roles in that order
+	usernames := ownedRoles(dbResource)
+	var roles []mirroredRole
+	for i, name := range credentialNames(dbResource) {
+		credentials, err := store.Get(dbResource, name)
+		if err != nil {
+			return nil, err
+		}
+		if credentials == nil {
+			if i == 0 {
+				return nil, fmt.Errorf("credentials of the owner role are not stored yet")
+			}
+			continue
+		}
+		roles = append(roles, mirroredRole{username: usernames[i], password: appliedPassword(credentials), owner: i == 0})
+	}
+	return roles, nil
+}
+
+// mirrorChecksum identifies the mirror, database, roles and passwords of
+// dbResource, so they are only applied to the mirror again once they change.
+func mirrorChecksum(dbResource *v1.Database, roles []mirroredRole) string {
+	sum := sha256.New()
+	fmt.Fprintf(sum, "%s\x00%s\x00%s\x00", dbResource.UID, dbResource.Spec.MirrorTo, databaseName(dbResource))
+	for _, role := range roles {
+		fmt.Fprintf(sum, "%s\x00%s\x00", role.username, role.password)
+	}
+	return fmt.Sprintf("%x", sum.Sum(nil))
+}
+
+// syncMirror provisions the roles of dbResource, with the passwords of their
+// credentials, and its database on the spec.mirrorTo instance whenever they
+// changed, and points the mirror keys of the credentials Secrets at it.
+// Privileges inside the database come with the data migrated to it. The
+// state of the mirror is recorded in status.mirror. Nothing is mirrored in
+// dry run.
+func (c *Controller) syncMirror(ctx context.Context, logger zerolog.Logger, dbResource *v1.Database, inst *instance) error {
+	if dbResource.Spec.MirrorTo == "" {
+		if dbResource.Status.Mirror == nil {
+			return nil
+		}
+		if err := c.syncMirrorCredentials(dbResource, nil); err != nil {
+			return err
+		}
+		dbCopy := dbResource.DeepCopy()
+		dbCopy.Status.Mirror = nil
+		return c.updateStatus(dbCopy)
+	}
+
+	current := dbResource.Status.Mirror
+	status := &v1.MirrorStatus{Instance: dbResource.Spec.MirrorTo}
+	if current != nil && current.Instance == status.Instance {
+		status.Checksum = current.Checksum
+		status.SyncedTime = current.SyncedTime
+	}
+	checksum, err := c.applyMirror(ctx, logger, dbResource, inst)
+	if err != nil {
+		status.State = "error"
+		status.Message = err.Error()
+	} else {
+		status.State = "provisioned"
+		if checksum != status.Checksum {
+			now := metav1.Now()
+			status.Checksum = checksum
+			status.SyncedTime = &now
+		}
+	}
+	if !reflect.DeepEqual(current, status) {
+		dbCopy := dbResource.DeepCopy()
+		dbCopy.Status.Mirror = status
+		if updateErr := c.updateStatus(dbCopy); updateErr != nil {
+			return updateErr
+		}
+	}
+	return err
+}
+
+// applyMirror provisions the roles and database of dbResource on its mirror
+// unless the checksum of status.mirror says they already are, returning the
+// checksum mirrored.
+func (c *Controller) applyMirror(ctx context.Context, logger zerolog.Logger, dbResource *v1.Database, inst *instance) (string, error) {
+	if schemaMode(dbResource) {
+		return "", fmt.Errorf("spec.mirrorTo is not supported in schema mode")
+	}
+	mirror, err := c.mirrorInstance(dbResource)
+	if err != nil {
+		return "", err
+	}
+	if net.JoinHostPort(mirror.hostPort()) == net.JoinHostPort(inst.hostPort()) {
+		return "", fmt.Errorf("mirror instance %q is the server of the Database", dbResource.Spec.MirrorTo)
+	}
+	if err := mirror.unavailable(); err != nil {
+		return "", fmt.Errorf("mirror instance %q unreachable: %s", dbResource.Spec.MirrorTo, err.Error())
+	}
+	roles, err := c.mirroredRoles(dbResource)
+	if err != nil {
+		return "", err
+	}
+	checksum := mirrorChecksum(dbResource, roles)
+	if current := dbResource.Status.Mirror; current != nil && current.Checksum == checksum && current.State == "provisioned" {
+		return checksum, nil
+	}
+
+	logger.Info().Str("mirror", dbResource.Spec.MirrorTo).Msg("mirroring database and roles")
+	exec := newExecutor(ctx, dbResource, mirror, logger)
+	database := databaseName(dbResource)
+	for _, role := range roles {
+		stmt, err := upsertRoleStatement(mirror.DB, role.username, role.password, passwordEncryptionFor(dbResource))
+		if err != nil {
+			return "", err
+		}
+		stmts := []string{stmt}
+		if role.owner {
+			stmts = append(stmts, mirror.dialect.createRoleStatements(role.username)...)
+		}
+		if err := exec.ExecDDL(mirror, stmts); err != nil {
+			return "", fmt.Errorf("error mirroring role %s: %s", role.username, err.Error())
+		}
+	}
+	exists, err := databaseExists(mirror.DB, database)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		for _, stmt := range mirror.dialect.createDatabaseStatements(database, roleName(dbResource)) {
+			if err := exec.Exec(mirror.DB, stmt); err != nil {
+				return "", fmt.Errorf("error mirroring database: %s", err.Error())
+			}
+		}
+	}
+	for _, role := range roles {
+		if role.owner {
+			continue
+		}
+		if err := exec.Exec(mirror.DB, fmt.Sprintf("GRANT CONNECT ON DATABASE %s TO %s", database, role.username)); err != nil {
+			return "", fmt.Errorf("error mirroring role %s: %s", role.username, err.Error())
+		}
+	}
+	if err := c.syncOwnershipComments(dbResource, mirror, exec); err != nil {
+		return "", err
+	}
+	if err := c.syncMirrorCredentials(dbResource, mirror); err != nil {
+		return "", err
+	}
+	c.recorder.Event(dbResource, corev1.EventTypeNormal, "Mirrored", fmt.Sprintf("Database and roles mirrored to instance %s", dbResource.Spec.MirrorTo))
+	return checksum, nil
+}
+
+// syncMirrorCredentials updates the mirror keys of the credentials Secrets
+// of dbResource. Other credential stores get them with the next credentials
+// written.
+func (c *Controller) syncMirrorCredentials(dbResource *v1.Database, mirror *instance) error {
+	if spec := dbResource.Spec.CredentialStore; spec != nil && spec.Type != "" && spec.Type != credentialStoreSecret {
+		return nil
+	}
+	store := &secretStore{c: c}
+	for _, name := range credentialNames(dbResource) {
+		data, err := store.Get(dbResource, name)
+		if err != nil {
+			return err
+		}
+		if data == nil || !setMirrorCredentials(data, mirror) {
+			continue
+		}
+		if err := store.Put(dbResource, name, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropMirror drops the database and roles of the deleted dbResource from its
+// mirror instance, once verified they belong to it.
+func (c *Controller) dropMirror(ctx context.Context, logger zerolog.Logger, dbResource *v1.Database) {
+	if dbResource.Spec.MirrorTo == "" || schemaMode(dbResource) {
+		return
+	}
+	mirror, err := c.mirrorInstance(dbResource)
+	if err != nil {
+		logger.Error().Err(err).Msg("error dropping mirrored database")
+		return
+	}
+	logger.Info().Str("mirror", dbResource.Spec.MirrorTo).Str("database", databaseName(dbResource)).Msg("dropping mirrored database")
+	exec := newExecutor(ctx, dbResource, mirror, logger)
+	exec.priority = true
+	err = verifyOwnership(mirror, orphanKindDatabase, databaseName(dbResource), dbResource)
+	if err == nil {
+		err = dropDatabase(logger, dbResource, mirror, exec)
+	}
+	if err != nil {
+		logger.Error().Err(err).Msg("error dropping mirrored database")
+	}
+	roles := ownedRoles(dbResource)
+	for i := len(roles) - 1; i >= 0; i-- {
+		if err := dropOwnedRole(mirror, exec, dbResource, roles[i]); err != nil {
+			logger.Error().Err(err).Str("role", roles[i]).Msg("error dropping mirrored role")
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/jackc/pgx"
+	"github.com/jackc/pgx/stdlib"
+)
+
+// targetSessionAttrs is the parameter of the admin URI asking for the
+// read-write host of a multi-host URI, as libpq does. pgx doesn't parse the
+// IPv6 literals of multi-host URIs, the controller picks the host itself.
+const targetSessionAttrs = "target_session_attrs"
+
+// normalizeDSN returns the admin URI dsn as a postgres:// URL, converting
+// it from the keyword/value form, e.g. host=a,b port=5432 dbname=postgres.
+// Comma separated hosts and ports are kept as a multi-host URL, and IPv6
+// literals put in brackets.
+func normalizeDSN(dsn string) (string, error) {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		return dsn, nil
+	}
+	params, err := parseKeywordDSN(dsn)
+	if err != nil {
+		return "", err
+	}
+	u := &url.URL{Scheme: "postgres", Path: "/" + params["dbname"]}
+	if user, ok := params["user"]; ok {
+		u.User = url.User(user)
+		if password, ok := params["password"]; ok {
+			u.User = url.UserPassword(user, password)
+		}
+	}
+	hosts := strings.Split(params["host"], ",")
+	ports := strings.Split(params["port"], ",")
+	if len(ports) > 1 && len(ports) != len(hosts) {
+		return "", fmt.Errorf("%d ports given for %d hosts", len(ports), len(hosts))
+	}
+	q := url.Values{}
+	if len(hosts) == 1 && strings.HasPrefix(hosts[0], "/") {
+		// a unix socket directory
+		q.Set("host", hosts[0])
+		if ports[0] != "" {
+			q.Set("port", ports[0])
+		}
+	} else {
+		addrs := make([]string, len(hosts))
+		for i, host := range hosts {
+			port := ports[0]
+			if len(ports) > 1 {
+				port = ports[i]
+			}
+			addrs[i] = joinHostPort(host, port)
+		}
+		u.Host = strings.Join(addrs, ",")
+	}
+	for key, value := range params {
+		switch key {
+		case "host", "port", "user", "password", "dbname":
+		default:
+			q.Set(key, value)
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// joinHostPort returns host with port, if any, in the form of URLs.
+func joinHostPort(host, port string) string {
+	if port != "" {
+		return net.JoinHostPort(host, port)
+	}
+	if strings.Contains(host, ":") {
+		return "[" + host + "]"
+	}
+	return host
+}
+
+// parseKeywordDSN parses the keyword/value form of a connection string,
+// whose values may be single quoted with backslash escapes.
+func parseKeywordDSN(dsn string) (map[string]string, error) {
+	params := map[string]string{}
+	s := []rune(dsn)
+	for i := 0; i < len(s); {
+		if unicode.IsSpace(s[i]) {
+			i++
+			continue
+		}
+		start := i
+		for i < len(s) && s[i] != '=' && !unicode.IsSpace(s[i]) {
+			i++
+		}
+		key := string(s[start:i])
+		for i < len(s) && unicode.IsSpace(s[i]) {
+			i++
+		}
+		if i == len(s) || s[i] != '=' {
+			return nil, fmt.Errorf("missing \"=\" after %q in connection string", key)
+		}
+		i++
+		for i < len(s) && unicode.IsSpace(s[i]) {
+			i++
+		}
+		var value []rune
+		if i < len(s) && s[i] == '\'' {
+			i++
+			for ; i < len(s) && s[i] != '\''; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				value = append(value, s[i])
+			}
+			if i == len(s) {
+				return nil, fmt.Errorf("unterminated quoted value of %q in connection string", key)
+			}
+			i++
+		} else {
+			for ; i < len(s) && !unicode.IsSpace(s[i]); i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				value = append(value, s[i])
+			}
+		}
+		params[key] = string(value)
+	}
+	return params, nil
+}
+
+// splitHosts returns the hosts of the URI uri, along with uri pointing at the
+// first one only. url.Parse rejects the lists of IPv6 literals.
+func splitHosts(uri string) (string, []string) {
+	scheme := strings.Index(uri, "://")
+	if scheme < 0 {
+		return uri, nil
+	}
+	authority := uri[scheme+3:]
+	if end := strings.IndexAny(authority, "/?"); end >= 0 {
+		authority = authority[:end]
+	}
+	hostsAt := strings.LastIndex(authority, "@") + 1
+	hosts := strings.Split(authority[hostsAt:], ",")
+	first := uri[:scheme+3] + authority[:hostsAt] + hosts[0] + uri[scheme+3+len(authority):]
+	return first, hosts
+}
+
+// parseURI parses the URI uri like url.Parse, the comma separated hosts of a
+// multi-host URI being kept in Host.
+func parseURI(uri string) (*url.URL, error) {
+	first, hosts := splitHosts(uri)
+	u, err := url.Parse(first)
+	if err != nil {
+		return nil, err
+	}
+	u.Host = strings.Join(hosts, ",")
+	return u, nil
+}
+
+// hostDSNs returns a DSN for each host of dsn, without target_session_attrs,
+// and whether the read-write host is to be picked.
+func hostDSNs(dsn string) ([]string, bool, error) {
+	u, err := parseURI(dsn)
+	if err != nil {
+		return nil, false, err
+	}
+	q := u.Query()
+	readWrite := q.Get(targetSessionAttrs) == "read-write"
+	if attrs := q.Get(targetSessionAttrs); attrs != "" && attrs != "any" && !readWrite {
+		return nil, false, fmt.Errorf("unsupported %s %q, must be any or read-write", targetSessionAttrs, attrs)
+	}
+	q.Del(targetSessionAttrs)
+	u.RawQuery = q.Encode()
+
+	hosts := strings.Split(u.Host, ",")
+	dsns := make([]string, len(hosts))
+	for i, host := range hosts {
+		u.Host = host
+		dsns[i] = u.String()
+	}
+	return dsns, readWrite, nil
+}
+
+// hostConfig returns the pgx configuration of the single host URI uri,
+// picking a read-write session when readWrite is set and dialing through
+// tunnel when it is not nil. pgx parses neither IPv6 literals nor the tunnel
+// out of a URI: the host is dialed here, whatever address pgx asks for, and
+// connect_timeout applied to the dial.
+func hostConfig(uri string, readWrite bool, tunnel *sshTunnel) (pgx.ConnConfig, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return pgx.ConnConfig{}, err
+	}
+	q := u.Query()
+	var timeout time.Duration
+	if seconds := q.Get("connect_timeout"); seconds != "" {
+		n, err := strconv.Atoi(seconds)
+		if err != nil {
+			return pgx.ConnConfig{}, fmt.Errorf("invalid connect_timeout %q", seconds)
+		}
+		timeout = time.Duration(n) * time.Second
+		q.Del("connect_timeout")
+	}
+	var socketPort uint16
+	if port := q.Get("port"); port != "" {
+		n, err := strconv.ParseUint(port, 10, 16)
+		if err != nil {
+			return pgx.ConnConfig{}, fmt.Errorf("invalid port %q", port)
+		}
+		socketPort = uint16(n)
+		q.Del("port")
+	}
+	host, port := u.Hostname(), u.Port()
+	u.Host = ""
+	u.RawQuery = q.Encode()
+	config, err := pgx.ParseURI(u.String())
+	if err != nil {
+		return config, err
+	}
+	if readWrite {
+		config.TargetSessionAttrs = pgx.ReadWriteTargetSession
+	}
+
+	dialer := &net.Dialer{Timeout: timeout, KeepAlive: 5 * time.Minute}
+	if host == "" {
+		// a unix socket directory, given in the query
+		config.Port = socketPort
+		config.Dial = dialer.Dial
+		return config, nil
+	}
+	if port == "" {
+		port = "5432"
+	}
+	addr := net.JoinHostPort(host, port)
+	config.Dial = func(network, _ string) (net.Conn, error) {
+		switch {
+		case tunnel == nil:
+			return dialer.Dial(network, addr)
+		case timeout > 0:
+			return tunnel.DialTimeout(network, addr, timeout)
+		}
+		return tunnel.Dial(network, addr)
+	}
+	if config.TLSConfig != nil && !config.TLSConfig.InsecureSkipVerify {
+		// verify-ca and verify-full check the certificate against the host
+		config.TLSConfig.ServerName = host
+	}
+	return config, nil
+}
+
+// failoverConnector connects to the first host of a multi-host URI that
+// answers, and is not read-only when their configurations ask for a
+// read-write session, starting with the last one picked. Host names are
+// resolved again on every connection, so a primary moved after a switchover
+// is found back. password, when set, returns the password of each new
+// connection in place of the one of the configurations.
+type failoverConnector struct {
+	configs  []pgx.ConnConfig
+	password func() (string, error)
+
+	mu        sync.Mutex
+	preferred int
+}
+
+func (c *failoverConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	c.mu.Lock()
+	preferred := c.preferred
+	c.mu.Unlock()
+
+	var password string
+	if c.password != nil {
+		var err error
+		if password, err = c.password(); err != nil {
+			return nil, err
+		}
+	}
+
+	var lastErr error
+	for n := 0; n < len(c.configs); n++ {
+		i := (preferred + n) % len(c.configs)
+		config := c.configs[i]
+		if c.password != nil {
+			config.Password = password
+		}
+		conn, err := openConn(config)
+		if err != nil {
+			lastErr = fmt.Errorf("host %d of the URI: %s", i+1, err.Error())
+			continue
+		}
+		c.mu.Lock()
+		c.preferred = i
+		c.mu.Unlock()
+		return conn, nil
+	}
+	return nil, lastErr
+}
+
+func (c *failoverConnector) Driver() driver.Driver {
+	return stdlib.GetDefaultDriver()
+}
+
+// openConn opens a connection with config. The pgx driver only opens
+// configurations registered with it, sslmode=disable keeping it from setting
+// its default TLS mode over the one of config.
+func openConn(config pgx.ConnConfig) (driver.Conn, error) {
+	driverConfig := &stdlib.DriverConfig{ConnConfig: config}
+	stdlib.RegisterDriverConfig(driverConfig)
+	defer stdlib.UnregisterDriverConfig(driverConfig)
+	return stdlib.GetDefaultDriver().Open(driverConfig.ConnectionString("sslmode=disable"))
+}
+
+// readOnly reports whether a session of the pool db is read-only, as the
+// former primary of a multi-host URI is after a switchover.
+func readOnly(ctx context.Context, db *sql.DB) (bool, error) {
+	var value string
+	if err := db.QueryRowContext(ctx, "SHOW transaction_read_only").Scan(&value); err != nil {
+		return false, err
+	}
+	return value == "on", nil
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+const (
+	defaultDatabaseNameTemplate = "{{ .Database }}"
+	defaultRoleNameTemplate     = "{{ .Username }}"
+
+	// maxIdentifierLength is NAMEDATALEN - 1, longer names are truncated by
+	// PostgreSQL.
+	maxIdentifierLength = 63
+)
+
+// nameData is what the naming templates are executed with.
+type nameData struct {
+	Namespace string
+	Name      string
+	Database  string
+	Username  string
+}
+
+// renderName executes the naming template tmpl for dbResource and normalizes
+// the result into a lowercase identifier that needs no quoting: characters
+// other than letters, digits and underscores become underscores.
+func renderName(tmpl string, dbResource *v1.Database) (string, error) {
+	t, err := template.New("name").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid naming template %q: %s", tmpl, err.Error())
+	}
+	var buf bytes.Buffer
+	err = t.Execute(&buf, nameData{
+		Namespace: dbResource.Namespace,
+		Name:      dbResource.Name,
+		Database:  dbResource.Spec.Database,
+		Username:  dbResource.Spec.Username,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error executing naming template %q: %s", tmpl, err.Error())
+	}
+
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		}
+		return '_'
+	}, strings.TrimSpace(buf.String()))
+	if name == "" {
+		return "", fmt.Errorf("naming template %q renders an empty name", tmpl)
+	}
+	if name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name, nil
+}
+
+// serverNames renders the names of the database and owner role of
+// dbResource on the server and checks they fit in an identifier. The shared
+// database of schema mode is not templated.
+func serverNames(dbResource *v1.Database) (string, string, error) {
+	s := getSettings()
+	databaseTemplate := s.DatabaseNameTemplate
+	if schemaMode(dbResource) {
+		databaseTemplate = defaultDatabaseNameTemplate
+	}
+	database, err := renderName(databaseTemplate, dbResource)
+	if err != nil {
+		return "", "", err
+	}
+	username, err := renderName(s.RoleNameTemplate, dbResource)
+	if err != nil {
+		return "", "", err
+	}
+	if len(database) > maxIdentifierLength {
+		return "", "", fmt.Errorf("database name %q is longer than %d characters", database, maxIdentifierLength)
+	}
+	// room for the suffix of the companion roles
+	suffix := 0
+	if dbResource.Spec.ReadOnlyUser {
+		suffix = len(readOnlyUsername(""))
+	}
+	if appRole(dbResource) && len(appUsername("")) > suffix {
+		suffix = len(appUsername(""))
+	}
+	maxRole := maxIdentifierLength - suffix
+	if len(username) > maxRole {
+		return "", "", fmt.Errorf("role name %q is longer than %d characters", username, maxRole)
+	}
+	return database, username, nil
+}
+
+// databaseName returns the name of the database of dbResource on the server,
+// as recorded in its status.
+func databaseName(dbResource *v1.Database) string {
+	if dbResource.Status.DatabaseName != "" {
+		return dbResource.Status.DatabaseName
+	}
+	return dbResource.Spec.Database
+}
+
+// roleName returns the name of the owner role of dbResource on the server,
+// as recorded in its status.
+func roleName(dbResource *v1.Database) string {
+	if dbResource.Status.RoleName != "" {
+		return dbResource.Status.RoleName
+	}
+	return dbResource.Spec.Username
+}
+
+// legacyIdentifierRegexp matches the names of the databases provisioned
+// before naming templates that PostgreSQL could have created unquoted.
+var legacyIdentifierRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// legacyDatabaseName returns the name on the server of a database
+// provisioned before naming templates from its spec name: created unquoted,
+// it was folded to lowercase. Names that aren't valid unquoted, which the
+// unquoted statements couldn't have created, are refused.
+func legacyDatabaseName(name string) (string, error) {
+	if !legacyIdentifierRegexp.MatchString(name) {
+		return "", fmt.Errorf("database name %q is not a valid unquoted identifier", name)
+	}
+	return strings.ToLower(name), nil
+}
+
+// syncNames records the server names of dbResource in its status, returning
+// true when the status was updated. The database name only changes once
+// recorded when spec.database is edited, see syncDatabaseRename, and
+// databases provisioned before naming templates keep their spec name, see
+// legacyDatabaseName. The
+// role name follows the spec and the template, the database being handed
+// over to the new role on changes.
+// In schema mode the schema keeps the name of the first owner role.
+func (c *Controller) syncNames(dbResource *v1.Database) (bool, error) {
+	database, username, err := serverNames(dbResource)
+	if err != nil {
+		return false, err
+	}
+	if dbResource.Status.DatabaseName != "" {
+		database = dbResource.Status.DatabaseName
+	} else if dbResource.Status.State == "provisioned" {
+		if database, err = legacyDatabaseName(dbResource.Spec.Database); err != nil {
+			return false, err
+		}
+	}
+	schema := dbResource.Status.SchemaName
+	if schemaMode(dbResource) && schema == "" {
+		schema = username
+	}
+	specDatabase := dbResource.Status.SpecDatabase
+	if specDatabase == "" {
+		specDatabase = dbResource.Spec.Database
+	}
+	if database == dbResource.Status.DatabaseName && username == dbResource.Status.RoleName && schema == dbResource.Status.SchemaName && specDatabase == dbResource.Status.SpecDatabase {
+		return false, nil
+	}
+
+	dbCopy := dbResource.DeepCopy()
+	dbCopy.Status.DatabaseName = database
+	dbCopy.Status.SpecDatabase = specDatabase
+	dbCopy.Status.RoleName = username
+	dbCopy.Status.SchemaName = schema
+	err = c.updateStatus(dbCopy)
+	return true, err
+}
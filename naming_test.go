@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestLegacyDatabaseName(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		want    string
+		invalid bool
+	}{
+		{name: "app", want: "app"},
+		{name: "MyApp_2", want: "myapp_2"},
+		{name: "_app", want: "_app"},
+		{name: "my-app", invalid: true},
+		{name: "2app", invalid: true},
+		{name: `app"; DROP DATABASE x; --`, invalid: true},
+	} {
+		got, err := legacyDatabaseName(tc.name)
+		if tc.invalid {
+			if err == nil {
+				t.Errorf("legacyDatabaseName(%q) = %q, want an error", tc.name, got)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("legacyDatabaseName(%q) = %q, %v, want %q", tc.name, got, err, tc.want)
+		}
+	}
+}
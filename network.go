@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+
+	corev1 "k8s.io/api/core/v1"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+// networkAccessDeniedCondition is set on the Databases whose server, or a
+// firewall in front of it, refuses the connections of the controller.
+const networkAccessDeniedCondition = "NetworkAccessDenied"
+
+var (
+	// hbaRejectPattern matches the error of a server without pg_hba.conf
+	// entry for the controller, which names the client address.
+	hbaRejectPattern = regexp.MustCompile(`no pg_hba\.conf entry for (?:replication connection from )?host "([^"]+)", user "([^"]*)", database "([^"]*)"`)
+	// dialTimeoutPattern matches connections dropped on the way to the
+	// server, as security groups and NetworkPolicies do.
+	dialTimeoutPattern = regexp.MustCompile(`dial tcp (\S+): (?:i/o timeout|connect: connection timed out)`)
+)
+
+// networkDenial explains why the controller can't reach a server.
+type networkDenial struct {
+	// reason is the reason of the NetworkAccessDenied condition.
+	reason   string
+	clientIP string
+	message  string
+}
+
+// detectNetworkDenial returns the network denial reported by err, nil when it
+// failed for another reason. Errors are matched on their text as they are
+// often wrapped by the time they are reported.
+func detectNetworkDenial(err string) *networkDenial {
+	if m := hbaRejectPattern.FindStringSubmatch(err); m != nil {
+		return &networkDenial{
+			reason:   "NoPgHbaEntry",
+			clientIP: m[1],
+			message: fmt.Sprintf("the server has no pg_hba.conf entry for the controller connecting from %s as user %q to database %q, add one allowing this address",
+				m[1], m[2], m[3]),
+		}
+	}
+	if m := dialTimeoutPattern.FindStringSubmatch(err); m != nil {
+		clientIP := outboundIP(m[1])
+		return &networkDenial{
+			reason:   "ConnectionTimeout",
+			clientIP: clientIP,
+			message: fmt.Sprintf("connecting to %s from %s timed out, a firewall, security group or NetworkPolicy may be dropping the traffic of the controller",
+				m[1], clientIP),
+		}
+	}
+	return nil
+}
+
+// outboundIP returns the local address the controller reaches addr from, as
+// seen before any NAT. Dialing UDP sends nothing.
+func outboundIP(addr string) string {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return "unknown address"
+	}
+	defer conn.Close()
+	if local, ok := conn.LocalAddr().(*net.UDPAddr); ok {
+		return local.IP.String()
+	}
+	return "unknown address"
+}
+
+// reportNetworkDenial records denial on dbResource as a warning event and
+// on its NetworkAccessDenied and Ready conditions.
+func (c *Controller) reportNetworkDenial(dbResource *v1.Database, denial *networkDenial) error {
+	c.recorder.Event(dbResource, corev1.EventTypeWarning, networkAccessDeniedCondition, denial.message)
+	dbCopy := dbResource.DeepCopy()
+	changed := setCondition(&dbCopy.Status, networkAccessDeniedCondition, conditionTrue, denial.reason, denial.message)
+	changed = setCondition(&dbCopy.Status, readyCondition, conditionFalse, networkAccessDeniedCondition, denial.message) || changed
+	if !changed {
+		return nil
+	}
+	return c.updateStatus(dbCopy)
+}
+
+// clearNetworkDenial sets the NetworkAccessDenied condition of status back
+// to False once the server was reached, returning whether it changed.
+func clearNetworkDenial(status *v1.DatabaseStatus) bool {
+	if findCondition(status, networkAccessDeniedCondition) == nil {
+		return false
+	}
+	return setCondition(status, networkAccessDeniedCondition, conditionFalse, "NetworkAccessAllowed", "")
+}
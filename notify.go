@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+// Lifecycle events notifications are sent for.
+const (
+	notifyDatabaseCreated    = "DatabaseCreated"
+	notifyDatabaseDeleted    = "DatabaseDeleted"
+	notifyProvisioningFailed = "ProvisioningFailed"
+	notifyPasswordRotated    = "PasswordRotated"
+)
+
+// notification is the JSON payload POSTed to the generic webhooks.
+type notification struct {
+	Time      time.Time `json:"time"`
+	Event     string    `json:"event"`
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	Database  string    `json:"database"`
+	Instance  string    `json:"instance,omitempty"`
+	Message   string    `json:"message,omitempty"`
+}
+
+// notifier delivers notifications to a webhook.
+type notifier interface {
+	Notify(n notification) error
+}
+
+// notifiers are configured from the --notify-* flags by setupNotifications.
+var notifiers []notifier
+
+// setupNotifications configures a notifier for every URL of the --notify-*
+// flags.
+func setupNotifications() {
+	client := &http.Client{Timeout: 5 * time.Second}
+	for _, url := range splitList(notifyWebhookURLs) {
+		notifiers = append(notifiers, &webhookNotifier{url: url, client: client})
+	}
+	for _, url := range splitList(notifySlackURLs) {
+		notifiers = append(notifiers, &slackNotifier{url: url, client: client})
+	}
+}
+
+// splitList splits a comma separated flag value, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// notify sends a notification of event for dbResource to every notifier, in
+// the background so slow webhooks don't hold up reconciles. Failures are
+// only logged.
+func notify(event string, dbResource *v1.Database, message string) {
+	if len(notifiers) == 0 {
+		return
+	}
+	n := notification{
+		Time:      time.Now().UTC(),
+		Event:     event,
+		Namespace: dbResource.Namespace,
+		Name:      dbResource.Name,
+		Database:  databaseName(dbResource),
+		Instance:  instanceName(dbResource),
+		Message:   message,
+	}
+	for _, sink := range notifiers {
+		go func(sink notifier) {
+			if err := sink.Notify(n); err != nil {
+				log.Error().Err(err).Str("event", event).Str("namespace", n.Namespace).Str("name", n.Name).Msg("error sending notification")
+			}
+		}(sink)
+	}
+}
+
+// postJSON POSTs payload as JSON to url.
+func postJSON(client *http.Client, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// webhookNotifier POSTs notifications as is.
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func (w *webhookNotifier) Notify(n notification) error {
+	return postJSON(w.client, w.url, n)
+}
+
+// slackNotifier POSTs notifications as Slack incoming webhook messages.
+type slackNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func (s *slackNotifier) Notify(n notification) error {
+	text := fmt.Sprintf("*%s* `%s/%s` (database `%s`)", n.Event, n.Namespace, n.Name, n.Database)
+	if n.Instance != "" {
+		text += fmt.Sprintf(" on instance `%s`", n.Instance)
+	}
+	if n.Message != "" {
+		text += "\n" + n.Message
+	}
+	return postJSON(s.client, s.url, map[string]string{"text": text})
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+	"github.com/joshrendek/k8s-external-postgres/pkg/provisioner"
+)
+
+const (
+	orphanKindDatabase = "database"
+	orphanKindRole     = "role"
+)
+
+// orphanedObject is a database or role commented as managed by the
+// controller whose Database doesn't exist anymore.
+type orphanedObject struct {
+	Instance  string `json:"instance"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Database  string `json:"database"`
+	UID       string `json:"uid,omitempty"`
+}
+
+// orphanReport holds the orphaned objects found by the last audit, served
+// as JSON on /orphans of the metrics server.
+type orphanReport struct {
+	mu        sync.Mutex
+	auditedAt time.Time
+	objects   []orphanedObject
+}
+
+// orphans is the report of the orphan audits of the controller.
+var orphans = &orphanReport{}
+
+func (r *orphanReport) set(objects []orphanedObject) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.auditedAt = time.Now()
+	r.objects = objects
+}
+
+func (r *orphanReport) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	report := struct {
+		AuditedAt       *time.Time       `json:"auditedAt,omitempty"`
+		OrphanedObjects []orphanedObject `json:"orphanedObjects"`
+	}{OrphanedObjects: r.objects}
+	if !r.auditedAt.IsZero() {
+		report.AuditedAt = &r.auditedAt
+	}
+	if report.OrphanedObjects == nil {
+		report.OrphanedObjects = []orphanedObject{}
+	}
+	apiJSON(w, http.StatusOK, report)
+}
+
+// auditOrphans lists the databases and roles of every server commented as
+// managed by this controller and reports the ones whose Database is gone,
+// dropping them with --gc-orphans. Databases pending drop are not orphans.
+func (c *Controller) auditOrphans() {
+	var found []orphanedObject
+	audited := map[string]bool{}
+	for _, inst := range c.instances.all() {
+		server := net.JoinHostPort(inst.hostPort())
+		if audited[server] || inst.unavailable() != nil || !inst.dialect.comments {
+			continue
+		}
+		audited[server] = true
+
+		objects, err := c.findOrphans(inst)
+		if err != nil {
+			log.Error().Err(err).Str("instance", inst.label()).Msg("error auditing orphaned objects")
+			continue
+		}
+		counts := map[string]float64{orphanKindDatabase: 0, orphanKindRole: 0}
+		for _, object := range objects {
+			counts[object.Kind]++
+			log.Warn().Str("instance", object.Instance).Str("kind", object.Kind).Str("object", object.Name).
+				Str("namespace", object.Namespace).Str("database", object.Database).Msg("Orphaned object, its Database is gone")
+		}
+		for kind, count := range counts {
+			orphanedObjects.WithLabelValues(inst.label(), kind).Set(count)
+		}
+		if gcOrphans {
+			c.dropOrphans(inst, objects)
+		}
+		found = append(found, objects...)
+	}
+	orphans.set(found)
+}
+
+// findOrphans returns the orphaned databases, then roles, of the server of
+// inst.
+func (c *Controller) findOrphans(inst *instance) ([]orphanedObject, error) {
+	var objects []orphanedObject
+	queries := []struct {
+		kind  string
+		query string
+	}{
+		{orphanKindDatabase, "SELECT datname, shobj_description(oid, 'pg_database') FROM pg_database WHERE shobj_description(oid, 'pg_database') LIKE '{%'"},
+		{orphanKindRole, "SELECT rolname, shobj_description(oid, 'pg_authid') FROM pg_roles WHERE shobj_description(oid, 'pg_authid') LIKE '{%'"},
+	}
+	for _, q := range queries {
+		rows, err := inst.DB.Query(q.query)
+		if err != nil {
+			return nil, err
+		}
+		kindObjects, err := c.scanOrphans(inst, q.kind, rows)
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, kindObjects...)
+	}
+	return objects, nil
+}
+
+func (c *Controller) scanOrphans(inst *instance, kind string, rows *sql.Rows) ([]orphanedObject, error) {
+	var objects []orphanedObject
+	for rows.Next() {
+		var name, comment string
+		if err := rows.Scan(&name, &comment); err != nil {
+			return nil, err
+		}
+		owner, ok := parseOwnershipComment(comment)
+		if !ok || !owner.ours() || owner.PendingDropAfter != "" || c.ownsObject(owner, kind, name) {
+			continue
+		}
+		if _, err := c.ConfigMapsLister.ConfigMaps(owner.Namespace).Get(pendingDropName(owner.Name)); err == nil {
+			// roles of a database pending drop
+			continue
+		}
+		objects = append(objects, orphanedObject{
+			Instance:  inst.label(),
+			Kind:      kind,
+			Name:      name,
+			Namespace: owner.Namespace,
+			Database:  owner.Name,
+			UID:       owner.UID,
+		})
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Name < objects[j].Name })
+	return objects, rows.Err()
+}
+
+// ownsObject reports whether the Database recorded in owner exists and still
+// has the kind object called name.
+func (c *Controller) ownsObject(owner ownershipComment, kind, name string) bool {
+	dbResource, err := c.DatabasesLister.Databases(owner.Namespace).Get(owner.Name)
+	if err != nil {
+		return false
+	}
+	if kind == orphanKindDatabase {
+		return databaseName(dbResource) == name
+	}
+	for _, role := range ownedRoles(dbResource) {
+		if role == name {
+			return true
+		}
+	}
+	return false
+}
+
+// dropOrphans drops the orphaned objects of the server of inst, databases
+// first as they may be owned by the roles. Errors are logged, the objects
+// left are dropped by the next audit.
+func (c *Controller) dropOrphans(inst *instance, objects []orphanedObject) {
+	for _, object := range objects {
+		ctx, logger := resourceContext(context.Background(), object.Namespace, object.Database)
+		dbResource := &v1.Database{ObjectMeta: metav1.ObjectMeta{
+			Namespace: object.Namespace,
+			Name:      object.Database,
+			UID:       types.UID(object.UID),
+		}}
+		exec := newExecutor(ctx, dbResource, inst, logger)
+		exec.priority = true
+		logger.Info().Str("kind", object.Kind).Str("object", object.Name).Msg("dropping orphaned object")
+		var err error
+		if object.Kind == orphanKindDatabase {
+			// the object may have been taken over since the audit
+			if err = verifyOwnership(inst, orphanKindDatabase, object.Name, dbResource); err == nil {
+				err = exec.Exec(inst.DB, provisioner.DropDatabaseStatement(object.Name, false))
+			}
+		} else {
+			err = dropOwnedRole(inst, exec, dbResource, object.Name)
+		}
+		if err != nil {
+			logger.Error().Err(err).Str("kind", object.Kind).Str("object", object.Name).Msg("error dropping orphaned object")
+		}
+	}
+}
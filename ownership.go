@@ -0,0 +1,168 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+	"github.com/joshrendek/k8s-external-postgres/pkg/provisioner"
+)
+
+// managedBy marks the comments of the databases and roles the controller
+// created, so they can be traced back to their Database.
+const managedBy = "k8s-external-postgres"
+
+// commentQueries read the comment of a database or role, by kind.
+var commentQueries = map[string]string{
+	orphanKindDatabase: "SELECT COALESCE(shobj_description(oid, 'pg_database'), '') FROM pg_database WHERE datname = $1",
+	orphanKindRole:     "SELECT COALESCE(shobj_description(oid, 'pg_authid'), '') FROM pg_roles WHERE rolname = $1",
+}
+
+// ownershipComment is the comment set on the databases and roles of a
+// Database, as JSON.
+type ownershipComment struct {
+	ManagedBy string `json:"managedBy"`
+	// Controller is the --controller-id of the controller managing the
+	// object, empty on the objects commented before it was recorded.
+	Controller string `json:"controller,omitempty"`
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name"`
+	UID        string `json:"uid,omitempty"`
+	// PendingDropAfter is set on the databases pending drop.
+	PendingDropAfter string `json:"pendingDropAfter,omitempty"`
+}
+
+// newOwnershipComment returns the comment of the objects of dbResource.
+func newOwnershipComment(dbResource *v1.Database) ownershipComment {
+	return ownershipComment{
+		ManagedBy:  managedBy,
+		Controller: controllerID,
+		Namespace:  dbResource.Namespace,
+		Name:       dbResource.Name,
+		UID:        string(dbResource.UID),
+	}
+}
+
+// String returns the comment as the literal of a COMMENT statement.
+func (o ownershipComment) String() string {
+	comment, _ := json.Marshal(o)
+	return provisioner.QuoteLiteral(string(comment))
+}
+
+// parseOwnershipComment returns the ownership recorded in comment, false when
+// it wasn't set by the controller.
+func parseOwnershipComment(comment string) (ownershipComment, bool) {
+	o := ownershipComment{}
+	if !strings.HasPrefix(comment, "{") || json.Unmarshal([]byte(comment), &o) != nil {
+		return o, false
+	}
+	return o, o.ManagedBy == managedBy
+}
+
+// ours reports whether the object commented with o is managed by this
+// controller.
+func (o ownershipComment) ours() bool {
+	return o.Controller == "" || o.Controller == controllerID
+}
+
+// ownedRoles returns the roles of dbResource on the server.
+func ownedRoles(dbResource *v1.Database) []string {
+	roles := []string{roleName(dbResource)}
+	if dbResource.Spec.ReadOnlyUser {
+		roles = append(roles, readOnlyUsername(roleName(dbResource)))
+	}
+	if appRole(dbResource) {
+		roles = append(roles, appUsername(roleName(dbResource)))
+	}
+	return roles
+}
+
+// objectComment returns the comment of the kind object called name, false
+// when it doesn't exist.
+func objectComment(inst *instance, kind, name string) (string, bool, error) {
+	var comment string
+	err := inst.DB.QueryRow(commentQueries[kind], name).Scan(&comment)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	return comment, err == nil, err
+}
+
+// ownedElsewhere describes who the kind object called name belongs to when
+// it is commented as managed by another controller or Database, and returns
+// an empty string otherwise. Objects without ownership comment, created by
+// older versions or on servers that don't take comments, are not checked.
+func ownedElsewhere(inst *instance, kind, name string, dbResource *v1.Database) (string, error) {
+	if !inst.dialect.comments {
+		return "", nil
+	}
+	comment, _, err := objectComment(inst, kind, name)
+	if err != nil {
+		return "", err
+	}
+	o, ok := parseOwnershipComment(comment)
+	if !ok {
+		return "", nil
+	}
+	if !o.ours() {
+		return fmt.Sprintf("%s %s is managed by controller %s", kind, name, o.Controller), nil
+	}
+	if o.UID != "" && dbResource.UID != "" && o.UID != string(dbResource.UID) {
+		return fmt.Sprintf("%s %s belongs to Database %s/%s (uid %s)", kind, name, o.Namespace, o.Name, o.UID), nil
+	}
+	return "", nil
+}
+
+// verifyOwnership checks, before it is dropped, that the kind object called
+// name belongs to dbResource as ownedElsewhere tells.
+func verifyOwnership(inst *instance, kind, name string, dbResource *v1.Database) error {
+	owner, err := ownedElsewhere(inst, kind, name, dbResource)
+	if err != nil {
+		return err
+	}
+	if owner != "" {
+		return fmt.Errorf("%s, not dropping it", owner)
+	}
+	return nil
+}
+
+// syncOwnershipComments comments the database, unless shared in schema
+// mode, and roles of dbResource with their ownership when they aren't yet.
+// Objects managed by another controller are left alone and reported.
+func (c *Controller) syncOwnershipComments(dbResource *v1.Database, inst *instance, exec *sqlExecutor) error {
+	if !inst.dialect.comments {
+		return nil
+	}
+	owned := map[string][]string{orphanKindRole: ownedRoles(dbResource)}
+	if !schemaMode(dbResource) {
+		owned[orphanKindDatabase] = []string{databaseName(dbResource)}
+	}
+	comment := newOwnershipComment(dbResource)
+	var stmts []string
+	for _, kind := range []string{orphanKindDatabase, orphanKindRole} {
+		for _, name := range owned[kind] {
+			current, exists, err := objectComment(inst, kind, name)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				// not created yet, or only planned in dry run
+				continue
+			}
+			o, ok := parseOwnershipComment(current)
+			if ok && o == comment {
+				continue
+			}
+			if ok && !o.ours() {
+				return fmt.Errorf("%s %s is managed by controller %s", kind, name, o.Controller)
+			}
+			stmts = append(stmts, fmt.Sprintf("COMMENT ON %s %s IS %s", strings.ToUpper(kind), name, comment))
+		}
+	}
+	if len(stmts) == 0 {
+		return nil
+	}
+	return exec.ExecDDL(inst, stmts)
+}
@@ -0,0 +1,479 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+	clientset "github.com/joshrendek/k8s-external-postgres/pkg/client/clientset/versioned"
+	informers "github.com/joshrendek/k8s-external-postgres/pkg/client/informers/externalversions"
+	listers "github.com/joshrendek/k8s-external-postgres/pkg/client/listers/postgresql/v1"
+	"github.com/joshrendek/k8s-external-postgres/pkg/provisioner"
+)
+
+// The targets of PostgresParameters, as recorded in status.applied.
+const (
+	parameterTargetSystem         = "system"
+	parameterTargetDatabase       = "database"
+	parameterTargetRole           = "role"
+	parameterTargetRoleInDatabase = "roleInDatabase"
+)
+
+// parameterNameRegexp matches the names of the parameters, including the
+// dotted ones of extensions, as stored lowercase by the server.
+var parameterNameRegexp = regexp.MustCompile(`^[a-z_][a-z0-9_]*(\.[a-z_][a-z0-9_]*)?$`)
+
+// ParametersController sets the parameters of PostgresParameters on their
+// servers, databases and roles, and sets them again when they drift.
+type ParametersController struct {
+	kubeclientset     kubernetes.Interface
+	databaseClientset clientset.Interface
+
+	DatabasesLister  listers.DatabaseLister
+	DatabasesSynced  cache.InformerSynced
+	ParametersLister listers.PostgresParametersLister
+	ParametersSynced cache.InformerSynced
+
+	queue     workqueue.RateLimitingInterface
+	recorder  record.EventRecorder
+	instances *instanceRegistry
+}
+
+// NewParametersController returns a new parameters controller
+func NewParametersController(
+	kubeclientset kubernetes.Interface,
+	databaseClientset clientset.Interface,
+	databaseInformerFactory informers.SharedInformerFactory,
+	instances *instanceRegistry) *ParametersController {
+
+	databaseInformer := databaseInformerFactory.Databases().V1().Databases()
+	parametersInformer := databaseInformerFactory.Databases().V1().PostgresParameters()
+
+	controller := &ParametersController{
+		kubeclientset:     kubeclientset,
+		databaseClientset: databaseClientset,
+		DatabasesLister:   databaseInformer.Lister(),
+		DatabasesSynced:   databaseInformer.Informer().HasSynced,
+		ParametersLister:  parametersInformer.Lister(),
+		ParametersSynced:  parametersInformer.Informer().HasSynced,
+		queue:             workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "PostgresParameters"),
+		recorder:          newEventRecorder(kubeclientset),
+		instances:         instances,
+	}
+
+	log.Info().Msg("Setting up parameters event handlers")
+	parametersInformer.Informer().AddEventHandler(instrumentHandler("postgresparameters", "parameters", cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			enqueue(controller.queue, obj)
+		},
+		UpdateFunc: func(old, new interface{}) {
+			// drift is checked every --parameters-drift-interval rather than
+			// on every resync of the informer
+			if old.(*v1.PostgresParameters).ResourceVersion == new.(*v1.PostgresParameters).ResourceVersion {
+				return
+			}
+			enqueue(controller.queue, new)
+		},
+		// handled immediately, the resource is gone by the time the work
+		// queue would get it
+		DeleteFunc: controller.resetParameters,
+	}))
+	return controller
+}
+
+// Run waits for the informer caches to sync and starts the workers. It blocks
+// until stopCh is closed.
+func (c *ParametersController) Run(threadiness int, stopCh <-chan struct{}) error {
+	defer runtime.HandleCrash()
+
+	log.Info().Msg("Starting parameters controller")
+	if ok := cache.WaitForCacheSync(stopCh, c.DatabasesSynced, c.ParametersSynced, c.instances.HasSynced); !ok {
+		return fmt.Errorf("failed to wait for caches to sync")
+	}
+
+	runQueueWorkers(c.queue, threadiness, c.syncParameters, stopCh)
+	log.Info().Msg("Shutting down parameters workers")
+	return nil
+}
+
+// parameterTarget is a server, database or role parameters are set on.
+type parameterTarget struct {
+	// key identifies the target in status.applied: system, or the kind of
+	// target and the name of its Database, e.g. database/mydb.
+	key  string
+	inst *instance
+	// alter is the statement the parameters are set and reset with.
+	alter string
+	// database and role select the settings of the target in
+	// pg_db_role_setting, empty for any.
+	database string
+	role     string
+}
+
+// current returns the parameters set on t, with their values normalized.
+func (t *parameterTarget) current() (map[string]string, error) {
+	var rows *sql.Rows
+	var err error
+	if t.key == parameterTargetSystem {
+		rows, err = t.inst.DB.Query("SELECT name, setting FROM pg_file_settings WHERE sourcefile LIKE '%postgresql.auto.conf'")
+	} else {
+		rows, err = t.inst.DB.Query(`SELECT split_part(config, '=', 1), substr(config, strpos(config, '=') + 1)
+			FROM pg_db_role_setting, unnest(setconfig) AS config
+			WHERE setdatabase = COALESCE((SELECT oid FROM pg_database WHERE datname = $1), 0)
+			AND setrole = COALESCE((SELECT oid FROM pg_roles WHERE rolname = $2), 0)`, t.database, t.role)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	current := map[string]string{}
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, err
+		}
+		current[name] = normalizeParameter(value)
+	}
+	return current, rows.Err()
+}
+
+// parameterValue returns value as the literals of a SET statement, one per
+// comma separated element so list parameters like search_path get several.
+func parameterValue(value string) string {
+	var literals []string
+	for _, element := range strings.Split(value, ",") {
+		literals = append(literals, provisioner.QuoteLiteral(strings.Trim(strings.TrimSpace(element), `"`)))
+	}
+	return strings.Join(literals, ", ")
+}
+
+// normalizeParameter returns value as compared with the settings of the
+// server, which quote the elements of list parameters.
+func normalizeParameter(value string) string {
+	var elements []string
+	for _, element := range strings.Split(value, ",") {
+		elements = append(elements, strings.Trim(strings.TrimSpace(element), `"`))
+	}
+	return strings.Join(elements, ", ")
+}
+
+// desiredParameters returns the parameters of the spec of params by target
+// key.
+func desiredParameters(params *v1.PostgresParameters) map[string]map[string]string {
+	desired := map[string]map[string]string{}
+	add := func(key string, parameters map[string]string) {
+		if desired[key] == nil {
+			desired[key] = map[string]string{}
+		}
+		for name, value := range parameters {
+			desired[key][name] = value
+		}
+	}
+	if len(params.Spec.System) > 0 {
+		add(parameterTargetSystem, params.Spec.System)
+	}
+	for _, d := range params.Spec.Databases {
+		add(parameterTargetDatabase+"/"+d.Database, d.Parameters)
+	}
+	for _, r := range params.Spec.Roles {
+		kind := parameterTargetRole
+		if r.InDatabase {
+			kind = parameterTargetRoleInDatabase
+		}
+		add(kind+"/"+r.Database, r.Parameters)
+	}
+	return desired
+}
+
+// splitApplied splits an entry of status.applied into its target key,
+// parameter name and value.
+func splitApplied(entry string) (string, string, string) {
+	path, value := entry, ""
+	if i := strings.Index(entry, "="); i >= 0 {
+		path, value = entry[:i], entry[i+1:]
+	}
+	i := strings.LastIndex(path, "/")
+	if i < 0 {
+		return "", path, value
+	}
+	return path[:i], path[i+1:], value
+}
+
+// target returns the target of params with key, nil when its Database is
+// gone along with its settings.
+func (c *ParametersController) target(params *v1.PostgresParameters, key string) (*parameterTarget, error) {
+	if key == parameterTargetSystem {
+		if !allowAlterSystem {
+			return nil, fmt.Errorf("system parameters require --allow-alter-system")
+		}
+		var inst *instance
+		var err error
+		if params.Spec.Instance == "" {
+			inst, err = c.instances.forNamespace(params.Namespace)
+		} else {
+			inst, err = c.instances.get(params.Namespace, params.Spec.Instance)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !inst.dialect.alterSystem {
+			return nil, fmt.Errorf("ALTER SYSTEM is not supported by %s", inst.dialect.name)
+		}
+		return &parameterTarget{key: key, inst: inst, alter: "ALTER SYSTEM"}, nil
+	}
+
+	i := strings.Index(key, "/")
+	if i < 0 {
+		return nil, fmt.Errorf("invalid parameter target %q", key)
+	}
+	kind, name := key[:i], key[i+1:]
+	dbResource, err := c.DatabasesLister.Databases(params.Namespace).Get(name)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if dbResource.Status.State != "provisioned" {
+		return nil, fmt.Errorf("database %q is not provisioned yet", name)
+	}
+	inst, err := c.instances.forDatabase(dbResource)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &parameterTarget{key: key, inst: inst}
+	switch kind {
+	case parameterTargetDatabase:
+		if schemaMode(dbResource) {
+			return nil, fmt.Errorf("database %q is provisioned in schema mode, its database is shared", name)
+		}
+		t.database = databaseName(dbResource)
+		t.alter = fmt.Sprintf("ALTER DATABASE %s", t.database)
+	case parameterTargetRole:
+		t.role = roleName(dbResource)
+		t.alter = fmt.Sprintf("ALTER ROLE %s", t.role)
+	case parameterTargetRoleInDatabase:
+		t.database, t.role = databaseName(dbResource), roleName(dbResource)
+		t.alter = fmt.Sprintf("ALTER ROLE %s IN DATABASE %s", t.role, t.database)
+	default:
+		return nil, fmt.Errorf("invalid parameter target %q", key)
+	}
+	return t, nil
+}
+
+// syncParameters sets the parameters of a PostgresParameters resource that
+// differ from the spec, and resets the ones removed from it. Parameters
+// recorded as applied but changed on the server since are reported as
+// drifted. The resource is checked again after --parameters-drift-interval.
+func (c *ParametersController) syncParameters(ctx context.Context, logger zerolog.Logger, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		runtime.HandleError(fmt.Errorf("invalid resource key: %s", key))
+		return nil
+	}
+
+	params, err := c.ParametersLister.PostgresParameters(namespace).Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			runtime.HandleError(fmt.Errorf("parameters '%s' in work queue no longer exists", key))
+			return nil
+		}
+		return err
+	}
+
+	// fail records err in the status, keeping what was applied before
+	fail := func(err error) error {
+		c.recorder.Event(params, corev1.EventTypeWarning, "ParametersFailed", err.Error())
+		if statusErr := c.updateParametersStatus(params, "error", err.Error(), params.Status.Applied, params.Status.PendingRestart); statusErr != nil {
+			return statusErr
+		}
+		return err
+	}
+
+	desired := desiredParameters(params)
+	for _, parameters := range desired {
+		for name := range parameters {
+			if !parameterNameRegexp.MatchString(name) {
+				return c.updateParametersStatus(params, "error", fmt.Sprintf("invalid parameter name %q", name), params.Status.Applied, params.Status.PendingRestart)
+			}
+		}
+	}
+	// the targets removed from the spec still get their parameters reset
+	appliedBefore := map[string]bool{}
+	for _, entry := range params.Status.Applied {
+		appliedBefore[entry] = true
+		if target, _, _ := splitApplied(entry); desired[target] == nil {
+			desired[target] = map[string]string{}
+		}
+	}
+
+	keys := make([]string, 0, len(desired))
+	for key := range desired {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	exec := newResourceExecutor(ctx, "PostgresParameters", params, logger)
+	var applied, drifted, pendingRestart []string
+	var system *parameterTarget
+	reload := false
+	for _, targetKey := range keys {
+		t, err := c.target(params, targetKey)
+		if err != nil {
+			return fail(err)
+		}
+		if t == nil {
+			continue
+		}
+		if t.key == parameterTargetSystem {
+			system = t
+		}
+		current, err := t.current()
+		if err != nil {
+			return fail(err)
+		}
+
+		names := make([]string, 0, len(desired[targetKey]))
+		for name := range desired[targetKey] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			value := normalizeParameter(desired[targetKey][name])
+			entry := fmt.Sprintf("%s/%s=%s", targetKey, name, value)
+			applied = append(applied, entry)
+			if current[name] == value {
+				continue
+			}
+			if appliedBefore[entry] {
+				drifted = append(drifted, fmt.Sprintf("%s/%s", targetKey, name))
+			}
+			stmt := fmt.Sprintf("%s SET %s = %s", t.alter, name, parameterValue(desired[targetKey][name]))
+			if err := exec.Exec(t.inst.DB, stmt); err != nil {
+				return fail(fmt.Errorf("error setting %s: %s", name, err.Error()))
+			}
+			reload = reload || t == system
+		}
+		// reset what was applied before but is no longer in the spec
+		for _, entry := range params.Status.Applied {
+			target, name, _ := splitApplied(entry)
+			if _, ok := desired[targetKey][name]; target != targetKey || ok {
+				continue
+			}
+			if _, ok := current[name]; !ok {
+				continue
+			}
+			if err := exec.Exec(t.inst.DB, fmt.Sprintf("%s RESET %s", t.alter, name)); err != nil {
+				return fail(fmt.Errorf("error resetting %s: %s", name, err.Error()))
+			}
+			reload = reload || t == system
+		}
+	}
+
+	if system != nil {
+		if reload {
+			if err := exec.Exec(system.inst.DB, "SELECT pg_reload_conf()"); err != nil {
+				return fail(err)
+			}
+		}
+		if pendingRestart, err = pendingRestartParameters(system.inst.DB); err != nil {
+			return fail(err)
+		}
+	}
+
+	if len(drifted) > 0 {
+		logger.Warn().Strs("parameters", drifted).Msg("parameters drifted from the spec, setting them again")
+		c.recorder.Event(params, corev1.EventTypeWarning, "ParametersDrifted", fmt.Sprintf("Set drifted parameters again: %s", strings.Join(drifted, ", ")))
+	}
+	if params.Status.State != "provisioned" {
+		c.recorder.Event(params, corev1.EventTypeNormal, SuccessSynced, "Parameters synced successfully")
+	}
+	if err := c.updateParametersStatus(params, "provisioned", "successful", applied, pendingRestart); err != nil {
+		return err
+	}
+	c.queue.AddAfter(key, parametersDriftInterval)
+	return nil
+}
+
+// pendingRestartParameters returns the parameters changed in the
+// configuration that only take effect once the server restarts.
+func pendingRestartParameters(db *sql.DB) ([]string, error) {
+	rows, err := db.Query("SELECT name FROM pg_settings WHERE pending_restart ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// resetParameters resets the parameters a deleted PostgresParameters applied.
+func (c *ParametersController) resetParameters(obj interface{}) {
+	params, ok := obj.(*v1.PostgresParameters)
+	if !ok {
+		return
+	}
+	ctx, logger := resourceContext(context.Background(), params.Namespace, params.Name)
+	exec := newResourceExecutor(ctx, "PostgresParameters", params, logger)
+	reload := map[*instance]bool{}
+	for _, entry := range params.Status.Applied {
+		targetKey, name, _ := splitApplied(entry)
+		t, err := c.target(params, targetKey)
+		if err != nil {
+			logger.Error().Err(err).Str("parameter", targetKey+"/"+name).Msg("error resetting parameter")
+			continue
+		}
+		if t == nil {
+			continue
+		}
+		if err := exec.Exec(t.inst.DB, fmt.Sprintf("%s RESET %s", t.alter, name)); err != nil {
+			logger.Error().Err(err).Str("parameter", targetKey+"/"+name).Msg("error resetting parameter")
+			continue
+		}
+		if t.key == parameterTargetSystem {
+			reload[t.inst] = true
+		}
+	}
+	for inst := range reload {
+		if err := exec.Exec(inst.DB, "SELECT pg_reload_conf()"); err != nil {
+			logger.Error().Err(err).Msg("error reloading the configuration")
+		}
+	}
+}
+
+func (c *ParametersController) updateParametersStatus(params *v1.PostgresParameters, state, message string, applied, pendingRestart []string) error {
+	if params.Status.State == state && params.Status.Message == message &&
+		strings.Join(params.Status.Applied, "\n") == strings.Join(applied, "\n") &&
+		strings.Join(params.Status.PendingRestart, "\n") == strings.Join(pendingRestart, "\n") {
+		return nil
+	}
+	paramsCopy := params.DeepCopy()
+	paramsCopy.Status.State = state
+	paramsCopy.Status.Message = message
+	paramsCopy.Status.Applied = applied
+	paramsCopy.Status.PendingRestart = pendingRestart
+	_, err := c.databaseClientset.DatabasesV1().PostgresParameters(params.Namespace).Update(paramsCopy)
+	return err
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"time"
+	"unicode"
+
+	corev1 "k8s.io/api/core/v1"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+// passwordExpiringCondition is set on Databases whose owner password expires
+// within --password-expiry-warning.
+const passwordExpiringCondition = "PasswordExpiring"
+
+// passwordPolicy are the rules passwords set on roles must follow.
+type passwordPolicy struct {
+	minLength  int
+	minEntropy float64
+	disallowed []*regexp.Regexp
+	expiry     time.Duration
+}
+
+// currentPasswordPolicy is built from the --password-* flags by
+// setupPasswordPolicy.
+var currentPasswordPolicy passwordPolicy
+
+// setupPasswordPolicy compiles the password policy of the --password-* flags.
+func setupPasswordPolicy() error {
+	p := passwordPolicy{
+		minLength:  passwordMinLength,
+		minEntropy: passwordMinEntropy,
+		expiry:     passwordExpiry,
+	}
+	for _, pattern := range splitList(passwordDisallowedPatterns) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid disallowed password pattern %q: %s", pattern, err.Error())
+		}
+		p.disallowed = append(p.disallowed, re)
+	}
+	currentPasswordPolicy = p
+	return nil
+}
+
+// passwordEntropy estimates the entropy of password in bits from its length
+// and the classes of characters it uses.
+func passwordEntropy(password string) float64 {
+	var lower, upper, digit, other bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+	pool := 0
+	for _, class := range []struct {
+		used bool
+		size int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {other, 33}} {
+		if class.used {
+			pool += class.size
+		}
+	}
+	if pool == 0 {
+		return 0
+	}
+	return float64(len([]rune(password))) * math.Log2(float64(pool))
+}
+
+// check returns an error describing the first rule password breaks.
+func (p passwordPolicy) check(password string) error {
+	if len([]rune(password)) < p.minLength {
+		return fmt.Errorf("password is shorter than %d characters", p.minLength)
+	}
+	if p.minEntropy > 0 && passwordEntropy(password) < p.minEntropy {
+		return fmt.Errorf("password is weaker than %.0f bits of entropy", p.minEntropy)
+	}
+	for _, re := range p.disallowed {
+		if re.MatchString(password) {
+			return fmt.Errorf("password matches the disallowed pattern %q", re.String())
+		}
+	}
+	return nil
+}
+
+// checkPasswordPolicy checks password, the plaintext password of the owner
+// role of dbResource, against the policy. Password verifiers can't be
+// checked, and passwordless roles have none.
+func checkPasswordPolicy(dbResource *v1.Database, password string) error {
+	if dbResource.Spec.PasswordVerifierSecret != nil || passwordless(dbResource) {
+		return nil
+	}
+	return currentPasswordPolicy.check(password)
+}
+
+// syncPasswordExpiry sets the PasswordExpiring condition of dbResource, with
+// a warning event, once the password of its owner role expires within
+// --password-expiry-warning.
+func (c *Controller) syncPasswordExpiry(dbResource *v1.Database, inst *instance) error {
+	var until *time.Time
+	err := inst.DB.QueryRow("SELECT rolvaliduntil FROM pg_roles WHERE rolname = $1", roleName(dbResource)).Scan(&until)
+	if err != nil {
+		return err
+	}
+	expiring := until != nil && time.Until(*until) < passwordExpiryWarning
+	cond := findCondition(&dbResource.Status, passwordExpiringCondition)
+	if !expiring {
+		if cond == nil || cond.Status == conditionFalse {
+			return nil
+		}
+		return c.updateCondition(dbResource, passwordExpiringCondition, conditionFalse, "PasswordValid", "")
+	}
+	if cond != nil && cond.Status == conditionTrue {
+		return nil
+	}
+	msg := fmt.Sprintf("password of role %s expires at %s, change spec.password to renew it", roleName(dbResource), until.UTC().Format(time.RFC3339))
+	c.recorder.Event(dbResource, corev1.EventTypeWarning, "PasswordExpiring", msg)
+	return c.updateCondition(dbResource, passwordExpiringCondition, conditionTrue, "PasswordExpiring", msg)
+}
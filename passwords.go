@@ -0,0 +1,63 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+	"github.com/joshrendek/k8s-external-postgres/pkg/provisioner"
+)
+
+const (
+	passwordEncryptionMD5   = provisioner.PasswordEncryptionMD5
+	passwordEncryptionScram = provisioner.PasswordEncryptionScram
+)
+
+// passwordEncryptionFor returns the method used to store the passwords of the
+// roles of dbResource, its own setting taking precedence over the
+// password-encryption setting. Empty means the server default.
+func passwordEncryptionFor(dbResource *v1.Database) string {
+	if dbResource.Spec.PasswordEncryption != "" {
+		return dbResource.Spec.PasswordEncryption
+	}
+	return getSettings().PasswordEncryption
+}
+
+// checkPasswordEncryption validates method against the dialect and version
+// of inst.
+func checkPasswordEncryption(method string, inst *instance) error {
+	if method != "" && !inst.dialect.passwordVerifiers {
+		return fmt.Errorf("%s does not support hashed passwords", inst.dialect.name)
+	}
+	switch method {
+	case "", passwordEncryptionMD5:
+		return nil
+	case passwordEncryptionScram:
+		if !inst.version.supportsScram() {
+			return fmt.Errorf("scram-sha-256 password encryption requires PostgreSQL 10, server is %s", inst.version)
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid password encryption %q, must be md5 or scram-sha-256", method)
+}
+
+// rolePasswordStatement returns the statement creating, or with verb ALTER
+// altering, username with password stored using method, valid until the
+// expiry of the password policy.
+func rolePasswordStatement(verb, username, password, method string) (string, error) {
+	return provisioner.RolePasswordStatement(verb, username, password, method, currentPasswordPolicy.expiry)
+}
+
+// upsertRoleStatement returns the statement creating username with password,
+// or setting its password when the role already exists so provisioning can
+// be run again.
+func upsertRoleStatement(db *sql.DB, username, password, method string) (string, error) {
+	exists, err := roleExists(db, username)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return rolePasswordStatement("ALTER ROLE", username, password, method)
+	}
+	return rolePasswordStatement("CREATE USER", username, password, method)
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+// pausedAnnotation set to "true" stops the reconciling of a Database: no
+// statement is run on its database and its status is left as is until the
+// annotation is removed. Its finalizers are still kept in sync so a
+// protected Database stays protected.
+const pausedAnnotation = "postgresql.org/paused"
+
+// paused reports whether dbResource carries the paused annotation.
+func paused(dbResource *v1.Database) bool {
+	return dbResource.Annotations[pausedAnnotation] == "true"
+}
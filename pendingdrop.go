@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+const (
+	// pendingDropLabel is set on the ConfigMaps recording the Databases
+	// deleted during their deletion grace period.
+	pendingDropLabel = "postgresql.org/pending-drop"
+	// pendingDropDatabaseKey holds the deleted Database as JSON.
+	pendingDropDatabaseKey = "database"
+	// pendingDropAfterKey holds the RFC 3339 time the database is dropped
+	// after.
+	pendingDropAfterKey = "dropAfter"
+	// pendingDropPhaseKey holds the delete hooks a deletion requeued while
+	// the Job of a hook was running resumes from, preDelete when missing.
+	pendingDropPhaseKey = "phase"
+)
+
+// deletionRequeued is returned by requeueDeletion once the deletion is left
+// for dropExpiredDatabases to resume.
+type deletionRequeued string
+
+func (e deletionRequeued) Error() string {
+	return string(e)
+}
+
+// deletionGracePeriod returns how long the database of dbResource is kept
+// once it is deleted, 0 when it is dropped right away.
+func deletionGracePeriod(dbResource *v1.Database) time.Duration {
+	if dbResource.Spec.DeletionGracePeriod == nil {
+		return 0
+	}
+	return dbResource.Spec.DeletionGracePeriod.Duration
+}
+
+// pendingDropName returns the name of the ConfigMap recording the Database
+// name while its database is pending drop.
+func pendingDropName(name string) string {
+	return name + "-pending-drop"
+}
+
+// connectRoles returns the roles of dbResource granted CONNECT on its
+// database, along with PUBLIC when the database is its own.
+func connectRoles(dbResource *v1.Database) string {
+	roles := ownedRoles(dbResource)
+	if !schemaMode(dbResource) {
+		roles = append([]string{"PUBLIC"}, roles...)
+	}
+	return strings.Join(roles, ", ")
+}
+
+// markPendingDrop revokes CONNECT on the database of the deleted dbResource
+// and records it in a ConfigMap, for dropExpiredDatabases to drop it after
+// dropAfter. Open sessions are left alone.
+func (c *Controller) markPendingDrop(logger zerolog.Logger, dbResource *v1.Database, inst *instance, exec *sqlExecutor, dropAfter time.Time) error {
+	until := dropAfter.UTC().Format(time.RFC3339)
+	database := databaseName(dbResource)
+	logger.Info().Str("database", database).Str("dropAfter", until).Msg("revoking access, database pending drop")
+
+	stmts := []string{fmt.Sprintf("REVOKE CONNECT ON DATABASE %s FROM %s", database, connectRoles(dbResource))}
+	if !schemaMode(dbResource) {
+		comment := newOwnershipComment(dbResource)
+		comment.PendingDropAfter = until
+		stmts = append(stmts, fmt.Sprintf("COMMENT ON DATABASE %s IS %s", database, comment))
+	}
+	if err := exec.ExecDDL(inst, stmts); err != nil {
+		return err
+	}
+	if exec.dryRun {
+		return nil
+	}
+	return c.recordPendingDrop(dbResource, until, "")
+}
+
+// requeueDeletion records the deleted dbResource in its pending drop
+// ConfigMap, for dropExpiredDatabases to resume its deletion from the phase
+// delete hooks on its next run rather than block a worker on the Job of a
+// hook. CONNECT is left alone, the preDelete Jobs may need it.
+func (c *Controller) requeueDeletion(logger zerolog.Logger, dbResource *v1.Database, phase string) error {
+	logger.Info().Str("phase", phase).Msg("hook job running, requeueing deletion")
+	if err := c.recordPendingDrop(dbResource, time.Now().UTC().Format(time.RFC3339), phase); err != nil {
+		logger.Error().Err(err).Msg("error requeueing deletion")
+		c.deletionFailed(logger, dbResource, dropObjectHook, err)
+		return err
+	}
+	return deletionRequeued(fmt.Sprintf("deletion requeued until the %s hooks are done", phase))
+}
+
+// recordPendingDrop creates, or updates, the pending drop ConfigMap of
+// dbResource.
+func (c *Controller) recordPendingDrop(dbResource *v1.Database, until, phase string) error {
+	dbJSON, err := json.Marshal(dbResource)
+	if err != nil {
+		return err
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pendingDropName(dbResource.Name),
+			Namespace: dbResource.Namespace,
+			Labels:    map[string]string{pendingDropLabel: "true"},
+		},
+		Data: map[string]string{
+			pendingDropDatabaseKey: string(dbJSON),
+			pendingDropAfterKey:    until,
+		},
+	}
+	if phase != "" {
+		configMap.Data[pendingDropPhaseKey] = phase
+	}
+	configMaps := c.kubeclientset.CoreV1().ConfigMaps(dbResource.Namespace)
+	_, err = configMaps.Create(configMap)
+	if errors.IsAlreadyExists(err) {
+		_, err = configMaps.Update(configMap)
+	}
+	return err
+}
+
+// pendingDrop returns the deleted Database recorded in configMap.
+func pendingDrop(configMap *corev1.ConfigMap) (*v1.Database, time.Time, error) {
+	dropAfter, err := time.Parse(time.RFC3339, configMap.Data[pendingDropAfterKey])
+	if err != nil {
+		return nil, dropAfter, fmt.Errorf("invalid %s in pending drop %s/%s: %s", pendingDropAfterKey, configMap.Namespace, configMap.Name, err.Error())
+	}
+	dbResource := &v1.Database{}
+	if err := json.Unmarshal([]byte(configMap.Data[pendingDropDatabaseKey]), dbResource); err != nil {
+		return nil, dropAfter, fmt.Errorf("invalid %s in pending drop %s/%s: %s", pendingDropDatabaseKey, configMap.Namespace, configMap.Name, err.Error())
+	}
+	return dbResource, dropAfter, nil
+}
+
+// cancelPendingDrop gives the database pending drop back to dbResource when
+// it is re-created with the same name and database, granting CONNECT again.
+// It returns whether there was such a database.
+func (c *Controller) cancelPendingDrop(logger zerolog.Logger, dbResource *v1.Database, inst *instance, exec *sqlExecutor) (bool, error) {
+	configMap, err := c.ConfigMapsLister.ConfigMaps(dbResource.Namespace).Get(pendingDropName(dbResource.Name))
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if configMap.Labels[pendingDropLabel] != "true" || configMap.Data[pendingDropPhaseKey] == hookPostDelete {
+		// the database of a deletion running its postDelete hooks is gone
+		return false, nil
+	}
+	deleted, _, err := pendingDrop(configMap)
+	if err != nil {
+		return false, err
+	}
+	if databaseName(deleted) != databaseName(dbResource) || instanceName(deleted) != instanceName(dbResource) ||
+		instanceNamespace(deleted) != instanceNamespace(dbResource) || schemaMode(deleted) != schemaMode(dbResource) {
+		return false, nil
+	}
+
+	database := databaseName(deleted)
+	logger.Info().Str("database", database).Msg("Database re-created, cancelling pending drop")
+	stmts := []string{fmt.Sprintf("GRANT CONNECT ON DATABASE %s TO %s", database, connectRoles(deleted))}
+	if !schemaMode(deleted) {
+		stmts = append(stmts, fmt.Sprintf("COMMENT ON DATABASE %s IS %s", database, newOwnershipComment(dbResource)))
+	}
+	if err := exec.ExecDDL(inst, stmts); err != nil {
+		return false, err
+	}
+	if exec.dryRun {
+		return true, nil
+	}
+	err = c.kubeclientset.CoreV1().ConfigMaps(configMap.Namespace).Delete(configMap.Name, &metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return false, err
+	}
+	c.recorder.Event(dbResource, corev1.EventTypeNormal, "PendingDropCancelled", fmt.Sprintf("Database %s was pending drop and is kept", database))
+	return true, nil
+}
+
+// dropExpiredDatabases drops the databases pending drop whose grace period
+// is over, forced drops only in the maintenance window, and resumes the
+// deletions requeued on a hook Job. A database taken over by another
+// Database meanwhile is kept.
+func (c *Controller) dropExpiredDatabases() {
+	configMaps, err := c.ConfigMapsLister.List(labels.SelectorFromSet(labels.Set{pendingDropLabel: "true"}))
+	if err != nil {
+		log.Error().Err(err).Msg("error listing databases pending drop")
+		return
+	}
+	for _, configMap := range configMaps {
+		dbResource, dropAfter, err := pendingDrop(configMap)
+		if err != nil {
+			log.Error().Err(err).Msg("error reading database pending drop")
+			continue
+		}
+		if time.Now().Before(dropAfter) {
+			continue
+		}
+		phase := configMap.Data[pendingDropPhaseKey]
+		if deferred, _ := deferForcedDrop(dbResource, time.Now()); deferred != nil && phase != hookPostDelete {
+			// the maintenance window closed meanwhile, wait for the next
+			continue
+		}
+		ctx, logger := resourceContext(context.Background(), dbResource.Namespace, dbResource.Name)
+		if current, err := c.DatabasesLister.Databases(dbResource.Namespace).Get(dbResource.Name); err == nil && phase != hookPostDelete &&
+			databaseName(current) == databaseName(dbResource) && current.Status.State == "provisioned" {
+			logger.Info().Str("database", databaseName(dbResource)).Msg("database pending drop was taken over, keeping it")
+		} else {
+			inst, err := c.instances.forDatabase(dbResource)
+			if err != nil {
+				logger.Error().Err(err).Msg("error dropping database pending drop")
+				c.deletionFailed(logger, dbResource, dropObjectInstance, err)
+				continue
+			}
+			if inst.unavailable() != nil {
+				continue
+			}
+			unlock, err := lockDatabase(ctx, logger, inst, lockName(dbResource))
+			if err != nil {
+				// retried on the next run
+				logger.Info().Err(err).Msg("could not lock database pending drop")
+				continue
+			}
+			exec := newExecutor(ctx, dbResource, inst, logger)
+			exec.priority = true
+			if phase == "" {
+				logger.Info().Str("database", databaseName(dbResource)).Msg("grace period over, dropping database")
+			} else {
+				logger.Info().Str("phase", phase).Msg("resuming deletion")
+			}
+			err = c.dropDeletedDatabase(logger, dbResource, inst, exec, phase)
+			unlock()
+			if _, failed := err.(deleteHookFailed); err != nil && !failed {
+				// retried, or resumed, on the next run
+				continue
+			}
+			if exec.dryRun {
+				continue
+			}
+		}
+		err = c.kubeclientset.CoreV1().ConfigMaps(configMap.Namespace).Delete(configMap.Name, &metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			logger.Error().Err(err).Msg("error deleting pending drop record")
+		}
+	}
+}
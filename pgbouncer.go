@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+// pgBouncerConfigKey is the key of the --pgbouncer-configmap holding the
+// rendered [databases] section.
+const pgBouncerConfigKey = "databases.ini"
+
+var poolModes = map[string]bool{"session": true, "transaction": true, "statement": true}
+
+// pgBouncerEntry returns the name and connection string of the [databases]
+// entry of dbResource.
+func pgBouncerEntry(dbResource *v1.Database, inst *instance) (string, string, error) {
+	name := databaseName(dbResource)
+	host, port := inst.hostPort()
+	entry := fmt.Sprintf("host=%s port=%s dbname=%s", host, port, name)
+
+	pooling := dbResource.Spec.Pooling
+	if pooling == nil {
+		return name, entry, nil
+	}
+	if pooling.Name != "" {
+		name = pooling.Name
+	}
+	if pooling.Mode != "" {
+		if !poolModes[pooling.Mode] {
+			return "", "", fmt.Errorf("unknown pool mode %q, must be session, transaction or statement", pooling.Mode)
+		}
+		entry += " pool_mode=" + pooling.Mode
+	}
+	if pooling.Size != nil {
+		entry += fmt.Sprintf(" pool_size=%d", *pooling.Size)
+	}
+	if pooling.MaxConnections != nil {
+		entry += fmt.Sprintf(" max_db_connections=%d", *pooling.MaxConnections)
+	}
+	return name, entry, nil
+}
+
+// renderPgBouncerConfig returns the pgBouncer [databases] section listing
+// every provisioned Database.
+func (c *Controller) renderPgBouncerConfig() (string, error) {
+	dbResources, err := c.DatabasesLister.List(labels.Everything())
+	if err != nil {
+		return "", err
+	}
+	sort.Slice(dbResources, func(i, j int) bool {
+		if dbResources[i].Namespace != dbResources[j].Namespace {
+			return dbResources[i].Namespace < dbResources[j].Namespace
+		}
+		return dbResources[i].Name < dbResources[j].Name
+	})
+
+	var buf bytes.Buffer
+	buf.WriteString("; generated by k8s-external-postgres, do not edit\n[databases]\n")
+	seen := map[string]string{}
+	for _, dbResource := range dbResources {
+		if dbResource.Status.State != "provisioned" {
+			continue
+		}
+		logger := resourceLogger(dbResource.Namespace, dbResource.Name)
+		inst, err := c.instances.forDatabase(dbResource)
+		if err != nil {
+			logger.Error().Err(err).Msg("error rendering pgbouncer entry")
+			continue
+		}
+		name, entry, err := pgBouncerEntry(dbResource, inst)
+		if err != nil {
+			c.recorder.Event(dbResource, corev1.EventTypeWarning, "InvalidPooling", err.Error())
+			continue
+		}
+		key := dbResource.Namespace + "/" + dbResource.Name
+		if other, ok := seen[name]; ok {
+			logger.Error().Str("pgbouncerName", name).Str("conflictsWith", other).Msg("pgbouncer database name already used, skipping")
+			continue
+		}
+		seen[name] = key
+		fmt.Fprintf(&buf, "%s = %s\n", name, entry)
+	}
+	return buf.String(), nil
+}
+
+// syncPgBouncerConfig writes the rendered [databases] section to the
+// --pgbouncer-configmap, creating it if missing.
+func (c *Controller) syncPgBouncerConfig() {
+	configMapKey := getSettings().PgBouncerConfigMap
+	if configMapKey == "" {
+		return
+	}
+	namespace, name, err := cache.SplitMetaNamespaceKey(configMapKey)
+	if err != nil {
+		runtime.HandleError(fmt.Errorf("invalid pgbouncer-configmap %q: %s", configMapKey, err.Error()))
+		return
+	}
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+	config, err := c.renderPgBouncerConfig()
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+
+	configMap, err := c.ConfigMapsLister.ConfigMaps(namespace).Get(name)
+	if errors.IsNotFound(err) {
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Data:       map[string]string{pgBouncerConfigKey: config},
+		}
+		_, err = c.kubeclientset.CoreV1().ConfigMaps(namespace).Create(configMap)
+	} else if err == nil && configMap.Data[pgBouncerConfigKey] != config {
+		configMap = configMap.DeepCopy()
+		if configMap.Data == nil {
+			configMap.Data = map[string]string{}
+		}
+		configMap.Data[pgBouncerConfigKey] = config
+		_, err = c.kubeclientset.CoreV1().ConfigMaps(namespace).Update(configMap)
+	}
+	if err != nil {
+		runtime.HandleError(fmt.Errorf("error writing pgbouncer configmap: %s", err.Error()))
+	}
+}
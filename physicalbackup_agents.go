@@ -0,0 +1,348 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+const (
+	physicalBackupPgBackRest = "pgbackrest"
+	physicalBackupWALG       = "walg"
+
+	// physicalBackupAnnotation is the pgBackRest annotation, or WAL-G user
+	// data key, holding the UID of the PhysicalBackup a backup was taken for.
+	physicalBackupAnnotation = "k8s-physical-backup"
+
+	// physicalBackupStartScript runs the backup command, its arguments,
+	// detached from the exec session, recording its output and exit code
+	// under /tmp for physicalBackupStatusScript.
+	physicalBackupStartScript = `id=$1
+shift
+("$@"; echo $? > "/tmp/pgdb-backup-$id.exit") > "/tmp/pgdb-backup-$id.log" 2>&1 < /dev/null &
+`
+	// physicalBackupStatusScript prints running, completed, or failed
+	// followed by the last lines of the output of the backup command.
+	physicalBackupStatusScript = `id=$1
+if [ ! -f "/tmp/pgdb-backup-$id.exit" ]; then echo running; exit 0; fi
+if [ "$(cat "/tmp/pgdb-backup-$id.exit")" = 0 ]; then echo completed; exit 0; fi
+echo failed
+tail -n 5 "/tmp/pgdb-backup-$id.log"
+`
+)
+
+// backupAgent runs the backup tool of a PhysicalBackup next to its server.
+type backupAgent interface {
+	// start starts the backup, without waiting for it to complete.
+	start(backup *v1.PhysicalBackup) error
+	// status returns the state of the backup, running, completed or failed,
+	// along with the reason it failed or the label of the completed backup.
+	status(backup *v1.PhysicalBackup) (state, message, label string, err error)
+}
+
+// backupAgent returns the agent backup is run by.
+func (c *PhysicalBackupController) backupAgent(backup *v1.PhysicalBackup) (backupAgent, error) {
+	if backup.Spec.Agent != nil {
+		return &httpBackupAgent{controller: c, spec: backup.Spec.Agent}, nil
+	}
+	if !allowPodExec {
+		return nil, fmt.Errorf("exec backups require --allow-pod-exec")
+	}
+	return &execBackupAgent{controller: c, spec: backup.Spec.Exec}, nil
+}
+
+// physicalBackupCommand returns the command taking backup, annotated with its
+// UID.
+func physicalBackupCommand(backup *v1.PhysicalBackup) []string {
+	spec := backup.Spec
+	if spec.Tool == physicalBackupWALG {
+		dataDirectory := spec.DataDirectory
+		if dataDirectory == "" {
+			dataDirectory = "/var/lib/postgresql/data"
+		}
+		userData, _ := json.Marshal(map[string]string{physicalBackupAnnotation: string(backup.UID)})
+		command := []string{"wal-g", "backup-push", dataDirectory, "--add-user-data", string(userData)}
+		if spec.Type == "" || spec.Type == "full" {
+			command = append(command, "--full")
+		}
+		return command
+	}
+	backupType := spec.Type
+	if backupType == "" {
+		backupType = "full"
+	}
+	return []string{"pgbackrest", "--stanza=" + spec.Stanza, "--type=" + backupType,
+		"--annotation=" + physicalBackupAnnotation + "=" + string(backup.UID), "backup"}
+}
+
+// physicalBackupListCommand returns the command listing the backups of the
+// repository of backup as JSON.
+func physicalBackupListCommand(backup *v1.PhysicalBackup) []string {
+	if backup.Spec.Tool == physicalBackupWALG {
+		return []string{"wal-g", "backup-list", "--json", "--detail"}
+	}
+	return []string{"pgbackrest", "--stanza=" + backup.Spec.Stanza, "--output=json", "info"}
+}
+
+// physicalBackupLabel finds the label of backup in listing, the output of
+// physicalBackupListCommand: the backup annotated with its UID, or else, for
+// the tool versions without annotations, the first started after it.
+func physicalBackupLabel(backup *v1.PhysicalBackup, listing []byte) (string, error) {
+	type entry struct {
+		label   string
+		started time.Time
+		uid     string
+	}
+	var entries []entry
+	if backup.Spec.Tool == physicalBackupWALG {
+		var backups []struct {
+			Name      string                 `json:"backup_name"`
+			StartTime time.Time              `json:"start_time"`
+			UserData  map[string]interface{} `json:"user_data"`
+		}
+		if err := json.Unmarshal(listing, &backups); err != nil {
+			return "", fmt.Errorf("error parsing wal-g backup-list: %s", err.Error())
+		}
+		for _, b := range backups {
+			uid, _ := b.UserData[physicalBackupAnnotation].(string)
+			entries = append(entries, entry{b.Name, b.StartTime, uid})
+		}
+	} else {
+		var stanzas []struct {
+			Backup []struct {
+				Label      string            `json:"label"`
+				Annotation map[string]string `json:"annotation"`
+				Timestamp  struct {
+					Start int64 `json:"start"`
+				} `json:"timestamp"`
+			} `json:"backup"`
+		}
+		if err := json.Unmarshal(listing, &stanzas); err != nil {
+			return "", fmt.Errorf("error parsing pgbackrest info: %s", err.Error())
+		}
+		for _, stanza := range stanzas {
+			for _, b := range stanza.Backup {
+				entries = append(entries, entry{b.Label, time.Unix(b.Timestamp.Start, 0), b.Annotation[physicalBackupAnnotation]})
+			}
+		}
+	}
+
+	var first *entry
+	for i, e := range entries {
+		if e.uid == string(backup.UID) {
+			return e.label, nil
+		}
+		if backup.Status.StartTime == nil || e.started.Before(backup.Status.StartTime.Time) {
+			continue
+		}
+		if first == nil || e.started.Before(first.started) {
+			first = &entries[i]
+		}
+	}
+	if first == nil {
+		return "", fmt.Errorf("the completed backup is not in the repository")
+	}
+	return first.label, nil
+}
+
+// execBackupAgent runs the backup tool with exec in a container of a pod.
+type execBackupAgent struct {
+	controller *PhysicalBackupController
+	spec       *v1.PhysicalBackupExec
+}
+
+func (a *execBackupAgent) start(backup *v1.PhysicalBackup) error {
+	command := append([]string{"sh", "-c", physicalBackupStartScript, "sh", string(backup.UID)}, physicalBackupCommand(backup)...)
+	_, err := a.exec(backup.Namespace, command)
+	return err
+}
+
+func (a *execBackupAgent) status(backup *v1.PhysicalBackup) (string, string, string, error) {
+	out, err := a.exec(backup.Namespace, []string{"sh", "-c", physicalBackupStatusScript, "sh", string(backup.UID)})
+	if err != nil {
+		return "", "", "", err
+	}
+	lines := strings.SplitN(strings.TrimSpace(out), "\n", 2)
+	switch lines[0] {
+	case backupStateRunning:
+		return backupStateRunning, "", "", nil
+	case backupStateFailed:
+		message := "the backup command failed"
+		if len(lines) > 1 {
+			message = strings.TrimSpace(lines[1])
+		}
+		return backupStateFailed, message, "", nil
+	}
+	listing, err := a.exec(backup.Namespace, physicalBackupListCommand(backup))
+	if err != nil {
+		return "", "", "", err
+	}
+	label, err := physicalBackupLabel(backup, []byte(listing))
+	if err != nil {
+		return backupStateFailed, err.Error(), "", nil
+	}
+	return backupStateCompleted, "", label, nil
+}
+
+// exec runs command in the container of the agent, returning its output.
+func (a *execBackupAgent) exec(namespace string, command []string) (string, error) {
+	pod, err := a.pod(namespace)
+	if err != nil {
+		return "", err
+	}
+	container := a.spec.Container
+	if container == "" {
+		container = pod.Spec.Containers[0].Name
+	}
+	req := a.controller.kubeclientset.CoreV1().RESTClient().Post().
+		Resource("pods").Namespace(namespace).Name(pod.Name).SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+	executor, err := remotecommand.NewSPDYExecutor(a.controller.config, "POST", req.URL())
+	if err != nil {
+		return "", err
+	}
+	var stdout, stderr bytes.Buffer
+	if err := executor.Stream(remotecommand.StreamOptions{Stdout: &stdout, Stderr: &stderr}); err != nil {
+		return "", fmt.Errorf("error running %s in pod %s: %s: %s", command[0], pod.Name, err.Error(), strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// pod returns the pod named by the agent, or the first running one matching
+// its selector by name, so the backup is checked on in the pod it runs in.
+func (a *execBackupAgent) pod(namespace string) (*corev1.Pod, error) {
+	pods := a.controller.kubeclientset.CoreV1().Pods(namespace)
+	if a.spec.Pod != "" {
+		return pods.Get(a.spec.Pod, metav1.GetOptions{})
+	}
+	selector := labels.SelectorFromSet(labels.Set(a.spec.PodSelector))
+	list, err := pods.List(metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	var first *corev1.Pod
+	for i, pod := range list.Items {
+		if pod.Status.Phase == corev1.PodRunning && (first == nil || pod.Name < first.Name) {
+			first = &list.Items[i]
+		}
+	}
+	if first == nil {
+		return nil, fmt.Errorf("no running pod matches %s", selector)
+	}
+	return first, nil
+}
+
+// physicalBackupClient is the client of the HTTP backup agents.
+var physicalBackupClient = &http.Client{Timeout: 30 * time.Second}
+
+// httpBackupAgent asks an agent running next to the server to run the
+// backup tool.
+type httpBackupAgent struct {
+	controller *PhysicalBackupController
+	spec       *v1.PhysicalBackupAgent
+}
+
+// physicalBackupRequest is the body POSTed to an agent to start a backup.
+// Command is the one the exec agent would run, for agents that don't build
+// their own.
+type physicalBackupRequest struct {
+	ID            string   `json:"id"`
+	Namespace     string   `json:"namespace"`
+	Name          string   `json:"name"`
+	Tool          string   `json:"tool"`
+	Type          string   `json:"type,omitempty"`
+	Stanza        string   `json:"stanza,omitempty"`
+	DataDirectory string   `json:"dataDirectory,omitempty"`
+	Command       []string `json:"command"`
+}
+
+// physicalBackupResponse is the state of a backup an agent returns.
+type physicalBackupResponse struct {
+	State   string `json:"state"`
+	Message string `json:"message,omitempty"`
+	Label   string `json:"label,omitempty"`
+}
+
+func (a *httpBackupAgent) start(backup *v1.PhysicalBackup) error {
+	body, err := json.Marshal(physicalBackupRequest{
+		ID:            string(backup.UID),
+		Namespace:     backup.Namespace,
+		Name:          backup.Name,
+		Tool:          backup.Spec.Tool,
+		Type:          backup.Spec.Type,
+		Stanza:        backup.Spec.Stanza,
+		DataDirectory: backup.Spec.DataDirectory,
+		Command:       physicalBackupCommand(backup),
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := a.do(backup, "POST", "/backups", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (a *httpBackupAgent) status(backup *v1.PhysicalBackup) (string, string, string, error) {
+	resp, err := a.do(backup, "GET", "/backups/"+string(backup.UID), nil)
+	if err != nil {
+		return "", "", "", err
+	}
+	defer resp.Body.Close()
+	var state physicalBackupResponse
+	if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+		return "", "", "", fmt.Errorf("error decoding the response of the backup agent: %s", err.Error())
+	}
+	switch state.State {
+	case backupStateRunning, backupStateCompleted, backupStateFailed:
+		return state.State, state.Message, state.Label, nil
+	}
+	return "", "", "", fmt.Errorf("unknown backup state %q returned by the backup agent", state.State)
+}
+
+// do sends a request to the agent with its bearer token, failing on error
+// statuses.
+func (a *httpBackupAgent) do(backup *v1.PhysicalBackup, method, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, strings.TrimSuffix(a.spec.URL, "/")+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if ref := a.spec.TokenSecret; ref != nil {
+		secret, err := a.controller.kubeclientset.CoreV1().Secrets(backup.Namespace).Get(ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("error reading token secret %q: %s", ref.Name, err.Error())
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(secret.Data[ref.Key])))
+	}
+	resp, err := physicalBackupClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("backup agent returned %s", resp.Status)
+	}
+	return resp, nil
+}
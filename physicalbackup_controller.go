@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+	clientset "github.com/joshrendek/k8s-external-postgres/pkg/client/clientset/versioned"
+	informers "github.com/joshrendek/k8s-external-postgres/pkg/client/informers/externalversions"
+	listers "github.com/joshrendek/k8s-external-postgres/pkg/client/listers/postgresql/v1"
+)
+
+const (
+	// physicalBackupPollInterval is how often a running physical backup is
+	// checked on.
+	physicalBackupPollInterval = 30 * time.Second
+	// physicalBackupTimeout is how long a physical backup may run without a
+	// spec.timeout.
+	physicalBackupTimeout = 24 * time.Hour
+)
+
+// PhysicalBackupController starts the pgBackRest and WAL-G backups of
+// PhysicalBackup resources and tracks them until they complete.
+type PhysicalBackupController struct {
+	kubeclientset     kubernetes.Interface
+	databaseClientset clientset.Interface
+	// config is the client configuration the exec requests are sent with.
+	config *rest.Config
+
+	DatabasesLister       listers.DatabaseLister
+	DatabasesSynced       cache.InformerSynced
+	PhysicalBackupsLister listers.PhysicalBackupLister
+	PhysicalBackupsSynced cache.InformerSynced
+
+	queue     workqueue.RateLimitingInterface
+	recorder  record.EventRecorder
+	instances *instanceRegistry
+}
+
+// NewPhysicalBackupController returns a new physical backup controller
+func NewPhysicalBackupController(
+	kubeclientset kubernetes.Interface,
+	databaseClientset clientset.Interface,
+	config *rest.Config,
+	databaseInformerFactory informers.SharedInformerFactory,
+	instances *instanceRegistry) *PhysicalBackupController {
+
+	databaseInformer := databaseInformerFactory.Databases().V1().Databases()
+	backupInformer := databaseInformerFactory.Databases().V1().PhysicalBackups()
+
+	controller := &PhysicalBackupController{
+		kubeclientset:         kubeclientset,
+		databaseClientset:     databaseClientset,
+		config:                config,
+		DatabasesLister:       databaseInformer.Lister(),
+		DatabasesSynced:       databaseInformer.Informer().HasSynced,
+		PhysicalBackupsLister: backupInformer.Lister(),
+		PhysicalBackupsSynced: backupInformer.Informer().HasSynced,
+		queue:                 workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "PhysicalBackups"),
+		recorder:              newEventRecorder(kubeclientset),
+		instances:             instances,
+	}
+
+	log.Info().Msg("Setting up physical backup event handlers")
+	backupInformer.Informer().AddEventHandler(instrumentHandler("physicalbackups", "physicalbackup", cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			enqueue(controller.queue, obj)
+		},
+		UpdateFunc: func(old, new interface{}) {
+			if old.(*v1.PhysicalBackup).ResourceVersion == new.(*v1.PhysicalBackup).ResourceVersion {
+				return
+			}
+			enqueue(controller.queue, new)
+		},
+	}))
+	return controller
+}
+
+// Run waits for the informer caches to sync and starts the workers. It blocks
+// until stopCh is closed.
+func (c *PhysicalBackupController) Run(threadiness int, stopCh <-chan struct{}) error {
+	defer runtime.HandleCrash()
+
+	log.Info().Msg("Starting physical backup controller")
+	if ok := cache.WaitForCacheSync(stopCh, c.DatabasesSynced, c.PhysicalBackupsSynced, c.instances.HasSynced); !ok {
+		return fmt.Errorf("failed to wait for caches to sync")
+	}
+
+	runQueueWorkers(c.queue, threadiness, c.syncPhysicalBackup, stopCh)
+	log.Info().Msg("Shutting down physical backup workers")
+	return nil
+}
+
+// forPhysicalBackup returns the instance backup is taken of.
+func (r *instanceRegistry) forPhysicalBackup(backup *v1.PhysicalBackup) (*instance, error) {
+	if backup.Spec.Instance == "" {
+		return r.forNamespace(backup.Namespace)
+	}
+	return r.get(backup.Namespace, backup.Spec.Instance)
+}
+
+// syncPhysicalBackup starts the backup of a new PhysicalBackup, recording the
+// Databases on its server, then checks on it until it completes, recording
+// its label.
+func (c *PhysicalBackupController) syncPhysicalBackup(ctx context.Context, logger zerolog.Logger, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		runtime.HandleError(fmt.Errorf("invalid resource key: %s", key))
+		return nil
+	}
+
+	backup, err := c.PhysicalBackupsLister.PhysicalBackups(namespace).Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			runtime.HandleError(fmt.Errorf("physical backup '%s' in work queue no longer exists", key))
+			return nil
+		}
+		return err
+	}
+
+	switch backup.Status.State {
+	case backupStateCompleted, backupStateFailed:
+		return nil
+	}
+	if err := validatePhysicalBackup(backup); err != nil {
+		return c.failPhysicalBackup(backup, err.Error())
+	}
+	agent, err := c.backupAgent(backup)
+	if err != nil {
+		return c.failPhysicalBackup(backup, err.Error())
+	}
+
+	if backup.Status.State == "" {
+		inst, err := c.instances.forPhysicalBackup(backup)
+		if err != nil {
+			return err
+		}
+		if !inst.dialect.physicalBackups {
+			return c.failPhysicalBackup(backup, fmt.Sprintf("physical backups are not supported by %s", inst.dialect.name))
+		}
+		databases, err := c.backedUpDatabases(inst)
+		if err != nil {
+			return err
+		}
+		// recorded first, so a backup is never started twice
+		backupCopy := backup.DeepCopy()
+		now := metav1.Now()
+		backupCopy.Status.State = backupStateRunning
+		backupCopy.Status.Message = "backup in progress"
+		backupCopy.Status.Databases = databases
+		backupCopy.Status.StartTime = &now
+		backup, err = c.databaseClientset.DatabasesV1().PhysicalBackups(namespace).Update(backupCopy)
+		if err != nil {
+			return err
+		}
+		logger.Info().Str("tool", backup.Spec.Tool).Int("databases", len(databases)).Msg("starting physical backup")
+		if err := agent.start(backup); err != nil {
+			return c.failPhysicalBackup(backup, fmt.Sprintf("error starting backup: %s", err.Error()))
+		}
+		c.queue.AddAfter(key, physicalBackupPollInterval)
+		return nil
+	}
+
+	state, message, label, err := agent.status(backup)
+	if err != nil {
+		return err
+	}
+	switch state {
+	case backupStateCompleted:
+		c.recorder.Event(backup, corev1.EventTypeNormal, "BackupCompleted", fmt.Sprintf("Backup %s completed", label))
+		backupCopy := backup.DeepCopy()
+		now := metav1.Now()
+		backupCopy.Status.State = backupStateCompleted
+		backupCopy.Status.Message = "successful"
+		backupCopy.Status.Label = label
+		backupCopy.Status.CompletionTime = &now
+		_, err := c.databaseClientset.DatabasesV1().PhysicalBackups(namespace).Update(backupCopy)
+		return err
+	case backupStateFailed:
+		return c.failPhysicalBackup(backup, fmt.Sprintf("backup failed: %s", message))
+	}
+	if started := backup.Status.StartTime; started != nil && time.Since(started.Time) > physicalBackupTimeoutFor(backup) {
+		return c.failPhysicalBackup(backup, fmt.Sprintf("backup still running after %s", physicalBackupTimeoutFor(backup)))
+	}
+	c.queue.AddAfter(key, physicalBackupPollInterval)
+	return nil
+}
+
+// validatePhysicalBackup checks the spec of backup before it is started.
+func validatePhysicalBackup(backup *v1.PhysicalBackup) error {
+	spec := backup.Spec
+	switch spec.Tool {
+	case physicalBackupPgBackRest:
+		if spec.Stanza == "" {
+			return fmt.Errorf("pgbackrest backups require a stanza")
+		}
+	case physicalBackupWALG:
+	default:
+		return fmt.Errorf("unknown tool %q, expected pgbackrest or walg", spec.Tool)
+	}
+	switch spec.Type {
+	case "", "full", "diff", "incr":
+	default:
+		return fmt.Errorf("unknown backup type %q, expected full, diff or incr", spec.Type)
+	}
+	if (spec.Exec == nil) == (spec.Agent == nil) {
+		return fmt.Errorf("exactly one of exec and agent must be set")
+	}
+	if spec.Exec != nil && (spec.Exec.Pod == "") == (len(spec.Exec.PodSelector) == 0) {
+		return fmt.Errorf("exactly one of exec.pod and exec.podSelector must be set")
+	}
+	return nil
+}
+
+// physicalBackupTimeoutFor returns how long backup may run.
+func physicalBackupTimeoutFor(backup *v1.PhysicalBackup) time.Duration {
+	if backup.Spec.Timeout != nil && backup.Spec.Timeout.Duration > 0 {
+		return backup.Spec.Timeout.Duration
+	}
+	return physicalBackupTimeout
+}
+
+// backedUpDatabases returns the provisioned Databases of inst whose database
+// is on the server, sorted by namespace and name.
+func (c *PhysicalBackupController) backedUpDatabases(inst *instance) ([]v1.PhysicalBackupDatabase, error) {
+	rows, err := inst.DB.Query("SELECT datname FROM pg_database WHERE NOT datistemplate")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	present := map[string]bool{}
+	for rows.Next() {
+		var datname string
+		if err := rows.Scan(&datname); err != nil {
+			return nil, err
+		}
+		present[datname] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	dbResources, err := c.DatabasesLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	var databases []v1.PhysicalBackupDatabase
+	for _, dbResource := range dbResources {
+		if dbResource.Status.State != "provisioned" || !present[databaseName(dbResource)] {
+			continue
+		}
+		if dbInst, err := c.instances.forDatabase(dbResource); err != nil || dbInst != inst {
+			continue
+		}
+		databases = append(databases, v1.PhysicalBackupDatabase{
+			Namespace: dbResource.Namespace,
+			Name:      dbResource.Name,
+			Database:  databaseName(dbResource),
+		})
+	}
+	sort.Slice(databases, func(i, j int) bool {
+		if databases[i].Namespace != databases[j].Namespace {
+			return databases[i].Namespace < databases[j].Namespace
+		}
+		return databases[i].Name < databases[j].Name
+	})
+	return databases, nil
+}
+
+// failPhysicalBackup marks backup failed with message, raising a warning
+// event.
+func (c *PhysicalBackupController) failPhysicalBackup(backup *v1.PhysicalBackup, message string) error {
+	c.recorder.Event(backup, corev1.EventTypeWarning, "BackupFailed", message)
+	backupCopy := backup.DeepCopy()
+	backupCopy.Status.State = backupStateFailed
+	backupCopy.Status.Message = message
+	_, err := c.databaseClientset.DatabasesV1().PhysicalBackups(backup.Namespace).Update(backupCopy)
+	return err
+}
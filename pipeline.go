@@ -0,0 +1,631 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+// provisioningStep is a step of the provisioning of a new Database. The
+// steps run in the order of provisioningSteps, each one picking up the
+// state the previous ones left in the provisioning.
+type provisioningStep interface {
+	// name identifies the step in the logs.
+	name() string
+	// run applies the step to p. It returns done to end the reconcile with
+	// err, e.g. once a failure is reported in the status or while a Job
+	// runs, an error ending it too.
+	run(c *Controller, p *provisioning) (done bool, err error)
+}
+
+// provisioningSteps are the steps provisioning a Database, in order. The
+// steps creating objects on the server record their progress in the status,
+// an interrupted provisioning skipping them when it resumes.
+var provisioningSteps = []provisioningStep{
+	checkSpec{},
+	checkQuota{},
+	checkExisting{},
+	checkPassword{},
+	createHooks{on: hookPreCreate},
+	ensureRole{},
+	ensureDatabase{},
+	ensureLimits{},
+	ensureGrants{},
+	ensureExtensions{},
+	ensureSecret{},
+	createHooks{on: hookPostCreate},
+	checkCanary{},
+	finishProvisioning{},
+}
+
+// provisioning is the state of the provisioning of a Database, passed from
+// step to step.
+type provisioning struct {
+	logger zerolog.Logger
+	// dbResource is the Database as last written by the steps.
+	dbResource *v1.Database
+	inst       *instance
+	exec       *sqlExecutor
+	username   string
+	database   string
+
+	// source is the Database cloned, nil unless spec.cloneFrom is set.
+	source      *v1.Database
+	exists      bool
+	roleExisted bool
+	adoption    *v1.DatabaseAdoption
+	password    string
+	// cloned is set once the database was created as a copy of the clone
+	// source, otherwise the copy is restored by a Job once provisioned.
+	cloned bool
+
+	memberOf          []string
+	roleSettings      []string
+	defaultPrivileges []v1.DefaultPrivilege
+	extensions        []v1.ExtensionStatus
+}
+
+// provision runs the provisioning steps on dbResource, new or in error and
+// retried, on inst.
+func (c *Controller) provision(ctx context.Context, logger zerolog.Logger, dbResource *v1.Database, inst *instance) error {
+	p := &provisioning{
+		logger:     logger,
+		dbResource: dbResource,
+		inst:       inst,
+		exec:       newExecutor(ctx, dbResource, inst, logger),
+		username:   roleName(dbResource),
+		database:   databaseName(dbResource),
+	}
+	logger.Info().Str("username", p.username).Str("database", p.database).Msg("provisioning")
+	key := dbResource.Namespace + "/" + dbResource.Name
+	for _, step := range provisioningSteps {
+		logger.Debug().Str("step", step.name()).Msg("running provisioning step")
+		// the statements of the step are traced under its span
+		stepCtx, end := startStepSpan(ctx, key, step.name())
+		p.exec.ctx = stepCtx
+		done, err := step.run(c, p)
+		p.exec.ctx = ctx
+		end(err)
+		if done || err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fail records err in the status of the Database, returning err so the
+// provisioning is retried.
+func (p *provisioning) fail(c *Controller, err error) (bool, error) {
+	if err := c.updateFooStatus(p.dbResource, err.Error(), "error"); err != nil {
+		return true, err
+	}
+	return true, err
+}
+
+// dropCreatedRole drops the role created by the failed provisioning, unless
+// it existed before.
+func (p *provisioning) dropCreatedRole() {
+	if !p.roleExisted {
+		p.dbResource = dropCreatedRole(p.logger, p.dbResource, p.inst, p.exec)
+	}
+}
+
+// checkSpec checks the mode, role layout, hooks and clone source of the
+// spec.
+type checkSpec struct{}
+
+func (checkSpec) name() string { return "CheckSpec" }
+
+func (checkSpec) run(c *Controller, p *provisioning) (bool, error) {
+	dbResource := p.dbResource
+	switch dbResource.Spec.Mode {
+	case "", modeDatabase, modeSchema:
+	default:
+		return true, c.updateFooStatus(dbResource, fmt.Sprintf("Unknown mode %q, must be database or schema", dbResource.Spec.Mode), "error")
+	}
+	switch dbResource.Spec.RoleLayout {
+	case "", roleLayoutOwner, roleLayoutOwnerApp:
+	default:
+		return true, c.updateFooStatus(dbResource, fmt.Sprintf("Unknown role layout %q, must be owner or owner-app", dbResource.Spec.RoleLayout), "error")
+	}
+	if err := validateHooks(dbResource); err != nil {
+		return true, c.updateFooStatus(dbResource, err.Error(), "error")
+	}
+
+	if dbResource.Spec.CloneFrom == "" {
+		return false, nil
+	}
+	if schemaMode(dbResource) {
+		return true, c.updateFooStatus(dbResource, "spec.cloneFrom is not supported in schema mode", "error")
+	}
+	source, err := c.cloneSource(dbResource)
+	if err != nil {
+		c.recorder.Event(dbResource, corev1.EventTypeWarning, "CloneSourceNotReady", err.Error())
+		return true, err
+	}
+	if schemaMode(source) {
+		return true, c.updateFooStatus(dbResource, fmt.Sprintf("Clone source %q is in schema mode, only databases can be cloned", source.Name), "error")
+	}
+	p.source = source
+	return false, nil
+}
+
+// checkQuota holds the provisioning back while the DatabaseQuota of the
+// namespace is exhausted.
+type checkQuota struct{}
+
+func (checkQuota) name() string { return "CheckQuota" }
+
+func (checkQuota) run(c *Controller, p *provisioning) (bool, error) {
+	reason, err := c.checkQuota(p.dbResource)
+	if err != nil || reason == "" {
+		return err != nil, err
+	}
+	c.recorder.Event(p.dbResource, corev1.EventTypeWarning, "QuotaExceeded", reason)
+	dbCopy := p.dbResource.DeepCopy()
+	changed := setCondition(&dbCopy.Status, quotaExceededCondition, conditionTrue, "QuotaExceeded", reason)
+	if dbCopy.Status.Reason != reasonQuotaExceeded || dbCopy.Status.Message != reason {
+		dbCopy.Status.Reason = reasonQuotaExceeded
+		dbCopy.Status.Message = reason
+		changed = true
+	}
+	if !changed {
+		return true, nil
+	}
+	return true, c.updateStatus(dbCopy)
+}
+
+// checkExisting finds the database and role already on the server, which
+// are only taken over when adopting or resuming.
+type checkExisting struct{}
+
+func (checkExisting) name() string { return "CheckExisting" }
+
+func (checkExisting) run(c *Controller, p *provisioning) (bool, error) {
+	dbResource := p.dbResource
+	// A Database re-created during the deletion grace period of the
+	// previous one gets its database back.
+	restored, err := c.cancelPendingDrop(p.logger, dbResource, p.inst, p.exec)
+	if err != nil {
+		return true, err
+	}
+
+	// A database or role that already exists before provisioning was not
+	// created for this resource, don't silently take it over. When
+	// resuming they were created by the earlier attempt.
+	exists, err := databaseExists(p.inst.DB, p.database)
+	if err != nil {
+		return true, err
+	}
+	if schemaMode(dbResource) {
+		// the shared database must exist, the schema is what is owned
+		if !exists {
+			return true, c.updateFooStatus(dbResource, fmt.Sprintf("Shared database %q does not exist", p.database), "error")
+		}
+		if exists, err = sharedSchemaExists(dbResource, p.inst); err != nil {
+			return true, err
+		}
+	}
+	roleExisted, err := roleExists(p.inst.DB, p.username)
+	if err != nil {
+		return true, err
+	}
+	p.exists, p.roleExisted = exists, roleExisted
+	if !restored {
+		// objects of another Database are never taken over, adoption or
+		// not: the owner role would get its password reset
+		var owner string
+		if exists && !schemaMode(dbResource) {
+			if owner, err = ownedElsewhere(p.inst, orphanKindDatabase, p.database, dbResource); err != nil {
+				return true, err
+			}
+		}
+		if owner == "" && roleExisted {
+			if owner, err = ownedElsewhere(p.inst, orphanKindRole, p.username, dbResource); err != nil {
+				return true, err
+			}
+		}
+		if owner != "" {
+			msg := fmt.Sprintf("%s, refusing to take it over", owner)
+			c.recorder.Event(dbResource, corev1.EventTypeWarning, ErrResourceExists, msg)
+			return true, c.updateFooStatus(dbResource, msg, "conflict")
+		}
+	}
+	takenOver := restored || resuming(dbResource)
+	if (exists || roleExisted) && !adoptExisting(dbResource) && !takenOver {
+		name := p.database
+		if schemaMode(dbResource) {
+			name = schemaName(dbResource)
+		}
+		if !exists {
+			name = p.username
+		}
+		msg := fmt.Sprintf(MessageResourceExists, name)
+		c.recorder.Event(dbResource, corev1.EventTypeWarning, ErrResourceExists, msg)
+		return true, c.updateFooStatus(dbResource, msg, "conflict")
+	}
+	if !exists && !takenOver && adoptExisting(dbResource) && dbResource.Spec.AdoptExisting {
+		// an import finding nothing names the wrong database, unlike
+		// allowAdoption it doesn't fall back to creating an empty one
+		name := p.database
+		if schemaMode(dbResource) {
+			name = schemaName(dbResource)
+		}
+		return true, c.updateFooStatus(dbResource, fmt.Sprintf("%q does not exist, there is nothing to adopt", name), "error")
+	}
+	if p.source != nil && exists {
+		return true, c.updateFooStatus(dbResource, fmt.Sprintf("Database %q already exists, it can't be cloned into", p.database), "error")
+	}
+	p.adoption = dbResource.Status.Adoption
+	if (exists || roleExisted) && !takenOver {
+		if p.adoption, err = fingerprintAdoption(dbResource, p.inst, exists, roleExisted); err != nil {
+			return true, err
+		}
+	}
+
+	if _, err := c.databaseTablespace(dbResource); err != nil {
+		// the Tablespace may not be provisioned yet, retry
+		c.recorder.Event(dbResource, corev1.EventTypeWarning, "TablespaceUnavailable", err.Error())
+		return true, err
+	}
+	return false, nil
+}
+
+// checkPassword picks the password of the owner role and checks it against
+// the authentication, password policy and encryption, then records the
+// start of the provisioning.
+type checkPassword struct{}
+
+func (checkPassword) name() string { return "CheckPassword" }
+
+func (checkPassword) run(c *Controller, p *provisioning) (bool, error) {
+	dbResource := p.dbResource
+	if ref := dbResource.Spec.PasswordSecretRef; ref != nil {
+		if _, err := c.SecretsLister.Secrets(dbResource.Namespace).Get(ref.Name); errors.IsNotFound(err) {
+			// the Secret may not be created yet, retry
+			c.recorder.Event(dbResource, corev1.EventTypeWarning, "PasswordSecretUnavailable", err.Error())
+			return true, err
+		}
+	}
+	password, err := c.ownerPassword(dbResource)
+	if err != nil {
+		return true, c.updateFooStatus(dbResource, err.Error(), "error")
+	}
+	if err := checkAuthentication(dbResource); err != nil {
+		return true, c.updateFooStatus(dbResource, err.Error(), "error")
+	}
+	if err := checkPasswordPolicy(dbResource, password); err != nil {
+		return true, c.updateFooStatus(dbResource, err.Error(), "error")
+	}
+	if err := checkPasswordEncryption(passwordEncryptionFor(dbResource), p.inst); err != nil {
+		return true, c.updateFooStatus(dbResource, err.Error(), "error")
+	}
+	p.password = password
+
+	// The steps completed are recorded in the status as they are, a
+	// provisioning interrupted by a crash or failure resumes after them.
+	if resuming(dbResource) {
+		p.logger.Info().Interface("progress", dbResource.Status.Progress).Msg("resuming provisioning")
+		return false, nil
+	}
+	dbCopy := dbResource.DeepCopy()
+	dbCopy.Status.Adoption = p.adoption
+	if p.dbResource, err = c.recordProgress(dbCopy, p.exec, func(*v1.ProvisioningProgress) {}); err != nil {
+		return true, err
+	}
+	return false, nil
+}
+
+// createHooks runs the hooks of spec.hooks set to run on.
+type createHooks struct {
+	on string
+}
+
+func (h createHooks) name() string { return "Hooks/" + h.on }
+
+func (h createHooks) run(c *Controller, p *provisioning) (bool, error) {
+	dbResource, running, err := c.runCreateHooks(p.logger, p.dbResource, p.inst, p.exec, h.on)
+	p.dbResource = dbResource
+	if err != nil {
+		c.recorder.Event(dbResource, corev1.EventTypeWarning, "HookFailed", err.Error())
+		return true, c.updateFooStatus(dbResource, err.Error(), "error")
+	}
+	return running, nil
+}
+
+// ensureRole creates the owner role, or sets the password of the one
+// adopted.
+type ensureRole struct{}
+
+func (ensureRole) name() string { return "EnsureRole" }
+
+func (ensureRole) run(c *Controller, p *provisioning) (bool, error) {
+	if progress(p.dbResource).RoleCreated {
+		return false, nil
+	}
+	stmt, err := upsertRoleStatement(p.inst.DB, p.username, p.password, passwordEncryptionFor(p.dbResource))
+	if err != nil {
+		return true, c.updateFooStatus(p.dbResource, err.Error(), "error")
+	}
+	roleStmts := append([]string{stmt}, p.inst.dialect.createRoleStatements(p.username)...)
+	if schemaMode(p.dbResource) {
+		roleStmts = append(roleStmts, fmt.Sprintf("GRANT CONNECT ON DATABASE %s TO %s", p.database, p.username))
+	}
+	if err := p.exec.ExecDDL(p.inst, roleStmts); err != nil {
+		p.logger.Error().Err(err).Msg("error creating user")
+		return true, c.updateFooStatus(p.dbResource, fmt.Sprintf("Error creating user: %s", err.Error()), "error")
+	}
+	if p.dbResource, err = c.recordProgress(p.dbResource, p.exec, func(progress *v1.ProvisioningProgress) { progress.RoleCreated = true }); err != nil {
+		return true, err
+	}
+	return false, nil
+}
+
+// ensureDatabase creates the database owned by the role, or the schema in
+// schema mode, hands the adopted one over or clones it from the source.
+type ensureDatabase struct{}
+
+func (ensureDatabase) name() string { return "EnsureDatabase" }
+
+func (ensureDatabase) run(c *Controller, p *provisioning) (bool, error) {
+	p.cloned = progress(p.dbResource).Cloned
+	if progress(p.dbResource).DatabaseCreated {
+		p.logger.Debug().Str("database", p.database).Msg("database created by an earlier attempt")
+		return false, nil
+	}
+	if schemaMode(p.dbResource) {
+		if err := provisionSchema(p.dbResource, p.inst, p.exec); err != nil {
+			if !p.exists {
+				p.dropCreatedRole()
+			}
+			return true, c.updateFooStatus(p.dbResource, err.Error(), "error")
+		}
+	} else if done, err := createDatabase(c, p); done || err != nil {
+		return done, err
+	}
+
+	var err error
+	p.dbResource, err = c.recordProgress(p.dbResource, p.exec, func(progress *v1.ProvisioningProgress) {
+		progress.DatabaseCreated = true
+		progress.Cloned = p.cloned
+	})
+	if err != nil {
+		return true, err
+	}
+	return false, nil
+}
+
+// createDatabase runs the statements creating, adopting or cloning the
+// database of p.
+func createDatabase(c *Controller, p *provisioning) (bool, error) {
+	dbStmts := p.inst.dialect.createDatabaseStatements(p.database, p.username)
+	if p.exists {
+		p.logger.Info().Str("database", p.database).Msg("adopting existing database")
+		dbStmts = p.inst.dialect.changeOwnerStatements(p.database, p.username)
+	}
+	if source := p.source; source != nil {
+		sourceInst, err := c.instances.forDatabase(source)
+		if err != nil {
+			return true, err
+		}
+		if sourceInst == p.inst && p.inst.dialect.templateClone {
+			if p.cloned, err = cloneWithTemplate(p.dbResource, source, p.inst, p.exec); err != nil {
+				p.dropCreatedRole()
+				return true, c.updateFooStatus(p.dbResource, fmt.Sprintf("Error cloning database: %s", err.Error()), "error")
+			}
+			if !p.cloned {
+				p.logger.Info().Str("source", databaseName(source)).Msg("clone source in use, dumping and restoring it instead")
+			}
+		}
+		if !p.cloned && (!hasCredentialsSecret(p.dbResource) || !hasCredentialsSecret(source)) {
+			p.dropCreatedRole()
+			return true, c.updateFooStatus(p.dbResource, "Cloning with a Job requires the credentials of both Databases in Secrets", "error")
+		}
+		if !p.cloned && backupImage == "" {
+			p.dropCreatedRole()
+			return true, c.updateFooStatus(p.dbResource, fmt.Sprintf("Cloning %q requires --backup-image to dump and restore it", source.Name), "error")
+		}
+	}
+	if p.cloned {
+		dbStmts = nil
+	}
+	for _, dbStmt := range dbStmts {
+		if err := p.exec.Exec(p.inst.DB, dbStmt); err != nil {
+			// CREATE DATABASE can't run in a transaction with the role,
+			// drop the role created above rather than leaving it behind.
+			if !p.exists {
+				p.dropCreatedRole()
+			}
+			return true, c.updateFooStatus(p.dbResource, fmt.Sprintf("Error creating database: %s", err.Error()), "error")
+		}
+	}
+	return false, nil
+}
+
+// ensureLimits applies the connection limits, role attributes and
+// tablespace of the spec.
+type ensureLimits struct{}
+
+func (ensureLimits) name() string { return "EnsureLimits" }
+
+func (ensureLimits) run(c *Controller, p *provisioning) (bool, error) {
+	for _, apply := range []func(*v1.Database, *instance, *sqlExecutor) error{
+		c.syncConnectionLimits,
+		c.syncRoleAttributes,
+		c.syncDatabaseTablespace,
+	} {
+		if err := apply(p.dbResource, p.inst, p.exec); err != nil {
+			return p.fail(c, err)
+		}
+	}
+	return false, nil
+}
+
+// ensureGrants revokes the privileges of PUBLIC, creates the read-only and
+// application roles, comments the objects as managed and grants the group
+// roles, role settings and default privileges of the spec. It isn't recorded
+// in the progress: every statement can run again when resuming, the roles
+// being upserted with a new password stored in their Secret.
+type ensureGrants struct{}
+
+func (ensureGrants) name() string { return "EnsureGrants" }
+
+func (ensureGrants) run(c *Controller, p *provisioning) (bool, error) {
+	dbResource := p.dbResource
+	if err := c.syncPublicPrivileges(dbResource, p.inst, p.exec); err != nil {
+		return p.fail(c, err)
+	}
+	if dbResource.Spec.ReadOnlyUser {
+		if err := c.provisionReadOnlyUser(dbResource, p.inst, p.exec); err != nil {
+			return p.fail(c, err)
+		}
+	}
+	if appRole(dbResource) {
+		if err := c.provisionAppUser(dbResource, p.inst, p.exec); err != nil {
+			return p.fail(c, err)
+		}
+	}
+	if err := c.syncOwnershipComments(dbResource, p.inst, p.exec); err != nil {
+		return p.fail(c, err)
+	}
+
+	var err error
+	if p.memberOf, err = applyMemberships(dbResource, p.inst, p.exec); err != nil {
+		return p.fail(c, err)
+	}
+	if p.roleSettings, err = applyRoleSettings(dbResource, p.inst, p.exec); err != nil {
+		return p.fail(c, err)
+	}
+	// default privileges go first so they cover the tables created by
+	// initSQL
+	if p.defaultPrivileges, err = applyDefaultPrivileges(dbResource, p.inst, p.exec); err != nil {
+		return p.fail(c, err)
+	}
+	return false, nil
+}
+
+// ensureExtensions creates the extensions of the spec, records what the
+// previous steps applied in the status and runs initSQL.
+type ensureExtensions struct{}
+
+func (ensureExtensions) name() string { return "EnsureExtensions" }
+
+func (ensureExtensions) run(c *Controller, p *provisioning) (bool, error) {
+	// extensions go before initSQL too, which may use them
+	extensions, err := applyExtensions(p.dbResource, p.inst, p.exec)
+	if err != nil {
+		return p.fail(c, err)
+	}
+	p.extensions = extensions
+	if !p.exec.dryRun {
+		p.dbResource = p.dbResource.DeepCopy()
+		p.dbResource.Status.MemberOf = p.memberOf
+		p.dbResource.Status.RoleSettings = p.roleSettings
+		p.dbResource.Status.DefaultPrivileges = p.defaultPrivileges
+		setExtensionStatus(&p.dbResource.Status, p.extensions)
+		if p.adoption != nil {
+			p.dbResource.Status.Adoption = p.adoption
+		}
+	}
+
+	switch {
+	case progress(p.dbResource).InitSQLApplied:
+		p.logger.Debug().Msg("initSQL applied by an earlier attempt")
+	case hasInitSQL(p.dbResource) && p.source != nil:
+		// clones hold the objects initSQL created in their source, record
+		// it as applied
+		script, err := c.initSQLScript(p.dbResource)
+		if err != nil {
+			return true, c.updateFooStatus(p.dbResource, err.Error(), "error")
+		}
+		if !p.exec.dryRun {
+			p.dbResource.Status.InitSQLChecksum = initSQLChecksum(script)
+		}
+	case hasInitSQL(p.dbResource):
+		// the credentials are not stored yet
+		db, err := openAsOwner(p.dbResource, p.inst, p.username, p.password)
+		if err != nil {
+			return p.fail(c, err)
+		}
+		checksum, err := c.applyInitSQL(p.dbResource, db, p.exec)
+		db.Close()
+		if err != nil {
+			return p.fail(c, err)
+		}
+		if !p.exec.dryRun {
+			p.dbResource.Status.InitSQLChecksum = checksum
+		}
+		if p.dbResource, err = c.recordProgress(p.dbResource, p.exec, func(progress *v1.ProvisioningProgress) { progress.InitSQLApplied = true }); err != nil {
+			return true, err
+		}
+	}
+	return false, nil
+}
+
+// ensureSecret stores the credentials of the Database in its Secret. In dry
+// run, the provisioning ends here with the statements planned.
+type ensureSecret struct{}
+
+func (ensureSecret) name() string { return "EnsureSecret" }
+
+func (ensureSecret) run(c *Controller, p *provisioning) (bool, error) {
+	if p.exec.dryRun {
+		return true, c.updatePlannedStatements(p.dbResource, p.exec.planned)
+	}
+	if progress(p.dbResource).SecretWritten {
+		return false, nil
+	}
+	if err := c.storeCredentials(p.dbResource, p.inst, p.dbResource.Name, p.username, p.password); err != nil {
+		return true, err
+	}
+	var err error
+	if p.dbResource, err = c.recordProgress(p.dbResource, p.exec, func(progress *v1.ProvisioningProgress) { progress.SecretWritten = true }); err != nil {
+		return true, err
+	}
+	return false, nil
+}
+
+// checkCanary logs in with the credentials stored, with --canary-connect.
+type checkCanary struct{}
+
+func (checkCanary) name() string { return "CheckCanary" }
+
+func (checkCanary) run(c *Controller, p *provisioning) (bool, error) {
+	if !canaryConnect {
+		return false, nil
+	}
+	if err := c.checkCredentials(p.dbResource, p.inst); err != nil {
+		return true, c.syncFailed(p.dbResource, "CanaryFailed", err)
+	}
+	return false, nil
+}
+
+// finishProvisioning starts the clone Job of a Database cloned by dump and
+// restore, or marks the Database provisioned.
+type finishProvisioning struct{}
+
+func (finishProvisioning) name() string { return "Finish" }
+
+func (finishProvisioning) run(c *Controller, p *provisioning) (bool, error) {
+	if p.source != nil && !p.cloned {
+		if err := c.startCloneJob(p.logger, p.dbResource, p.source); err != nil {
+			return true, err
+		}
+		return true, c.updateFooStatus(p.dbResource, fmt.Sprintf("cloning from %s", p.source.Name), "cloning")
+	}
+
+	if err := c.updateFooStatus(p.dbResource, "successful", "provisioned"); err != nil {
+		return true, err
+	}
+	if p.adoption != nil {
+		c.recorder.Event(p.dbResource, corev1.EventTypeNormal, "Adopted", adoptionMessage(p.dbResource, p.adoption))
+	}
+	notify(notifyDatabaseCreated, p.dbResource, "")
+	c.recorder.Event(p.dbResource, corev1.EventTypeNormal, SuccessSynced, MessageResourceSynced)
+	return true, nil
+}
@@ -0,0 +1,48 @@
+package v1
+
+import (
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	BackupCRDPlural   string = "databasebackups"
+	FullBackupCRDName string = BackupCRDPlural + "." + CRDGroup
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DatabaseBackup is a one-off pg_dump of a Database uploaded to object storage
+type DatabaseBackup struct {
+	meta_v1.TypeMeta   `json:",inline"`
+	meta_v1.ObjectMeta `json:"metadata"`
+	Spec               DatabaseBackupSpec   `json:"spec"`
+	Status             DatabaseBackupStatus `json:"status,omitempty"`
+}
+
+type DatabaseBackupSpec struct {
+	// Database is the name of the Database resource, in the same namespace, to dump.
+	Database string `json:"database"`
+	// StorageSecret names a Secret whose keys are exposed to the backup Job as
+	// environment variables, typically the rclone remote configuration
+	// (RCLONE_CONFIG_<REMOTE>_TYPE, credentials, region, ...) for S3, GCS or Azure.
+	StorageSecret string `json:"storageSecret"`
+	// Destination is the rclone path the dump is uploaded under, e.g. "s3:bucket/backups".
+	Destination string `json:"destination"`
+}
+
+type DatabaseBackupStatus struct {
+	State          string        `json:"state,omitempty"`
+	Message        string        `json:"message,omitempty"`
+	Job            string        `json:"job,omitempty"`
+	Location       string        `json:"location,omitempty"`
+	Checksum       string        `json:"checksum,omitempty"`
+	CompletionTime *meta_v1.Time `json:"completionTime,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type DatabaseBackupList struct {
+	meta_v1.TypeMeta `json:",inline"`
+	meta_v1.ListMeta `json:"metadata"`
+	Items            []DatabaseBackup `json:"items"`
+}
@@ -0,0 +1,46 @@
+package v1
+
+import (
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	DatabaseClassCRDPlural   string = "databaseclasses"
+	FullDatabaseClassCRDName string = DatabaseClassCRDPlural + "." + CRDGroup
+)
+
+// +genclient
+// +genclient:nonNamespaced
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DatabaseClass is a cluster wide set of defaults Databases name with
+// spec.className, like StorageClasses for volumes
+type DatabaseClass struct {
+	meta_v1.TypeMeta   `json:",inline"`
+	meta_v1.ObjectMeta `json:"metadata"`
+	Spec               DatabaseClassSpec `json:"spec"`
+}
+
+// DatabaseClassSpec are the defaults of the fields of the same name of the
+// Databases of the class, for those they leave empty. They are copied to the
+// Database when it is first reconciled, changing the class doesn't change
+// the existing Databases.
+type DatabaseClassSpec struct {
+	// Instance is the name of the PostgresInstance, in the namespace of the
+	// Database.
+	Instance  string     `json:"instance,omitempty"`
+	Placement *Placement `json:"placement,omitempty"`
+	// Extensions are only copied to Databases listing none.
+	Extensions     []Extension     `json:"extensions,omitempty"`
+	RoleLayout     string          `json:"roleLayout,omitempty"`
+	DeletionPolicy *DeletionPolicy `json:"deletionPolicy,omitempty"`
+	Backup         *BackupSchedule `json:"backup,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type DatabaseClassList struct {
+	meta_v1.TypeMeta `json:",inline"`
+	meta_v1.ListMeta `json:"metadata"`
+	Items            []DatabaseClass `json:"items"`
+}
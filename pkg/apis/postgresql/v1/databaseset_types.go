@@ -0,0 +1,70 @@
+package v1
+
+import (
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	DatabaseSetCRDPlural   string = "databasesets"
+	FullDatabaseSetCRDName string = DatabaseSetCRDPlural + "." + CRDGroup
+)
+
+// databaseSetColumns are the columns kubectl get databasesets shows.
+var databaseSetColumns = []printerColumn{
+	{Name: "State", Type: "string", JSONPath: ".status.state"},
+	{Name: "Tenants", Type: "integer", JSONPath: ".status.tenants"},
+	{Name: "Provisioned", Type: "integer", JSONPath: ".status.provisioned"},
+	{Name: "Age", Type: "date", JSONPath: ".metadata.creationTimestamp"},
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DatabaseSet creates a Database from a template for each of its tenants
+type DatabaseSet struct {
+	meta_v1.TypeMeta   `json:",inline"`
+	meta_v1.ObjectMeta `json:"metadata"`
+	Spec               DatabaseSetSpec   `json:"spec"`
+	Status             DatabaseSetStatus `json:"status,omitempty"`
+}
+
+type DatabaseSetSpec struct {
+	// Template is the Database created for every tenant, named
+	// <set>-<tenant>.
+	Template DatabaseTemplate `json:"template"`
+	// Tenants are the names of the tenants, lowercase letters, digits and
+	// dashes.
+	Tenants []string `json:"tenants,omitempty"`
+	// Count creates the tenants 0 to count-1 when tenants is empty.
+	Count int `json:"count,omitempty"`
+}
+
+// DatabaseTemplate is the Database created for each tenant of a DatabaseSet.
+// The username and database of its spec are templates executed with .Set,
+// .Tenant and .Index, {{ .Set }}_{{ .Tenant }} when empty. A password is
+// generated for every tenant when empty.
+type DatabaseTemplate struct {
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Spec        DatabaseConfig    `json:"spec"`
+}
+
+type DatabaseSetStatus struct {
+	// State is provisioned once every Database is, error when one of them
+	// failed, provisioning otherwise.
+	State   string `json:"state,omitempty"`
+	Message string `json:"message,omitempty"`
+	// Tenants is the number of tenants of the set.
+	Tenants int `json:"tenants,omitempty"`
+	// Provisioned is the number of Databases provisioned.
+	Provisioned int `json:"provisioned,omitempty"`
+	// Failed are the tenants whose Database failed.
+	Failed []string `json:"failed,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type DatabaseSetList struct {
+	meta_v1.TypeMeta `json:",inline"`
+	meta_v1.ListMeta `json:"metadata"`
+	Items            []DatabaseSet `json:"items"`
+}
@@ -0,0 +1,103 @@
+package v1
+
+import (
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	ForeignServerCRDPlural   string = "foreignservers"
+	FullForeignServerCRDName string = ForeignServerCRDPlural + "." + CRDGroup
+
+	UserMappingCRDPlural   string = "usermappings"
+	FullUserMappingCRDName string = UserMappingCRDPlural + "." + CRDGroup
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ForeignServer creates a postgres_fdw foreign server in a managed Database,
+// linking it to another managed Database
+type ForeignServer struct {
+	meta_v1.TypeMeta   `json:",inline"`
+	meta_v1.ObjectMeta `json:"metadata"`
+	Spec               ForeignServerSpec   `json:"spec"`
+	Status             ForeignServerStatus `json:"status,omitempty"`
+}
+
+type ForeignServerSpec struct {
+	// Database is the name of the Database resource, in the same namespace,
+	// the foreign server is created in.
+	Database string `json:"database"`
+	// Target is the name of the Database resource, in the same namespace,
+	// the foreign server connects to. Its host, port and dbname options are
+	// those of the server and database of Target.
+	Target string `json:"target,omitempty"`
+	// Name is the name of the foreign server, the name of the resource with
+	// dashes replaced by underscores when empty.
+	Name string `json:"name,omitempty"`
+	// Options are the options of the foreign server, e.g. fetch_size. The
+	// host, port and dbname options override the ones of Target, and are
+	// required without it. Options set on the server otherwise are dropped.
+	Options map[string]string `json:"options,omitempty"`
+}
+
+type ForeignServerStatus struct {
+	State   string `json:"state,omitempty"`
+	Message string `json:"message,omitempty"`
+	// ServerName is the name of the foreign server created.
+	ServerName string `json:"serverName,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type ForeignServerList struct {
+	meta_v1.TypeMeta `json:",inline"`
+	meta_v1.ListMeta `json:"metadata"`
+	Items            []ForeignServer `json:"items"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// UserMapping maps a role of the Database of a ForeignServer to the
+// credentials of a Secret on the remote server
+type UserMapping struct {
+	meta_v1.TypeMeta   `json:",inline"`
+	meta_v1.ObjectMeta `json:"metadata"`
+	Spec               UserMappingSpec   `json:"spec"`
+	Status             UserMappingStatus `json:"status,omitempty"`
+}
+
+type UserMappingSpec struct {
+	// Server is the name of the ForeignServer resource, in the same
+	// namespace, the user mapping is created for.
+	Server string `json:"server"`
+	// User is the local role mapped, PUBLIC for every role. The owner role
+	// of the Database of Server when empty.
+	User string `json:"user,omitempty"`
+	// CredentialsSecret names a Secret, in the same namespace, holding the
+	// username and password of the remote role, such as the credentials
+	// Secret of the target Database.
+	CredentialsSecret string `json:"credentialsSecret"`
+	// UsernameKey is the key of the username in CredentialsSecret, USERNAME
+	// when empty.
+	UsernameKey string `json:"usernameKey,omitempty"`
+	// PasswordKey is the key of the password in CredentialsSecret, PASSWORD
+	// when empty.
+	PasswordKey string `json:"passwordKey,omitempty"`
+}
+
+type UserMappingStatus struct {
+	State   string `json:"state,omitempty"`
+	Message string `json:"message,omitempty"`
+	// ServerName is the name of the foreign server of the user mapping.
+	ServerName string `json:"serverName,omitempty"`
+	// User is the local role of the user mapping.
+	User string `json:"user,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type UserMappingList struct {
+	meta_v1.TypeMeta `json:",inline"`
+	meta_v1.ListMeta `json:"metadata"`
+	Items            []UserMapping `json:"items"`
+}
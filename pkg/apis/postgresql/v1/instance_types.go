@@ -0,0 +1,94 @@
+package v1
+
+import (
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	InstanceCRDPlural   string = "postgresinstances"
+	FullInstanceCRDName string = InstanceCRDPlural + "." + CRDGroup
+)
+
+// +genclient
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PostgresInstance is a PostgreSQL compatible server Databases can be
+// provisioned on instead of the one the controller is started with
+type PostgresInstance struct {
+	meta_v1.TypeMeta   `json:",inline"`
+	meta_v1.ObjectMeta `json:"metadata"`
+	Spec               PostgresInstanceSpec `json:"spec"`
+}
+
+type PostgresInstanceSpec struct {
+	// AdminSecret names a Secret, in the same namespace, whose DATABASE_URL
+	// key is the URI the controller connects to the server with.
+	AdminSecret string `json:"adminSecret"`
+	// Dialect is the flavour of the server: postgres, the default,
+	// cockroachdb, alloydb or yugabyte.
+	Dialect string `json:"dialect,omitempty"`
+	// Timeouts override the --*-timeout flags for the sessions the controller
+	// opens on the server.
+	Timeouts *SessionTimeouts `json:"timeouts,omitempty"`
+	// ReadReplicas are the host:port endpoints of the read replicas of the
+	// server, published in the credentials of its Databases.
+	ReadReplicas []string `json:"readReplicas,omitempty"`
+	// SSHTunnel reaches the server through an SSH bastion host, for the
+	// admin connection and the connections to the databases alike.
+	SSHTunnel *SSHTunnel `json:"sshTunnel,omitempty"`
+	// MaxDatabases is the number of Databases placed on the instance past
+	// which no more are, unlimited when unset. Databases naming the
+	// instance in their spec are not limited.
+	MaxDatabases *int32 `json:"maxDatabases,omitempty"`
+	// AllowedNamespaces are the namespaces, besides its own, whose
+	// Databases may be provisioned on the instance with
+	// spec.instanceNamespace. NamespaceSelector allows those of the
+	// namespaces matching it. No other namespace may when both are unset.
+	AllowedNamespaces []string               `json:"allowedNamespaces,omitempty"`
+	NamespaceSelector *meta_v1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// AzureAD logs the admin connection in with Azure AD access tokens,
+	// obtained through workload identity, instead of the password of the
+	// admin URI, for Azure Database for PostgreSQL flexible servers.
+	AzureAD *AzureADAuthentication `json:"azureAD,omitempty"`
+}
+
+// AzureADAuthentication is the managed identity the controller logs in to
+// a server as.
+type AzureADAuthentication struct {
+	// TenantID is the Azure AD tenant of the identity, AZURE_TENANT_ID when
+	// omitted.
+	TenantID string `json:"tenantID,omitempty"`
+	// ClientID is the client ID of the identity, AZURE_CLIENT_ID, the one
+	// of the service account of the controller, when omitted.
+	ClientID string `json:"clientID,omitempty"`
+}
+
+// SSHTunnel is the bastion host the connections to a server are forwarded
+// through.
+type SSHTunnel struct {
+	// Host is the host:port of the bastion, port 22 when omitted.
+	Host string `json:"host"`
+	// User is the user logged in to the bastion as.
+	User string `json:"user"`
+	// Secret names a Secret, in the same namespace, holding the private key
+	// under ssh-privatekey and the host keys of the bastion, in known_hosts
+	// format, under known_hosts.
+	Secret string `json:"secret"`
+}
+
+// SessionTimeouts set statement_timeout, lock_timeout and
+// idle_in_transaction_session_timeout on the sessions of the controller.
+// Zero durations keep the server setting.
+type SessionTimeouts struct {
+	Statement         meta_v1.Duration `json:"statement,omitempty"`
+	Lock              meta_v1.Duration `json:"lock,omitempty"`
+	IdleInTransaction meta_v1.Duration `json:"idleInTransaction,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type PostgresInstanceList struct {
+	meta_v1.TypeMeta `json:",inline"`
+	meta_v1.ListMeta `json:"metadata"`
+	Items            []PostgresInstance `json:"items"`
+}
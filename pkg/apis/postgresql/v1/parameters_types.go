@@ -0,0 +1,73 @@
+package v1
+
+import (
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	ParametersCRDPlural   string = "postgresparameters"
+	FullParametersCRDName string = ParametersCRDPlural + "." + CRDGroup
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PostgresParameters sets configuration parameters of a server, or of the
+// databases and owner roles of Databases, and keeps them from drifting
+type PostgresParameters struct {
+	meta_v1.TypeMeta   `json:",inline"`
+	meta_v1.ObjectMeta `json:"metadata"`
+	Spec               PostgresParametersSpec   `json:"spec"`
+	Status             PostgresParametersStatus `json:"status,omitempty"`
+}
+
+type PostgresParametersSpec struct {
+	// Instance is the PostgresInstance, in the same namespace, System is set
+	// on. The server the controller is started with when empty.
+	Instance string `json:"instance,omitempty"`
+	// System parameters are set server wide with ALTER SYSTEM, which requires
+	// --allow-alter-system, and applied with pg_reload_conf().
+	System map[string]string `json:"system,omitempty"`
+	// Databases set parameters of the databases of Databases with ALTER
+	// DATABASE ... SET.
+	Databases []DatabaseParameters `json:"databases,omitempty"`
+	// Roles set parameters of the owner roles of Databases with ALTER ROLE
+	// ... SET.
+	Roles []RoleParameters `json:"roles,omitempty"`
+}
+
+type DatabaseParameters struct {
+	// Database is the name of the Database resource, in the same namespace,
+	// whose database is altered.
+	Database   string            `json:"database"`
+	Parameters map[string]string `json:"parameters"`
+}
+
+type RoleParameters struct {
+	// Database is the name of the Database resource, in the same namespace,
+	// whose owner role is altered.
+	Database string `json:"database"`
+	// InDatabase only sets the parameters for the sessions of the role in the
+	// database of the Database, with ALTER ROLE ... IN DATABASE.
+	InDatabase bool              `json:"inDatabase,omitempty"`
+	Parameters map[string]string `json:"parameters"`
+}
+
+type PostgresParametersStatus struct {
+	State   string `json:"state,omitempty"`
+	Message string `json:"message,omitempty"`
+	// Applied lists the parameters set by the controller as
+	// target/name=value, so the ones removed from the spec are reset and the
+	// ones changed behind its back are reported as drifted.
+	Applied []string `json:"applied,omitempty"`
+	// PendingRestart lists the system parameters only applied once the
+	// server restarts.
+	PendingRestart []string `json:"pendingRestart,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type PostgresParametersList struct {
+	meta_v1.TypeMeta `json:",inline"`
+	meta_v1.ListMeta `json:"metadata"`
+	Items            []PostgresParameters `json:"items"`
+}
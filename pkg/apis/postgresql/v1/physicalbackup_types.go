@@ -0,0 +1,102 @@
+package v1
+
+import (
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	PhysicalBackupCRDPlural   string = "physicalbackups"
+	FullPhysicalBackupCRDName string = PhysicalBackupCRDPlural + "." + CRDGroup
+)
+
+// physicalBackupColumns are the columns kubectl get physicalbackups shows.
+var physicalBackupColumns = []printerColumn{
+	{Name: "Tool", Type: "string", JSONPath: ".spec.tool"},
+	{Name: "State", Type: "string", JSONPath: ".status.state"},
+	{Name: "Label", Type: "string", JSONPath: ".status.label"},
+	{Name: "Age", Type: "date", JSONPath: ".metadata.creationTimestamp"},
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PhysicalBackup is a pgBackRest or WAL-G backup of a whole self-hosted
+// server, recording the Databases it holds
+type PhysicalBackup struct {
+	meta_v1.TypeMeta   `json:",inline"`
+	meta_v1.ObjectMeta `json:"metadata"`
+	Spec               PhysicalBackupSpec   `json:"spec"`
+	Status             PhysicalBackupStatus `json:"status,omitempty"`
+}
+
+type PhysicalBackupSpec struct {
+	// Instance is the PostgresInstance, in the same namespace, backed up.
+	// The server the controller is started with when empty.
+	Instance string `json:"instance,omitempty"`
+	// Tool is the backup tool installed next to the server: pgbackrest or
+	// walg.
+	Tool string `json:"tool"`
+	// Type is the pgBackRest backup type: full, the default, diff or incr.
+	// WAL-G takes a full backup for full and a delta backup otherwise.
+	Type string `json:"type,omitempty"`
+	// Stanza is the pgBackRest stanza of the server.
+	Stanza string `json:"stanza,omitempty"`
+	// DataDirectory is the data directory WAL-G backs up,
+	// /var/lib/postgresql/data when empty.
+	DataDirectory string `json:"dataDirectory,omitempty"`
+	// Exec runs the tool in a container of a pod of the cluster, Agent asks
+	// an agent running next to the server to.
+	Exec  *PhysicalBackupExec  `json:"exec,omitempty"`
+	Agent *PhysicalBackupAgent `json:"agent,omitempty"`
+	// Timeout is how long the backup may run before it is marked failed,
+	// 24 hours when unset.
+	Timeout *meta_v1.Duration `json:"timeout,omitempty"`
+}
+
+// PhysicalBackupExec is the container the backup tool is run in, found by
+// name or by the labels of its pod, in the namespace of the PhysicalBackup.
+type PhysicalBackupExec struct {
+	Pod         string            `json:"pod,omitempty"`
+	PodSelector map[string]string `json:"podSelector,omitempty"`
+	// Container is the container of the pod running the tool, its first
+	// when empty.
+	Container string `json:"container,omitempty"`
+}
+
+// PhysicalBackupAgent is the HTTP API of an agent running the backup tool.
+type PhysicalBackupAgent struct {
+	// URL is the base URL of the agent, the backups are POSTed to
+	// <url>/backups and their state read from <url>/backups/<id>.
+	URL string `json:"url"`
+	// TokenSecret selects the bearer token sent to the agent.
+	TokenSecret *SecretKeyRef `json:"tokenSecret,omitempty"`
+}
+
+type PhysicalBackupStatus struct {
+	State   string `json:"state,omitempty"`
+	Message string `json:"message,omitempty"`
+	// Label is the label of the backup in the repository of the tool, e.g.
+	// 20240501-100000F for pgBackRest or base_000000010000000000000005 for
+	// WAL-G, as passed to restore it.
+	Label string `json:"label,omitempty"`
+	// Databases are the Databases whose database was on the server when
+	// the backup started.
+	Databases      []PhysicalBackupDatabase `json:"databases,omitempty"`
+	StartTime      *meta_v1.Time            `json:"startTime,omitempty"`
+	CompletionTime *meta_v1.Time            `json:"completionTime,omitempty"`
+}
+
+// PhysicalBackupDatabase is a Database held by a physical backup.
+type PhysicalBackupDatabase struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// Database is the name of its database on the server.
+	Database string `json:"database"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type PhysicalBackupList struct {
+	meta_v1.TypeMeta `json:",inline"`
+	meta_v1.ListMeta `json:"metadata"`
+	Items            []PhysicalBackup `json:"items"`
+}
@@ -0,0 +1,43 @@
+package v1
+
+import (
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	QuotaCRDPlural   string = "databasequotas"
+	FullQuotaCRDName string = QuotaCRDPlural + "." + CRDGroup
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DatabaseQuota caps the number of Databases provisioned in its namespace, or
+// on one of its PostgresInstances
+type DatabaseQuota struct {
+	meta_v1.TypeMeta   `json:",inline"`
+	meta_v1.ObjectMeta `json:"metadata"`
+	Spec               DatabaseQuotaSpec   `json:"spec"`
+	Status             DatabaseQuotaStatus `json:"status,omitempty"`
+}
+
+type DatabaseQuotaSpec struct {
+	// MaxDatabases is the number of Databases that may be provisioned.
+	MaxDatabases int32 `json:"maxDatabases"`
+	// Instance restricts the quota to the Databases provisioned on the
+	// PostgresInstance of that name, the quota covers the whole namespace
+	// when empty.
+	Instance string `json:"instance,omitempty"`
+}
+
+type DatabaseQuotaStatus struct {
+	// Used is the number of provisioned Databases the quota covers.
+	Used int32 `json:"used"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type DatabaseQuotaList struct {
+	meta_v1.TypeMeta `json:",inline"`
+	meta_v1.ListMeta `json:"metadata"`
+	Items            []DatabaseQuota `json:"items"`
+}
@@ -29,6 +29,32 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&Database{},
 		&DatabaseList{},
+		&DatabaseBackup{},
+		&DatabaseBackupList{},
+		&DatabaseRestore{},
+		&DatabaseRestoreList{},
+		&Publication{},
+		&PublicationList{},
+		&Subscription{},
+		&SubscriptionList{},
+		&PostgresInstance{},
+		&PostgresInstanceList{},
+		&DatabaseQuota{},
+		&DatabaseQuotaList{},
+		&PostgresParameters{},
+		&PostgresParametersList{},
+		&Tablespace{},
+		&TablespaceList{},
+		&DatabaseSet{},
+		&DatabaseSetList{},
+		&ForeignServer{},
+		&ForeignServerList{},
+		&UserMapping{},
+		&UserMappingList{},
+		&DatabaseClass{},
+		&DatabaseClassList{},
+		&PhysicalBackup{},
+		&PhysicalBackupList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
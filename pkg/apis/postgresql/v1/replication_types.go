@@ -0,0 +1,73 @@
+package v1
+
+import (
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	PublicationCRDPlural   string = "publications"
+	FullPublicationCRDName string = PublicationCRDPlural + "." + CRDGroup
+
+	SubscriptionCRDPlural   string = "subscriptions"
+	FullSubscriptionCRDName string = SubscriptionCRDPlural + "." + CRDGroup
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// Publication manages a logical replication publication on a managed Database
+type Publication struct {
+	meta_v1.TypeMeta   `json:",inline"`
+	meta_v1.ObjectMeta `json:"metadata"`
+	Spec               PublicationSpec   `json:"spec"`
+	Status             ReplicationStatus `json:"status,omitempty"`
+}
+
+type PublicationSpec struct {
+	// Database is the name of the Database resource, in the same namespace,
+	// the publication is created in.
+	Database string `json:"database"`
+	// AllTables publishes every table of the database, Tables is ignored.
+	AllTables bool `json:"allTables,omitempty"`
+	// Tables lists the published tables, optionally schema qualified.
+	Tables []string `json:"tables,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type PublicationList struct {
+	meta_v1.TypeMeta `json:",inline"`
+	meta_v1.ListMeta `json:"metadata"`
+	Items            []Publication `json:"items"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// Subscription subscribes a database on a target instance to a Publication
+type Subscription struct {
+	meta_v1.TypeMeta   `json:",inline"`
+	meta_v1.ObjectMeta `json:"metadata"`
+	Spec               SubscriptionSpec  `json:"spec"`
+	Status             ReplicationStatus `json:"status,omitempty"`
+}
+
+type SubscriptionSpec struct {
+	// Publication is the name of the Publication resource, in the same
+	// namespace, to subscribe to.
+	Publication string `json:"publication"`
+	// TargetSecret names a Secret whose DATABASE_URL key points at the
+	// subscriber database, as a role allowed to CREATE SUBSCRIPTION.
+	TargetSecret string `json:"targetSecret"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type SubscriptionList struct {
+	meta_v1.TypeMeta `json:",inline"`
+	meta_v1.ListMeta `json:"metadata"`
+	Items            []Subscription `json:"items"`
+}
+
+type ReplicationStatus struct {
+	State   string `json:"state,omitempty"`
+	Message string `json:"message,omitempty"`
+}
@@ -0,0 +1,64 @@
+package v1
+
+import (
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	RestoreCRDPlural   string = "databaserestores"
+	FullRestoreCRDName string = RestoreCRDPlural + "." + CRDGroup
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DatabaseRestore loads a dump from object storage into a freshly provisioned Database,
+// created by the restore unless it exists
+type DatabaseRestore struct {
+	meta_v1.TypeMeta   `json:",inline"`
+	meta_v1.ObjectMeta `json:"metadata"`
+	Spec               DatabaseRestoreSpec   `json:"spec"`
+	Status             DatabaseRestoreStatus `json:"status,omitempty"`
+}
+
+type DatabaseRestoreSpec struct {
+	// Database is the name of the Database resource, in the same namespace, the
+	// dump is restored into. It is created from DatabaseSpec when it doesn't
+	// exist. The restore starts once it has been provisioned, and fails when
+	// its database already holds tables.
+	Database string `json:"database"`
+	// DatabaseSpec is the spec of the Database created when Database doesn't
+	// exist. The role and database default to its name and a password is
+	// generated. InitSQL and Migrations can't be set.
+	DatabaseSpec *DatabaseConfig `json:"databaseSpec,omitempty"`
+	// Backup names a completed DatabaseBackup to restore. Its location, storage
+	// Secret and checksum are used, Location and StorageSecret are ignored.
+	Backup string `json:"backup,omitempty"`
+	// Location is the rclone path of a pg_dump custom format archive.
+	Location string `json:"location,omitempty"`
+	// StorageSecret names a Secret exposed to the restore Job as environment
+	// variables, see DatabaseBackupSpec.StorageSecret.
+	StorageSecret string `json:"storageSecret,omitempty"`
+}
+
+type DatabaseRestoreStatus struct {
+	State   string `json:"state,omitempty"`
+	Message string `json:"message,omitempty"`
+	Job     string `json:"job,omitempty"`
+	// BytesDownloaded is the size of the downloaded dump.
+	BytesDownloaded int64 `json:"bytesDownloaded,omitempty"`
+	// BytesRestored is the size of the target database.
+	BytesRestored int64 `json:"bytesRestored,omitempty"`
+	// ItemsRestored and ItemsTotal are the archive items pg_restore has
+	// restored so far, out of those listed in the dump.
+	ItemsRestored  int64         `json:"itemsRestored,omitempty"`
+	ItemsTotal     int64         `json:"itemsTotal,omitempty"`
+	CompletionTime *meta_v1.Time `json:"completionTime,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type DatabaseRestoreList struct {
+	meta_v1.TypeMeta `json:",inline"`
+	meta_v1.ListMeta `json:"metadata"`
+	Items            []DatabaseRestore `json:"items"`
+}
@@ -0,0 +1,52 @@
+package v1
+
+import (
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	TablespaceCRDPlural   string = "tablespaces"
+	FullTablespaceCRDName string = TablespaceCRDPlural + "." + CRDGroup
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// Tablespace creates a tablespace on a self-hosted server, which Databases
+// are placed in with spec.tablespace
+type Tablespace struct {
+	meta_v1.TypeMeta   `json:",inline"`
+	meta_v1.ObjectMeta `json:"metadata"`
+	Spec               TablespaceSpec   `json:"spec"`
+	Status             TablespaceStatus `json:"status,omitempty"`
+}
+
+type TablespaceSpec struct {
+	// Instance is the PostgresInstance, in the same namespace, the
+	// tablespace is created on. The server the controller is started with
+	// when empty.
+	Instance string `json:"instance,omitempty"`
+	// Name is the name of the tablespace on the server, the name of the
+	// resource with dashes replaced by underscores when empty.
+	Name string `json:"name,omitempty"`
+	// Location is the absolute path of the directory of the tablespace on
+	// the server. It must exist, be empty and belong to the system user
+	// running the server.
+	Location string `json:"location"`
+	// Owner is the role owning the tablespace, the admin role when empty.
+	Owner string `json:"owner,omitempty"`
+}
+
+type TablespaceStatus struct {
+	State   string `json:"state,omitempty"`
+	Message string `json:"message,omitempty"`
+	// TablespaceName is the name of the tablespace created on the server.
+	TablespaceName string `json:"tablespaceName,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type TablespaceList struct {
+	meta_v1.TypeMeta `json:",inline"`
+	meta_v1.ListMeta `json:"metadata"`
+	Items            []Tablespace `json:"items"`
+}
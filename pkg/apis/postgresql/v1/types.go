@@ -1,6 +1,8 @@
 package v1
 
 import (
+	"encoding/json"
+	"fmt"
 	"reflect"
 
 	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
@@ -19,26 +21,166 @@ const (
 	FullCRDName string = CRDPlural + "." + CRDGroup
 )
 
-//Create the CRD resource, ignore error if it already exists
+// databaseColumns are the columns kubectl get databases shows, the priority 1
+// ones only with -o wide.
+var databaseColumns = []printerColumn{
+	{Name: "State", Type: "string", JSONPath: ".status.state"},
+	{Name: "Owner", Type: "string", JSONPath: ".status.roleName"},
+	{Name: "Instance", Type: "string", JSONPath: ".spec.instance"},
+	{Name: "Size", Type: "integer", Description: "Size of the database in bytes", JSONPath: ".status.usage.sizeBytes"},
+	{Name: "Age", Type: "date", JSONPath: ".metadata.creationTimestamp"},
+	{Name: "Ready", Type: "string", Priority: 1, JSONPath: `.status.conditions[?(@.type=="Ready")].status`},
+	{Name: "Reason", Type: "string", Priority: 1, JSONPath: ".status.reason"},
+	{Name: "Database", Type: "string", Priority: 1, JSONPath: ".status.databaseName"},
+	{Name: "Connections", Type: "integer", Priority: 1, JSONPath: ".status.usage.connections"},
+	{Name: "Last-Activity", Type: "date", Priority: 1, JSONPath: ".status.usage.lastActivityTime"},
+}
+
+//Create the CRD resources, ignore errors if they already exist
 func CreateCRD(clientset apiextcs.Interface) error {
-	crd := &apiextv1beta1.CustomResourceDefinition{
-		Spec: apiextv1beta1.CustomResourceDefinitionSpec{
-			Group:   CRDGroup,
-			Version: CRDVersion,
-			Scope:   apiextv1beta1.NamespaceScoped,
-			Names: apiextv1beta1.CustomResourceDefinitionNames{
-				Plural: CRDPlural,
-				Kind:   reflect.TypeOf(Database{}).Name(),
-			},
+	return installCRDs(clientset, false)
+}
+
+// InstallCRDs creates the CRD resources, or updates them to the definitions
+// of this version of the controller when they already exist.
+func InstallCRDs(clientset apiextcs.Interface) error {
+	return installCRDs(clientset, true)
+}
+
+// crds are the CRDs of the controller.
+var crds = []struct {
+	plural     string
+	kind       interface{}
+	shortNames []string
+	columns    []printerColumn
+	// status serves the status through its own subresource, so the
+	// generation only moves with the spec.
+	status bool
+	// validation is the schema the API server validates the resources
+	// against.
+	validation *apiextv1beta1.CustomResourceValidation
+	// clusterScoped resources have no namespace.
+	clusterScoped bool
+}{
+	{CRDPlural, Database{}, []string{"pgdb"}, databaseColumns, true, databaseValidation(), false},
+	{BackupCRDPlural, DatabaseBackup{}, nil, nil, false, nil, false},
+	{RestoreCRDPlural, DatabaseRestore{}, nil, nil, false, nil, false},
+	{PublicationCRDPlural, Publication{}, nil, nil, false, nil, false},
+	{SubscriptionCRDPlural, Subscription{}, nil, nil, false, nil, false},
+	{InstanceCRDPlural, PostgresInstance{}, nil, nil, false, nil, false},
+	{QuotaCRDPlural, DatabaseQuota{}, nil, nil, false, nil, false},
+	{ParametersCRDPlural, PostgresParameters{}, nil, nil, false, nil, false},
+	{TablespaceCRDPlural, Tablespace{}, nil, nil, false, nil, false},
+	{DatabaseSetCRDPlural, DatabaseSet{}, nil, databaseSetColumns, false, nil, false},
+	{ForeignServerCRDPlural, ForeignServer{}, nil, nil, false, nil, false},
+	{UserMappingCRDPlural, UserMapping{}, nil, nil, false, nil, false},
+	{DatabaseClassCRDPlural, DatabaseClass{}, nil, nil, false, nil, true},
+	{PhysicalBackupCRDPlural, PhysicalBackup{}, nil, physicalBackupColumns, false, nil, false},
+}
+
+func installCRDs(clientset apiextcs.Interface, update bool) error {
+	for _, crd := range crds {
+		definition := crdDefinition(crd.plural, reflect.TypeOf(crd.kind).Name(), crd.shortNames, crd.columns, crd.status, crd.validation, crd.clusterScoped)
+		if err := createCRD(clientset, definition, update); err != nil {
+			return fmt.Errorf("error installing CRD %s.%s: %s", crd.plural, CRDGroup, err.Error())
+		}
+	}
+	return nil
+}
+
+// CRDs returns the definitions of the CRDs of the controller, as installed
+// with InstallCRDs, for them to be rendered as manifests.
+func CRDs() []interface{} {
+	var definitions []interface{}
+	for _, crd := range crds {
+		definitions = append(definitions, crdDefinition(crd.plural, reflect.TypeOf(crd.kind).Name(), crd.shortNames, crd.columns, crd.status, crd.validation, crd.clusterScoped))
+	}
+	return definitions
+}
+
+// printerColumn is an additionalPrinterColumns entry of a CRD, which the
+// vendored apiextensions types predate.
+type printerColumn struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+	Priority    int32  `json:"priority,omitempty"`
+	JSONPath    string `json:"JSONPath"`
+}
+
+// crdWithColumns is a CRD along with its printer columns, sent as is to the
+// API server.
+type crdWithColumns struct {
+	meta_v1.TypeMeta   `json:",inline"`
+	meta_v1.ObjectMeta `json:"metadata"`
+	Spec               struct {
+		apiextv1beta1.CustomResourceDefinitionSpec `json:",inline"`
+		AdditionalPrinterColumns                   []printerColumn `json:"additionalPrinterColumns,omitempty"`
+	} `json:"spec"`
+}
+
+// crdDefinition returns the definition of the CRD of kind.
+func crdDefinition(plural, kind string, shortNames []string, columns []printerColumn, status bool, validation *apiextv1beta1.CustomResourceValidation, clusterScoped bool) *crdWithColumns {
+	scope := apiextv1beta1.NamespaceScoped
+	if clusterScoped {
+		scope = apiextv1beta1.ClusterScoped
+	}
+	crd := &crdWithColumns{}
+	crd.APIVersion = apiextv1beta1.SchemeGroupVersion.String()
+	crd.Kind = "CustomResourceDefinition"
+	crd.Spec.CustomResourceDefinitionSpec = apiextv1beta1.CustomResourceDefinitionSpec{
+		Group:   CRDGroup,
+		Version: CRDVersion,
+		Scope:   scope,
+		Names: apiextv1beta1.CustomResourceDefinitionNames{
+			Plural:     plural,
+			Kind:       kind,
+			ShortNames: shortNames,
 		},
+		Validation: validation,
+	}
+	if status {
+		crd.Spec.Subresources = &apiextv1beta1.CustomResourceSubresources{
+			Status: &apiextv1beta1.CustomResourceSubresourceStatus{},
+		}
 	}
-	crd.ObjectMeta.Name = FullCRDName
+	crd.Spec.AdditionalPrinterColumns = columns
+	crd.ObjectMeta.Name = plural + "." + CRDGroup
+	return crd
+}
 
-	_, err := clientset.ApiextensionsV1beta1().CustomResourceDefinitions().Create(crd)
-	if err != nil && apierrors.IsAlreadyExists(err) {
+func createCRD(clientset apiextcs.Interface, crd *crdWithColumns, update bool) error {
+	body, err := json.Marshal(crd)
+	if err != nil {
+		return err
+	}
+	client := clientset.ApiextensionsV1beta1()
+	err = client.RESTClient().Post().
+		Resource("customresourcedefinitions").
+		Body(body).
+		Do().
+		Error()
+	if err == nil || !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	if !update {
 		return nil
 	}
-	return err
+
+	existing, err := client.CustomResourceDefinitions().Get(crd.Name, meta_v1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	crd.ResourceVersion = existing.ResourceVersion
+	if body, err = json.Marshal(crd); err != nil {
+		return err
+	}
+	return client.RESTClient().Put().
+		Resource("customresourcedefinitions").
+		Name(crd.Name).
+		Body(body).
+		Do().
+		Error()
 	// Note the original apiextensions example adds logic to wait for creation and exception handling
 }
 
@@ -57,6 +199,332 @@ type DatabaseConfig struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
 	Database string `json:"database"`
+	// Instance is the name of the PostgresInstance, in the same namespace,
+	// the database is provisioned on. The server the controller is started
+	// with is used when empty, unless Placement is set.
+	Instance string `json:"instance,omitempty"`
+	// InstanceNamespace is the namespace of Instance when it is shared from
+	// another one, which the PostgresInstance must allow.
+	InstanceNamespace string `json:"instanceNamespace,omitempty"`
+	// Placement lets the controller pick the PostgresInstance of the
+	// Database when Instance is empty.
+	Placement *Placement `json:"placement,omitempty"`
+	// ClassName is the DatabaseClass the fields left empty default to.
+	ClassName string `json:"className,omitempty"`
+	// MaintenanceWindow defers the disruptive actions, such as owner
+	// changes, password rotations and forced drops, until it is open.
+	MaintenanceWindow *MaintenanceWindow `json:"maintenanceWindow,omitempty"`
+	// Tablespace is the name of the Tablespace, in the same namespace and on
+	// the same instance, the database is stored in. The default tablespace
+	// of the server when empty.
+	Tablespace string `json:"tablespace,omitempty"`
+	// Mode is database, the default, to provision a database of its own, or
+	// schema to provision a schema named after the owner role inside the
+	// existing Database, shared with other tenants.
+	Mode string `json:"mode,omitempty"`
+	// CloneFrom is the name of a provisioned Database, in the same namespace,
+	// the database is created as a copy of.
+	CloneFrom string `json:"cloneFrom,omitempty"`
+	// MirrorTo names a PostgresInstance, in the same namespace, the database
+	// and roles are also provisioned on with the same passwords, to migrate
+	// to another server.
+	MirrorTo string `json:"mirrorTo,omitempty"`
+	// ReadOnlyUser provisions an additional <username>_ro role that can only
+	// SELECT from the database, with its credentials stored in its own Secret.
+	ReadOnlyUser bool `json:"readOnlyUser,omitempty"`
+	// RoleLayout is owner, the default, for a single owner role, or
+	// owner-app to also provision a <username>_app role with DML rights
+	// only, with its credentials stored in the <name>-app Secret.
+	RoleLayout string `json:"roleLayout,omitempty"`
+	// AllowAdoption lets the controller take over the database and owner
+	// role when they already exist on the server instead of reporting a
+	// conflict, creating them otherwise, when the controller runs with
+	// --allow-adoption.
+	AllowAdoption bool `json:"allowAdoption,omitempty"`
+	// AdoptExisting imports a database that already exists on the server:
+	// the controller takes it over with its owner role, when it runs with
+	// --allow-adoption, and fails rather than creating it when it doesn't
+	// exist. What was found is recorded in status.adoption.
+	AdoptExisting bool `json:"adoptExisting,omitempty"`
+	// Backup schedules DatabaseBackups of the database.
+	Backup *BackupSchedule `json:"backup,omitempty"`
+	// ConnectionLimit caps the concurrent connections to the database and
+	// RoleConnectionLimit the ones opened by its owner role. Unset means no
+	// limit.
+	ConnectionLimit     *int32 `json:"connectionLimit,omitempty"`
+	RoleConnectionLimit *int32 `json:"roleConnectionLimit,omitempty"`
+	// IdleSessionTimeout terminates the sessions of the roles of the
+	// Database that stayed idle, or idle in a transaction, for longer, as
+	// checked every --idle-session-interval. Unset leaves them alone.
+	IdleSessionTimeout *meta_v1.Duration `json:"idleSessionTimeout,omitempty"`
+	// MaxSizeBytes is the size past which the database is reported with the
+	// SizeExceeded condition, as checked every --usage-interval. Unset means
+	// no limit.
+	MaxSizeBytes *int64 `json:"maxSizeBytes,omitempty"`
+	// EnforceMaxSize revokes CONNECT on the database from the role of the
+	// application while it exceeds MaxSizeBytes, and grants it back once it
+	// no longer does.
+	EnforceMaxSize bool `json:"enforceMaxSize,omitempty"`
+	// RevokePublic revokes the privileges PUBLIC holds by default on the
+	// database and its public schema, so other roles of the server can't
+	// connect to it or create objects in it. Unset defaults to the
+	// controller --revoke-public flag.
+	RevokePublic *bool `json:"revokePublic,omitempty"`
+	// PasswordEncryption, md5 or scram-sha-256, hashes the passwords of the
+	// roles before they are sent to the server. It overrides the controller
+	// --password-encryption flag.
+	PasswordEncryption string `json:"passwordEncryption,omitempty"`
+	// PasswordVerifierSecret references an already hashed SCRAM-SHA-256 or
+	// md5 verifier used instead of Password, so the plaintext never leaves the
+	// application. The credentials Secret then holds no password.
+	PasswordVerifierSecret *SecretKeyRef `json:"passwordVerifierSecret,omitempty"`
+	// PasswordSecretRef references an existing Secret holding the plaintext
+	// password of the owner role, used instead of Password. The role gets the
+	// new password whenever the Secret changes.
+	PasswordSecretRef *SecretKeyRef `json:"passwordSecretRef,omitempty"`
+	// Authentication makes the owner role log in with a client certificate,
+	// LDAP or ident rather than a password. Unset means password.
+	Authentication *Authentication `json:"authentication,omitempty"`
+	// InitSQL is run once against the database after it is created.
+	InitSQL *InitSQL `json:"initSQL,omitempty"`
+	// RowLevelSecurity enables row level security, with a policy, on the
+	// tables of the owner role once initSQL created them.
+	RowLevelSecurity *RowLevelSecurity `json:"rowLevelSecurity,omitempty"`
+	// Migrations are run in order against the database once provisioned.
+	Migrations *Migrations `json:"migrations,omitempty"`
+	// DefaultPrivileges are granted on the objects roles will create in the
+	// database, e.g. so the read-only user can read the tables added by
+	// migrations.
+	DefaultPrivileges []DefaultPrivilege `json:"defaultPrivileges,omitempty"`
+	// MemberOf are the group roles the owner role is a member of. Missing
+	// group roles are created without LOGIN.
+	MemberOf []string `json:"memberOf,omitempty"`
+	// RoleSettings are the parameters set on the roles of the Database with
+	// ALTER ROLE ... SET, e.g. search_path or statement_timeout, taking effect
+	// in their new sessions.
+	RoleSettings map[string]string `json:"roleSettings,omitempty"`
+	// RoleAttributes are the attributes of the owner role, altered when
+	// they change. Unset attributes are the least privileged ones.
+	RoleAttributes *RoleAttributes `json:"roleAttributes,omitempty"`
+	// Extensions are created in the database, and updated to their version.
+	// Extensions removed from the list are left installed.
+	Extensions []Extension `json:"extensions,omitempty"`
+	// Hooks run custom steps before and after the database is created or
+	// dropped.
+	Hooks []Hook `json:"hooks,omitempty"`
+	// Pooling configures the pgBouncer entry of the database.
+	Pooling *Pooling `json:"pooling,omitempty"`
+	// DeletionPolicy controls how the database is dropped when the Database
+	// is deleted.
+	DeletionPolicy *DeletionPolicy `json:"deletionPolicy,omitempty"`
+	// RenamePolicy controls how the database is renamed when spec.database
+	// is edited.
+	RenamePolicy *RenamePolicy `json:"renamePolicy,omitempty"`
+	// DeletionGracePeriod keeps the database of a deleted Database, with
+	// CONNECT revoked, for this long before dropping it. Re-creating the
+	// Database meanwhile gives it back.
+	DeletionGracePeriod *meta_v1.Duration `json:"deletionGracePeriod,omitempty"`
+	// TTL deletes the Database, and drops its database, this long after it
+	// was created. ExpiresAt deletes it at a set time, the earliest of the
+	// two applying when both are set.
+	TTL       *meta_v1.Duration `json:"ttl,omitempty"`
+	ExpiresAt *meta_v1.Time     `json:"expiresAt,omitempty"`
+	// CredentialStore selects where the credentials are written, a Secret
+	// named after the Database when unset.
+	CredentialStore *CredentialStore `json:"credentialStore,omitempty"`
+}
+
+// CredentialStore selects where the credentials of a Database are written.
+type CredentialStore struct {
+	// Type is secret, the default, to write a Secret, vault to write them to
+	// the Vault KV v2 engine of the controller only, or externalSecret to
+	// write them to Vault and have External Secrets sync them into the
+	// Secret.
+	Type string `json:"type,omitempty"`
+	// Path is the Vault key the owner credentials are written to, the
+	// read-only ones going to <path>-ro. Defaults to <namespace>/<name>.
+	Path string `json:"path,omitempty"`
+	// SecretStoreRef is the SecretStore, or ClusterSecretStore, reading the
+	// Vault KV engine, required by externalSecret.
+	SecretStoreRef *SecretStoreRef `json:"secretStoreRef,omitempty"`
+}
+
+// SecretStoreRef references an External Secrets secret store.
+type SecretStoreRef struct {
+	Name string `json:"name"`
+	// Kind is SecretStore, the default, or ClusterSecretStore.
+	Kind string `json:"kind,omitempty"`
+}
+
+type DeletionPolicy struct {
+	// Force terminates the sessions connected to the database so they don't
+	// block DROP DATABASE, with WITH (FORCE) on PostgreSQL 13+.
+	Force bool `json:"force,omitempty"`
+	// OwnedObjects is what happens to the objects the roles own, and the
+	// privileges they were granted, in other databases before they are
+	// dropped: reassign them to ReassignTo, or drop them. DROP ROLE fails on
+	// them when unset.
+	OwnedObjects string `json:"ownedObjects,omitempty"`
+	// ReassignTo is the role given the objects with reassign, another role
+	// of the Database, the admin role when empty.
+	ReassignTo string `json:"reassignTo,omitempty"`
+}
+
+type RenamePolicy struct {
+	// Force terminates the sessions connected to the database so they don't
+	// block ALTER DATABASE ... RENAME TO.
+	Force bool `json:"force,omitempty"`
+}
+
+// Pooling are the per database pgBouncer settings.
+type Pooling struct {
+	// Name applications connect to through pgBouncer, the database name when
+	// empty.
+	Name string `json:"name,omitempty"`
+	// Mode is the pool_mode: session, transaction or statement. The pgBouncer
+	// default applies when empty.
+	Mode string `json:"mode,omitempty"`
+	// Size is the pool_size, the pgBouncer default_pool_size when unset.
+	Size *int32 `json:"size,omitempty"`
+	// MaxConnections is the max_db_connections, unlimited when unset.
+	MaxConnections *int32 `json:"maxConnections,omitempty"`
+}
+
+// DefaultPrivilege is an ALTER DEFAULT PRIVILEGES rule.
+type DefaultPrivilege struct {
+	// Role creating the objects, the owner role when empty. It must be one of
+	// the roles of the Database: its owner, read-only or application role.
+	Role string `json:"role,omitempty"`
+	// Schema the objects are created in, every schema when empty.
+	Schema string `json:"schema,omitempty"`
+	// On is the kind of objects: tables, the default, sequences, functions or
+	// types.
+	On string `json:"on,omitempty"`
+	// Privileges granted, e.g. SELECT, or ALL.
+	Privileges []string `json:"privileges"`
+	// Grantee is the role, or PUBLIC, the privileges are granted to.
+	Grantee string `json:"grantee"`
+}
+
+// SecretKeyRef selects a key of a Secret in the namespace of the resource.
+type SecretKeyRef struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+}
+
+// ConfigMapKeyRef selects a key of a ConfigMap in the namespace of the
+// resource.
+type ConfigMapKeyRef struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+}
+
+// MaintenanceWindow is a recurring window disruptive actions run in.
+type MaintenanceWindow struct {
+	// Schedule is the cron expression of the start of the windows, e.g.
+	// "0 2 * * SUN".
+	Schedule string `json:"schedule"`
+	// Duration is how long the windows last.
+	Duration meta_v1.Duration `json:"duration"`
+}
+
+// RowLevelSecurity bootstraps row level security on the tables of a
+// database, or of a schema in schema mode, for multi-tenant deployments.
+type RowLevelSecurity struct {
+	Enabled bool `json:"enabled"`
+	// PolicyName is the name of the policy created on every table,
+	// tenant_isolation by default.
+	PolicyName string `json:"policyName,omitempty"`
+	// Policy is the USING expression of the policy, a template executed with
+	// .Namespace, .Name, .Database, .Username, .Role and .Schema. Defaults to
+	// tenant_id = current_setting('app.tenant_id', true).
+	Policy string `json:"policy,omitempty"`
+	// Force subjects the owner role of the tables to the policy too, with
+	// FORCE ROW LEVEL SECURITY.
+	Force bool `json:"force,omitempty"`
+}
+
+// InitSQL is a script creating the schemas, tables or seed rows of a new
+// database. It is run logged in as the owner role, so the objects belong
+// to it and the script can't do more than it.
+type InitSQL struct {
+	// SQL is the script itself.
+	SQL string `json:"sql,omitempty"`
+	// ConfigMap references a ConfigMap key holding the script, used when SQL
+	// is empty.
+	ConfigMap *ConfigMapKeyRef `json:"configMap,omitempty"`
+	// OnChange is what happens when the script is edited after it was
+	// applied: "reject", the default, leaves the database untouched and
+	// reports the change while "reapply" runs the new script.
+	OnChange string `json:"onChange,omitempty"`
+}
+
+// Placement selects the PostgresInstances a new Database may be placed on,
+// the least loaded one being picked.
+type Placement struct {
+	// InstanceSelector selects the PostgresInstances of the namespace by
+	// label, all of them when unset.
+	InstanceSelector *meta_v1.LabelSelector `json:"instanceSelector,omitempty"`
+	// Strategy measures the load of the instances: "databases", the
+	// default, counts their Databases while "size" sums their usage.
+	Strategy string `json:"strategy,omitempty"`
+}
+
+// RoleAttributes are the attributes of a role. SUPERUSER, CREATEROLE,
+// REPLICATION and BYPASSRLS require the --allow-privileged-roles flag of the
+// controller.
+type RoleAttributes struct {
+	Superuser   bool `json:"superuser,omitempty"`
+	CreateDB    bool `json:"createDB,omitempty"`
+	CreateRole  bool `json:"createRole,omitempty"`
+	Replication bool `json:"replication,omitempty"`
+	BypassRLS   bool `json:"bypassRLS,omitempty"`
+	// Inherit makes the role use the privileges of the roles it is a member
+	// of. Unset defaults to true.
+	Inherit *bool `json:"inherit,omitempty"`
+}
+
+// Authentication is how a role logs in.
+type Authentication struct {
+	// Method is password, cert, ldap or ident. The role has no password
+	// with the last three.
+	Method string `json:"method"`
+	// IdentMap is the pg_ident.conf map of the suggested entries, k8s when
+	// unset.
+	IdentMap string `json:"identMap,omitempty"`
+	// SystemUsers are the identities logging in as the role: the common
+	// names of the client certificates or the ident user names. The role
+	// name when empty.
+	SystemUsers []string `json:"systemUsers,omitempty"`
+}
+
+// Migrations are the SQL migrations of the application, named as for
+// golang-migrate and recorded in its table, so an application can move from
+// one to the other.
+type Migrations struct {
+	// ConfigMapRef names the ConfigMap holding the migrations, a
+	// <version>_<title>.up.sql key each. Other keys, e.g. the .down.sql
+	// migrations, are ignored.
+	ConfigMapRef ConfigMapRef `json:"configMapRef"`
+	// Table records the version migrated to, schema_migrations by default.
+	Table string `json:"table,omitempty"`
+}
+
+// ConfigMapRef names a ConfigMap in the namespace of the resource.
+type ConfigMapRef struct {
+	Name string `json:"name"`
+}
+
+type BackupSchedule struct {
+	// Schedule is a standard cron expression, e.g. "0 2 * * *".
+	Schedule string `json:"schedule"`
+	// Retention is the number of completed scheduled backups kept, older
+	// DatabaseBackups are deleted. Zero keeps every backup.
+	Retention int `json:"retention,omitempty"`
+	// StorageSecret and Destination are passed to the DatabaseBackups, see
+	// DatabaseBackupSpec.
+	StorageSecret string `json:"storageSecret"`
+	Destination   string `json:"destination"`
 }
 type DatabaseSpec struct {
 	Foo string `json:"foo"`
@@ -67,6 +535,234 @@ type DatabaseSpec struct {
 type DatabaseStatus struct {
 	State   string `json:"state,omitempty"`
 	Message string `json:"message,omitempty"`
+	// Reason is the machine readable code of State, detailed by Message:
+	// Provisioned, Cloning, DuplicateDatabase, InvalidIdentifier,
+	// ConnectionRefused, PermissionDenied, QuotaExceeded, InvalidSpec or
+	// ProvisioningFailed.
+	Reason string `json:"reason,omitempty"`
+	// ObservedGeneration is the generation of the spec last reconciled.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// DatabaseName and RoleName are the names of the database and owner role
+	// on the server, rendered from the controller naming templates.
+	DatabaseName string `json:"databaseName,omitempty"`
+	RoleName     string `json:"roleName,omitempty"`
+	// SpecDatabase is the spec.database the database name was recorded for,
+	// so editing it renames the database while changing the naming
+	// templates doesn't.
+	SpecDatabase string `json:"specDatabase,omitempty"`
+	// PreviousDatabaseName is the name of the database before it was last
+	// renamed. Setting spec.database back renames it back.
+	PreviousDatabaseName string `json:"previousDatabaseName,omitempty"`
+	// SchemaName is the schema provisioned in schema mode.
+	SchemaName string `json:"schemaName,omitempty"`
+	// PlannedStatements lists the statements a dry-run reconcile would
+	// execute, with passwords redacted.
+	PlannedStatements []string `json:"plannedStatements,omitempty"`
+	// ServerVersion is the PostgreSQL version detected on the server.
+	ServerVersion string `json:"serverVersion,omitempty"`
+	// ReconcileRequest and RotateRequest are the last handled values of the
+	// postgresql.org/reconcile and postgresql.org/rotate annotations.
+	ReconcileRequest string `json:"reconcileRequest,omitempty"`
+	RotateRequest    string `json:"rotateRequest,omitempty"`
+	// Instance is the PostgresInstance the Database was placed on, per
+	// spec.placement.
+	Instance string `json:"instance,omitempty"`
+	// InitSQLChecksum is the SHA-256 of the last applied initSQL script.
+	InitSQLChecksum string `json:"initSQLChecksum,omitempty"`
+	// MigrationVersion is the version of the last applied migration.
+	MigrationVersion int64 `json:"migrationVersion,omitempty"`
+	// DefaultPrivileges are the default privileges rules applied, so the ones
+	// removed from the spec can be revoked.
+	DefaultPrivileges []DefaultPrivilege `json:"defaultPrivileges,omitempty"`
+	// MemberOf are the group roles granted to the owner role, so the ones
+	// removed from the spec can be revoked.
+	MemberOf []string `json:"memberOf,omitempty"`
+	// RoleSettings are the names of the role settings applied, so the ones
+	// removed from the spec can be reset.
+	RoleSettings []string `json:"roleSettings,omitempty"`
+	// Conditions detail the state of the Database beyond State.
+	Conditions []DatabaseCondition `json:"conditions,omitempty"`
+	// Usage are the statistics of the database last collected from the
+	// server.
+	Usage *DatabaseUsage `json:"usage,omitempty"`
+	// PendingActions are the disruptive actions deferred until the
+	// maintenance window, at NextMaintenanceTime.
+	PendingActions      []string      `json:"pendingActions,omitempty"`
+	NextMaintenanceTime *meta_v1.Time `json:"nextMaintenanceTime,omitempty"`
+	// Adoption is the fingerprint of the database and role taken over with
+	// spec.adoptExisting, as they were found.
+	Adoption *DatabaseAdoption `json:"adoption,omitempty"`
+	// Progress are the provisioning steps completed, while provisioning.
+	Progress *ProvisioningProgress `json:"progress,omitempty"`
+	// AppliedSpec identifies the spec fields last applied on the server, so
+	// the reconciles of a provisioned Database skip the statements of the
+	// unchanged ones.
+	AppliedSpec *AppliedSpec `json:"appliedSpec,omitempty"`
+	// Extensions are the versions of spec.extensions installed and
+	// available on the server.
+	Extensions []ExtensionStatus `json:"extensions,omitempty"`
+	// SlowQueries are the slowest statements run in the database, as last
+	// collected from pg_stat_statements with --slow-query-interval.
+	SlowQueries *SlowQueryReport `json:"slowQueries,omitempty"`
+	// Mirror is the state of the database and roles on the spec.mirrorTo
+	// instance.
+	Mirror *MirrorStatus `json:"mirror,omitempty"`
+}
+
+// AppliedSpec are the SHA-256 hashes of the spec fields a provisioned
+// Database was last reconciled with.
+type AppliedSpec struct {
+	// Hash changes with any of Fields.
+	Hash string `json:"hash"`
+	// Fields are the hashes of the groups of spec fields applied by a step of
+	// the reconcile each, by the name of the field.
+	Fields map[string]string `json:"fields,omitempty"`
+	// VerifiedTime is when every step last ran, whether its fields changed or
+	// not, checking the server for drift.
+	VerifiedTime meta_v1.Time `json:"verifiedTime,omitempty"`
+}
+
+// MirrorStatus is the state of the copy of the database and roles of a
+// Database on its mirror instance.
+type MirrorStatus struct {
+	Instance string `json:"instance"`
+	State    string `json:"state,omitempty"`
+	Message  string `json:"message,omitempty"`
+	// Checksum identifies the roles and passwords last mirrored.
+	Checksum   string        `json:"checksum,omitempty"`
+	SyncedTime *meta_v1.Time `json:"syncedTime,omitempty"`
+}
+
+// SlowQueryReport are the statements of a database with the highest mean
+// execution time.
+type SlowQueryReport struct {
+	Queries []SlowQuery `json:"queries,omitempty"`
+	// CollectedTime is when the statistics were collected.
+	CollectedTime meta_v1.Time `json:"collectedTime"`
+}
+
+// SlowQuery are the statistics of a normalized statement, its constants
+// replaced by placeholders.
+type SlowQuery struct {
+	QueryID   string           `json:"queryID"`
+	Query     string           `json:"query"`
+	Calls     int64            `json:"calls"`
+	MeanTime  meta_v1.Duration `json:"meanTime"`
+	TotalTime meta_v1.Duration `json:"totalTime"`
+}
+
+// ExtensionStatus is the version of an extension installed in the database
+// and the one the server provides.
+type ExtensionStatus struct {
+	Name             string `json:"name"`
+	InstalledVersion string `json:"installedVersion,omitempty"`
+	// AvailableVersion is the default version of the extension on the
+	// server, usually the newest it packages.
+	AvailableVersion string `json:"availableVersion,omitempty"`
+}
+
+// Extension is an extension created in the database of a Database.
+type Extension struct {
+	Name string `json:"name"`
+	// Version is the version of the extension, updated to with ALTER
+	// EXTENSION ... UPDATE. latest follows the default version of the
+	// server, e.g. after an upgrade. The version installed is kept when
+	// empty.
+	Version string `json:"version,omitempty"`
+	// Schema is the schema the extension is created in, the first of the
+	// search_path when empty.
+	Schema string `json:"schema,omitempty"`
+}
+
+// Hook is a custom step run at a point of the lifecycle of a Database: an
+// SQL snippet, a webhook call or a Job, exactly one of them.
+type Hook struct {
+	// Name identifies the hook among the hooks of the Database.
+	Name string `json:"name"`
+	// On is preCreate, before the role and database are created,
+	// postCreate, once the credentials are stored, preDelete, before the
+	// database is dropped, or postDelete, once it is.
+	On string `json:"on"`
+	// SQL is run in the database logged in as the owner role, for
+	// postCreate and preDelete hooks only.
+	SQL string `json:"sql,omitempty"`
+	// Webhook is a URL the Database and the hook are POSTed to as JSON, on
+	// one of the --hook-webhook-hosts of the controller.
+	Webhook string `json:"webhook,omitempty"`
+	// Job is run to completion.
+	Job *HookJob `json:"job,omitempty"`
+	// FailurePolicy is Fail, the default, for a failed create hook to fail
+	// the Database and a failed preDelete hook to keep the database, or
+	// Ignore to carry on.
+	FailurePolicy string `json:"failurePolicy,omitempty"`
+}
+
+// HookJob is the container of the Job of a hook. Its environment holds
+// HOOK, DATABASE_NAMESPACE, DATABASE_NAME, DATABASE and ROLE, along with
+// DATABASE_URL from the credentials Secret once it exists.
+type HookJob struct {
+	Image   string   `json:"image"`
+	Command []string `json:"command,omitempty"`
+	Args    []string `json:"args,omitempty"`
+}
+
+// ProvisioningProgress records the provisioning steps of a Database as they
+// complete, so a provisioning interrupted by a crash or failure resumes from
+// the last completed step.
+type ProvisioningProgress struct {
+	// StartedTime is when the first attempt started running statements.
+	// The database and role found afterwards are the ones it created.
+	StartedTime meta_v1.Time `json:"startedTime"`
+	// RoleCreated is set once the owner role exists.
+	RoleCreated bool `json:"roleCreated,omitempty"`
+	// DatabaseCreated is set once the database, or schema, exists, Cloned
+	// when it was created as a copy of the clone source.
+	DatabaseCreated bool `json:"dbCreated,omitempty"`
+	Cloned          bool `json:"cloned,omitempty"`
+	// InitSQLApplied is set once initSQL was applied.
+	InitSQLApplied bool `json:"initSQLApplied,omitempty"`
+	// SecretWritten is set once the credentials were stored.
+	SecretWritten bool `json:"secretWritten,omitempty"`
+	// HooksRun are the names of the hooks that completed, the delete hooks
+	// only in the pending drop record of a requeued deletion.
+	HooksRun []string `json:"hooksRun,omitempty"`
+}
+
+// DatabaseAdoption describes a database and owner role that existed before
+// the Database took them over.
+type DatabaseAdoption struct {
+	// Owner, Encoding and Collation are the ones of the database before it
+	// was handed over to the owner role, empty when only the role existed.
+	Owner     string `json:"owner,omitempty"`
+	Encoding  string `json:"encoding,omitempty"`
+	Collation string `json:"collation,omitempty"`
+	// RoleExisted is set when the owner role existed and was taken over.
+	RoleExisted bool `json:"roleExisted,omitempty"`
+	// AdoptedTime is when the Database took them over.
+	AdoptedTime meta_v1.Time `json:"adoptedTime"`
+}
+
+// DatabaseUsage are statistics of a provisioned database.
+type DatabaseUsage struct {
+	// SizeBytes is the disk space used by the database.
+	SizeBytes int64 `json:"sizeBytes"`
+	// Connections is the number of sessions connected to the database.
+	Connections int32 `json:"connections"`
+	// LastActivityTime is the last time a session connected to the database
+	// was seen changing state.
+	LastActivityTime *meta_v1.Time `json:"lastActivityTime,omitempty"`
+	// CollectedTime is when the statistics were collected.
+	CollectedTime meta_v1.Time `json:"collectedTime"`
+}
+
+// DatabaseCondition is an observation about a Database, e.g. QuotaExceeded.
+type DatabaseCondition struct {
+	Type string `json:"type"`
+	// Status is True, False or Unknown.
+	Status             string       `json:"status"`
+	LastTransitionTime meta_v1.Time `json:"lastTransitionTime,omitempty"`
+	Reason             string       `json:"reason,omitempty"`
+	Message            string       `json:"message,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
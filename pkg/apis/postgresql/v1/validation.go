@@ -0,0 +1,191 @@
+package v1
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// identifierPattern is what spec.database and spec.username look like.
+	// The controller lowercases them and turns hyphens into underscores on
+	// the server.
+	identifierPattern = `^[A-Za-z0-9_][A-Za-z0-9_-]*$`
+	// roleNamePattern is what the group roles of spec.memberOf look like,
+	// written unquoted in the statements.
+	roleNamePattern = `^[A-Za-z_][A-Za-z0-9_]*$`
+	// maxIdentifierLength is NAMEDATALEN - 1, longer names are truncated by
+	// PostgreSQL.
+	maxIdentifierLength = 63
+	// resourceNamePattern is a DNS-1123 subdomain, the name of the
+	// resources referenced by a Database.
+	resourceNamePattern   = `^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
+	maxResourceNameLength = 253
+)
+
+// fieldRule constrains a field of a spec beyond its type.
+type fieldRule struct {
+	pattern   string
+	maxLength int64
+	minimum   *float64
+	enum      []string
+	// optional fields are not required even though they are serialized
+	// without omitempty.
+	optional bool
+}
+
+var (
+	identifierRule   = fieldRule{pattern: identifierPattern, maxLength: maxIdentifierLength}
+	resourceNameRule = fieldRule{pattern: resourceNamePattern, maxLength: maxResourceNameLength}
+)
+
+// minimum returns a pointer to min, for fieldRule.minimum.
+func minimum(min float64) *float64 {
+	return &min
+}
+
+// databaseSpecRules are the rules of the fields of the Database spec, by
+// their path in the spec, with [] standing for the items of a list. Fields
+// serialized without omitempty are required.
+var databaseSpecRules = map[string]fieldRule{
+	"username":                            identifierRule,
+	"database":                            identifierRule,
+	"password":                            {optional: true},
+	"instance":                            resourceNameRule,
+	"instanceNamespace":                   resourceNameRule,
+	"tablespace":                          resourceNameRule,
+	"cloneFrom":                           resourceNameRule,
+	"mirrorTo":                            resourceNameRule,
+	"className":                           resourceNameRule,
+	"mode":                                {enum: []string{"database", "schema"}},
+	"roleLayout":                          {enum: []string{"owner", "owner-app"}},
+	"passwordEncryption":                  {enum: []string{"md5", "scram-sha-256"}},
+	"authentication.method":               {enum: []string{"password", "cert", "ldap", "ident"}},
+	"credentialStore.type":                {enum: []string{"secret", "vault", "externalSecret"}},
+	"credentialStore.secretStoreRef.kind": {enum: []string{"SecretStore", "ClusterSecretStore"}},
+	"placement.strategy":                  {enum: []string{"databases", "size"}},
+	"pooling.mode":                        {enum: []string{"session", "transaction", "statement"}},
+	"pooling.size":                        {minimum: minimum(0)},
+	"pooling.maxConnections":              {minimum: minimum(0)},
+	"initSQL.onChange":                    {enum: []string{"reject", "reapply"}},
+	"defaultPrivileges[].on":              {enum: []string{"tables", "sequences", "functions", "types"}},
+	"memberOf[]":                          {pattern: roleNamePattern, maxLength: maxIdentifierLength},
+	"deletionPolicy.ownedObjects":         {enum: []string{"reassign", "drop"}},
+	"deletionPolicy.reassignTo":           {pattern: roleNamePattern, maxLength: maxIdentifierLength},
+	"extensions[].name":                   {maxLength: maxIdentifierLength},
+	"hooks[].on":                          {enum: []string{"preCreate", "postCreate", "preDelete", "postDelete"}},
+	"hooks[].failurePolicy":               {enum: []string{"Fail", "Ignore"}},
+	"connectionLimit":                     {minimum: minimum(-1)},
+	"roleConnectionLimit":                 {minimum: minimum(-1)},
+	"maxSizeBytes":                        {minimum: minimum(0)},
+	"backup.retention":                    {minimum: minimum(0)},
+	"migrations.configMapRef.name":        resourceNameRule,
+}
+
+var (
+	durationType = reflect.TypeOf(meta_v1.Duration{})
+	timeType     = reflect.TypeOf(meta_v1.Time{})
+)
+
+// databaseValidation is the OpenAPI v3 schema of Databases, generated from
+// DatabaseConfig so the API server rejects the specs the controller would
+// fail on without the admission webhook.
+func databaseValidation() *apiextv1beta1.CustomResourceValidation {
+	spec := fieldSchema(reflect.TypeOf(DatabaseConfig{}), "", databaseSpecRules)
+	return &apiextv1beta1.CustomResourceValidation{
+		OpenAPIV3Schema: &apiextv1beta1.JSONSchemaProps{
+			Type:       "object",
+			Required:   []string{"spec"},
+			Properties: map[string]apiextv1beta1.JSONSchemaProps{"spec": spec},
+		},
+	}
+}
+
+// fieldSchema returns the schema of the values of t, serialized at path,
+// applying its rule.
+func fieldSchema(t reflect.Type, path string, rules map[string]fieldRule) apiextv1beta1.JSONSchemaProps {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var schema apiextv1beta1.JSONSchemaProps
+	switch {
+	case t == durationType:
+		schema.Type = "string"
+	case t == timeType:
+		schema.Type = "string"
+		schema.Format = "date-time"
+	case t.Kind() == reflect.String:
+		schema.Type = "string"
+	case t.Kind() == reflect.Bool:
+		schema.Type = "boolean"
+	case t.Kind() == reflect.Int, t.Kind() == reflect.Int32, t.Kind() == reflect.Int64:
+		schema.Type = "integer"
+	case t.Kind() == reflect.Float32, t.Kind() == reflect.Float64:
+		schema.Type = "number"
+	case t.Kind() == reflect.Slice:
+		items := fieldSchema(t.Elem(), path+"[]", rules)
+		schema.Type = "array"
+		schema.Items = &apiextv1beta1.JSONSchemaPropsOrArray{Schema: &items}
+	case t.Kind() == reflect.Map:
+		values := fieldSchema(t.Elem(), path+"[]", rules)
+		schema.Type = "object"
+		schema.AdditionalProperties = &apiextv1beta1.JSONSchemaPropsOrBool{Allows: true, Schema: &values}
+	case t.Kind() == reflect.Struct:
+		schema.Type = "object"
+		schema.Properties = map[string]apiextv1beta1.JSONSchemaProps{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, omitempty := jsonName(field)
+			if name == "" {
+				continue
+			}
+			fieldPath := name
+			if path != "" {
+				fieldPath = path + "." + name
+			}
+			schema.Properties[name] = fieldSchema(field.Type, fieldPath, rules)
+			if !omitempty && !rules[fieldPath].optional {
+				schema.Required = append(schema.Required, name)
+			}
+		}
+	}
+
+	rule := rules[path]
+	schema.Pattern = rule.pattern
+	if rule.maxLength > 0 {
+		maxLength := rule.maxLength
+		schema.MaxLength = &maxLength
+	}
+	schema.Minimum = rule.minimum
+	for _, value := range rule.enum {
+		raw, _ := json.Marshal(value)
+		schema.Enum = append(schema.Enum, apiextv1beta1.JSON{Raw: raw})
+	}
+	return schema
+}
+
+// jsonName returns the name field is serialized under, empty when it isn't,
+// and whether it is omitted when empty.
+func jsonName(field reflect.StructField) (string, bool) {
+	if field.PkgPath != "" {
+		return "", false
+	}
+	tag := strings.Split(field.Tag.Get("json"), ",")
+	if tag[0] == "-" {
+		return "", false
+	}
+	name := tag[0]
+	if name == "" {
+		name = field.Name
+	}
+	for _, option := range tag[1:] {
+		if option == "omitempty" {
+			return name, true
+		}
+	}
+	return name, false
+}
@@ -32,6 +32,58 @@ func (c *FakeDatabasesV1) Databases(namespace string) v1.DatabaseInterface {
 	return &FakeDatabases{c, namespace}
 }
 
+func (c *FakeDatabasesV1) PhysicalBackups(namespace string) v1.PhysicalBackupInterface {
+	return &FakePhysicalBackups{c, namespace}
+}
+
+func (c *FakeDatabasesV1) DatabaseClasses() v1.DatabaseClassInterface {
+	return &FakeDatabaseClasses{c}
+}
+
+func (c *FakeDatabasesV1) UserMappings(namespace string) v1.UserMappingInterface {
+	return &FakeUserMappings{c, namespace}
+}
+
+func (c *FakeDatabasesV1) ForeignServers(namespace string) v1.ForeignServerInterface {
+	return &FakeForeignServers{c, namespace}
+}
+
+func (c *FakeDatabasesV1) DatabaseSets(namespace string) v1.DatabaseSetInterface {
+	return &FakeDatabaseSets{c, namespace}
+}
+
+func (c *FakeDatabasesV1) Tablespaces(namespace string) v1.TablespaceInterface {
+	return &FakeTablespaces{c, namespace}
+}
+
+func (c *FakeDatabasesV1) PostgresParameters(namespace string) v1.PostgresParametersInterface {
+	return &FakePostgresParameters{c, namespace}
+}
+
+func (c *FakeDatabasesV1) DatabaseQuotas(namespace string) v1.DatabaseQuotaInterface {
+	return &FakeDatabaseQuotas{c, namespace}
+}
+
+func (c *FakeDatabasesV1) PostgresInstances(namespace string) v1.PostgresInstanceInterface {
+	return &FakePostgresInstances{c, namespace}
+}
+
+func (c *FakeDatabasesV1) Subscriptions(namespace string) v1.SubscriptionInterface {
+	return &FakeSubscriptions{c, namespace}
+}
+
+func (c *FakeDatabasesV1) Publications(namespace string) v1.PublicationInterface {
+	return &FakePublications{c, namespace}
+}
+
+func (c *FakeDatabasesV1) DatabaseRestores(namespace string) v1.DatabaseRestoreInterface {
+	return &FakeDatabaseRestores{c, namespace}
+}
+
+func (c *FakeDatabasesV1) DatabaseBackups(namespace string) v1.DatabaseBackupInterface {
+	return &FakeDatabaseBackups{c, namespace}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeDatabasesV1) RESTClient() rest.Interface {
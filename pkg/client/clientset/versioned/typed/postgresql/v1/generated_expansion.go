@@ -19,3 +19,29 @@ limitations under the License.
 package v1
 
 type DatabaseExpansion interface{}
+
+type DatabaseBackupExpansion interface{}
+
+type DatabaseRestoreExpansion interface{}
+
+type PublicationExpansion interface{}
+
+type SubscriptionExpansion interface{}
+
+type PostgresInstanceExpansion interface{}
+
+type DatabaseQuotaExpansion interface{}
+
+type PostgresParametersExpansion interface{}
+
+type TablespaceExpansion interface{}
+
+type DatabaseSetExpansion interface{}
+
+type ForeignServerExpansion interface{}
+
+type UserMappingExpansion interface{}
+
+type DatabaseClassExpansion interface{}
+
+type PhysicalBackupExpansion interface{}
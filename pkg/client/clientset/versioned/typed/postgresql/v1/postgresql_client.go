@@ -28,6 +28,19 @@ import (
 type DatabasesV1Interface interface {
 	RESTClient() rest.Interface
 	DatabasesGetter
+	PhysicalBackupsGetter
+	DatabaseClassesGetter
+	UserMappingsGetter
+	ForeignServersGetter
+	DatabaseSetsGetter
+	TablespacesGetter
+	PostgresParametersGetter
+	DatabaseQuotasGetter
+	PostgresInstancesGetter
+	SubscriptionsGetter
+	PublicationsGetter
+	DatabaseRestoresGetter
+	DatabaseBackupsGetter
 }
 
 // DatabasesV1Client is used to interact with features provided by the databases.postgresql.org group.
@@ -39,6 +52,58 @@ func (c *DatabasesV1Client) Databases(namespace string) DatabaseInterface {
 	return newDatabases(c, namespace)
 }
 
+func (c *DatabasesV1Client) PhysicalBackups(namespace string) PhysicalBackupInterface {
+	return newPhysicalBackups(c, namespace)
+}
+
+func (c *DatabasesV1Client) DatabaseClasses() DatabaseClassInterface {
+	return newDatabaseClasses(c)
+}
+
+func (c *DatabasesV1Client) UserMappings(namespace string) UserMappingInterface {
+	return newUserMappings(c, namespace)
+}
+
+func (c *DatabasesV1Client) ForeignServers(namespace string) ForeignServerInterface {
+	return newForeignServers(c, namespace)
+}
+
+func (c *DatabasesV1Client) DatabaseSets(namespace string) DatabaseSetInterface {
+	return newDatabaseSets(c, namespace)
+}
+
+func (c *DatabasesV1Client) Tablespaces(namespace string) TablespaceInterface {
+	return newTablespaces(c, namespace)
+}
+
+func (c *DatabasesV1Client) PostgresParameters(namespace string) PostgresParametersInterface {
+	return newPostgresParameters(c, namespace)
+}
+
+func (c *DatabasesV1Client) DatabaseQuotas(namespace string) DatabaseQuotaInterface {
+	return newDatabaseQuotas(c, namespace)
+}
+
+func (c *DatabasesV1Client) PostgresInstances(namespace string) PostgresInstanceInterface {
+	return newPostgresInstances(c, namespace)
+}
+
+func (c *DatabasesV1Client) Subscriptions(namespace string) SubscriptionInterface {
+	return newSubscriptions(c, namespace)
+}
+
+func (c *DatabasesV1Client) Publications(namespace string) PublicationInterface {
+	return newPublications(c, namespace)
+}
+
+func (c *DatabasesV1Client) DatabaseRestores(namespace string) DatabaseRestoreInterface {
+	return newDatabaseRestores(c, namespace)
+}
+
+func (c *DatabasesV1Client) DatabaseBackups(namespace string) DatabaseBackupInterface {
+	return newDatabaseBackups(c, namespace)
+}
+
 // NewForConfig creates a new DatabasesV1Client for the given config.
 func NewForConfig(c *rest.Config) (*DatabasesV1Client, error) {
 	config := *c
@@ -55,6 +55,32 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 	// Group=databases.postgresql.org, Version=v1
 	case v1.SchemeGroupVersion.WithResource("databases"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Databases().V1().Databases().Informer()}, nil
+	case v1.SchemeGroupVersion.WithResource("physicalbackups"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Databases().V1().PhysicalBackups().Informer()}, nil
+	case v1.SchemeGroupVersion.WithResource("databaseclasses"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Databases().V1().DatabaseClasses().Informer()}, nil
+	case v1.SchemeGroupVersion.WithResource("usermappings"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Databases().V1().UserMappings().Informer()}, nil
+	case v1.SchemeGroupVersion.WithResource("foreignservers"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Databases().V1().ForeignServers().Informer()}, nil
+	case v1.SchemeGroupVersion.WithResource("databasesets"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Databases().V1().DatabaseSets().Informer()}, nil
+	case v1.SchemeGroupVersion.WithResource("tablespaces"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Databases().V1().Tablespaces().Informer()}, nil
+	case v1.SchemeGroupVersion.WithResource("postgresparameters"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Databases().V1().PostgresParameters().Informer()}, nil
+	case v1.SchemeGroupVersion.WithResource("databasequotas"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Databases().V1().DatabaseQuotas().Informer()}, nil
+	case v1.SchemeGroupVersion.WithResource("postgresinstances"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Databases().V1().PostgresInstances().Informer()}, nil
+	case v1.SchemeGroupVersion.WithResource("subscriptions"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Databases().V1().Subscriptions().Informer()}, nil
+	case v1.SchemeGroupVersion.WithResource("publications"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Databases().V1().Publications().Informer()}, nil
+	case v1.SchemeGroupVersion.WithResource("databaserestores"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Databases().V1().DatabaseRestores().Informer()}, nil
+	case v1.SchemeGroupVersion.WithResource("databasebackups"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Databases().V1().DatabaseBackups().Informer()}, nil
 
 	}
 
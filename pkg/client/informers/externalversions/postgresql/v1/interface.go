@@ -26,6 +26,32 @@ import (
 type Interface interface {
 	// Databases returns a DatabaseInformer.
 	Databases() DatabaseInformer
+	// DatabaseBackups returns a DatabaseBackupInformer.
+	DatabaseBackups() DatabaseBackupInformer
+	// DatabaseRestores returns a DatabaseRestoreInformer.
+	DatabaseRestores() DatabaseRestoreInformer
+	// Publications returns a PublicationInformer.
+	Publications() PublicationInformer
+	// Subscriptions returns a SubscriptionInformer.
+	Subscriptions() SubscriptionInformer
+	// PostgresInstances returns a PostgresInstanceInformer.
+	PostgresInstances() PostgresInstanceInformer
+	// DatabaseQuotas returns a DatabaseQuotaInformer.
+	DatabaseQuotas() DatabaseQuotaInformer
+	// PostgresParameters returns a PostgresParametersInformer.
+	PostgresParameters() PostgresParametersInformer
+	// Tablespaces returns a TablespaceInformer.
+	Tablespaces() TablespaceInformer
+	// DatabaseSets returns a DatabaseSetInformer.
+	DatabaseSets() DatabaseSetInformer
+	// ForeignServers returns a ForeignServerInformer.
+	ForeignServers() ForeignServerInformer
+	// UserMappings returns a UserMappingInformer.
+	UserMappings() UserMappingInformer
+	// DatabaseClasses returns a DatabaseClassInformer.
+	DatabaseClasses() DatabaseClassInformer
+	// PhysicalBackups returns a PhysicalBackupInformer.
+	PhysicalBackups() PhysicalBackupInformer
 }
 
 type version struct {
@@ -43,3 +69,68 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 func (v *version) Databases() DatabaseInformer {
 	return &databaseInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
+
+// DatabaseBackups returns a DatabaseBackupInformer.
+func (v *version) DatabaseBackups() DatabaseBackupInformer {
+	return &databaseBackupInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
+// DatabaseRestores returns a DatabaseRestoreInformer.
+func (v *version) DatabaseRestores() DatabaseRestoreInformer {
+	return &databaseRestoreInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
+// Publications returns a PublicationInformer.
+func (v *version) Publications() PublicationInformer {
+	return &publicationInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
+// Subscriptions returns a SubscriptionInformer.
+func (v *version) Subscriptions() SubscriptionInformer {
+	return &subscriptionInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
+// PostgresInstances returns a PostgresInstanceInformer.
+func (v *version) PostgresInstances() PostgresInstanceInformer {
+	return &postgresInstanceInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
+// DatabaseQuotas returns a DatabaseQuotaInformer.
+func (v *version) DatabaseQuotas() DatabaseQuotaInformer {
+	return &databaseQuotaInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
+// PostgresParameters returns a PostgresParametersInformer.
+func (v *version) PostgresParameters() PostgresParametersInformer {
+	return &postgresParametersInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
+// Tablespaces returns a TablespaceInformer.
+func (v *version) Tablespaces() TablespaceInformer {
+	return &tablespaceInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
+// DatabaseSets returns a DatabaseSetInformer.
+func (v *version) DatabaseSets() DatabaseSetInformer {
+	return &databaseSetInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
+// ForeignServers returns a ForeignServerInformer.
+func (v *version) ForeignServers() ForeignServerInformer {
+	return &foreignServerInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
+// UserMappings returns a UserMappingInformer.
+func (v *version) UserMappings() UserMappingInformer {
+	return &userMappingInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
+// DatabaseClasses returns a DatabaseClassInformer.
+func (v *version) DatabaseClasses() DatabaseClassInformer {
+	return &databaseClassInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
+// PhysicalBackups returns a PhysicalBackupInformer.
+func (v *version) PhysicalBackups() PhysicalBackupInformer {
+	return &physicalBackupInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
@@ -25,3 +25,103 @@ type DatabaseListerExpansion interface{}
 // DatabaseNamespaceListerExpansion allows custom methods to be added to
 // DatabaseNamespaceLister.
 type DatabaseNamespaceListerExpansion interface{}
+
+// DatabaseBackupListerExpansion allows custom methods to be added to
+// DatabaseBackupLister.
+type DatabaseBackupListerExpansion interface{}
+
+// DatabaseBackupNamespaceListerExpansion allows custom methods to be added to
+// DatabaseBackupNamespaceLister.
+type DatabaseBackupNamespaceListerExpansion interface{}
+
+// DatabaseRestoreListerExpansion allows custom methods to be added to
+// DatabaseRestoreLister.
+type DatabaseRestoreListerExpansion interface{}
+
+// DatabaseRestoreNamespaceListerExpansion allows custom methods to be added to
+// DatabaseRestoreNamespaceLister.
+type DatabaseRestoreNamespaceListerExpansion interface{}
+
+// PublicationListerExpansion allows custom methods to be added to
+// PublicationLister.
+type PublicationListerExpansion interface{}
+
+// PublicationNamespaceListerExpansion allows custom methods to be added to
+// PublicationNamespaceLister.
+type PublicationNamespaceListerExpansion interface{}
+
+// SubscriptionListerExpansion allows custom methods to be added to
+// SubscriptionLister.
+type SubscriptionListerExpansion interface{}
+
+// SubscriptionNamespaceListerExpansion allows custom methods to be added to
+// SubscriptionNamespaceLister.
+type SubscriptionNamespaceListerExpansion interface{}
+
+// PostgresInstanceListerExpansion allows custom methods to be added to
+// PostgresInstanceLister.
+type PostgresInstanceListerExpansion interface{}
+
+// PostgresInstanceNamespaceListerExpansion allows custom methods to be added to
+// PostgresInstanceNamespaceLister.
+type PostgresInstanceNamespaceListerExpansion interface{}
+
+// DatabaseQuotaListerExpansion allows custom methods to be added to
+// DatabaseQuotaLister.
+type DatabaseQuotaListerExpansion interface{}
+
+// DatabaseQuotaNamespaceListerExpansion allows custom methods to be added to
+// DatabaseQuotaNamespaceLister.
+type DatabaseQuotaNamespaceListerExpansion interface{}
+
+// PostgresParametersListerExpansion allows custom methods to be added to
+// PostgresParametersLister.
+type PostgresParametersListerExpansion interface{}
+
+// PostgresParametersNamespaceListerExpansion allows custom methods to be added to
+// PostgresParametersNamespaceLister.
+type PostgresParametersNamespaceListerExpansion interface{}
+
+// TablespaceListerExpansion allows custom methods to be added to
+// TablespaceLister.
+type TablespaceListerExpansion interface{}
+
+// TablespaceNamespaceListerExpansion allows custom methods to be added to
+// TablespaceNamespaceLister.
+type TablespaceNamespaceListerExpansion interface{}
+
+// DatabaseSetListerExpansion allows custom methods to be added to
+// DatabaseSetLister.
+type DatabaseSetListerExpansion interface{}
+
+// DatabaseSetNamespaceListerExpansion allows custom methods to be added to
+// DatabaseSetNamespaceLister.
+type DatabaseSetNamespaceListerExpansion interface{}
+
+// ForeignServerListerExpansion allows custom methods to be added to
+// ForeignServerLister.
+type ForeignServerListerExpansion interface{}
+
+// ForeignServerNamespaceListerExpansion allows custom methods to be added to
+// ForeignServerNamespaceLister.
+type ForeignServerNamespaceListerExpansion interface{}
+
+// UserMappingListerExpansion allows custom methods to be added to
+// UserMappingLister.
+type UserMappingListerExpansion interface{}
+
+// UserMappingNamespaceListerExpansion allows custom methods to be added to
+// UserMappingNamespaceLister.
+type UserMappingNamespaceListerExpansion interface{}
+
+// DatabaseClassListerExpansion allows custom methods to be added to
+// DatabaseClassLister.
+type DatabaseClassListerExpansion interface{}
+
+// PhysicalBackupListerExpansion allows custom methods to be added to
+// PhysicalBackupLister.
+type PhysicalBackupListerExpansion interface{}
+
+// PhysicalBackupNamespaceListerExpansion allows custom methods to be added to
+// PhysicalBackupNamespaceLister.
+type PhysicalBackupNamespaceListerExpansion interface{}
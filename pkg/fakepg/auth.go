@@ -0,0 +1,91 @@
+package fakepg
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// passwordOf returns the password of the URI dsn, false without one.
+func passwordOf(dsn string) (string, bool) {
+	u, err := url.Parse(dsn)
+	if err != nil || u.User == nil {
+		return "", false
+	}
+	return u.User.Password()
+}
+
+// authenticate checks that user may log in with password. Users missing
+// from the catalog, as the admin role, are let in with any password. s.mu
+// must be held.
+func (s *Server) authenticate(user, password string) error {
+	r, ok := s.roles[user]
+	if !ok {
+		return nil
+	}
+	if !r.Login {
+		return pgError("28000", "role %q is not permitted to log in", user)
+	}
+	if r.Password == nil {
+		return nil
+	}
+	if !passwordMatches(user, *r.Password, password) {
+		return pgError("28P01", "password authentication failed for user %q", user)
+	}
+	if r.ValidUntil != nil && r.ValidUntil.Before(time.Now()) {
+		return pgError("28P01", "password authentication failed for user %q", user)
+	}
+	return nil
+}
+
+// passwordMatches reports whether password is the one of user stored as
+// stored, in plaintext or as an md5 or SCRAM-SHA-256 verifier.
+func passwordMatches(user, stored, password string) bool {
+	if strings.HasPrefix(stored, "md5") && len(stored) == 35 {
+		sum := md5.Sum([]byte(password + user))
+		return stored == "md5"+hex.EncodeToString(sum[:])
+	}
+	if strings.HasPrefix(stored, "SCRAM-SHA-256$") {
+		return scramMatches(stored, password)
+	}
+	return stored == password
+}
+
+// scramMatches reports whether password is the one of the SCRAM-SHA-256
+// verifier, iterations:salt$storedKey:serverKey.
+func scramMatches(verifier, password string) bool {
+	parts := strings.Split(strings.TrimPrefix(verifier, "SCRAM-SHA-256$"), "$")
+	if len(parts) != 2 {
+		return false
+	}
+	params := strings.SplitN(parts[0], ":", 2)
+	keys := strings.SplitN(parts[1], ":", 2)
+	if len(params) != 2 || len(keys) != 2 {
+		return false
+	}
+	iterations, err := strconv.Atoi(params[0])
+	if err != nil {
+		return false
+	}
+	salt, err := base64.StdEncoding.DecodeString(params[1])
+	if err != nil {
+		return false
+	}
+	storedKey, err := base64.StdEncoding.DecodeString(keys[0])
+	if err != nil {
+		return false
+	}
+	salted := pbkdf2.Key([]byte(password), salt, iterations, sha256.Size, sha256.New)
+	mac := hmac.New(sha256.New, salted)
+	mac.Write([]byte("Client Key"))
+	clientKey := sha256.Sum256(mac.Sum(nil))
+	return hmac.Equal(clientKey[:], storedKey)
+}
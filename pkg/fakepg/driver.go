@@ -0,0 +1,178 @@
+package fakepg
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+)
+
+// fakeDriver opens connections to the fake servers.
+type fakeDriver struct{}
+
+func (fakeDriver) Open(dsn string) (driver.Conn, error) {
+	s, database, user, err := parseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	password, _ := passwordOf(dsn)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.injected("CONNECT " + database); err != nil {
+		return nil, err
+	}
+	if _, ok := s.databases[database]; !ok {
+		return nil, pgError("3D000", "database %q does not exist", database)
+	}
+	if err := s.authenticate(user, password); err != nil {
+		return nil, err
+	}
+	return &conn{server: s, database: database, user: user}, nil
+}
+
+// conn is a session on a database of a fake server.
+type conn struct {
+	server   *Server
+	database string
+	user     string
+	// tx is the transaction in progress, nil outside of one.
+	tx *tx
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{conn: c, query: query}, nil
+}
+
+// Close ends the session, releasing its advisory locks.
+func (c *conn) Close() error {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	for key, holder := range c.server.advisoryLocks {
+		if holder == c {
+			delete(c.server.advisoryLocks, key)
+		}
+	}
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if c.tx != nil {
+		return nil, errors.New("fakepg: transaction already in progress")
+	}
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	roles, databases := c.server.snapshot()
+	c.tx = &tx{conn: c, roles: roles, databases: databases}
+	return c.tx, nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	c.server.statements = append(c.server.statements, query)
+	if err := c.server.injected(query); err != nil {
+		return nil, err
+	}
+	if err := c.server.exec(c, query); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	if err := c.server.injected(query); err != nil {
+		return nil, err
+	}
+	return c.server.query(c, query, values)
+}
+
+// tx is a transaction, rolled back by restoring the catalog as it was when
+// it began. Statements run meanwhile on other connections are undone too.
+type tx struct {
+	conn      *conn
+	roles     map[string]*Role
+	databases map[string]*Database
+}
+
+func (t *tx) Commit() error {
+	t.conn.tx = nil
+	return nil
+}
+
+func (t *tx) Rollback() error {
+	t.conn.tx = nil
+	s := t.conn.server
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.roles, s.databases = t.roles, t.databases
+	return nil
+}
+
+// stmt is a prepared statement, run as is when executed.
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+func (s *stmt) NumInput() int {
+	return -1
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, named(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.QueryContext(context.Background(), s.query, named(args))
+}
+
+func named(args []driver.Value) []driver.NamedValue {
+	values := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		values[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return values
+}
+
+// rows are the result of a query, computed up front.
+type rows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *rows) Columns() []string {
+	return r.columns
+}
+
+func (r *rows) Close() error {
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
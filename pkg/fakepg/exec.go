@@ -0,0 +1,630 @@
+package fakepg
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// identifier matches a bare or double-quoted identifier.
+	identifier = `("(?:[^"]|"")+"|[A-Za-z_][A-Za-z0-9_$]*)`
+	// literal matches a string constant, escaped or not.
+	literal = `(E?'(?:[^']|'')*')`
+)
+
+// statement applies the statements matching pattern to the catalog.
+type statement struct {
+	pattern *regexp.Regexp
+	apply   func(s *Server, c *conn, m []string) error
+}
+
+// compile compiles the statement pattern, in which {id} stands for an
+// identifier and {lit} for a string constant.
+func compile(pattern string) *regexp.Regexp {
+	pattern = strings.Replace(pattern, "{id}", identifier, -1)
+	pattern = strings.Replace(pattern, "{lit}", literal, -1)
+	return regexp.MustCompile(`(?is)^` + pattern + `$`)
+}
+
+// statements are the statements changing the catalog. The others are
+// accepted and change nothing.
+var statements = []statement{
+	{compile(`CREATE\s+(USER|ROLE)\s+{id}(.*)`), createRole},
+	{compile(`ALTER\s+(?:ROLE|USER)\s+{id}\s+(.*)`), alterRole},
+	{compile(`DROP\s+(?:ROLE|USER)\s+(IF\s+EXISTS\s+)?{id}`), dropRole},
+	{compile(`REASSIGN\s+OWNED\s+BY\s+{id}\s+TO\s+{id}`), reassignOwned},
+	{compile(`DROP\s+OWNED\s+BY\s+(.+?)(?:\s+CASCADE)?`), dropOwned},
+	{compile(`CREATE\s+DATABASE\s+{id}(?:\s+TEMPLATE\s+{id})?(?:\s+OWNER\s+{id})?`), createDatabase},
+	{compile(`DROP\s+DATABASE\s+(IF\s+EXISTS\s+)?{id}(?:\s+WITH\s*\(\s*FORCE\s*\))?`), dropDatabase},
+	{compile(`ALTER\s+DATABASE\s+{id}\s+(.*)`), alterDatabase},
+	{compile(`COMMENT\s+ON\s+(DATABASE|ROLE)\s+{id}\s+IS\s+(NULL|\s*{lit})`), comment},
+	{compile(`GRANT\s+(.+?)\s+TO\s+(.+?)(?:\s+WITH\s+\w+\s+OPTION)?`), grant},
+	{compile(`REVOKE\s+(.+?)\s+FROM\s+(.+)`), revoke},
+	{compile(`CREATE\s+SCHEMA\s+(IF\s+NOT\s+EXISTS\s+)?{id}(?:\s+AUTHORIZATION\s+{id})?`), createSchema},
+	{compile(`DROP\s+SCHEMA\s+(IF\s+EXISTS\s+)?{id}(?:\s+CASCADE)?`), dropSchema},
+	{compile(`ALTER\s+SCHEMA\s+{id}\s+OWNER\s+TO\s+{id}`), alterSchemaOwner},
+	{compile(`CREATE\s+EXTENSION\s+(IF\s+NOT\s+EXISTS\s+)?{id}(.*)`), createExtension},
+	{compile(`ALTER\s+EXTENSION\s+{id}\s+UPDATE(?:\s+TO\s+(?:\s*{lit}|{id}))?`), alterExtension},
+	{compile(`DROP\s+EXTENSION\s+(IF\s+EXISTS\s+)?{id}(?:\s+CASCADE)?`), dropExtension},
+	{compile(`CREATE\s+TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?([^\s(]+)\s*\(.*`), createTable},
+	{compile(`INSERT\s+INTO\s+([^\s(]+)\s*\(\s*version\s*,\s*dirty\s*\)\s*VALUES\s*\(\s*(\d+)\s*,\s*\w+\s*\)`), insertVersion},
+	{compile(`TRUNCATE\s+(?:TABLE\s+)?(\S+)`), truncate},
+}
+
+// exec runs the statements of query, separated by semicolons, on the
+// database of c. s.mu must be held.
+func (s *Server) exec(c *conn, query string) error {
+	for _, stmt := range split(query) {
+		for _, st := range statements {
+			if m := st.pattern.FindStringSubmatch(stmt); m != nil {
+				if err := st.apply(s, c, m); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// split splits query into its statements, skipping over the semicolons of
+// string constants, quoted identifiers and dollar-quoted bodies.
+func split(query string) []string {
+	var stmts []string
+	start := 0
+	for i := 0; i < len(query); i++ {
+		switch query[i] {
+		case '\'', '"':
+			if end := strings.IndexByte(query[i+1:], query[i]); end >= 0 {
+				i += end + 1
+			}
+		case '$':
+			if tag := dollarTag.FindString(query[i:]); tag != "" {
+				if end := strings.Index(query[i+len(tag):], tag); end >= 0 {
+					i += len(tag) + end + len(tag) - 1
+				}
+			}
+		case ';':
+			stmts = append(stmts, query[start:i])
+			start = i + 1
+		}
+	}
+	stmts = append(stmts, query[start:])
+	var trimmed []string
+	for _, stmt := range stmts {
+		if stmt = strings.TrimSpace(stmt); stmt != "" {
+			trimmed = append(trimmed, stmt)
+		}
+	}
+	return trimmed
+}
+
+var dollarTag = regexp.MustCompile(`^\$[A-Za-z_]*\$`)
+
+// unquote returns the name of identifier, folded to lower case unless
+// double-quoted.
+func unquote(identifier string) string {
+	if strings.HasPrefix(identifier, `"`) {
+		return strings.Replace(identifier[1:len(identifier)-1], `""`, `"`, -1)
+	}
+	return strings.ToLower(identifier)
+}
+
+// unquoteLiteral returns the value of the string constant lit.
+func unquoteLiteral(lit string) string {
+	lit = strings.TrimSpace(lit)
+	escaped := strings.HasPrefix(lit, "E") || strings.HasPrefix(lit, "e")
+	if escaped {
+		lit = lit[1:]
+	}
+	value := strings.Replace(lit[1:len(lit)-1], "''", "'", -1)
+	if escaped {
+		value = strings.Replace(value, `\\`, `\`, -1)
+	}
+	return value
+}
+
+// names returns the names of the comma-separated identifiers of list, c's
+// user for CURRENT_USER and SESSION_USER.
+func names(c *conn, list string) []string {
+	var result []string
+	for _, name := range identifiers.FindAllString(list, -1) {
+		switch strings.ToUpper(name) {
+		case "CURRENT_USER", "SESSION_USER":
+			result = append(result, c.user)
+		default:
+			result = append(result, unquote(name))
+		}
+	}
+	return result
+}
+
+var identifiers = regexp.MustCompile(identifier)
+
+// roleOption matches the options of CREATE ROLE and ALTER ROLE, one at a
+// time.
+var roleOption = regexp.MustCompile(`(?is)^\s*(?:PASSWORD\s+(NULL|` + literal + `)|VALID\s+UNTIL\s+` + literal + `|CONNECTION\s+LIMIT\s+(-?\d+)|(\w+))`)
+
+// setOptions applies the options of CREATE ROLE and ALTER ROLE to r.
+func setOptions(r *Role, options string) {
+	for {
+		m := roleOption.FindStringSubmatch(options)
+		if m == nil {
+			return
+		}
+		options = options[len(m[0]):]
+		switch {
+		case m[1] != "":
+			if strings.EqualFold(m[1], "NULL") {
+				r.Password = nil
+			} else {
+				password := unquoteLiteral(m[2])
+				r.Password = &password
+			}
+		case m[3] != "":
+			if until, err := time.Parse(time.RFC3339, unquoteLiteral(m[3])); err == nil {
+				r.ValidUntil = &until
+			}
+		case m[4] != "":
+			limit, _ := strconv.Atoi(m[4])
+			r.ConnLimit = int32(limit)
+		default:
+			switch keyword := strings.ToLower(m[5]); keyword {
+			case "with", "encrypted":
+			case "login":
+				r.Login = true
+			case "nologin":
+				r.Login = false
+			default:
+				if strings.HasPrefix(keyword, "no") {
+					r.Attributes[keyword[2:]] = false
+				} else {
+					r.Attributes[keyword] = true
+				}
+			}
+		}
+	}
+}
+
+func createRole(s *Server, c *conn, m []string) error {
+	name := unquote(m[2])
+	if _, ok := s.roles[name]; ok {
+		return pgError("42710", "role %q already exists", name)
+	}
+	r := &Role{
+		Name:       name,
+		Login:      strings.EqualFold(m[1], "USER"),
+		ConnLimit:  -1,
+		Attributes: map[string]bool{"inherit": true},
+		MemberOf:   map[string]bool{},
+		Settings:   map[string]string{},
+	}
+	setOptions(r, m[3])
+	s.roles[name] = r
+	return nil
+}
+
+var (
+	renameTo    = regexp.MustCompile(`(?is)^RENAME\s+TO\s+` + identifier + `$`)
+	inDatabase  = regexp.MustCompile(`(?is)^IN\s+DATABASE\s+`)
+	setSetting  = regexp.MustCompile(`(?is)^SET\s+([\w.]+)\s*(?:TO|=)\s*(.+)$`)
+	resetOption = regexp.MustCompile(`(?is)^RESET\s+([\w.]+)$`)
+)
+
+func alterRole(s *Server, c *conn, m []string) error {
+	name := unquote(m[1])
+	r, ok := s.roles[name]
+	if !ok {
+		return pgError("42704", "role %q does not exist", name)
+	}
+	options := strings.TrimSpace(m[2])
+	switch {
+	case renameTo.MatchString(options):
+		to := unquote(renameTo.FindStringSubmatch(options)[1])
+		if _, ok := s.roles[to]; ok {
+			return pgError("42710", "role %q already exists", to)
+		}
+		delete(s.roles, name)
+		r.Name = to
+		s.roles[to] = r
+		for _, d := range s.databases {
+			if d.Owner == name {
+				d.Owner = to
+			}
+		}
+	case inDatabase.MatchString(options):
+	case setSetting.MatchString(options):
+		setting := setSetting.FindStringSubmatch(options)
+		r.Settings[strings.ToLower(setting[1])] = setting[2]
+	case resetOption.MatchString(options):
+		delete(r.Settings, strings.ToLower(resetOption.FindStringSubmatch(options)[1]))
+	default:
+		setOptions(r, options)
+	}
+	return nil
+}
+
+func dropRole(s *Server, c *conn, m []string) error {
+	name := unquote(m[2])
+	if _, ok := s.roles[name]; !ok {
+		if m[1] != "" {
+			return nil
+		}
+		return pgError("42704", "role %q does not exist", name)
+	}
+	for _, d := range s.databases {
+		if d.Owner == name {
+			return pgError("2BP01", "role %q cannot be dropped because some objects depend on it: owner of database %s", name, d.Name)
+		}
+		for schema, owner := range d.Schemas {
+			if owner == name {
+				return pgError("2BP01", "role %q cannot be dropped because some objects depend on it: owner of schema %s", name, schema)
+			}
+		}
+	}
+	delete(s.roles, name)
+	for _, r := range s.roles {
+		delete(r.MemberOf, name)
+	}
+	return nil
+}
+
+func reassignOwned(s *Server, c *conn, m []string) error {
+	from, to := unquote(m[1]), unquote(m[2])
+	for _, name := range []string{from, to} {
+		if _, ok := s.roles[name]; !ok {
+			return pgError("42704", "role %q does not exist", name)
+		}
+	}
+	for _, d := range s.databases {
+		if d.Owner == from {
+			d.Owner = to
+		}
+	}
+	if d, ok := s.databases[c.database]; ok {
+		for schema, owner := range d.Schemas {
+			if owner == from {
+				d.Schemas[schema] = to
+			}
+		}
+	}
+	return nil
+}
+
+func dropOwned(s *Server, c *conn, m []string) error {
+	d, ok := s.databases[c.database]
+	if !ok {
+		return nil
+	}
+	for _, name := range names(c, m[1]) {
+		if _, ok := s.roles[name]; !ok {
+			return pgError("42704", "role %q does not exist", name)
+		}
+		for schema, owner := range d.Schemas {
+			if owner == name {
+				delete(d.Schemas, schema)
+			}
+		}
+	}
+	return nil
+}
+
+func createDatabase(s *Server, c *conn, m []string) error {
+	name := unquote(m[1])
+	if _, ok := s.databases[name]; ok {
+		return pgError("42P04", "database %q already exists", name)
+	}
+	owner := c.user
+	if m[3] != "" {
+		owner = names(c, m[3])[0]
+		if _, ok := s.roles[owner]; !ok {
+			return pgError("42704", "role %q does not exist", owner)
+		}
+	}
+	d := newDatabase(name, owner)
+	if m[2] != "" {
+		template, ok := s.databases[unquote(m[2])]
+		if !ok {
+			return pgError("3D000", "template database %q does not exist", unquote(m[2]))
+		}
+		d = template.clone()
+		d.Name, d.Owner, d.Comment, d.ConnLimit = name, owner, "", -1
+	}
+	s.databases[name] = d
+	return nil
+}
+
+func dropDatabase(s *Server, c *conn, m []string) error {
+	name := unquote(m[2])
+	if _, ok := s.databases[name]; !ok {
+		if m[1] != "" {
+			return nil
+		}
+		return pgError("3D000", "database %q does not exist", name)
+	}
+	if name == c.database {
+		return pgError("55006", "cannot drop the currently open database")
+	}
+	delete(s.databases, name)
+	return nil
+}
+
+var (
+	ownerTo         = regexp.MustCompile(`(?is)^OWNER\s+TO\s+` + identifier + `$`)
+	connectionLimit = regexp.MustCompile(`(?is)^(?:WITH\s+)?CONNECTION\s+LIMIT\s+(-?\d+)$`)
+	setTablespace   = regexp.MustCompile(`(?is)^SET\s+TABLESPACE\s+` + identifier + `$`)
+)
+
+func alterDatabase(s *Server, c *conn, m []string) error {
+	name := unquote(m[1])
+	d, ok := s.databases[name]
+	if !ok {
+		return pgError("3D000", "database %q does not exist", name)
+	}
+	options := strings.TrimSpace(m[2])
+	switch {
+	case renameTo.MatchString(options):
+		to := unquote(renameTo.FindStringSubmatch(options)[1])
+		if _, ok := s.databases[to]; ok {
+			return pgError("42P04", "database %q already exists", to)
+		}
+		if name == c.database {
+			return pgError("55006", "current database cannot be renamed")
+		}
+		delete(s.databases, name)
+		d.Name = to
+		s.databases[to] = d
+	case ownerTo.MatchString(options):
+		owner := names(c, ownerTo.FindStringSubmatch(options)[1])[0]
+		if _, ok := s.roles[owner]; !ok {
+			return pgError("42704", "role %q does not exist", owner)
+		}
+		d.Owner = owner
+	case connectionLimit.MatchString(options):
+		limit, _ := strconv.Atoi(connectionLimit.FindStringSubmatch(options)[1])
+		d.ConnLimit = int32(limit)
+	case setTablespace.MatchString(options):
+		d.Tablespace = unquote(setTablespace.FindStringSubmatch(options)[1])
+	}
+	return nil
+}
+
+func comment(s *Server, c *conn, m []string) error {
+	name := unquote(m[2])
+	text := ""
+	if !strings.EqualFold(m[3], "NULL") {
+		text = unquoteLiteral(m[4])
+	}
+	if strings.EqualFold(m[1], "DATABASE") {
+		d, ok := s.databases[name]
+		if !ok {
+			return pgError("3D000", "database %q does not exist", name)
+		}
+		d.Comment = text
+		return nil
+	}
+	r, ok := s.roles[name]
+	if !ok {
+		return pgError("42704", "role %q does not exist", name)
+	}
+	r.Comment = text
+	return nil
+}
+
+// onObject matches the privileges of a GRANT or REVOKE on objects, telling
+// them apart from role memberships.
+var onObject = regexp.MustCompile(`(?is)\sON\s+(?:(DATABASE|SCHEMA)\s+(\S+))?`)
+
+func grant(s *Server, c *conn, m []string) error {
+	if onObject.MatchString(m[1]) {
+		return nil
+	}
+	return membership(s, c, m[1], m[2], true)
+}
+
+func revoke(s *Server, c *conn, m []string) error {
+	if on := onObject.FindStringSubmatch(m[1]); on != nil {
+		if !strings.EqualFold(strings.TrimSpace(m[2]), "PUBLIC") || !strings.HasPrefix(strings.ToUpper(m[1]), "ALL ") {
+			return nil
+		}
+		switch strings.ToUpper(on[1]) {
+		case "DATABASE":
+			if d, ok := s.databases[unquote(on[2])]; ok {
+				d.PublicConnect = false
+			}
+		case "SCHEMA":
+			if d, ok := s.databases[c.database]; ok && unquote(on[2]) == "public" {
+				d.PublicSchema = false
+			}
+		}
+		return nil
+	}
+	return membership(s, c, m[1], m[2], false)
+}
+
+// membership grants, or revokes, the roles of the list groups to the ones of
+// members. Members missing from the catalog are skipped when they are c's
+// user, the admin role of the server.
+func membership(s *Server, c *conn, groups, members string, granted bool) error {
+	for _, group := range names(c, groups) {
+		if _, ok := s.roles[group]; !ok {
+			return pgError("42704", "role %q does not exist", group)
+		}
+		for _, member := range names(c, members) {
+			r, ok := s.roles[member]
+			if !ok {
+				if member == c.user {
+					continue
+				}
+				return pgError("42704", "role %q does not exist", member)
+			}
+			if granted {
+				r.MemberOf[group] = true
+			} else {
+				delete(r.MemberOf, group)
+			}
+		}
+	}
+	return nil
+}
+
+// currentDatabase returns the database of c, an error once dropped.
+func (s *Server) currentDatabase(c *conn) (*Database, error) {
+	d, ok := s.databases[c.database]
+	if !ok {
+		return nil, pgError("3D000", "database %q does not exist", c.database)
+	}
+	return d, nil
+}
+
+func createSchema(s *Server, c *conn, m []string) error {
+	d, err := s.currentDatabase(c)
+	if err != nil {
+		return err
+	}
+	name := unquote(m[2])
+	if _, ok := d.Schemas[name]; ok || name == "public" {
+		if m[1] != "" {
+			return nil
+		}
+		return pgError("42P06", "schema %q already exists", name)
+	}
+	owner := c.user
+	if m[3] != "" {
+		owner = names(c, m[3])[0]
+		if _, ok := s.roles[owner]; !ok {
+			return pgError("42704", "role %q does not exist", owner)
+		}
+	}
+	d.Schemas[name] = owner
+	return nil
+}
+
+func dropSchema(s *Server, c *conn, m []string) error {
+	d, err := s.currentDatabase(c)
+	if err != nil {
+		return err
+	}
+	name := unquote(m[2])
+	if _, ok := d.Schemas[name]; !ok {
+		if m[1] != "" {
+			return nil
+		}
+		return pgError("3F000", "schema %q does not exist", name)
+	}
+	delete(d.Schemas, name)
+	return nil
+}
+
+func alterSchemaOwner(s *Server, c *conn, m []string) error {
+	d, err := s.currentDatabase(c)
+	if err != nil {
+		return err
+	}
+	name, owner := unquote(m[1]), names(c, m[2])[0]
+	if _, ok := d.Schemas[name]; !ok {
+		return pgError("3F000", "schema %q does not exist", name)
+	}
+	if _, ok := s.roles[owner]; !ok {
+		return pgError("42704", "role %q does not exist", owner)
+	}
+	d.Schemas[name] = owner
+	return nil
+}
+
+var extensionVersion = regexp.MustCompile(`(?is)\sVERSION\s+(?:` + literal + `|` + identifier + `)`)
+
+// version returns the version of m, the match of extensionVersion, 1.0 when
+// it is nil.
+func version(m []string) string {
+	switch {
+	case m == nil:
+		return "1.0"
+	case m[1] != "":
+		return unquoteLiteral(m[1])
+	}
+	return unquote(m[2])
+}
+
+func createExtension(s *Server, c *conn, m []string) error {
+	d, err := s.currentDatabase(c)
+	if err != nil {
+		return err
+	}
+	name := unquote(m[2])
+	if _, ok := d.Extensions[name]; ok {
+		if m[1] != "" {
+			return nil
+		}
+		return pgError("42710", "extension %q already exists", name)
+	}
+	d.Extensions[name] = version(extensionVersion.FindStringSubmatch(m[3]))
+	return nil
+}
+
+func alterExtension(s *Server, c *conn, m []string) error {
+	d, err := s.currentDatabase(c)
+	if err != nil {
+		return err
+	}
+	name := unquote(m[1])
+	if _, ok := d.Extensions[name]; !ok {
+		return pgError("42704", "extension %q does not exist", name)
+	}
+	switch {
+	case m[2] != "":
+		d.Extensions[name] = unquoteLiteral(m[2])
+	case m[3] != "":
+		d.Extensions[name] = unquote(m[3])
+	}
+	return nil
+}
+
+func dropExtension(s *Server, c *conn, m []string) error {
+	d, err := s.currentDatabase(c)
+	if err != nil {
+		return err
+	}
+	name := unquote(m[2])
+	if _, ok := d.Extensions[name]; !ok {
+		if m[1] != "" {
+			return nil
+		}
+		return pgError("42704", "extension %q does not exist", name)
+	}
+	delete(d.Extensions, name)
+	return nil
+}
+
+func createTable(s *Server, c *conn, m []string) error {
+	d, err := s.currentDatabase(c)
+	if err != nil {
+		return err
+	}
+	d.Tables[m[1]] = true
+	return nil
+}
+
+func insertVersion(s *Server, c *conn, m []string) error {
+	d, err := s.currentDatabase(c)
+	if err != nil {
+		return err
+	}
+	if !d.Tables[m[1]] {
+		return pgError("42P01", "relation %q does not exist", m[1])
+	}
+	version, _ := strconv.ParseInt(m[2], 10, 64)
+	d.Migrations[m[1]] = version
+	return nil
+}
+
+func truncate(s *Server, c *conn, m []string) error {
+	d, err := s.currentDatabase(c)
+	if err != nil {
+		return err
+	}
+	delete(d.Migrations, m[1])
+	return nil
+}
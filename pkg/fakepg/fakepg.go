@@ -0,0 +1,312 @@
+// Package fakepg is an in-memory stand-in for a PostgreSQL server, served
+// through the "fakepg" database/sql driver. It keeps a catalog of the roles,
+// databases, schemas and extensions the provisioning statements create,
+// answers the catalog queries of the controller from it and accepts the
+// other statements as no-ops, so the reconciles can run end to end without
+// a server. Failures are injected by matching the statements and queries
+// against patterns.
+//
+// Servers are told apart by the host of the connection URI, the database by
+// its path. Authentication is only checked for the roles of the catalog.
+package fakepg
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx"
+)
+
+// DriverName is the name the driver is registered with in database/sql.
+const DriverName = "fakepg"
+
+// DefaultVersion is the server_version_num of new servers.
+const DefaultVersion = 150000
+
+// Role is a role of the catalog.
+type Role struct {
+	Name  string
+	Login bool
+	// Password is the password, or verifier, as set, nil without one.
+	Password   *string
+	ValidUntil *time.Time
+	ConnLimit  int32
+	// Attributes are the role attributes other than LOGIN, by lowercase
+	// keyword, e.g. createdb.
+	Attributes map[string]bool
+	Comment    string
+	MemberOf   map[string]bool
+	Settings   map[string]string
+}
+
+// Database is a database of the catalog.
+type Database struct {
+	Name       string
+	Owner      string
+	ConnLimit  int32
+	Comment    string
+	Tablespace string
+	// PublicConnect is set while PUBLIC has its default privileges on the
+	// database, PublicSchema while it has them on its public schema.
+	PublicConnect bool
+	PublicSchema  bool
+	// Schemas are the schemas other than public, by name, with their owner.
+	Schemas map[string]string
+	// Extensions are the installed extensions, by name, with their version.
+	Extensions map[string]string
+	// Tables are the tables created, by name as written in CREATE TABLE.
+	Tables map[string]bool
+	// Migrations are the versions recorded in the migration tables, by
+	// table.
+	Migrations map[string]int64
+}
+
+// failure is a failure injected with FailOn.
+type failure struct {
+	pattern *regexp.Regexp
+	code    string
+}
+
+// Server is a fake server, its catalog and the failures injected into it.
+type Server struct {
+	mu         sync.Mutex
+	version    int
+	roles      map[string]*Role
+	databases  map[string]*Database
+	failures   []failure
+	statements []string
+	// advisoryLocks are the sessions holding the advisory locks, by the
+	// text the key was hashed from.
+	advisoryLocks map[string]*conn
+}
+
+var (
+	serversMu sync.Mutex
+	servers   = map[string]*Server{}
+	// failures are injected into every server.
+	failures []failure
+)
+
+func init() {
+	sql.Register(DriverName, &fakeDriver{})
+}
+
+// Lookup returns the fake server of host, created empty but for its postgres
+// database on first use.
+func Lookup(host string) *Server {
+	serversMu.Lock()
+	defer serversMu.Unlock()
+	s, ok := servers[host]
+	if !ok {
+		s = &Server{version: DefaultVersion, roles: map[string]*Role{}, databases: map[string]*Database{}, advisoryLocks: map[string]*conn{}}
+		s.databases["postgres"] = newDatabase("postgres", "postgres")
+		servers[host] = s
+	}
+	return s
+}
+
+// Open opens a connection pool to the fake server and database of the
+// postgres:// URI dsn.
+func Open(dsn string) (*sql.DB, error) {
+	if _, _, _, err := parseDSN(dsn); err != nil {
+		return nil, err
+	}
+	return sql.Open(DriverName, dsn)
+}
+
+// parseDSN returns the server, database and user of dsn.
+func parseDSN(dsn string) (*Server, string, string, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("invalid fake server URI: %s", err.Error())
+	}
+	database := strings.TrimPrefix(u.Path, "/")
+	if database == "" {
+		database = "postgres"
+	}
+	return Lookup(u.Host), database, u.User.Username(), nil
+}
+
+func newDatabase(name, owner string) *Database {
+	return &Database{
+		Name:          name,
+		Owner:         owner,
+		ConnLimit:     -1,
+		Tablespace:    "pg_default",
+		PublicConnect: true,
+		PublicSchema:  true,
+		Schemas:       map[string]string{},
+		Extensions:    map[string]string{"plpgsql": "1.0"},
+		Tables:        map[string]bool{},
+		Migrations:    map[string]int64{},
+	}
+}
+
+// SetVersion sets the server_version_num the server reports.
+func (s *Server) SetVersion(version int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version = version
+}
+
+// FailOn makes the statements and queries matching pattern fail with the
+// SQLSTATE code, e.g. 55006 for object_in_use, until Reset.
+func (s *Server) FailOn(pattern *regexp.Regexp, code string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, failure{pattern: pattern, code: code})
+}
+
+// FailOn makes the statements and queries matching pattern fail with the
+// SQLSTATE code on every server.
+func FailOn(pattern *regexp.Regexp, code string) {
+	serversMu.Lock()
+	defer serversMu.Unlock()
+	failures = append(failures, failure{pattern: pattern, code: code})
+}
+
+// Reset empties the catalog, the injected failures and the statements
+// recorded.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.roles = map[string]*Role{}
+	s.databases = map[string]*Database{"postgres": newDatabase("postgres", "postgres")}
+	s.failures = nil
+	s.statements = nil
+	s.advisoryLocks = map[string]*conn{}
+}
+
+// Statements returns the statements run on the server, in order.
+func (s *Server) Statements() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.statements...)
+}
+
+// Roles returns the names of the roles of the catalog, sorted.
+func (s *Server) Roles() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for name := range s.roles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Databases returns the names of the databases of the catalog, sorted.
+func (s *Server) Databases() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for name := range s.databases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Role returns a copy of the role called name, nil when missing.
+func (s *Server) Role(name string) *Role {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.roles[name]; ok {
+		return r.clone()
+	}
+	return nil
+}
+
+// Database returns a copy of the database called name, nil when missing.
+func (s *Server) Database(name string) *Database {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if d, ok := s.databases[name]; ok {
+		return d.clone()
+	}
+	return nil
+}
+
+// injected returns the failure injected for query, nil when it runs.
+// s.mu must be held.
+func (s *Server) injected(query string) error {
+	serversMu.Lock()
+	injected := append(append([]failure{}, failures...), s.failures...)
+	serversMu.Unlock()
+	for _, f := range injected {
+		if f.pattern.MatchString(query) {
+			return pgx.PgError{Severity: "ERROR", Code: f.code, Message: fmt.Sprintf("injected failure matching %q", f.pattern.String())}
+		}
+	}
+	return nil
+}
+
+// snapshot returns a copy of the catalog, restored when a transaction is
+// rolled back. s.mu must be held.
+func (s *Server) snapshot() (map[string]*Role, map[string]*Database) {
+	roles := make(map[string]*Role, len(s.roles))
+	for name, r := range s.roles {
+		roles[name] = r.clone()
+	}
+	databases := make(map[string]*Database, len(s.databases))
+	for name, d := range s.databases {
+		databases[name] = d.clone()
+	}
+	return roles, databases
+}
+
+func (r *Role) clone() *Role {
+	c := *r
+	if r.Password != nil {
+		password := *r.Password
+		c.Password = &password
+	}
+	if r.ValidUntil != nil {
+		until := *r.ValidUntil
+		c.ValidUntil = &until
+	}
+	c.Attributes = copyBools(r.Attributes)
+	c.MemberOf = copyBools(r.MemberOf)
+	c.Settings = copyStrings(r.Settings)
+	return &c
+}
+
+func (d *Database) clone() *Database {
+	c := *d
+	c.Schemas = copyStrings(d.Schemas)
+	c.Extensions = copyStrings(d.Extensions)
+	c.Tables = copyBools(d.Tables)
+	c.Migrations = make(map[string]int64, len(d.Migrations))
+	for table, version := range d.Migrations {
+		c.Migrations[table] = version
+	}
+	return &c
+}
+
+func copyBools(m map[string]bool) map[string]bool {
+	c := make(map[string]bool, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+func copyStrings(m map[string]string) map[string]string {
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// pgError returns the server error of SQLSTATE code.
+func pgError(code, format string, args ...interface{}) error {
+	return pgx.PgError{Severity: "ERROR", Code: code, Message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,109 @@
+package fakepg
+
+import (
+	"context"
+
+	"github.com/joshrendek/k8s-external-postgres/pkg/provisioner"
+)
+
+// Provisioner implements provisioner.Interface on the catalog of a fake
+// server, as the admin role of its postgres database, without going through
+// database/sql. It runs the statements the provisioner package builds, so
+// they are recorded in Statements and fail as injected.
+type Provisioner struct {
+	server *Server
+	opts   provisioner.Options
+	// session is the connection the statements run on.
+	session *conn
+}
+
+var _ provisioner.Interface = &Provisioner{}
+
+// NewProvisioner returns the Provisioner of the fake server of host, hashing
+// passwords and creating databases as opts says.
+func NewProvisioner(host string, opts provisioner.Options) *Provisioner {
+	s := Lookup(host)
+	return &Provisioner{server: s, opts: opts, session: &conn{server: s, database: "postgres", user: "postgres"}}
+}
+
+// CreateDatabase creates database owned by owner, created with password or
+// given it when the role already exists, as provisioner.Provisioner does.
+func (p *Provisioner) CreateDatabase(ctx context.Context, database, owner, password string) error {
+	p.server.mu.Lock()
+	defer p.server.mu.Unlock()
+	_, roleExists := p.server.roles[owner]
+	verb := "CREATE USER"
+	if roleExists {
+		verb = "ALTER ROLE"
+	}
+	stmt, err := provisioner.RolePasswordStatement(verb, owner, password, p.opts.PasswordEncryption, p.opts.PasswordExpiry)
+	if err != nil {
+		return err
+	}
+	stmts := []string{stmt}
+	if !roleExists {
+		stmts = append(stmts, provisioner.CreateRoleStatements(owner, p.opts.GrantRoleToAdmin)...)
+	}
+	if _, ok := p.server.databases[database]; ok {
+		stmts = append(stmts, provisioner.ChangeOwnerStatements(database, owner, p.opts.DatabaseOwner)...)
+	} else {
+		stmts = append(stmts, provisioner.CreateDatabaseStatements(database, owner, p.opts.DatabaseOwner)...)
+	}
+	return p.run(ctx, stmts...)
+}
+
+// DropDatabase drops database, terminating its sessions first with force.
+func (p *Provisioner) DropDatabase(ctx context.Context, database string, force bool) error {
+	p.server.mu.Lock()
+	defer p.server.mu.Unlock()
+	if force {
+		if err := p.run(ctx, provisioner.TerminateSessionsStatement(database)); err != nil {
+			return err
+		}
+	}
+	return p.run(ctx, provisioner.DropDatabaseStatement(database, false))
+}
+
+// DropRole drops username, which must not own databases or schemas anymore.
+func (p *Provisioner) DropRole(ctx context.Context, username string) error {
+	p.server.mu.Lock()
+	defer p.server.mu.Unlock()
+	return p.run(ctx, provisioner.DropRoleStatement(username))
+}
+
+// RotatePassword sets the password of username.
+func (p *Provisioner) RotatePassword(ctx context.Context, username, password string) error {
+	stmt, err := provisioner.RolePasswordStatement("ALTER ROLE", username, password, p.opts.PasswordEncryption, p.opts.PasswordExpiry)
+	if err != nil {
+		return err
+	}
+	p.server.mu.Lock()
+	defer p.server.mu.Unlock()
+	return p.run(ctx, stmt)
+}
+
+// Grant grants role every privilege on database, which changes nothing in
+// the catalog.
+func (p *Provisioner) Grant(ctx context.Context, database, role string) error {
+	p.server.mu.Lock()
+	defer p.server.mu.Unlock()
+	return p.run(ctx, provisioner.GrantStatement(database, role))
+}
+
+// run records and applies stmts one at a time, stopping at the first one
+// failing. p.server.mu must be held.
+func (p *Provisioner) run(ctx context.Context, stmts ...string) error {
+	for _, stmt := range stmts {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		p.server.statements = append(p.server.statements, stmt)
+		if err := p.server.injected(stmt); err != nil {
+			return err
+		}
+		if err := p.server.exec(p.session, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package fakepg
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/jackc/pgx"
+
+	"github.com/joshrendek/k8s-external-postgres/pkg/provisioner"
+)
+
+func TestProvisionerCreateDatabase(t *testing.T) {
+	ctx := context.Background()
+	p := NewProvisioner("provisioner-create", provisioner.Options{DatabaseOwner: true})
+	defer p.server.Reset()
+
+	if err := p.CreateDatabase(ctx, "app", "app", "secret"); err != nil {
+		t.Fatalf("CreateDatabase: %s", err)
+	}
+	d := p.server.Database("app")
+	if d == nil || d.Owner != "app" {
+		t.Fatalf("database app = %+v, want owned by app", d)
+	}
+	r := p.server.Role("app")
+	if r == nil || !r.Login || r.Password == nil || !passwordMatches("app", *r.Password, "secret") {
+		t.Fatalf("role app = %+v, want a login with password secret", r)
+	}
+
+	// run again, the role gets the new password and keeps the database
+	if err := p.CreateDatabase(ctx, "app", "app", "rotated"); err != nil {
+		t.Fatalf("CreateDatabase again: %s", err)
+	}
+	if r := p.server.Role("app"); !passwordMatches("app", *r.Password, "rotated") {
+		t.Errorf("password of app not updated by CreateDatabase")
+	}
+	if got := p.server.Databases(); len(got) != 2 {
+		t.Errorf("databases = %v, want postgres and app", got)
+	}
+}
+
+func TestProvisionerDrop(t *testing.T) {
+	ctx := context.Background()
+	p := NewProvisioner("provisioner-drop", provisioner.Options{DatabaseOwner: true})
+	defer p.server.Reset()
+
+	if err := p.CreateDatabase(ctx, "app", "app", "secret"); err != nil {
+		t.Fatalf("CreateDatabase: %s", err)
+	}
+	if err := p.DropRole(ctx, "app"); err == nil {
+		t.Fatalf("DropRole of the owner of a database succeeded")
+	}
+	if err := p.DropDatabase(ctx, "app", true); err != nil {
+		t.Fatalf("DropDatabase: %s", err)
+	}
+	if err := p.DropRole(ctx, "app"); err != nil {
+		t.Fatalf("DropRole: %s", err)
+	}
+	if got := p.server.Roles(); len(got) != 0 {
+		t.Errorf("roles = %v, want none", got)
+	}
+	if got := p.server.Databases(); len(got) != 1 {
+		t.Errorf("databases = %v, want postgres only", got)
+	}
+}
+
+func TestProvisionerRotatePassword(t *testing.T) {
+	ctx := context.Background()
+	p := NewProvisioner("provisioner-rotate", provisioner.Options{PasswordEncryption: "md5"})
+	defer p.server.Reset()
+
+	if err := p.CreateDatabase(ctx, "app", "app", "secret"); err != nil {
+		t.Fatalf("CreateDatabase: %s", err)
+	}
+	if err := p.RotatePassword(ctx, "app", "rotated"); err != nil {
+		t.Fatalf("RotatePassword: %s", err)
+	}
+	r := p.server.Role("app")
+	if !passwordMatches("app", *r.Password, "rotated") || passwordMatches("app", *r.Password, "secret") {
+		t.Errorf("password of app not rotated")
+	}
+}
+
+func TestProvisionerInjectedFailure(t *testing.T) {
+	ctx := context.Background()
+	p := NewProvisioner("provisioner-failure", provisioner.Options{DatabaseOwner: true})
+	defer p.server.Reset()
+	p.server.FailOn(regexp.MustCompile(`^CREATE DATABASE`), "53100")
+
+	err := p.CreateDatabase(ctx, "app", "app", "secret")
+	if pgErr, ok := err.(pgx.PgError); !ok || pgErr.Code != "53100" {
+		t.Fatalf("CreateDatabase error = %v, want the injected 53100", err)
+	}
+	if p.server.Database("app") != nil {
+		t.Errorf("database app created despite the failure")
+	}
+	if p.server.Role("app") == nil {
+		t.Errorf("role app not created before the failing statement")
+	}
+}
@@ -0,0 +1,298 @@
+package fakepg
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// query answers the queries matching pattern from the catalog.
+type query struct {
+	pattern *regexp.Regexp
+	answer  func(s *Server, c *conn, m []string, args []driver.Value) (*rows, error)
+}
+
+// q compiles the pattern of a query, matched against its text with
+// whitespace collapsed.
+func q(pattern string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)^` + pattern + `$`)
+}
+
+// queries are the catalog queries the controller runs. The others return no
+// rows.
+var queries = []query{
+	{q(`SHOW server_version_num`), func(s *Server, c *conn, m []string, args []driver.Value) (*rows, error) {
+		return row(strconv.Itoa(s.version)), nil
+	}},
+	{q(`SHOW transaction_read_only`), func(s *Server, c *conn, m []string, args []driver.Value) (*rows, error) {
+		return row("off"), nil
+	}},
+	{q(`SELECT 1`), func(s *Server, c *conn, m []string, args []driver.Value) (*rows, error) {
+		return row(int64(1)), nil
+	}},
+	{q(`SELECT pg_try_advisory_lock\(hashtext\(\$1\)\)`), func(s *Server, c *conn, m []string, args []driver.Value) (*rows, error) {
+		holder, ok := s.advisoryLocks[arg(args, 0)]
+		if ok && holder != c {
+			return row(false), nil
+		}
+		s.advisoryLocks[arg(args, 0)] = c
+		return row(true), nil
+	}},
+	{q(`SELECT pg_advisory_unlock\(hashtext\(\$1\)\)`), func(s *Server, c *conn, m []string, args []driver.Value) (*rows, error) {
+		if s.advisoryLocks[arg(args, 0)] != c {
+			return row(false), nil
+		}
+		delete(s.advisoryLocks, arg(args, 0))
+		return row(true), nil
+	}},
+	{q(`SELECT EXISTS \(SELECT 1 FROM pg_database WHERE datname = \$1\)`), func(s *Server, c *conn, m []string, args []driver.Value) (*rows, error) {
+		_, ok := s.databases[arg(args, 0)]
+		return row(ok), nil
+	}},
+	{q(`SELECT EXISTS \(SELECT 1 FROM pg_roles WHERE rolname = \$1\)`), func(s *Server, c *conn, m []string, args []driver.Value) (*rows, error) {
+		_, ok := s.roles[arg(args, 0)]
+		return row(ok), nil
+	}},
+	{q(`SELECT EXISTS \(SELECT 1 FROM pg_namespace WHERE nspname = \$1\)`), func(s *Server, c *conn, m []string, args []driver.Value) (*rows, error) {
+		d, err := s.currentDatabase(c)
+		if err != nil {
+			return nil, err
+		}
+		_, ok := d.Schemas[arg(args, 0)]
+		return row(ok || arg(args, 0) == "public"), nil
+	}},
+	{q(`SELECT EXISTS \(SELECT 1 FROM pg_auth_members m JOIN pg_roles r ON r.oid = m.roleid JOIN pg_roles u ON u.oid = m.member WHERE r.rolname = \$1 AND u.rolname = \$2\)`), func(s *Server, c *conn, m []string, args []driver.Value) (*rows, error) {
+		r, ok := s.roles[arg(args, 1)]
+		return row(ok && r.MemberOf[arg(args, 0)]), nil
+	}},
+	{q(`SELECT EXISTS \(SELECT 1 FROM pg_extension WHERE extname = '([^']*)'\)`), func(s *Server, c *conn, m []string, args []driver.Value) (*rows, error) {
+		d, err := s.currentDatabase(c)
+		if err != nil {
+			return nil, err
+		}
+		_, ok := d.Extensions[m[1]]
+		return row(ok), nil
+	}},
+	{q(`SELECT EXISTS \(SELECT 1 FROM pg_available_extension_versions WHERE name = \$1 AND version = \$2\)`), func(s *Server, c *conn, m []string, args []driver.Value) (*rows, error) {
+		return row(true), nil
+	}},
+	{q(`SELECT name, COALESCE\(installed_version, ''\), COALESCE\(default_version, ''\) FROM pg_available_extensions WHERE name = ANY\(\$1\)`), func(s *Server, c *conn, m []string, args []driver.Value) (*rows, error) {
+		d, err := s.currentDatabase(c)
+		if err != nil {
+			return nil, err
+		}
+		result := &rows{columns: []string{"name", "installed_version", "default_version"}}
+		for _, name := range textArray(arg(args, 0)) {
+			installed := d.Extensions[name]
+			available := installed
+			if available == "" {
+				available = "1.0"
+			}
+			result.values = append(result.values, []driver.Value{name, installed, available})
+		}
+		return result, nil
+	}},
+	{q(`SELECT pg_get_userbyid\(datdba\) FROM pg_database WHERE datname = \$1`), func(s *Server, c *conn, m []string, args []driver.Value) (*rows, error) {
+		return databaseRow(s, args, func(d *Database) []driver.Value { return []driver.Value{d.Owner} }), nil
+	}},
+	{q(`SELECT pg_get_userbyid\(datdba\), pg_encoding_to_char\(encoding\), datcollate FROM pg_database WHERE datname = \$1`), func(s *Server, c *conn, m []string, args []driver.Value) (*rows, error) {
+		return databaseRow(s, args, func(d *Database) []driver.Value { return []driver.Value{d.Owner, "UTF8", "en_US.UTF-8"} }), nil
+	}},
+	{q(`SELECT datconnlimit FROM pg_database WHERE datname = \$1`), func(s *Server, c *conn, m []string, args []driver.Value) (*rows, error) {
+		return databaseRow(s, args, func(d *Database) []driver.Value { return []driver.Value{int64(d.ConnLimit)} }), nil
+	}},
+	{q(`SELECT COALESCE\(shobj_description\(oid, 'pg_database'\), ''\) FROM pg_database WHERE datname = \$1`), func(s *Server, c *conn, m []string, args []driver.Value) (*rows, error) {
+		return databaseRow(s, args, func(d *Database) []driver.Value { return []driver.Value{d.Comment} }), nil
+	}},
+	{q(`SELECT has_database_privilege\('public', datname, 'CONNECT'\) OR has_database_privilege\('public', datname, 'TEMPORARY'\) FROM pg_database WHERE datname = \$1`), func(s *Server, c *conn, m []string, args []driver.Value) (*rows, error) {
+		return databaseRow(s, args, func(d *Database) []driver.Value { return []driver.Value{d.PublicConnect} }), nil
+	}},
+	{q(`SELECT t.spcname FROM pg_database d JOIN pg_tablespace t ON t.oid = d.dattablespace WHERE d.datname = \$1`), func(s *Server, c *conn, m []string, args []driver.Value) (*rows, error) {
+		return databaseRow(s, args, func(d *Database) []driver.Value { return []driver.Value{d.Tablespace} }), nil
+	}},
+	{q(`SELECT pg_database_size\(datname\), .* FROM pg_database WHERE datname = \$1`), func(s *Server, c *conn, m []string, args []driver.Value) (*rows, error) {
+		return databaseRow(s, args, func(d *Database) []driver.Value { return []driver.Value{int64(8192), int64(0), nil} }), nil
+	}},
+	{q(`SELECT datname, shobj_description\(oid, 'pg_database'\) FROM pg_database WHERE shobj_description\(oid, 'pg_database'\) LIKE '\{%'`), func(s *Server, c *conn, m []string, args []driver.Value) (*rows, error) {
+		result := &rows{columns: []string{"datname", "shobj_description"}}
+		for name, d := range s.databases {
+			if strings.HasPrefix(d.Comment, "{") {
+				result.values = append(result.values, []driver.Value{name, d.Comment})
+			}
+		}
+		return result, nil
+	}},
+	{q(`SELECT rolname, shobj_description\(oid, 'pg_authid'\) FROM pg_roles WHERE shobj_description\(oid, 'pg_authid'\) LIKE '\{%'`), func(s *Server, c *conn, m []string, args []driver.Value) (*rows, error) {
+		result := &rows{columns: []string{"rolname", "shobj_description"}}
+		for name, r := range s.roles {
+			if strings.HasPrefix(r.Comment, "{") {
+				result.values = append(result.values, []driver.Value{name, r.Comment})
+			}
+		}
+		return result, nil
+	}},
+	{q(`SELECT COALESCE\(shobj_description\(oid, 'pg_authid'\), ''\) FROM pg_roles WHERE rolname = \$1`), func(s *Server, c *conn, m []string, args []driver.Value) (*rows, error) {
+		return roleRow(s, args, func(r *Role) []driver.Value { return []driver.Value{r.Comment} }), nil
+	}},
+	{q(`SELECT rolpassword FROM pg_authid WHERE rolname = \$1`), func(s *Server, c *conn, m []string, args []driver.Value) (*rows, error) {
+		return roleRow(s, args, func(r *Role) []driver.Value {
+			if r.Password == nil {
+				return []driver.Value{nil}
+			}
+			return []driver.Value{*r.Password}
+		}), nil
+	}},
+	{q(`SELECT (rol\w+(?:, rol\w+)*) FROM pg_roles WHERE rolname = \$1`), func(s *Server, c *conn, m []string, args []driver.Value) (*rows, error) {
+		columns := strings.Split(m[1], ", ")
+		for _, column := range columns {
+			if _, err := roleColumn(&Role{}, column); err != nil {
+				return nil, err
+			}
+		}
+		return roleRow(s, args, func(r *Role) []driver.Value {
+			values := make([]driver.Value, len(columns))
+			for i, column := range columns {
+				values[i], _ = roleColumn(r, column)
+			}
+			return values
+		}), nil
+	}},
+	{q(`SELECT has_schema_privilege\('public', 'public', 'USAGE'\) OR has_schema_privilege\('public', 'public', 'CREATE'\) FROM pg_namespace WHERE nspname = 'public'`), func(s *Server, c *conn, m []string, args []driver.Value) (*rows, error) {
+		d, err := s.currentDatabase(c)
+		if err != nil {
+			return nil, err
+		}
+		return row(d.PublicSchema), nil
+	}},
+	{q(`SELECT pg_get_userbyid\(nspowner\) FROM pg_namespace WHERE nspname = \$1`), func(s *Server, c *conn, m []string, args []driver.Value) (*rows, error) {
+		d, err := s.currentDatabase(c)
+		if err != nil {
+			return nil, err
+		}
+		if owner, ok := d.Schemas[arg(args, 0)]; ok {
+			return row(owner), nil
+		}
+		return &rows{columns: []string{"pg_get_userbyid"}}, nil
+	}},
+	{q(`SELECT to_regclass\(\$1\) IS NOT NULL`), func(s *Server, c *conn, m []string, args []driver.Value) (*rows, error) {
+		d, err := s.currentDatabase(c)
+		if err != nil {
+			return nil, err
+		}
+		return row(d.Tables[arg(args, 0)]), nil
+	}},
+	{q(`SELECT version, dirty FROM (\S+) LIMIT 1`), func(s *Server, c *conn, m []string, args []driver.Value) (*rows, error) {
+		d, err := s.currentDatabase(c)
+		if err != nil {
+			return nil, err
+		}
+		if !d.Tables[m[1]] {
+			return nil, pgError("42P01", "relation %q does not exist", m[1])
+		}
+		result := &rows{columns: []string{"version", "dirty"}}
+		if version, ok := d.Migrations[m[1]]; ok {
+			result.values = append(result.values, []driver.Value{version, false})
+		}
+		return result, nil
+	}},
+}
+
+// query answers query, with its arguments args, on the database of c. s.mu
+// must be held.
+func (s *Server) query(c *conn, text string, args []driver.Value) (driver.Rows, error) {
+	text = strings.Join(strings.Fields(text), " ")
+	for _, known := range queries {
+		if m := known.pattern.FindStringSubmatch(text); m != nil {
+			return known.answer(s, c, m, args)
+		}
+	}
+	return &rows{}, nil
+}
+
+// row returns the single row of values.
+func row(values ...driver.Value) *rows {
+	columns := make([]string, len(values))
+	for i := range values {
+		columns[i] = fmt.Sprintf("column%d", i+1)
+	}
+	return &rows{columns: columns, values: [][]driver.Value{values}}
+}
+
+// databaseRow returns the row of the database named by the first argument,
+// no row when it is missing.
+func databaseRow(s *Server, args []driver.Value, values func(*Database) []driver.Value) *rows {
+	if d, ok := s.databases[arg(args, 0)]; ok {
+		return row(values(d)...)
+	}
+	return &rows{columns: []string{"column1"}}
+}
+
+// roleRow returns the row of the role named by the first argument, no row
+// when it is missing.
+func roleRow(s *Server, args []driver.Value, values func(*Role) []driver.Value) *rows {
+	if r, ok := s.roles[arg(args, 0)]; ok {
+		return row(values(r)...)
+	}
+	return &rows{columns: []string{"column1"}}
+}
+
+// roleAttributeColumns are the pg_roles columns of the role attributes, by
+// the keyword of Role.Attributes.
+var roleAttributeColumns = map[string]string{
+	"rolsuper":       "superuser",
+	"rolinherit":     "inherit",
+	"rolcreaterole":  "createrole",
+	"rolcreatedb":    "createdb",
+	"rolreplication": "replication",
+	"rolbypassrls":   "bypassrls",
+}
+
+// roleColumn returns the value of the pg_roles column of r.
+func roleColumn(r *Role, column string) (driver.Value, error) {
+	switch column {
+	case "rolname":
+		return r.Name, nil
+	case "rolcanlogin":
+		return r.Login, nil
+	case "rolconnlimit":
+		return int64(r.ConnLimit), nil
+	case "rolvaliduntil":
+		if r.ValidUntil == nil {
+			return nil, nil
+		}
+		return *r.ValidUntil, nil
+	}
+	if keyword, ok := roleAttributeColumns[column]; ok {
+		return r.Attributes[keyword], nil
+	}
+	return nil, pgError("42703", "column %q does not exist", column)
+}
+
+// arg returns the text of the argument i, empty when missing.
+func arg(args []driver.Value, i int) string {
+	if i >= len(args) || args[i] == nil {
+		return ""
+	}
+	if b, ok := args[i].([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(args[i])
+}
+
+// textArray returns the elements of the array literal text, e.g. {a,"b c"}.
+func textArray(text string) []string {
+	text = strings.TrimSuffix(strings.TrimPrefix(text, "{"), "}")
+	if text == "" {
+		return nil
+	}
+	var elements []string
+	for _, element := range strings.Split(text, ",") {
+		if strings.HasPrefix(element, `"`) {
+			element = strings.Replace(strings.Trim(element, `"`), `\"`, `"`, -1)
+		}
+		elements = append(elements, element)
+	}
+	return elements
+}
@@ -0,0 +1,132 @@
+package provisioner
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	// PasswordEncryptionMD5 hashes passwords into md5 verifiers.
+	PasswordEncryptionMD5 = "md5"
+	// PasswordEncryptionScram hashes passwords into SCRAM-SHA-256 verifiers,
+	// supported by PostgreSQL 10+.
+	PasswordEncryptionScram = "scram-sha-256"
+
+	// scramIterations matches the PostgreSQL default scram_iterations.
+	scramIterations = 4096
+)
+
+var (
+	scramVerifierPattern = regexp.MustCompile(`^SCRAM-SHA-256\$\d+:[A-Za-z0-9+/=]+\$[A-Za-z0-9+/=]+:[A-Za-z0-9+/=]+$`)
+	md5VerifierPattern   = regexp.MustCompile(`^md5[0-9a-f]{32}$`)
+)
+
+// IsPasswordVerifier reports whether password is an already hashed SCRAM or
+// md5 verifier, which PostgreSQL stores as is.
+func IsPasswordVerifier(password string) bool {
+	return scramVerifierPattern.MatchString(password) || md5VerifierPattern.MatchString(password)
+}
+
+// EncryptPassword returns the value of the PASSWORD clause setting password
+// on username. It is hashed here with method so the plaintext never reaches
+// the server, verifiers are passed through and with no method the server
+// applies its password_encryption setting.
+func EncryptPassword(username, password, method string) (string, error) {
+	if IsPasswordVerifier(password) {
+		return password, nil
+	}
+	switch method {
+	case "":
+		return password, nil
+	case PasswordEncryptionMD5:
+		sum := md5.Sum([]byte(password + username))
+		return "md5" + hex.EncodeToString(sum[:]), nil
+	case PasswordEncryptionScram:
+		return scramVerifier(password)
+	}
+	return "", fmt.Errorf("invalid password encryption %q, must be md5 or scram-sha-256", method)
+}
+
+// scramVerifier hashes password into a SCRAM-SHA-256 verifier as described in
+// RFC 7677, in the format of pg_authid.rolpassword.
+func scramVerifier(password string) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	salted := pbkdf2.Key([]byte(password), salt, scramIterations, sha256.Size, sha256.New)
+	storedKey := sha256.Sum256(scramHMAC(salted, "Client Key"))
+	serverKey := scramHMAC(salted, "Server Key")
+
+	enc := base64.StdEncoding
+	return fmt.Sprintf("SCRAM-SHA-256$%d:%s$%s:%s", scramIterations,
+		enc.EncodeToString(salt), enc.EncodeToString(storedKey[:]), enc.EncodeToString(serverKey)), nil
+}
+
+// PasswordMatches reports whether stored, the pg_authid.rolpassword of
+// username, is the one set with password: the same verifier when password
+// is one, otherwise its md5 or SCRAM-SHA-256 hash, or the plaintext stored
+// by servers without password encryption.
+func PasswordMatches(username, password, stored string) bool {
+	if IsPasswordVerifier(password) {
+		return stored == password
+	}
+	if md5VerifierPattern.MatchString(stored) {
+		sum := md5.Sum([]byte(password + username))
+		return stored == "md5"+hex.EncodeToString(sum[:])
+	}
+	if scramVerifierPattern.MatchString(stored) {
+		return scramMatches(stored, password)
+	}
+	return stored == password
+}
+
+// scramMatches reports whether verifier, as stored in pg_authid.rolpassword,
+// was derived from password.
+func scramMatches(verifier, password string) bool {
+	// SCRAM-SHA-256$<iterations>:<salt>$<storedKey>:<serverKey>
+	parts := strings.Split(strings.TrimPrefix(verifier, "SCRAM-SHA-256$"), "$")
+	params, keys := strings.SplitN(parts[0], ":", 2), strings.SplitN(parts[1], ":", 2)
+	iterations, err := strconv.Atoi(params[0])
+	if err != nil {
+		return false
+	}
+	enc := base64.StdEncoding
+	salt, err := enc.DecodeString(params[1])
+	if err != nil {
+		return false
+	}
+	storedKey, err := enc.DecodeString(keys[0])
+	if err != nil {
+		return false
+	}
+	salted := pbkdf2.Key([]byte(password), salt, iterations, sha256.Size, sha256.New)
+	clientKey := sha256.Sum256(scramHMAC(salted, "Client Key"))
+	return hmac.Equal(clientKey[:], storedKey)
+}
+
+func scramHMAC(key []byte, message string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(message))
+	return mac.Sum(nil)
+}
+
+// ValidUntil returns the VALID UNTIL clause of a password expiring in
+// expiry, empty when it doesn't expire.
+func ValidUntil(expiry time.Duration) string {
+	if expiry <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" VALID UNTIL %s", QuoteLiteral(time.Now().Add(expiry).UTC().Format(time.RFC3339)))
+}
@@ -0,0 +1,136 @@
+package provisioner
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Options describe the server a Provisioner runs its statements on.
+type Options struct {
+	// DatabaseOwner is set when the server supports CREATE DATABASE ...
+	// OWNER, as PostgreSQL does.
+	DatabaseOwner bool
+	// GrantRoleToAdmin grants the roles created to the admin role, as
+	// needed when it isn't a superuser.
+	GrantRoleToAdmin bool
+	// PasswordEncryption is the method passwords are hashed with before
+	// they are sent: md5 or scram-sha-256. The server applies its
+	// password_encryption setting when empty.
+	PasswordEncryption string
+	// PasswordExpiry is the time passwords are valid for once set. They
+	// don't expire when 0.
+	PasswordExpiry time.Duration
+}
+
+// Interface provisions databases and their owner roles. Provisioner
+// implements it on a server, the fakepg package on an in-memory fake for
+// tests.
+type Interface interface {
+	// CreateDatabase creates database owned by owner, created with
+	// password or given it when the role already exists. A database that
+	// already exists is handed over to owner.
+	CreateDatabase(ctx context.Context, database, owner, password string) error
+	// DropDatabase drops database, terminating its sessions first with
+	// force.
+	DropDatabase(ctx context.Context, database string, force bool) error
+	// DropRole drops username, which must not own objects anymore.
+	DropRole(ctx context.Context, username string) error
+	// RotatePassword sets the password of username.
+	RotatePassword(ctx context.Context, username, password string) error
+	// Grant grants role every privilege on database.
+	Grant(ctx context.Context, database, role string) error
+}
+
+// Provisioner runs the provisioning statements on the server of an admin
+// connection.
+type Provisioner struct {
+	db   *sql.DB
+	opts Options
+}
+
+var _ Interface = &Provisioner{}
+
+// New returns a Provisioner running its statements on db.
+func New(db *sql.DB, opts Options) *Provisioner {
+	return &Provisioner{db: db, opts: opts}
+}
+
+// CreateDatabase creates database owned by owner, created with password or
+// given it when the role already exists. A database that already exists is
+// handed over to owner, so CreateDatabase can be run again.
+func (p *Provisioner) CreateDatabase(ctx context.Context, database, owner, password string) error {
+	roleExists, err := p.exists(ctx, "SELECT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = $1)", owner)
+	if err != nil {
+		return err
+	}
+	verb := "CREATE USER"
+	if roleExists {
+		verb = "ALTER ROLE"
+	}
+	stmt, err := RolePasswordStatement(verb, owner, password, p.opts.PasswordEncryption, p.opts.PasswordExpiry)
+	if err != nil {
+		return err
+	}
+	stmts := []string{stmt}
+	if !roleExists {
+		stmts = append(stmts, CreateRoleStatements(owner, p.opts.GrantRoleToAdmin)...)
+	}
+
+	databaseExists, err := p.exists(ctx, "SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)", database)
+	if err != nil {
+		return err
+	}
+	if databaseExists {
+		stmts = append(stmts, ChangeOwnerStatements(database, owner, p.opts.DatabaseOwner)...)
+	} else {
+		stmts = append(stmts, CreateDatabaseStatements(database, owner, p.opts.DatabaseOwner)...)
+	}
+	return p.exec(ctx, stmts...)
+}
+
+// DropDatabase drops database, terminating its sessions first with force.
+func (p *Provisioner) DropDatabase(ctx context.Context, database string, force bool) error {
+	if force {
+		if err := p.exec(ctx, TerminateSessionsStatement(database)); err != nil {
+			return err
+		}
+	}
+	return p.exec(ctx, DropDatabaseStatement(database, false))
+}
+
+// DropRole drops username, which must not own objects anymore.
+func (p *Provisioner) DropRole(ctx context.Context, username string) error {
+	return p.exec(ctx, DropRoleStatement(username))
+}
+
+// RotatePassword sets the password of username.
+func (p *Provisioner) RotatePassword(ctx context.Context, username, password string) error {
+	stmt, err := RolePasswordStatement("ALTER ROLE", username, password, p.opts.PasswordEncryption, p.opts.PasswordExpiry)
+	if err != nil {
+		return err
+	}
+	return p.exec(ctx, stmt)
+}
+
+// Grant grants role every privilege on database.
+func (p *Provisioner) Grant(ctx context.Context, database, role string) error {
+	return p.exec(ctx, GrantStatement(database, role))
+}
+
+func (p *Provisioner) exists(ctx context.Context, query, name string) (bool, error) {
+	var exists bool
+	err := p.db.QueryRowContext(ctx, query, name).Scan(&exists)
+	return exists, err
+}
+
+// exec runs stmts one at a time, as CREATE DATABASE can't run in a
+// transaction.
+func (p *Provisioner) exec(ctx context.Context, stmts ...string) error {
+	for _, stmt := range stmts {
+		if _, err := p.db.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package provisioner
+
+import (
+	"strings"
+
+	"github.com/jackc/pgx"
+)
+
+// QuoteIdentifier returns name quoted as an identifier, e.g. a database or
+// role name, its double quotes doubled.
+func QuoteIdentifier(name string) string {
+	return pgx.Identifier{name}.Sanitize()
+}
+
+// QuoteLiteral returns literal quoted as a string constant, its single
+// quotes doubled. A literal holding backslashes is written as an escape
+// string, so it reads the same whatever standard_conforming_strings is.
+func QuoteLiteral(literal string) string {
+	literal = strings.Replace(literal, `'`, `''`, -1)
+	if strings.Contains(literal, `\`) {
+		return ` E'` + strings.Replace(literal, `\`, `\\`, -1) + `'`
+	}
+	return `'` + literal + `'`
+}
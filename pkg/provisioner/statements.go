@@ -0,0 +1,98 @@
+// Package provisioner generates, and runs, the statements the controller
+// provisions databases and their roles with, independent of Kubernetes.
+//
+// Database and role names are used as is in the statements: they must be
+// valid identifiers, quoted by the caller when needed.
+package provisioner
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RolePasswordStatement returns the statement creating, with verb CREATE
+// USER, or altering, with verb ALTER ROLE, username with password stored
+// using method. The password expires in expiry unless it is 0. An empty
+// password sets none, the role then only logging in with other methods.
+func RolePasswordStatement(verb, username, password, method string, expiry time.Duration) (string, error) {
+	if password == "" {
+		return fmt.Sprintf("%s %s WITH PASSWORD NULL", verb, username), nil
+	}
+	encrypted, err := EncryptPassword(username, password, method)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s %s WITH PASSWORD '%s'%s", verb, username, strings.Replace(encrypted, "'", "''", -1), ValidUntil(expiry)), nil
+}
+
+// CreateRoleStatements returns the statements run once username has been
+// created. With grantToAdmin the role is granted to the admin role, which
+// must be a member of it to hand databases over to it when it isn't a
+// superuser.
+func CreateRoleStatements(username string, grantToAdmin bool) []string {
+	if !grantToAdmin {
+		return nil
+	}
+	return []string{fmt.Sprintf("GRANT %s TO CURRENT_USER", username)}
+}
+
+// CreateDatabaseStatements returns the statements creating database owned
+// by owner. Without withOwner, for servers lacking CREATE DATABASE ... OWNER,
+// owner is granted every privilege on it instead.
+func CreateDatabaseStatements(database, owner string, withOwner bool) []string {
+	if withOwner {
+		return []string{fmt.Sprintf("CREATE DATABASE %s OWNER %s", database, owner)}
+	}
+	return []string{
+		fmt.Sprintf("CREATE DATABASE %s", database),
+		GrantStatement(database, owner),
+	}
+}
+
+// ChangeOwnerStatements returns the statements handing the existing
+// database over to owner, granting it every privilege without withOwner.
+func ChangeOwnerStatements(database, owner string, withOwner bool) []string {
+	if withOwner {
+		return []string{fmt.Sprintf("ALTER DATABASE %s OWNER TO %s", database, owner)}
+	}
+	return []string{GrantStatement(database, owner)}
+}
+
+// GrantStatement returns the statement granting role every privilege on
+// database.
+func GrantStatement(database, role string) string {
+	return fmt.Sprintf("GRANT ALL ON DATABASE %s TO %s", database, role)
+}
+
+// DropDatabaseStatement returns the statement dropping database, along with
+// its sessions with force, which requires PostgreSQL 13+.
+func DropDatabaseStatement(database string, force bool) string {
+	if force {
+		return fmt.Sprintf("DROP DATABASE %s WITH (FORCE)", database)
+	}
+	return fmt.Sprintf("DROP DATABASE %s", database)
+}
+
+// TerminateSessionsStatement returns the statement terminating the sessions
+// connected to database, other than the one running it.
+func TerminateSessionsStatement(database string) string {
+	return fmt.Sprintf("SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname = %s AND pid <> pg_backend_pid()", QuoteLiteral(database))
+}
+
+// ReassignOwnedStatement returns the statement handing the objects username
+// owns in the current database, and the shared ones, over to role.
+func ReassignOwnedStatement(username, role string) string {
+	return fmt.Sprintf("REASSIGN OWNED BY %s TO %s", username, role)
+}
+
+// DropOwnedStatement returns the statement dropping the objects username owns
+// in the current database and revoking its privileges there.
+func DropOwnedStatement(username string) string {
+	return fmt.Sprintf("DROP OWNED BY %s", username)
+}
+
+// DropRoleStatement returns the statement dropping username.
+func DropRoleStatement(username string) string {
+	return fmt.Sprintf("DROP ROLE %s", username)
+}
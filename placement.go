@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/rs/zerolog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+const (
+	// placementDatabases places Databases on the instance holding the fewest.
+	placementDatabases = "databases"
+	// placementSize places Databases on the instance whose databases use the
+	// least disk space, as last collected.
+	placementSize = "size"
+)
+
+// needsPlacement reports whether the PostgresInstance of dbResource is yet to
+// be picked per its spec.placement.
+func needsPlacement(dbResource *v1.Database) bool {
+	return dbResource.Spec.Placement != nil && dbResource.Spec.Instance == "" && dbResource.Status.Instance == "" &&
+		dbResource.DeletionTimestamp == nil
+}
+
+// placeDatabase picks the PostgresInstance of a new dbResource with
+// spec.placement among the ones of its namespace matching the selector and
+// below their maxDatabases, the least loaded per the placement strategy, and
+// records it in status.instance. Ties go to the first instance by name.
+func (c *Controller) placeDatabase(logger zerolog.Logger, dbResource *v1.Database) error {
+	placement := dbResource.Spec.Placement
+	selector := labels.Everything()
+	if placement.InstanceSelector != nil {
+		var err error
+		if selector, err = metav1.LabelSelectorAsSelector(placement.InstanceSelector); err != nil {
+			return fmt.Errorf("invalid spec.placement.instanceSelector: %s", err.Error())
+		}
+	}
+	var load func(*v1.Database) int64
+	switch placement.Strategy {
+	case "", placementDatabases:
+		load = func(*v1.Database) int64 { return 1 }
+	case placementSize:
+		load = func(db *v1.Database) int64 {
+			if db.Status.Usage == nil {
+				return 0
+			}
+			return db.Status.Usage.SizeBytes
+		}
+	default:
+		return fmt.Errorf("unknown placement strategy %q, must be databases or size", placement.Strategy)
+	}
+
+	pgInstances, err := c.instances.InstancesLister.PostgresInstances(dbResource.Namespace).List(selector)
+	if err != nil {
+		return err
+	}
+	if len(pgInstances) == 0 {
+		return fmt.Errorf("no PostgresInstance matches spec.placement")
+	}
+	dbResources, err := c.DatabasesLister.Databases(dbResource.Namespace).List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	loads := map[string]int64{}
+	counts := map[string]int32{}
+	for _, db := range dbResources {
+		if name := instanceName(db); name != "" && instanceNamespace(db) == dbResource.Namespace {
+			loads[name] += load(db)
+			counts[name]++
+		}
+	}
+
+	sort.Slice(pgInstances, func(i, j int) bool { return pgInstances[i].Name < pgInstances[j].Name })
+	picked := ""
+	for _, pgInstance := range pgInstances {
+		if max := pgInstance.Spec.MaxDatabases; max != nil && counts[pgInstance.Name] >= *max {
+			continue
+		}
+		if picked == "" || loads[pgInstance.Name] < loads[picked] {
+			picked = pgInstance.Name
+		}
+	}
+	if picked == "" {
+		return fmt.Errorf("every PostgresInstance matching spec.placement holds its maxDatabases")
+	}
+
+	logger.Info().Str("instance", picked).Msg("placing database")
+	dbCopy := dbResource.DeepCopy()
+	dbCopy.Status.Instance = picked
+	if err := c.updateStatus(dbCopy); err != nil {
+		return err
+	}
+	c.recorder.Event(dbResource, corev1.EventTypeNormal, "Placed", fmt.Sprintf("Database placed on instance %s", picked))
+	return nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+)
+
+// generatePassword returns a random, URL safe password suitable for roles the
+// controller creates on its own behalf, following the password policy.
+func generatePassword() (string, error) {
+	var err error
+	for attempt := 0; attempt < 10; attempt++ {
+		b := make([]byte, 24)
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		password := base64.RawURLEncoding.EncodeToString(b)
+		if err = currentPasswordPolicy.check(password); err == nil {
+			return password, nil
+		}
+	}
+	return "", fmt.Errorf("error generating a password: %s", err.Error())
+}
+
+// databaseExists reports whether a database called name exists on the server.
+func databaseExists(db *sql.DB, name string) (bool, error) {
+	var exists bool
+	err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)", name).Scan(&exists)
+	return exists, err
+}
+
+// roleExists reports whether a role called name exists on the server.
+func roleExists(db *sql.DB, name string) (bool, error) {
+	var exists bool
+	err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = $1)", name).Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/rs/zerolog"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+// rotationPending reports whether a password rotation of dbResource was
+// requested with the rotate annotation and not carried out yet.
+func rotationPending(dbResource *v1.Database) bool {
+	return dbResource.Annotations[rotateAnnotation] != dbResource.Status.RotateRequest
+}
+
+// priorityDatabase reports whether the reconcile of dbResource goes ahead of
+// the bulk of provisioning: password rotations are security sensitive and
+// shouldn't wait behind a flood of new Databases.
+func priorityDatabase(dbResource *v1.Database) bool {
+	return rotationPending(dbResource)
+}
+
+// keyLocks serializes the reconciles of a key, which may be queued on both
+// the work queue and the priority queue at once.
+type keyLocks struct {
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+type keyLock struct {
+	sync.Mutex
+	// waiters counts the reconciles holding or waiting for the lock, it is
+	// removed once none are left.
+	waiters int
+}
+
+// lock waits until no other reconcile of key runs and returns the function
+// releasing it.
+func (k *keyLocks) lock(key string) func() {
+	k.mu.Lock()
+	if k.locks == nil {
+		k.locks = map[string]*keyLock{}
+	}
+	l, ok := k.locks[key]
+	if !ok {
+		l = &keyLock{}
+		k.locks[key] = l
+	}
+	l.waiters++
+	k.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		k.mu.Lock()
+		defer k.mu.Unlock()
+		if l.waiters--; l.waiters == 0 {
+			delete(k.locks, key)
+		}
+	}
+}
+
+// syncExclusive runs syncHandler for key once no reconcile of the same key
+// runs from the other queue.
+func (c *Controller) syncExclusive(ctx context.Context, logger zerolog.Logger, key string) error {
+	unlock := c.keyLocks.lock(key)
+	defer unlock()
+	ctx, end := startReconcileSpan(ctx, key)
+	err := c.syncHandler(ctx, logger, key)
+	end(err)
+	return err
+}
+
+// startPriorityWorkers starts the --priority-workers, which only serve the
+// priority queue so rotations are not held up by the provisioning workers
+// being busy.
+func (c *Controller) startPriorityWorkers() {
+	for i := 0; i < priorityWorkers; i++ {
+		c.workers.Add(1)
+		go func() {
+			defer c.workers.Done()
+			for processNextWorkItem(c.ctx, c.priorityQueue, c.syncExclusive) {
+			}
+		}()
+	}
+}
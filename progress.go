@@ -0,0 +1,37 @@
+package main
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+// progress returns the provisioning steps dbResource completed, none when it
+// isn't being provisioned.
+func progress(dbResource *v1.Database) v1.ProvisioningProgress {
+	if dbResource.Status.Progress == nil {
+		return v1.ProvisioningProgress{}
+	}
+	return *dbResource.Status.Progress
+}
+
+// resuming reports whether an earlier attempt to provision dbResource started
+// running statements, so the database and role found were created by it.
+func resuming(dbResource *v1.Database) bool {
+	return dbResource.Status.Progress != nil
+}
+
+// recordProgress marks a provisioning step of dbResource with step in its
+// status, along with the rest of the status set so far, returning the
+// updated Database the next steps run with. Nothing is recorded in dry run.
+func (c *Controller) recordProgress(dbResource *v1.Database, exec *sqlExecutor, step func(*v1.ProvisioningProgress)) (*v1.Database, error) {
+	if exec.dryRun {
+		return dbResource, nil
+	}
+	dbCopy := dbResource.DeepCopy()
+	if dbCopy.Status.Progress == nil {
+		dbCopy.Status.Progress = &v1.ProvisioningProgress{StartedTime: metav1.Now()}
+	}
+	step(dbCopy.Status.Progress)
+	return c.writeStatus(dbCopy)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/rs/zerolog"
+	corev1 "k8s.io/api/core/v1"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+const (
+	// protectedAnnotation set to "true" keeps the database of a Database
+	// from being dropped: deleting the Database waits on
+	// protectionFinalizer until the annotation is removed.
+	protectedAnnotation = "postgresql.org/protected"
+	// protectionFinalizer is set on protected Databases only, the others
+	// are dropped once gone.
+	protectionFinalizer = "postgresql.org/protection"
+	// deletionBlockedCondition is True while the deletion of a protected
+	// Database waits for the annotation to be removed.
+	deletionBlockedCondition = "DeletionBlocked"
+)
+
+// protected reports whether dbResource carries the protected annotation.
+func protected(dbResource *v1.Database) bool {
+	return dbResource.Annotations[protectedAnnotation] == "true"
+}
+
+// hasFinalizer reports whether dbResource carries the finalizer called name.
+func hasFinalizer(dbResource *v1.Database, name string) bool {
+	for _, finalizer := range dbResource.Finalizers {
+		if finalizer == name {
+			return true
+		}
+	}
+	return false
+}
+
+// syncProtection adds the protection finalizer to protected Databases and
+// removes it from the others, letting the deletion of a Database whose
+// annotation was removed go ahead. It returns true when dbResource must not
+// be reconciled further: it was updated, the next reconcile running with the
+// new finalizers, or it is being deleted. The blocked deletion of a protected
+// Database is reported with the DeletionBlocked condition.
+func (c *Controller) syncProtection(logger zerolog.Logger, dbResource *v1.Database) (bool, error) {
+	deleting := dbResource.DeletionTimestamp != nil
+	switch {
+	case protected(dbResource) && !deleting && !hasFinalizer(dbResource, protectionFinalizer):
+		dbCopy := dbResource.DeepCopy()
+		dbCopy.Finalizers = append(dbCopy.Finalizers, protectionFinalizer)
+		_, err := c.databaseClientset.DatabasesV1().Databases(dbResource.Namespace).Update(dbCopy)
+		return err == nil, err
+	case !protected(dbResource) && hasFinalizer(dbResource, protectionFinalizer):
+		dbCopy := dbResource.DeepCopy()
+		dbCopy.Finalizers = nil
+		for _, finalizer := range dbResource.Finalizers {
+			if finalizer != protectionFinalizer {
+				dbCopy.Finalizers = append(dbCopy.Finalizers, finalizer)
+			}
+		}
+		if deleting {
+			logger.Info().Msg("protection removed, deleting database")
+		}
+		_, err := c.databaseClientset.DatabasesV1().Databases(dbResource.Namespace).Update(dbCopy)
+		return err == nil, err
+	case !deleting:
+		return false, nil
+	}
+
+	message := fmt.Sprintf("Database is protected, remove the %s annotation to drop it", protectedAnnotation)
+	dbCopy := dbResource.DeepCopy()
+	if !setCondition(&dbCopy.Status, deletionBlockedCondition, conditionTrue, "Protected", message) {
+		return true, nil
+	}
+	logger.Warn().Msg("deletion of protected database blocked")
+	c.recorder.Event(dbResource, corev1.EventTypeWarning, "DeletionBlocked", message)
+	return true, c.updateStatus(dbCopy)
+}
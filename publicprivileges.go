@@ -0,0 +1,69 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+// revokePublic reports whether the privileges of PUBLIC on the database of
+// dbResource are revoked, per spec.revokePublic or else --revoke-public.
+func revokePublic(dbResource *v1.Database) bool {
+	if dbResource.Spec.RevokePublic != nil {
+		return *dbResource.Spec.RevokePublic
+	}
+	return revokePublicDefault
+}
+
+// syncPublicPrivileges revokes the CONNECT and TEMPORARY privileges PUBLIC
+// holds by default on the database of dbResource, then its USAGE and CREATE
+// on the public schema, while the server reports PUBLIC still holds them.
+// The roles of the Database are granted CONNECT by name, so they are not
+// affected. Privileges are left as they are when revokePublic is turned off,
+// and shared databases of schema mode are not up to a single tenant.
+func (c *Controller) syncPublicPrivileges(dbResource *v1.Database, inst *instance, exec *sqlExecutor) error {
+	if !revokePublic(dbResource) || schemaMode(dbResource) || !inst.dialect.publicPrivileges {
+		return nil
+	}
+	database := databaseName(dbResource)
+
+	var onDatabase bool
+	err := inst.DB.QueryRow(`SELECT has_database_privilege('public', datname, 'CONNECT') OR has_database_privilege('public', datname, 'TEMPORARY')
+		FROM pg_database WHERE datname = $1`, database).Scan(&onDatabase)
+	if err == sql.ErrNoRows && exec.dryRun {
+		// the database is not created in dry run
+		onDatabase = true
+	} else if err != nil {
+		return err
+	}
+	if onDatabase {
+		if err := exec.Exec(inst.DB, fmt.Sprintf("REVOKE ALL ON DATABASE %s FROM PUBLIC", database)); err != nil {
+			return fmt.Errorf("error revoking PUBLIC privileges on the database: %s", err.Error())
+		}
+	}
+
+	db, err := inst.openDatabase(database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	var onSchema bool
+	err = db.QueryRow(`SELECT has_schema_privilege('public', 'public', 'USAGE') OR has_schema_privilege('public', 'public', 'CREATE')
+		FROM pg_namespace WHERE nspname = 'public'`).Scan(&onSchema)
+	if err == sql.ErrNoRows {
+		// databases created without a public schema have nothing to revoke
+		return nil
+	} else if err != nil {
+		if !exec.dryRun {
+			return err
+		}
+		onSchema = true
+	}
+	if onSchema {
+		if err := exec.Exec(db, "REVOKE ALL ON SCHEMA public FROM PUBLIC"); err != nil {
+			return fmt.Errorf("error revoking PUBLIC privileges on the public schema: %s", err.Error())
+		}
+	}
+	return nil
+}
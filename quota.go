@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/runtime"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+// quotaExceededCondition is set on Databases left unprovisioned because a
+// DatabaseQuota, or --max-databases, is reached.
+const quotaExceededCondition = "QuotaExceeded"
+
+// countProvisioned returns the number of provisioned Databases, other than
+// exclude, matching covered.
+func countProvisioned(dbResources []*v1.Database, exclude *v1.Database, covered func(*v1.Database) bool) int32 {
+	var used int32
+	for _, dbResource := range dbResources {
+		if dbResource.Status.State == "provisioned" && dbResource.UID != exclude.UID && covered(dbResource) {
+			used++
+		}
+	}
+	return used
+}
+
+// quotaCovers tells whether quota applies to dbResource.
+func quotaCovers(quota *v1.DatabaseQuota, dbResource *v1.Database) bool {
+	return dbResource.Namespace == quota.Namespace &&
+		(quota.Spec.Instance == "" || quota.Spec.Instance == instanceName(dbResource))
+}
+
+// checkQuota returns why provisioning dbResource would exceed a quota, empty
+// when it fits in every quota covering it.
+// Quotas are checked before provisioning so Databases being provisioned
+// concurrently may both fit in the last slot.
+func (c *Controller) checkQuota(dbResource *v1.Database) (string, error) {
+	dbResources, err := c.DatabasesLister.List(labels.Everything())
+	if err != nil {
+		return "", err
+	}
+
+	if max := getSettings().MaxDatabases; max > 0 && instanceName(dbResource) == "" {
+		onDefault := func(db *v1.Database) bool { return instanceName(db) == "" }
+		if used := countProvisioned(dbResources, dbResource, onDefault); used >= int32(max) {
+			return fmt.Sprintf("the default server already holds %d of its %d databases", used, max), nil
+		}
+	}
+
+	quotas, err := c.QuotasLister.DatabaseQuotas(dbResource.Namespace).List(labels.Everything())
+	if err != nil {
+		return "", err
+	}
+	for _, quota := range quotas {
+		if !quotaCovers(quota, dbResource) {
+			continue
+		}
+		used := countProvisioned(dbResources, dbResource, func(db *v1.Database) bool { return quotaCovers(quota, db) })
+		if used >= quota.Spec.MaxDatabases {
+			return fmt.Sprintf("DatabaseQuota %q allows %d databases and %d are provisioned", quota.Name, quota.Spec.MaxDatabases, used), nil
+		}
+	}
+	return "", nil
+}
+
+// syncQuotaUsage records in the status of every DatabaseQuota the number of
+// Databases it covers.
+func (c *Controller) syncQuotaUsage() {
+	dbResources, err := c.DatabasesLister.List(labels.Everything())
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	quotas, err := c.QuotasLister.List(labels.Everything())
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	for _, quota := range quotas {
+		used := countProvisioned(dbResources, &v1.Database{}, func(db *v1.Database) bool { return quotaCovers(quota, db) })
+		if used == quota.Status.Used {
+			continue
+		}
+		quotaCopy := quota.DeepCopy()
+		quotaCopy.Status.Used = used
+		if _, err := c.databaseClientset.DatabasesV1().DatabaseQuotas(quota.Namespace).Update(quotaCopy); err != nil {
+			runtime.HandleError(fmt.Errorf("error updating usage of quota %s/%s: %s", quota.Namespace, quota.Name, err.Error()))
+		}
+	}
+}
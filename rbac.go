@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// permission is a set of verbs the controller needs on a resource, or
+// resource/subresource, in every namespace.
+type permission struct {
+	group    string
+	resource string
+	verbs    []string
+}
+
+// requiredPermissions are the permissions the informers and reconciles rely
+// on.
+var requiredPermissions = []permission{
+	{"", "secrets", []string{"get", "list", "watch", "create", "update"}},
+	{"", "configmaps", []string{"get", "list", "watch", "create", "update", "delete"}},
+	{"", "events", []string{"create", "patch"}},
+	{"", "pods", []string{"list"}},
+	{"", "pods/log", []string{"get"}},
+	{"", "namespaces", []string{"get", "list", "watch"}},
+	{"batch", "jobs", []string{"get", "list", "watch", "create", "delete"}},
+	{"postgresql.org", "databases", []string{"get", "list", "watch", "create", "update", "delete"}},
+	{"postgresql.org", "databases/status", []string{"update"}},
+	{"postgresql.org", "databasebackups", []string{"get", "list", "watch", "create", "update", "delete"}},
+	{"postgresql.org", "databaserestores", []string{"get", "list", "watch", "update"}},
+	{"postgresql.org", "publications", []string{"get", "list", "watch", "update"}},
+	{"postgresql.org", "subscriptions", []string{"get", "list", "watch", "update"}},
+	{"postgresql.org", "postgresinstances", []string{"get", "list", "watch"}},
+	{"postgresql.org", "databasequotas", []string{"get", "list", "watch", "update"}},
+	{"postgresql.org", "postgresparameters", []string{"get", "list", "watch", "update"}},
+	{"postgresql.org", "tablespaces", []string{"get", "list", "watch", "update"}},
+	{"postgresql.org", "databasesets", []string{"get", "list", "watch", "update"}},
+	{"postgresql.org", "foreignservers", []string{"get", "list", "watch", "update"}},
+	{"postgresql.org", "usermappings", []string{"get", "list", "watch", "update"}},
+	{"postgresql.org", "databaseclasses", []string{"get", "list", "watch"}},
+	{"postgresql.org", "physicalbackups", []string{"get", "list", "watch", "update"}},
+}
+
+// podExecPermissions are needed on top of requiredPermissions by
+// --allow-pod-exec, for the PhysicalBackups run with exec.
+var podExecPermissions = []permission{
+	{"", "pods", []string{"get"}},
+	{"", "pods/exec", []string{"create"}},
+}
+
+// crdPermission is needed on top of requiredPermissions by --install-crds.
+var crdPermission = permission{"apiextensions.k8s.io", "customresourcedefinitions", []string{"get", "create", "update"}}
+
+// checkPermissions asks the API server whether the controller has every
+// permission it needs, returning an error listing the missing ones so a
+// misconfigured RBAC fails at startup rather than in the informers.
+func checkPermissions(kubeClient kubernetes.Interface, installCRDs bool) error {
+	permissions := requiredPermissions
+	if installCRDs {
+		permissions = append(permissions, crdPermission)
+	}
+	if apiAddr != "" {
+		permissions = append(permissions, apiPermissions...)
+	}
+	if webhookAddr != "" {
+		permissions = append(permissions, webhookPermissions...)
+	}
+	if allowPodExec {
+		permissions = append(permissions, podExecPermissions...)
+	}
+
+	var missing []string
+	for _, p := range permissions {
+		resource, subresource := p.resource, ""
+		if i := strings.Index(resource, "/"); i >= 0 {
+			resource, subresource = resource[:i], resource[i+1:]
+		}
+		for _, verb := range p.verbs {
+			review := &authorizationv1.SelfSubjectAccessReview{
+				Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+					ResourceAttributes: &authorizationv1.ResourceAttributes{
+						Group:       p.group,
+						Resource:    resource,
+						Subresource: subresource,
+						Verb:        verb,
+					},
+				},
+			}
+			result, err := kubeClient.AuthorizationV1().SelfSubjectAccessReviews().Create(review)
+			if err != nil {
+				return fmt.Errorf("error checking permissions: %s", err.Error())
+			}
+			if !result.Status.Allowed {
+				resource := p.resource
+				if p.group != "" {
+					resource += "." + p.group
+				}
+				missing = append(missing, verb+" "+resource)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing permissions: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+// readOnlyUsername returns the name of the read-only companion role of username.
+func readOnlyUsername(username string) string {
+	return username + "_ro"
+}
+
+// provisionReadOnlyUser creates the <username>_ro role for dbResource, grants
+// it SELECT on every existing and future table in the public schema, or its
+// own schema in schema mode, and
+// stores its credentials in the <name>-ro Secret. An existing role is given a
+// new password, so it can be run again after a failed attempt.
+func (c *Controller) provisionReadOnlyUser(dbResource *v1.Database, inst *instance, exec *sqlExecutor) error {
+	username := readOnlyUsername(roleName(dbResource))
+	database := databaseName(dbResource)
+
+	password, err := generatePassword()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := upsertRoleStatement(inst.DB, username, password, passwordEncryptionFor(dbResource))
+	if err != nil {
+		return err
+	}
+	roleStmts := []string{stmt, fmt.Sprintf("GRANT CONNECT ON DATABASE %s TO %s", database, username)}
+	if err := exec.ExecDDL(inst, roleStmts); err != nil {
+		return fmt.Errorf("error creating read-only user: %s", err.Error())
+	}
+
+	db, err := inst.openDatabase(database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	schema := tenantSchema(dbResource)
+	stmts := []string{
+		fmt.Sprintf("GRANT USAGE ON SCHEMA %s TO %s", schema, username),
+		fmt.Sprintf("GRANT SELECT ON ALL TABLES IN SCHEMA %s TO %s", schema, username),
+	}
+	if inst.dialect.defaultPrivileges {
+		stmts = append(stmts, fmt.Sprintf("ALTER DEFAULT PRIVILEGES FOR ROLE %s IN SCHEMA %s GRANT SELECT ON TABLES TO %s", roleName(dbResource), schema, username))
+	}
+	for _, stmt := range stmts {
+		if err := exec.Exec(db, stmt); err != nil {
+			return fmt.Errorf("error granting read-only privileges: %s", err.Error())
+		}
+	}
+
+	if exec.dryRun {
+		return nil
+	}
+	return c.storeCredentials(dbResource, inst, dbResource.Name+readOnlySecretSuffix, username, password)
+}
+
+// rotateReadOnlyPassword sets a new generated password on the read-only role
+// of dbResource and stores it in the <name>-ro Secret.
+func (c *Controller) rotateReadOnlyPassword(dbResource *v1.Database, inst *instance, exec *sqlExecutor) error {
+	username := readOnlyUsername(roleName(dbResource))
+	password, err := generatePassword()
+	if err != nil {
+		return err
+	}
+	stmt, err := rolePasswordStatement("ALTER ROLE", username, password, passwordEncryptionFor(dbResource))
+	if err != nil {
+		return err
+	}
+	if err := exec.Exec(inst.DB, stmt); err != nil {
+		return fmt.Errorf("error rotating read-only password: %s", err.Error())
+	}
+
+	if exec.dryRun {
+		return nil
+	}
+	return c.storeCredentials(dbResource, inst, dbResource.Name+readOnlySecretSuffix, username, password)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// The codes of status.reason, for automation to branch on the outcome of a
+// provisioning rather than parse status.message.
+const (
+	reasonProvisioned        = "Provisioned"
+	reasonCloning            = "Cloning"
+	reasonDuplicateDatabase  = "DuplicateDatabase"
+	reasonInvalidIdentifier  = "InvalidIdentifier"
+	reasonConnectionRefused  = "ConnectionRefused"
+	reasonPermissionDenied   = "PermissionDenied"
+	reasonQuotaExceeded      = "QuotaExceeded"
+	reasonInvalidSpec        = "InvalidSpec"
+	reasonProvisioningFailed = "ProvisioningFailed"
+)
+
+// sqlStateRegexp matches the SQLSTATE code pgx appends to the messages of
+// the server errors.
+var sqlStateRegexp = regexp.MustCompile(`\(SQLSTATE ([0-9A-Z]{5})\)`)
+
+// sqlStateReasons classify the failed provisionings whose message carries a
+// server error, by SQLSTATE code or class, ahead of reasonPatterns.
+var sqlStateReasons = map[string]string{
+	"08":    reasonConnectionRefused, // connection_exception
+	"28":    reasonPermissionDenied,  // invalid_authorization_specification
+	"42501": reasonPermissionDenied,  // insufficient_privilege
+	"42P04": reasonDuplicateDatabase, // duplicate_database
+	"42710": reasonDuplicateDatabase, // duplicate_object
+	"42602": reasonInvalidIdentifier, // invalid_name
+	"42622": reasonInvalidIdentifier, // name_too_long
+	"22023": reasonInvalidSpec,       // invalid_parameter_value
+	"0A000": reasonInvalidSpec,       // feature_not_supported
+}
+
+// reasonPatterns classify the messages of failed provisionings, which carry
+// the errors of the server and of the controller, in order.
+var reasonPatterns = []struct {
+	reason   string
+	patterns []string
+}{
+	{reasonConnectionRefused, []string{"connection refused", "no such host", "i/o timeout", "connection reset", "unreachable"}},
+	{reasonPermissionDenied, []string{"permission denied", "must be superuser", "must be owner", "must have createrole", "must have createdb", "password authentication failed", "pg_hba.conf", "insufficient privilege"}},
+	{reasonDuplicateDatabase, []string{"already exists", "belongs to database", "is managed by controller"}},
+	{reasonInvalidIdentifier, []string{"naming template", "longer than", "renders an empty name", "invalid name", "invalid identifier", "zero-length delimited identifier"}},
+	{reasonInvalidSpec, []string{"unknown mode", "unknown role layout", "must be", "invalid", "not supported", "requires"}},
+}
+
+// statusReason returns the status.reason code of the state and message of a
+// Database.
+func statusReason(state, message string) string {
+	switch state {
+	case "provisioned":
+		return reasonProvisioned
+	case "cloning":
+		return reasonCloning
+	case "conflict":
+		return reasonDuplicateDatabase
+	}
+	if m := sqlStateRegexp.FindStringSubmatch(message); m != nil {
+		if reason, ok := sqlStateReasons[m[1]]; ok {
+			return reason
+		}
+		if reason, ok := sqlStateReasons[m[1][:2]]; ok {
+			return reason
+		}
+	}
+	lower := strings.ToLower(message)
+	for _, r := range reasonPatterns {
+		for _, pattern := range r.patterns {
+			if strings.Contains(lower, pattern) {
+				return r.reason
+			}
+		}
+	}
+	return reasonProvisioningFailed
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+// referencedSecrets returns the names of the Secrets of its namespace
+// dbResource reads when reconciled: its password or password verifier.
+func referencedSecrets(dbResource *v1.Database) []string {
+	var names []string
+	for _, ref := range []*v1.SecretKeyRef{dbResource.Spec.PasswordSecretRef, dbResource.Spec.PasswordVerifierSecret} {
+		if ref != nil && ref.Name != "" {
+			names = append(names, ref.Name)
+		}
+	}
+	return names
+}
+
+// referencedConfigMaps returns the names of the ConfigMaps of its namespace
+// dbResource reads when reconciled: its initSQL script and its migrations.
+func referencedConfigMaps(dbResource *v1.Database) []string {
+	var names []string
+	if init := dbResource.Spec.InitSQL; init != nil && init.ConfigMap != nil && init.ConfigMap.Name != "" {
+		names = append(names, init.ConfigMap.Name)
+	}
+	if migrations := dbResource.Spec.Migrations; migrations != nil && migrations.ConfigMapRef.Name != "" {
+		names = append(names, migrations.ConfigMapRef.Name)
+	}
+	return names
+}
+
+// handleReferencedSecret enqueues the Databases referencing the Secret obj,
+// so a new password is set on their role.
+func (c *Controller) handleReferencedSecret(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	if secret, ok := obj.(*corev1.Secret); ok {
+		c.enqueueReferencing(secret.Namespace, secret.Name, referencedSecrets)
+	}
+}
+
+// handleReferencedConfigMap enqueues the Databases referencing the
+// ConfigMap obj, so an edited initSQL script or a new migration is applied.
+func (c *Controller) handleReferencedConfigMap(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	if configMap, ok := obj.(*corev1.ConfigMap); ok {
+		c.enqueueReferencing(configMap.Namespace, configMap.Name, referencedConfigMaps)
+	}
+}
+
+// enqueueReferencing enqueues the Databases of namespace whose references
+// include name.
+func (c *Controller) enqueueReferencing(namespace, name string, references func(*v1.Database) []string) {
+	dbResources, err := c.DatabasesLister.Databases(namespace).List(labels.Everything())
+	if err != nil {
+		return
+	}
+	for _, dbResource := range dbResources {
+		for _, ref := range references(dbResource) {
+			if ref == name {
+				c.enqueueDatabase(dbResource)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/rs/zerolog"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+	"github.com/joshrendek/k8s-external-postgres/pkg/provisioner"
+)
+
+// syncDatabaseRename renames the database of dbResource once spec.database
+// was edited, to the name rendered for the new spec, and points its
+// credentials at it. The former name is kept in status.previousDatabaseName.
+// The rename waits for the maintenance window, and for the sessions connected
+// to the database unless renamePolicy.force terminates them. It returns true
+// when the status was updated, the next reconcile running with the new name.
+func (c *Controller) syncDatabaseRename(logger zerolog.Logger, dbResource *v1.Database, inst *instance, exec *sqlExecutor, m *maintenance) (bool, error) {
+	if dbResource.Status.SpecDatabase == "" || dbResource.Spec.Database == dbResource.Status.SpecDatabase {
+		return false, nil
+	}
+	if schemaMode(dbResource) {
+		return false, fmt.Errorf("the shared database of schema mode can't be renamed")
+	}
+	from := databaseName(dbResource)
+	to, _, err := serverNames(dbResource)
+	if err != nil {
+		return false, err
+	}
+	if to != from {
+		if !m.allow(actionRename) {
+			return false, nil
+		}
+		renamed, err := renameDatabase(logger, dbResource, inst, exec, from, to)
+		if err != nil {
+			return false, err
+		}
+		if !renamed {
+			return false, fmt.Errorf("database %q already exists", to)
+		}
+	}
+	if exec.dryRun {
+		return false, nil
+	}
+
+	dbCopy := dbResource.DeepCopy()
+	dbCopy.Status.SpecDatabase = dbResource.Spec.Database
+	if to != from {
+		dbCopy.Status.DatabaseName = to
+		dbCopy.Status.PreviousDatabaseName = from
+		if err := c.renameCredentials(dbCopy); err != nil {
+			return false, err
+		}
+	}
+	if err := c.updateStatus(dbCopy); err != nil {
+		return false, err
+	}
+	if to != from {
+		logger.Info().Str("from", from).Str("to", to).Msg("database renamed")
+		c.recorder.Event(dbResource, corev1.EventTypeNormal, "Renamed", fmt.Sprintf("Database renamed from %s to %s", from, to))
+	}
+	return true, nil
+}
+
+// renameDatabase renames database from to to, retrying while connected
+// sessions block it. With renamePolicy.force the sessions are terminated
+// first. It reports false when to exists already, unless from is gone, as
+// happens when the status could not be updated after an earlier rename.
+func renameDatabase(logger zerolog.Logger, dbResource *v1.Database, inst *instance, exec *sqlExecutor, from, to string) (bool, error) {
+	exists, err := databaseExists(inst.DB, to)
+	if err != nil {
+		return false, err
+	}
+	if exists {
+		previous, err := databaseExists(inst.DB, from)
+		if err != nil || previous {
+			return false, err
+		}
+		return true, verifyOwnership(inst, orphanKindDatabase, to, dbResource)
+	}
+	if err := verifyOwnership(inst, orphanKindDatabase, from, dbResource); err != nil {
+		return false, err
+	}
+
+	force := dbResource.Spec.RenamePolicy != nil && dbResource.Spec.RenamePolicy.Force
+	if force && !inst.dialect.terminateBackends {
+		logger.Info().Str("dialect", inst.dialect.name).Msg("sessions can't be terminated, renaming without force")
+		force = false
+	}
+	stmt := fmt.Sprintf("ALTER DATABASE %s RENAME TO %s", provisioner.QuoteIdentifier(from), provisioner.QuoteIdentifier(to))
+	logger.Info().Str("from", from).Str("to", to).Msg("renaming database")
+
+	var lastErr error
+	err = wait.ExponentialBackoff(dropBackoff, func() (bool, error) {
+		if force {
+			if err := exec.Exec(inst.DB, provisioner.TerminateSessionsStatement(from)); err != nil {
+				return false, fmt.Errorf("error terminating sessions: %s", err.Error())
+			}
+		}
+		lastErr = exec.Exec(inst.DB, stmt)
+		if lastErr == nil {
+			return true, nil
+		}
+		if sqlState(lastErr) == sqlStateObjectInUse {
+			// object_in_use: sessions are still connected
+			logger.Info().Err(lastErr).Msg("database in use, retrying rename")
+			return false, nil
+		}
+		return false, lastErr
+	})
+	if err == wait.ErrWaitTimeout {
+		err = lastErr
+	}
+	if err != nil {
+		return false, fmt.Errorf("error renaming database: %s", err.Error())
+	}
+	return true, nil
+}
+
+// renameCredentials points the credentials of the roles of dbResource at the
+// database name recorded in its status.
+func (c *Controller) renameCredentials(dbResource *v1.Database) error {
+	store, err := c.credentialStore(dbResource)
+	if err != nil {
+		return err
+	}
+	database := databaseName(dbResource)
+	replicas := c.readReplicas(dbResource)
+	mirror, mirrorErr := c.mirrorInstance(dbResource)
+	for _, name := range credentialNames(dbResource) {
+		data, err := store.Get(dbResource, name)
+		if err != nil {
+			return err
+		}
+		if data == nil {
+			continue
+		}
+		data["DATABASE"] = database
+		if dsn, err := parseURI(data["DATABASE_URL"]); err == nil && data["DATABASE_URL"] != "" {
+			dsn.Path = "/" + database
+			data["DATABASE_URL"] = dsn.String()
+		}
+		// the URLs of the replicas and mirror follow DATABASE_URL
+		setReplicaCredentials(data, replicas)
+		if mirrorErr == nil {
+			setMirrorCredentials(data, mirror)
+		}
+		if err := store.Put(dbResource, name, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
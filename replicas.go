@@ -0,0 +1,102 @@
+package main
+
+import (
+	"net"
+	"strings"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+// replicaKeys are the credential keys pointing at the read replicas of the
+// server, removed once it has none.
+var replicaKeys = []string{"HOST_RO", "PORT_RO", "DATABASE_URL_RO", "READ_REPLICAS"}
+
+// parseReadReplicas splits the comma separated endpoints of --read-replicas.
+func parseReadReplicas(endpoints string) []string {
+	var replicas []string
+	for _, endpoint := range strings.Split(endpoints, ",") {
+		if endpoint = strings.TrimSpace(endpoint); endpoint != "" {
+			replicas = append(replicas, endpoint)
+		}
+	}
+	return replicas
+}
+
+// readReplicas returns the read replica endpoints of the server of
+// dbResource: the readReplicas of its PostgresInstance, or --read-replicas
+// for the default server.
+func (c *Controller) readReplicas(dbResource *v1.Database) []string {
+	if instanceName(dbResource) == "" {
+		return parseReadReplicas(readReplicaEndpoints)
+	}
+	pgInstance, err := c.instances.InstancesLister.PostgresInstances(instanceNamespace(dbResource)).Get(instanceName(dbResource))
+	if err != nil {
+		return nil
+	}
+	return pgInstance.Spec.ReadReplicas
+}
+
+// splitEndpoint returns the host and port of a replica endpoint, the port
+// defaulting to 5432.
+func splitEndpoint(endpoint string) (string, string) {
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return endpoint, "5432"
+	}
+	return host, port
+}
+
+// setReplicaCredentials points the read-only keys of the credentials data at
+// the first of replicas, listing all of them in READ_REPLICAS, or removes
+// them when there are none. It returns whether data changed.
+func setReplicaCredentials(data map[string]string, replicas []string) bool {
+	want := map[string]string{}
+	if len(replicas) > 0 {
+		host, port := splitEndpoint(replicas[0])
+		want["HOST_RO"] = host
+		want["PORT_RO"] = port
+		want["READ_REPLICAS"] = strings.Join(replicas, ",")
+		if dsn, err := parseURI(data["DATABASE_URL"]); err == nil && data["DATABASE_URL"] != "" {
+			dsn.Host = net.JoinHostPort(host, port)
+			want["DATABASE_URL_RO"] = dsn.String()
+		}
+	}
+	changed := false
+	for _, key := range replicaKeys {
+		value, ok := want[key]
+		if current, exists := data[key]; exists == ok && current == value {
+			continue
+		}
+		changed = true
+		if ok {
+			data[key] = value
+		} else {
+			delete(data, key)
+		}
+	}
+	return changed
+}
+
+// syncReplicaCredentials updates the read replica endpoints in the
+// credentials Secrets of dbResource once the replicas of its server changed.
+// Other credential stores get them with the next credentials written.
+func (c *Controller) syncReplicaCredentials(dbResource *v1.Database) error {
+	if spec := dbResource.Spec.CredentialStore; spec != nil && spec.Type != "" && spec.Type != credentialStoreSecret {
+		return nil
+	}
+	store := &secretStore{c: c}
+	replicas := c.readReplicas(dbResource)
+	for _, name := range credentialNames(dbResource) {
+		data, err := store.Get(dbResource, name)
+		if err != nil {
+			return err
+		}
+		if data == nil || !setReplicaCredentials(data, replicas) {
+			continue
+		}
+		if err := store.Put(dbResource, name, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
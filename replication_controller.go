@@ -0,0 +1,597 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+	clientset "github.com/joshrendek/k8s-external-postgres/pkg/client/clientset/versioned"
+	informers "github.com/joshrendek/k8s-external-postgres/pkg/client/informers/externalversions"
+	listers "github.com/joshrendek/k8s-external-postgres/pkg/client/listers/postgresql/v1"
+	"github.com/joshrendek/k8s-external-postgres/pkg/provisioner"
+)
+
+// ReplicationController manages logical replication publications on managed
+// databases and the subscriptions to them on target instances.
+type ReplicationController struct {
+	kubeclientset     kubernetes.Interface
+	databaseClientset clientset.Interface
+
+	DatabasesLister     listers.DatabaseLister
+	DatabasesSynced     cache.InformerSynced
+	PublicationsLister  listers.PublicationLister
+	PublicationsSynced  cache.InformerSynced
+	SubscriptionsLister listers.SubscriptionLister
+	SubscriptionsSynced cache.InformerSynced
+
+	publicationQueue  workqueue.RateLimitingInterface
+	subscriptionQueue workqueue.RateLimitingInterface
+	recorder          record.EventRecorder
+	instances         *instanceRegistry
+}
+
+// NewReplicationController returns a new replication controller
+func NewReplicationController(
+	kubeclientset kubernetes.Interface,
+	databaseClientset clientset.Interface,
+	databaseInformerFactory informers.SharedInformerFactory,
+	instances *instanceRegistry) *ReplicationController {
+
+	databaseInformer := databaseInformerFactory.Databases().V1().Databases()
+	publicationInformer := databaseInformerFactory.Databases().V1().Publications()
+	subscriptionInformer := databaseInformerFactory.Databases().V1().Subscriptions()
+
+	controller := &ReplicationController{
+		kubeclientset:       kubeclientset,
+		databaseClientset:   databaseClientset,
+		DatabasesLister:     databaseInformer.Lister(),
+		DatabasesSynced:     databaseInformer.Informer().HasSynced,
+		PublicationsLister:  publicationInformer.Lister(),
+		PublicationsSynced:  publicationInformer.Informer().HasSynced,
+		SubscriptionsLister: subscriptionInformer.Lister(),
+		SubscriptionsSynced: subscriptionInformer.Informer().HasSynced,
+		publicationQueue:    workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "Publications"),
+		subscriptionQueue:   workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "Subscriptions"),
+		recorder:            newEventRecorder(kubeclientset),
+		instances:           instances,
+	}
+
+	log.Info().Msg("Setting up replication event handlers")
+	publicationInformer.Informer().AddEventHandler(instrumentHandler("publications", "replication", cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			enqueue(controller.publicationQueue, obj)
+		},
+		UpdateFunc: func(old, new interface{}) {
+			enqueue(controller.publicationQueue, new)
+		},
+		// handled immediately, the resource is gone by the time the work
+		// queue would get it
+		DeleteFunc: controller.dropPublication,
+	}))
+	subscriptionInformer.Informer().AddEventHandler(instrumentHandler("subscriptions", "replication", cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			enqueue(controller.subscriptionQueue, obj)
+		},
+		UpdateFunc: func(old, new interface{}) {
+			enqueue(controller.subscriptionQueue, new)
+		},
+		DeleteFunc: controller.dropSubscription,
+	}))
+	return controller
+}
+
+// Run waits for the informer caches to sync and starts the publication and
+// subscription workers. It blocks until stopCh is closed.
+func (c *ReplicationController) Run(threadiness int, stopCh <-chan struct{}) error {
+	defer runtime.HandleCrash()
+	defer c.publicationQueue.ShutDown()
+	defer c.subscriptionQueue.ShutDown()
+
+	log.Info().Msg("Starting replication controller")
+	if ok := cache.WaitForCacheSync(stopCh, c.DatabasesSynced, c.PublicationsSynced, c.SubscriptionsSynced, c.instances.HasSynced); !ok {
+		return fmt.Errorf("failed to wait for caches to sync")
+	}
+
+	var queues sync.WaitGroup
+	queues.Add(2)
+	go func() {
+		defer queues.Done()
+		runQueueWorkers(c.publicationQueue, threadiness, c.syncPublication, stopCh)
+	}()
+	go func() {
+		defer queues.Done()
+		runQueueWorkers(c.subscriptionQueue, threadiness, c.syncSubscription, stopCh)
+	}()
+
+	<-stopCh
+	log.Info().Msg("Shutting down replication workers")
+	queues.Wait()
+
+	return nil
+}
+
+// syncPublication creates the publication of a Publication resource and keeps
+// its published tables in line with the spec.
+func (c *ReplicationController) syncPublication(ctx context.Context, logger zerolog.Logger, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		runtime.HandleError(fmt.Errorf("invalid resource key: %s", key))
+		return nil
+	}
+
+	publication, err := c.PublicationsLister.Publications(namespace).Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			runtime.HandleError(fmt.Errorf("publication '%s' in work queue no longer exists", key))
+			return nil
+		}
+		return err
+	}
+
+	dbResource, err := c.DatabasesLister.Databases(namespace).Get(publication.Spec.Database)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return fmt.Errorf("database %q does not exist yet", publication.Spec.Database)
+		}
+		return err
+	}
+	if dbResource.Status.State != "provisioned" {
+		return fmt.Errorf("database %q is not provisioned yet", publication.Spec.Database)
+	}
+
+	inst, err := c.instances.forDatabase(dbResource)
+	if err != nil {
+		return err
+	}
+	if err := requireLogicalReplication(inst.dialect, inst.version); err != nil {
+		return c.updatePublicationStatus(publication, "error", err.Error())
+	}
+	db, err := inst.openDatabase(databaseName(dbResource))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	exec := newResourceExecutor(ctx, "Publication", publication, logger)
+	changed, err := ensurePublication(exec, db, publication)
+	if err == nil {
+		err = c.ensureReplicationRole(exec, inst, db, dbResource, publication, changed)
+	}
+	if err != nil {
+		c.recorder.Event(publication, corev1.EventTypeWarning, "PublicationFailed", err.Error())
+		return c.updatePublicationStatus(publication, "error", err.Error())
+	}
+	if publication.Status.State == "provisioned" {
+		return nil
+	}
+	c.recorder.Event(publication, corev1.EventTypeNormal, SuccessSynced, "Publication synced successfully")
+	return c.updatePublicationStatus(publication, "provisioned", "successful")
+}
+
+// ensurePublication creates the publication if it is missing, re-creates it
+// when switching between all and listed tables, and otherwise only issues
+// ALTER PUBLICATION when the published tables differ from the spec. It
+// reports whether the publication was created or changed.
+func ensurePublication(exec *sqlExecutor, db *sql.DB, publication *v1.Publication) (bool, error) {
+	name := provisioner.QuoteIdentifier(publication.Name)
+
+	var allTables bool
+	err := db.QueryRow("SELECT puballtables FROM pg_publication WHERE pubname = $1", publication.Name).Scan(&allTables)
+	switch {
+	case err == sql.ErrNoRows:
+		return true, createPublication(exec, db, publication)
+	case err != nil:
+		return false, err
+	case allTables != publication.Spec.AllTables:
+		exec.logger.Info().Msg("re-creating publication")
+		if err := exec.Exec(db, fmt.Sprintf("DROP PUBLICATION %s", name)); err != nil {
+			return false, err
+		}
+		return true, createPublication(exec, db, publication)
+	case allTables:
+		return false, nil
+	}
+
+	rows, err := db.Query("SELECT schemaname || '.' || tablename FROM pg_publication_tables WHERE pubname = $1", publication.Name)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	var current []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return false, err
+		}
+		current = append(current, table)
+	}
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+
+	desired := qualifiedTables(publication.Spec.Tables)
+	sort.Strings(current)
+	if strings.Join(current, ",") == strings.Join(desired, ",") {
+		return false, nil
+	}
+	stmt := fmt.Sprintf("ALTER PUBLICATION %s SET TABLE %s", name, quoteTables(publication.Spec.Tables))
+	return true, exec.Exec(db, stmt)
+}
+
+func createPublication(exec *sqlExecutor, db *sql.DB, publication *v1.Publication) error {
+	stmt := fmt.Sprintf("CREATE PUBLICATION %s FOR ALL TABLES", provisioner.QuoteIdentifier(publication.Name))
+	if !publication.Spec.AllTables {
+		stmt = fmt.Sprintf("CREATE PUBLICATION %s FOR TABLE %s", provisioner.QuoteIdentifier(publication.Name), quoteTables(publication.Spec.Tables))
+	}
+	return exec.Exec(db, stmt)
+}
+
+// quoteTable quotes table, and its schema when qualified.
+func quoteTable(table string) string {
+	parts := strings.SplitN(table, ".", 2)
+	for i := range parts {
+		parts[i] = provisioner.QuoteIdentifier(parts[i])
+	}
+	return strings.Join(parts, ".")
+}
+
+// quoteTables returns the quoted tables as a comma separated list.
+func quoteTables(tables []string) string {
+	quoted := make([]string, 0, len(tables))
+	for _, table := range tables {
+		quoted = append(quoted, quoteTable(table))
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// replicationRoleName returns the name of the role the subscribers of
+// publication log in as, after the owner role of its Database.
+func replicationRoleName(dbResource *v1.Database, publication *v1.Publication) string {
+	sum := sha256.Sum256([]byte(publication.UID))
+	suffix := "_repl_" + hex.EncodeToString(sum[:4])
+	name := roleName(dbResource)
+	if len(name)+len(suffix) > 63 {
+		name = name[:63-len(suffix)]
+	}
+	return name + suffix
+}
+
+// replicationSecretName returns the name of the Secret holding the
+// credentials of the replication role of publication.
+func replicationSecretName(publication *v1.Publication) string {
+	return publication.Name + "-replication"
+}
+
+// ensureReplicationRole creates the role the subscribers of publication log
+// in as, rather than the admin role, and its credentials in a Secret owned by
+// the Publication. The role can only connect to the published database and
+// read the published tables: its privileges are granted again whenever it is
+// created or the publication changed.
+func (c *ReplicationController) ensureReplicationRole(exec *sqlExecutor, inst *instance, db *sql.DB, dbResource *v1.Database, publication *v1.Publication, changed bool) error {
+	username := replicationRoleName(dbResource, publication)
+	secrets := c.kubeclientset.CoreV1().Secrets(publication.Namespace)
+	secret, err := secrets.Get(replicationSecretName(publication), metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		password, err := generatePassword()
+		if err != nil {
+			return err
+		}
+		secret, err = secrets.Create(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      replicationSecretName(publication),
+				Namespace: publication.Namespace,
+				OwnerReferences: []metav1.OwnerReference{
+					*metav1.NewControllerRef(publication, v1.SchemeGroupVersion.WithKind("Publication")),
+				},
+			},
+			StringData: map[string]string{"USERNAME": username, "PASSWORD": password},
+		})
+		if err != nil {
+			return err
+		}
+		// StringData is write only, read it back as the API server stores it
+		if secret, err = secrets.Get(secret.Name, metav1.GetOptions{}); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	exists, err := roleExists(inst.DB, username)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		// the password doesn't expire with the password policy, it would
+		// stop the replication
+		stmt, err := provisioner.RolePasswordStatement("CREATE ROLE", username, string(secret.Data["PASSWORD"]), passwordEncryptionFor(dbResource), 0)
+		if err != nil {
+			return err
+		}
+		stmts := []string{stmt, fmt.Sprintf("ALTER ROLE %s WITH LOGIN REPLICATION", username)}
+		if err := exec.ExecDDL(inst, stmts); err != nil {
+			return err
+		}
+	} else if !changed {
+		return nil
+	}
+
+	grants, err := replicationGrants(db, databaseName(dbResource), username, publication)
+	if err != nil {
+		return err
+	}
+	return exec.ExecTx(db, grants)
+}
+
+// replicationGrants returns the statements granting the replication role
+// username the privileges needed by the initial copy of the tables of
+// publication: CONNECT on database, USAGE on the schemas and SELECT on the
+// tables published. Its former privileges in database are revoked first.
+func replicationGrants(db *sql.DB, database, username string, publication *v1.Publication) ([]string, error) {
+	stmts := []string{
+		fmt.Sprintf("DROP OWNED BY %s", username),
+		fmt.Sprintf("GRANT CONNECT ON DATABASE %s TO %s", database, username),
+	}
+	if publication.Spec.AllTables {
+		rows, err := db.Query("SELECT nspname FROM pg_namespace WHERE nspname NOT LIKE 'pg\\_%' AND nspname <> 'information_schema'")
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var schema string
+			if err := rows.Scan(&schema); err != nil {
+				return nil, err
+			}
+			schema = provisioner.QuoteIdentifier(schema)
+			stmts = append(stmts,
+				fmt.Sprintf("GRANT USAGE ON SCHEMA %s TO %s", schema, username),
+				fmt.Sprintf("GRANT SELECT ON ALL TABLES IN SCHEMA %s TO %s", schema, username))
+		}
+		return stmts, rows.Err()
+	}
+	schemas := map[string]bool{}
+	for _, table := range qualifiedTables(publication.Spec.Tables) {
+		schema := strings.SplitN(table, ".", 2)[0]
+		if !schemas[schema] {
+			schemas[schema] = true
+			stmts = append(stmts, fmt.Sprintf("GRANT USAGE ON SCHEMA %s TO %s", provisioner.QuoteIdentifier(schema), username))
+		}
+	}
+	return append(stmts, fmt.Sprintf("GRANT SELECT ON TABLE %s TO %s", quoteTables(publication.Spec.Tables), username)), nil
+}
+
+// requireLogicalReplication returns an error when a server of dialect d and
+// version v does not support publications and subscriptions.
+func requireLogicalReplication(d dialect, v serverVersion) error {
+	if !d.logicalReplication {
+		return fmt.Errorf("logical replication is not supported by %s", d.name)
+	}
+	if !v.supportsLogicalReplication() {
+		return fmt.Errorf("logical replication requires PostgreSQL 10, server is %s", v)
+	}
+	return nil
+}
+
+// qualifiedTables returns the sorted tables with the public schema made
+// explicit, as reported by pg_publication_tables.
+func qualifiedTables(tables []string) []string {
+	qualified := make([]string, 0, len(tables))
+	for _, table := range tables {
+		if !strings.Contains(table, ".") {
+			table = "public." + table
+		}
+		qualified = append(qualified, table)
+	}
+	sort.Strings(qualified)
+	return qualified
+}
+
+// syncSubscription creates the subscription of a Subscription resource on its
+// target database, once the Publication it refers to is provisioned.
+func (c *ReplicationController) syncSubscription(ctx context.Context, logger zerolog.Logger, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		runtime.HandleError(fmt.Errorf("invalid resource key: %s", key))
+		return nil
+	}
+
+	subscription, err := c.SubscriptionsLister.Subscriptions(namespace).Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			runtime.HandleError(fmt.Errorf("subscription '%s' in work queue no longer exists", key))
+			return nil
+		}
+		return err
+	}
+	if subscription.Status.State == "provisioned" {
+		return nil
+	}
+
+	publication, err := c.PublicationsLister.Publications(namespace).Get(subscription.Spec.Publication)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return fmt.Errorf("publication %q does not exist yet", subscription.Spec.Publication)
+		}
+		return err
+	}
+	if publication.Status.State != "provisioned" {
+		return fmt.Errorf("publication %q is not provisioned yet", subscription.Spec.Publication)
+	}
+	dbResource, err := c.DatabasesLister.Databases(namespace).Get(publication.Spec.Database)
+	if err != nil {
+		return err
+	}
+
+	target, err := c.openSubscriptionTarget(subscription)
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+
+	// the dialect of the subscriber is not known, assume PostgreSQL
+	targetVersion, err := detectServerVersion(target)
+	if err != nil {
+		return err
+	}
+	if err := requireLogicalReplication(dialects["postgres"], targetVersion); err != nil {
+		return c.updateSubscriptionStatus(subscription, "error", err.Error())
+	}
+	var exists bool
+	if err := target.QueryRow("SELECT EXISTS (SELECT 1 FROM pg_subscription WHERE subname = $1)", subscription.Name).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		// The subscriber connects to the publisher as the replication role
+		// of the Publication, never with the admin credentials.
+		inst, err := c.instances.forDatabase(dbResource)
+		if err != nil {
+			return err
+		}
+		secret, err := c.kubeclientset.CoreV1().Secrets(namespace).Get(replicationSecretName(publication), metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("error reading the replication credentials of publication %q: %s", publication.Name, err.Error())
+		}
+		publisherURL, err := inst.databaseURL(databaseName(dbResource), string(secret.Data["USERNAME"]), string(secret.Data["PASSWORD"]))
+		if err != nil {
+			return err
+		}
+		stmt := fmt.Sprintf("CREATE SUBSCRIPTION %s CONNECTION '%s' PUBLICATION %s",
+			provisioner.QuoteIdentifier(subscription.Name), strings.Replace(publisherURL, "'", "''", -1), provisioner.QuoteIdentifier(publication.Name))
+		logger.Info().Str("publication", publication.Name).Msg("creating subscription")
+		exec := newResourceExecutor(ctx, "Subscription", subscription, logger)
+		if err := exec.Exec(target, stmt); err != nil {
+			c.recorder.Event(subscription, corev1.EventTypeWarning, "SubscriptionFailed", err.Error())
+			return c.updateSubscriptionStatus(subscription, "error", err.Error())
+		}
+	}
+
+	c.recorder.Event(subscription, corev1.EventTypeNormal, SuccessSynced, "Subscription synced successfully")
+	return c.updateSubscriptionStatus(subscription, "provisioned", "successful")
+}
+
+// openSubscriptionTarget connects to the subscriber database using the
+// DATABASE_URL of the target Secret.
+func (c *ReplicationController) openSubscriptionTarget(subscription *v1.Subscription) (*sql.DB, error) {
+	secret, err := c.kubeclientset.CoreV1().Secrets(subscription.Namespace).Get(subscription.Spec.TargetSecret, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	dsn, ok := secret.Data["DATABASE_URL"]
+	if !ok {
+		return nil, fmt.Errorf("secret %q has no DATABASE_URL key", subscription.Spec.TargetSecret)
+	}
+	target, err := sessionDSN(string(dsn), flagTimeouts())
+	if err != nil {
+		return nil, err
+	}
+	return openDSN(target, nil, nil)
+}
+
+func (c *ReplicationController) dropPublication(obj interface{}) {
+	publication, ok := obj.(*v1.Publication)
+	if !ok {
+		return
+	}
+	dbResource, err := c.DatabasesLister.Databases(publication.Namespace).Get(publication.Spec.Database)
+	if err != nil {
+		// the database, and the publication with it, is already gone
+		return
+	}
+	inst, err := c.instances.forDatabase(dbResource)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	db, err := inst.openDatabase(databaseName(dbResource))
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	defer db.Close()
+
+	ctx, logger := resourceContext(context.Background(), publication.Namespace, publication.Name)
+	logger.Info().Msg("dropping publication")
+	exec := newResourceExecutor(ctx, "Publication", publication, logger)
+	if err := exec.Exec(db, fmt.Sprintf("DROP PUBLICATION IF EXISTS %s", provisioner.QuoteIdentifier(publication.Name))); err != nil {
+		logger.Error().Err(err).Msg("error dropping publication")
+	}
+
+	// the Secret of the replication role goes with the Publication
+	username := replicationRoleName(dbResource, publication)
+	exists, err := roleExists(inst.DB, username)
+	if err != nil || !exists {
+		return
+	}
+	if err := exec.Exec(db, fmt.Sprintf("DROP OWNED BY %s", username)); err != nil {
+		logger.Error().Err(err).Msg("error revoking the privileges of the replication role")
+		return
+	}
+	if err := exec.Exec(inst.DB, fmt.Sprintf("DROP ROLE IF EXISTS %s", username)); err != nil {
+		logger.Error().Err(err).Msg("error dropping replication role")
+	}
+}
+
+func (c *ReplicationController) dropSubscription(obj interface{}) {
+	subscription, ok := obj.(*v1.Subscription)
+	if !ok {
+		return
+	}
+	target, err := c.openSubscriptionTarget(subscription)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	defer target.Close()
+
+	// DROP SUBSCRIPTION also drops the replication slot on the publisher
+	ctx, logger := resourceContext(context.Background(), subscription.Namespace, subscription.Name)
+	logger.Info().Msg("dropping subscription")
+	exec := newResourceExecutor(ctx, "Subscription", subscription, logger)
+	if err := exec.Exec(target, fmt.Sprintf("DROP SUBSCRIPTION IF EXISTS %s", provisioner.QuoteIdentifier(subscription.Name))); err != nil {
+		logger.Error().Err(err).Msg("error dropping subscription")
+	}
+}
+
+func (c *ReplicationController) updatePublicationStatus(publication *v1.Publication, state, message string) error {
+	publicationCopy := publication.DeepCopy()
+	publicationCopy.Status.State = state
+	publicationCopy.Status.Message = message
+	_, err := c.databaseClientset.DatabasesV1().Publications(publication.Namespace).Update(publicationCopy)
+	return err
+}
+
+func (c *ReplicationController) updateSubscriptionStatus(subscription *v1.Subscription, state, message string) error {
+	subscriptionCopy := subscription.DeepCopy()
+	subscriptionCopy.Status.State = state
+	subscriptionCopy.Status.Message = message
+	_, err := c.databaseClientset.DatabasesV1().Subscriptions(subscription.Namespace).Update(subscriptionCopy)
+	return err
+}
+
+// enqueue takes a resource and puts its namespace/name key onto queue.
+func enqueue(queue workqueue.RateLimitingInterface, obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	queue.AddRateLimited(key)
+}
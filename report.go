@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+	clientset "github.com/joshrendek/k8s-external-postgres/pkg/client/clientset/versioned"
+	listers "github.com/joshrendek/k8s-external-postgres/pkg/client/listers/postgresql/v1"
+)
+
+var invalidLabelNameChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// usageReporter exports the usage recorded in the status of the Databases,
+// along with the --usage-labels of the Databases for cost attribution, as
+// metrics and as reports on /usage.
+type usageReporter struct {
+	databaseClientset clientset.Interface
+	labelKeys         []string
+
+	sizeDesc        *prometheus.Desc
+	maxSizeDesc     *prometheus.Desc
+	connectionsDesc *prometheus.Desc
+
+	mu     sync.Mutex
+	lister listers.DatabaseLister
+}
+
+// usageRow is a line of a usage report, for a Database or for the Databases
+// sharing a label value.
+type usageRow struct {
+	Namespace   string            `json:"namespace,omitempty"`
+	Name        string            `json:"name,omitempty"`
+	Instance    string            `json:"instance,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Databases   int               `json:"databases"`
+	SizeBytes   int64             `json:"sizeBytes"`
+	Connections int64             `json:"connections"`
+}
+
+func newUsageReporter(databaseClientset clientset.Interface, labelKeys []string) *usageReporter {
+	variableLabels := []string{"namespace", "name", "instance"}
+	for _, key := range labelKeys {
+		variableLabels = append(variableLabels, usageLabelName(key))
+	}
+	return &usageReporter{
+		databaseClientset: databaseClientset,
+		labelKeys:         labelKeys,
+		sizeDesc: prometheus.NewDesc("external_postgres_database_size_bytes",
+			"Disk space used by the database, as last collected.", variableLabels, nil),
+		maxSizeDesc: prometheus.NewDesc("external_postgres_database_max_size_bytes",
+			"The maxSizeBytes of the Database.", variableLabels, nil),
+		connectionsDesc: prometheus.NewDesc("external_postgres_database_connections",
+			"Number of sessions connected to the database, as last collected.", variableLabels, nil),
+	}
+}
+
+// usageLabelName returns the name of the metric label of the Database label
+// key, e.g. label_cost_center for cost-center.
+func usageLabelName(key string) string {
+	return "label_" + invalidLabelNameChars.ReplaceAllString(key, "_")
+}
+
+// setLister makes the reporter read the Databases from the informer cache
+// once this replica runs the controller. Only then are the metrics exported,
+// so standby replicas don't report the same databases again.
+func (r *usageReporter) setLister(lister listers.DatabaseLister) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lister = lister
+}
+
+func (r *usageReporter) getLister() listers.DatabaseLister {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lister
+}
+
+// list returns the Databases with usage, from the informer cache when there
+// is one.
+func (r *usageReporter) list() ([]*v1.Database, error) {
+	var dbResources []*v1.Database
+	if lister := r.getLister(); lister != nil {
+		all, err := lister.List(labels.Everything())
+		if err != nil {
+			return nil, err
+		}
+		dbResources = all
+	} else {
+		list, err := r.databaseClientset.DatabasesV1().Databases(metav1.NamespaceAll).List(metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			dbResources = append(dbResources, &list.Items[i])
+		}
+	}
+
+	withUsage := dbResources[:0]
+	for _, dbResource := range dbResources {
+		if dbResource.Status.Usage != nil {
+			withUsage = append(withUsage, dbResource)
+		}
+	}
+	return withUsage, nil
+}
+
+func (r *usageReporter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- r.sizeDesc
+	ch <- r.maxSizeDesc
+	ch <- r.connectionsDesc
+}
+
+func (r *usageReporter) Collect(ch chan<- prometheus.Metric) {
+	if r.getLister() == nil {
+		return
+	}
+	dbResources, err := r.list()
+	if err != nil {
+		log.Error().Err(err).Msg("error listing Databases for usage metrics")
+		return
+	}
+	for _, dbResource := range dbResources {
+		labelValues := []string{dbResource.Namespace, dbResource.Name, instanceName(dbResource)}
+		for _, key := range r.labelKeys {
+			labelValues = append(labelValues, dbResource.Labels[key])
+		}
+		usage := dbResource.Status.Usage
+		ch <- prometheus.MustNewConstMetric(r.sizeDesc, prometheus.GaugeValue, float64(usage.SizeBytes), labelValues...)
+		ch <- prometheus.MustNewConstMetric(r.connectionsDesc, prometheus.GaugeValue, float64(usage.Connections), labelValues...)
+		if limit := dbResource.Spec.MaxSizeBytes; limit != nil {
+			ch <- prometheus.MustNewConstMetric(r.maxSizeDesc, prometheus.GaugeValue, float64(*limit), labelValues...)
+		}
+	}
+}
+
+// ServeHTTP answers the usage report, a row per Database or, with
+// ?groupBy=<label>, per value of the label, as JSON or with ?format=csv as
+// CSV.
+func (r *usageReporter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	dbResources, err := r.list()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	groupBy := req.URL.Query().Get("groupBy")
+	rows := r.report(dbResources, groupBy)
+
+	switch req.URL.Query().Get("format") {
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(rows); err != nil {
+			log.Error().Err(err).Msg("error writing usage report")
+		}
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		out := csv.NewWriter(w)
+		keys := r.labelKeys
+		header := []string{"namespace", "name", "instance"}
+		if groupBy != "" {
+			keys = []string{groupBy}
+			header = nil
+		}
+		header = append(append(header, keys...), "databases", "sizeBytes", "connections")
+		out.Write(header)
+		for _, row := range rows {
+			var record []string
+			if groupBy == "" {
+				record = []string{row.Namespace, row.Name, row.Instance}
+			}
+			for _, key := range keys {
+				record = append(record, row.Labels[key])
+			}
+			out.Write(append(record, strconv.Itoa(row.Databases), strconv.FormatInt(row.SizeBytes, 10), strconv.FormatInt(row.Connections, 10)))
+		}
+		out.Flush()
+		if err := out.Error(); err != nil {
+			log.Error().Err(err).Msg("error writing usage report")
+		}
+	default:
+		http.Error(w, "format must be json or csv", http.StatusBadRequest)
+	}
+}
+
+// report returns the rows of the usage report of dbResources, summed per
+// value of the groupBy label unless it is empty.
+func (r *usageReporter) report(dbResources []*v1.Database, groupBy string) []usageRow {
+	rows := []usageRow{}
+	groups := map[string]int{}
+	for _, dbResource := range dbResources {
+		usage := dbResource.Status.Usage
+		if groupBy != "" {
+			value := dbResource.Labels[groupBy]
+			i, ok := groups[value]
+			if !ok {
+				i = len(rows)
+				groups[value] = i
+				rows = append(rows, usageRow{Labels: map[string]string{groupBy: value}})
+			}
+			rows[i].Databases++
+			rows[i].SizeBytes += usage.SizeBytes
+			rows[i].Connections += int64(usage.Connections)
+			continue
+		}
+
+		row := usageRow{
+			Namespace:   dbResource.Namespace,
+			Name:        dbResource.Name,
+			Instance:    instanceName(dbResource),
+			Labels:      map[string]string{},
+			Databases:   1,
+			SizeBytes:   usage.SizeBytes,
+			Connections: int64(usage.Connections),
+		}
+		for _, key := range r.labelKeys {
+			row.Labels[key] = dbResource.Labels[key]
+		}
+		rows = append(rows, row)
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		if groupBy != "" {
+			return rows[i].Labels[groupBy] < rows[j].Labels[groupBy]
+		}
+		return rows[i].Namespace+"/"+rows[i].Name < rows[j].Namespace+"/"+rows[j].Name
+	})
+	return rows
+}
+
+// parseUsageLabels splits the --usage-labels flag.
+func parseUsageLabels(value string) []string {
+	var keys []string
+	for _, key := range strings.Split(value, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,508 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	batchlisters "k8s.io/client-go/listers/batch/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+	clientset "github.com/joshrendek/k8s-external-postgres/pkg/client/clientset/versioned"
+	informers "github.com/joshrendek/k8s-external-postgres/pkg/client/informers/externalversions"
+	listers "github.com/joshrendek/k8s-external-postgres/pkg/client/listers/postgresql/v1"
+)
+
+const (
+	restoreStateRunning   = "running"
+	restoreStateCompleted = "completed"
+	restoreStateFailed    = "failed"
+
+	// restoreLabel is set, to the name of the DatabaseRestore, on the
+	// Databases created as restore targets.
+	restoreLabel = "postgresql.org/restore"
+
+	// restoreScript downloads the dump, verifies it when a checksum is known,
+	// restores it and reports the dump size through the termination message.
+	// Each item pg_restore reports in verbose mode is logged as a progress
+	// line out of the items listed in the dump, see restoreProgressRegexp.
+	restoreScript = `set -e
+rclone copyto "$RESTORE_LOCATION" /tmp/restore.dump
+if [ -n "$RESTORE_CHECKSUM" ]; then
+  echo "$RESTORE_CHECKSUM  /tmp/restore.dump" | sha256sum -c -
+fi
+total=$(pg_restore --list /tmp/restore.dump | grep -c '^[0-9]' || true)
+echo "restore progress 0/$total"
+echo 0 > /tmp/restore.status
+(pg_restore --verbose --no-owner --no-privileges --exit-on-error --dbname="$DATABASE_URL" /tmp/restore.dump 2>&1 || echo $? > /tmp/restore.status) | {
+  restored=0
+  while read -r line; do
+    echo "$line"
+    case "$line" in
+    "pg_restore: creating "*|"pg_restore: processing "*)
+      if [ "$restored" -lt "$total" ]; then restored=$((restored + 1)); fi
+      echo "restore progress $restored/$total";;
+    esac
+  done
+}
+[ "$(cat /tmp/restore.status)" = 0 ]
+psql "$DATABASE_URL" -c ANALYZE
+wc -c < /tmp/restore.dump > /dev/termination-log
+`
+
+	// restoreLogLines is how many lines of the restore Job logs are read
+	// back for its last progress line.
+	restoreLogLines = 20
+)
+
+// restoreProgressRegexp matches the progress lines logged by restoreScript.
+var restoreProgressRegexp = regexp.MustCompile(`^restore progress ([0-9]+)/([0-9]+)$`)
+
+// RestoreController runs a pg_restore Job for every DatabaseRestore resource
+// and reports its progress in the resource status.
+type RestoreController struct {
+	kubeclientset     kubernetes.Interface
+	databaseClientset clientset.Interface
+
+	DatabasesLister listers.DatabaseLister
+	DatabasesSynced cache.InformerSynced
+	BackupsLister   listers.DatabaseBackupLister
+	BackupsSynced   cache.InformerSynced
+	RestoresLister  listers.DatabaseRestoreLister
+	RestoresSynced  cache.InformerSynced
+	JobsLister      batchlisters.JobLister
+	JobsSynced      cache.InformerSynced
+
+	workqueue workqueue.RateLimitingInterface
+	recorder  record.EventRecorder
+	instances *instanceRegistry
+}
+
+// NewRestoreController returns a new restore controller
+func NewRestoreController(
+	kubeclientset kubernetes.Interface,
+	databaseClientset clientset.Interface,
+	kubeInformerFactory kubeinformers.SharedInformerFactory,
+	databaseInformerFactory informers.SharedInformerFactory,
+	instances *instanceRegistry) *RestoreController {
+
+	databaseInformer := databaseInformerFactory.Databases().V1().Databases()
+	backupInformer := databaseInformerFactory.Databases().V1().DatabaseBackups()
+	restoreInformer := databaseInformerFactory.Databases().V1().DatabaseRestores()
+	jobInformer := kubeInformerFactory.Batch().V1().Jobs()
+
+	controller := &RestoreController{
+		kubeclientset:     kubeclientset,
+		databaseClientset: databaseClientset,
+		DatabasesLister:   databaseInformer.Lister(),
+		DatabasesSynced:   databaseInformer.Informer().HasSynced,
+		BackupsLister:     backupInformer.Lister(),
+		BackupsSynced:     backupInformer.Informer().HasSynced,
+		RestoresLister:    restoreInformer.Lister(),
+		RestoresSynced:    restoreInformer.Informer().HasSynced,
+		JobsLister:        jobInformer.Lister(),
+		JobsSynced:        jobInformer.Informer().HasSynced,
+		workqueue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "DatabaseRestores"),
+		recorder:          newEventRecorder(kubeclientset),
+		instances:         instances,
+	}
+
+	log.Info().Msg("Setting up restore event handlers")
+	// The informer resyncs periodically, which is what refreshes the progress
+	// counters of running restores.
+	restoreInformer.Informer().AddEventHandler(instrumentHandler("databaserestores", "restore", cache.ResourceEventHandlerFuncs{
+		AddFunc: controller.enqueueRestore,
+		UpdateFunc: func(old, new interface{}) {
+			controller.enqueueRestore(new)
+		},
+	}))
+	jobInformer.Informer().AddEventHandler(instrumentHandler("jobs", "restore", cache.ResourceEventHandlerFuncs{
+		AddFunc: controller.handleJob,
+		UpdateFunc: func(old, new interface{}) {
+			controller.handleJob(new)
+		},
+	}))
+	return controller
+}
+
+// Run waits for the informer caches to sync and starts the restore workers.
+// It blocks until stopCh is closed.
+func (c *RestoreController) Run(threadiness int, stopCh <-chan struct{}) error {
+	defer runtime.HandleCrash()
+	defer c.workqueue.ShutDown()
+
+	log.Info().Msg("Starting DatabaseRestore controller")
+	if ok := cache.WaitForCacheSync(stopCh, c.DatabasesSynced, c.BackupsSynced, c.RestoresSynced, c.JobsSynced, c.instances.HasSynced); !ok {
+		return fmt.Errorf("failed to wait for caches to sync")
+	}
+
+	workersDone := make(chan struct{})
+	go func() {
+		runQueueWorkers(c.workqueue, threadiness, c.syncHandler, stopCh)
+		close(workersDone)
+	}()
+
+	<-stopCh
+	log.Info().Msg("Shutting down restore workers")
+	<-workersDone
+
+	return nil
+}
+
+// syncHandler creates the target Database of a DatabaseRestore unless it
+// exists, starts the restore Job once it is provisioned, then tracks the Job
+// and the restore progress.
+func (c *RestoreController) syncHandler(ctx context.Context, logger zerolog.Logger, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		runtime.HandleError(fmt.Errorf("invalid resource key: %s", key))
+		return nil
+	}
+
+	restore, err := c.RestoresLister.DatabaseRestores(namespace).Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			runtime.HandleError(fmt.Errorf("restore '%s' in work queue no longer exists", key))
+			return nil
+		}
+		return err
+	}
+
+	switch restore.Status.State {
+	case restoreStateCompleted, restoreStateFailed:
+		return nil
+	}
+
+	dbResource, err := c.DatabasesLister.Databases(namespace).Get(restore.Spec.Database)
+	if errors.IsNotFound(err) {
+		target, err := restoreDatabase(restore)
+		if err != nil {
+			return c.updateRestoreStatus(restore, func(status *v1.DatabaseRestoreStatus) {
+				status.State = restoreStateFailed
+				status.Message = err.Error()
+			})
+		}
+		logger.Info().Str("database", target.Name).Msg("creating restore target")
+		if _, err := c.databaseClientset.DatabasesV1().Databases(namespace).Create(target); err != nil && !errors.IsAlreadyExists(err) {
+			return err
+		}
+		return fmt.Errorf("database %q is not provisioned yet", restore.Spec.Database)
+	}
+	if err != nil {
+		return err
+	}
+	if dbResource.Status.State != "provisioned" {
+		return fmt.Errorf("database %q is not provisioned yet", restore.Spec.Database)
+	}
+	if !hasCredentialsSecret(dbResource) {
+		return c.updateRestoreStatus(restore, func(status *v1.DatabaseRestoreStatus) {
+			status.State = restoreStateFailed
+			status.Message = fmt.Sprintf("database %q stores its credentials in Vault only, the restore job needs its Secret", restore.Spec.Database)
+		})
+	}
+
+	location, storageSecret, checksum, err := c.restoreSource(restore)
+	if err != nil {
+		return c.updateRestoreStatus(restore, func(status *v1.DatabaseRestoreStatus) {
+			status.State = restoreStateFailed
+			status.Message = err.Error()
+		})
+	}
+
+	inst, err := c.instances.forDatabase(dbResource)
+	if err != nil {
+		return err
+	}
+
+	jobName := restore.Name + "-restore"
+	job, err := c.JobsLister.Jobs(namespace).Get(jobName)
+	if errors.IsNotFound(err) {
+		if backupImage == "" {
+			return c.updateRestoreStatus(restore, func(status *v1.DatabaseRestoreStatus) {
+				status.State = restoreStateFailed
+				status.Message = "Restores require --backup-image to run pg_restore"
+			})
+		}
+		// pg_restore is run once, into a database without tables of its
+		// own: restored on top of existing data, the dump would fail half
+		// way or mix with it.
+		tables, tablesErr := restoreTargetTables(inst, dbResource)
+		if tablesErr != nil {
+			return tablesErr
+		}
+		if tables > 0 {
+			return c.updateRestoreStatus(restore, func(status *v1.DatabaseRestoreStatus) {
+				status.State = restoreStateFailed
+				status.Message = fmt.Sprintf("database %q is not empty, it holds %d tables", restore.Spec.Database, tables)
+			})
+		}
+		logger.Info().Str("database", databaseName(dbResource)).Msg("starting restore job")
+		job, err = c.kubeclientset.BatchV1().Jobs(namespace).Create(newRestoreJob(restore, dbResource, jobName, location, storageSecret, checksum))
+	}
+	if err != nil {
+		return err
+	}
+	if !metav1.IsControlledBy(job, restore) {
+		msg := fmt.Sprintf(MessageResourceExists, job.Name)
+		c.recorder.Event(restore, corev1.EventTypeWarning, ErrResourceExists, msg)
+		return fmt.Errorf("%s", msg)
+	}
+
+	size, err := restoreSize(inst, databaseName(dbResource))
+	if err != nil {
+		return err
+	}
+	items, total, err := restoreProgress(c.kubeclientset, job)
+	if err != nil {
+		return err
+	}
+
+	if cond := finishedJobCondition(job); cond != nil {
+		if cond.Type == batchv1.JobFailed {
+			c.recorder.Event(restore, corev1.EventTypeWarning, "RestoreFailed", cond.Message)
+			return c.updateRestoreStatus(restore, func(status *v1.DatabaseRestoreStatus) {
+				status.State = restoreStateFailed
+				status.Message = fmt.Sprintf("restore job failed: %s", cond.Message)
+				status.ItemsRestored = items
+				status.ItemsTotal = total
+				status.BytesRestored = size
+			})
+		}
+		msg, err := jobTerminationMessage(c.kubeclientset, job)
+		if err != nil {
+			return err
+		}
+		downloaded, _ := strconv.ParseInt(msg, 10, 64)
+		c.recorder.Event(restore, corev1.EventTypeNormal, SuccessSynced, "Restore completed successfully")
+		return c.updateRestoreStatus(restore, func(status *v1.DatabaseRestoreStatus) {
+			now := metav1.Now()
+			status.State = restoreStateCompleted
+			status.Message = "successful"
+			status.Job = job.Name
+			status.BytesDownloaded = downloaded
+			status.ItemsRestored = total
+			status.ItemsTotal = total
+			status.BytesRestored = size
+			status.CompletionTime = &now
+		})
+	}
+
+	if restore.Status.State == restoreStateRunning && restore.Status.ItemsRestored == items && restore.Status.ItemsTotal == total && restore.Status.BytesRestored == size {
+		return nil
+	}
+	return c.updateRestoreStatus(restore, func(status *v1.DatabaseRestoreStatus) {
+		status.State = restoreStateRunning
+		status.Message = "restore in progress"
+		status.Job = job.Name
+		status.ItemsRestored = items
+		status.ItemsTotal = total
+		status.BytesRestored = size
+	})
+}
+
+// restoreDatabase returns the Database created for restore when its target
+// doesn't exist, from its databaseSpec. The role and database are named after
+// the target unless set, and a password is generated. initSQL and migrations
+// would fill the database before the restore and are refused.
+func restoreDatabase(restore *v1.DatabaseRestore) (*v1.Database, error) {
+	spec := v1.DatabaseConfig{}
+	if restore.Spec.DatabaseSpec != nil {
+		spec = *restore.Spec.DatabaseSpec.DeepCopy()
+	}
+	if spec.InitSQL != nil || spec.Migrations != nil {
+		return nil, fmt.Errorf("the databaseSpec of a restore can't set initSQL or migrations")
+	}
+	if spec.Username == "" {
+		spec.Username = restore.Spec.Database
+	}
+	if spec.Database == "" {
+		spec.Database = restore.Spec.Database
+	}
+	if spec.Password == "" {
+		var err error
+		if spec.Password, err = generatePassword(); err != nil {
+			return nil, err
+		}
+	}
+	return &v1.Database{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      restore.Spec.Database,
+			Namespace: restore.Namespace,
+			Labels:    map[string]string{restoreLabel: restore.Name},
+		},
+		Spec: spec,
+	}, nil
+}
+
+// restoreSource returns the location, storage Secret and expected checksum of
+// the dump restored by restore.
+func (c *RestoreController) restoreSource(restore *v1.DatabaseRestore) (string, string, string, error) {
+	if restore.Spec.Backup == "" {
+		if restore.Spec.Location == "" || restore.Spec.StorageSecret == "" {
+			return "", "", "", fmt.Errorf("either backup or location and storageSecret must be set")
+		}
+		return restore.Spec.Location, restore.Spec.StorageSecret, "", nil
+	}
+
+	backup, err := c.BackupsLister.DatabaseBackups(restore.Namespace).Get(restore.Spec.Backup)
+	if err != nil {
+		return "", "", "", fmt.Errorf("backup %q: %s", restore.Spec.Backup, err.Error())
+	}
+	if backup.Status.State != backupStateCompleted {
+		return "", "", "", fmt.Errorf("backup %q has not completed", restore.Spec.Backup)
+	}
+	return backup.Status.Location, backup.Spec.StorageSecret, backup.Status.Checksum, nil
+}
+
+// restoreTargetTables returns the number of tables, views and sequences in
+// the database of dbResource, or its schema in schema mode, leaving out the
+// ones created by extensions. The catalog of another database can't be read
+// from the admin connection, the database is connected to once, before the
+// restore Job is created.
+func restoreTargetTables(inst *instance, dbResource *v1.Database) (int64, error) {
+	db, err := inst.openDatabase(databaseName(dbResource))
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	schema := ""
+	if schemaMode(dbResource) {
+		schema = schemaName(dbResource)
+	}
+	var tables int64
+	err = db.QueryRow(`SELECT count(*) FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('r', 'p', 'v', 'm', 'S', 'f')
+		AND n.nspname NOT IN ('pg_catalog', 'information_schema') AND n.nspname NOT LIKE 'pg\_%'
+		AND ($1 = '' OR n.nspname = $1)
+		AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.classid = 'pg_class'::regclass AND d.objid = c.oid AND d.deptype = 'e')`,
+		schema).Scan(&tables)
+	return tables, err
+}
+
+// restoreSize returns the size of the database being restored, queried on
+// the admin connection of inst.
+func restoreSize(inst *instance, database string) (int64, error) {
+	var size int64
+	err := inst.DB.QueryRow(`SELECT pg_database_size($1)`, database).Scan(&size)
+	return size, err
+}
+
+// restoreProgress returns the items restored so far and the items of the
+// dump from the last progress line logged by the pod of the restore job,
+// zero before pg_restore started.
+func restoreProgress(kubeclientset kubernetes.Interface, job *batchv1.Job) (int64, int64, error) {
+	selector := labels.SelectorFromSet(labels.Set{"job-name": job.Name})
+	pods, err := kubeclientset.CoreV1().Pods(job.Namespace).List(metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return 0, 0, err
+	}
+	var items, total int64
+	tail := int64(restoreLogLines)
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == corev1.PodPending {
+			continue
+		}
+		logs, err := kubeclientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{Container: "restore", TailLines: &tail}).Do().Raw()
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, line := range strings.Split(string(logs), "\n") {
+			if m := restoreProgressRegexp.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+				items, _ = strconv.ParseInt(m[1], 10, 64)
+				total, _ = strconv.ParseInt(m[2], 10, 64)
+			}
+		}
+	}
+	return items, total, nil
+}
+
+func (c *RestoreController) updateRestoreStatus(restore *v1.DatabaseRestore, mutate func(status *v1.DatabaseRestoreStatus)) error {
+	// NEVER modify objects from the store. It's a read-only, local cache.
+	restoreCopy := restore.DeepCopy()
+	mutate(&restoreCopy.Status)
+	_, err := c.databaseClientset.DatabasesV1().DatabaseRestores(restore.Namespace).Update(restoreCopy)
+	return err
+}
+
+// newRestoreJob builds the Job downloading the dump at location and restoring
+// it into dbResource with the credentials from its Secret.
+func newRestoreJob(restore *v1.DatabaseRestore, dbResource *v1.Database, name, location, storageSecret, checksum string) *batchv1.Job {
+	var backoffLimit int32 = 0
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: restore.Namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(restore, v1.SchemeGroupVersion.WithKind("DatabaseRestore")),
+			},
+		},
+		Spec: batchv1.JobSpec{
+			// pg_restore is not idempotent, a failed restore is not retried
+			// into a half restored database.
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{
+						{
+							Name:    "restore",
+							Image:   backupImage,
+							Command: []string{"/bin/sh", "-c", restoreScript},
+							Env: []corev1.EnvVar{
+								databaseURLEnv(dbResource.Name),
+								{Name: "RESTORE_LOCATION", Value: location},
+								{Name: "RESTORE_CHECKSUM", Value: checksum},
+							},
+							EnvFrom: storageEnvFrom(storageSecret),
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// enqueueRestore takes a DatabaseRestore resource and puts its namespace/name
+// key onto the work queue.
+func (c *RestoreController) enqueueRestore(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	c.workqueue.AddRateLimited(key)
+}
+
+// handleJob enqueues the DatabaseRestore owning a Job, ignoring Jobs that are
+// not managed by this controller.
+func (c *RestoreController) handleJob(obj interface{}) {
+	job, ok := obj.(*batchv1.Job)
+	if !ok {
+		return
+	}
+	ownerRef := metav1.GetControllerOf(job)
+	if ownerRef == nil || ownerRef.Kind != "DatabaseRestore" {
+		return
+	}
+	restore, err := c.RestoresLister.DatabaseRestores(job.Namespace).Get(ownerRef.Name)
+	if err != nil {
+		return
+	}
+	c.enqueueRestore(restore)
+}
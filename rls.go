@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+	"github.com/joshrendek/k8s-external-postgres/pkg/provisioner"
+)
+
+const (
+	defaultPolicyName     = "tenant_isolation"
+	defaultPolicyTemplate = "tenant_id = current_setting('app.tenant_id', true)"
+)
+
+// policyData is what the row level security policy template is executed
+// with.
+type policyData struct {
+	nameData
+	// Role and Schema are the owner role of the Database and the schema of
+	// its objects on the server.
+	Role   string
+	Schema string
+}
+
+// rowLevelSecurity reports whether spec.rowLevelSecurity is enabled.
+func rowLevelSecurity(dbResource *v1.Database) bool {
+	return dbResource.Spec.RowLevelSecurity != nil && dbResource.Spec.RowLevelSecurity.Enabled
+}
+
+// rowLevelSecurityScript returns the block enabling row level security on
+// the tables of the owner role of dbResource, in its schema in schema mode or
+// in every schema otherwise, and creating the policy of the spec on them.
+// The policy is recreated each time so edits to the template apply.
+func rowLevelSecurityScript(dbResource *v1.Database) (string, error) {
+	rls := dbResource.Spec.RowLevelSecurity
+	name := rls.PolicyName
+	if name == "" {
+		name = defaultPolicyName
+	}
+	tmpl := rls.Policy
+	if tmpl == "" {
+		tmpl = defaultPolicyTemplate
+	}
+	t, err := template.New("policy").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid row level security policy %q: %s", tmpl, err.Error())
+	}
+	var policy bytes.Buffer
+	err = t.Execute(&policy, policyData{
+		nameData: nameData{
+			Namespace: dbResource.Namespace,
+			Name:      dbResource.Name,
+			Database:  dbResource.Spec.Database,
+			Username:  dbResource.Spec.Username,
+		},
+		Role:   roleName(dbResource),
+		Schema: tenantSchema(dbResource),
+	})
+	if err != nil {
+		return "", fmt.Errorf("error executing row level security policy %q: %s", tmpl, err.Error())
+	}
+
+	schemas := "schemaname NOT IN ('pg_catalog', 'information_schema')"
+	if schemaMode(dbResource) {
+		schemas = fmt.Sprintf("schemaname = %s", provisioner.QuoteLiteral(schemaName(dbResource)))
+	}
+	force := ""
+	if rls.Force {
+		force = "\n\t\tEXECUTE format('ALTER TABLE %I.%I FORCE ROW LEVEL SECURITY', t.schemaname, t.tablename);"
+	}
+	return fmt.Sprintf(`DO $rls$
+DECLARE
+	t record;
+BEGIN
+	FOR t IN SELECT schemaname, tablename FROM pg_tables WHERE tableowner = %s AND %s LOOP
+		EXECUTE format('ALTER TABLE %%I.%%I ENABLE ROW LEVEL SECURITY', t.schemaname, t.tablename);%s
+		EXECUTE format('DROP POLICY IF EXISTS %%I ON %%I.%%I', %s, t.schemaname, t.tablename);
+		EXECUTE format('CREATE POLICY %%I ON %%I.%%I USING (%%s)', %s, t.schemaname, t.tablename, %s);
+	END LOOP;
+END
+$rls$`, provisioner.QuoteLiteral(roleName(dbResource)), schemas, force,
+		provisioner.QuoteLiteral(name), provisioner.QuoteLiteral(name), provisioner.QuoteLiteral(policy.String())), nil
+}
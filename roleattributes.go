@@ -0,0 +1,115 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+// roleAttribute is an attribute of roles, set with keyword and cleared with
+// NO followed by keyword, recorded in the column of pg_roles.
+type roleAttribute struct {
+	keyword string
+	column  string
+	// privileged attributes require --allow-privileged-roles.
+	privileged bool
+	desired    func(attrs *v1.RoleAttributes) bool
+}
+
+var roleAttributes = []roleAttribute{
+	{"SUPERUSER", "rolsuper", true, func(a *v1.RoleAttributes) bool { return a.Superuser }},
+	{"CREATEDB", "rolcreatedb", false, func(a *v1.RoleAttributes) bool { return a.CreateDB }},
+	{"CREATEROLE", "rolcreaterole", true, func(a *v1.RoleAttributes) bool { return a.CreateRole }},
+	{"INHERIT", "rolinherit", false, func(a *v1.RoleAttributes) bool { return a.Inherit == nil || *a.Inherit }},
+	{"REPLICATION", "rolreplication", true, func(a *v1.RoleAttributes) bool { return a.Replication }},
+	{"BYPASSRLS", "rolbypassrls", true, func(a *v1.RoleAttributes) bool { return a.BypassRLS }},
+}
+
+// supportedRoleAttributes returns the attributes of roleAttributes the server
+// of inst knows.
+func supportedRoleAttributes(inst *instance) []roleAttribute {
+	if inst.version.supportsBypassRLS() {
+		return roleAttributes
+	}
+	return roleAttributes[:len(roleAttributes)-1]
+}
+
+// syncRoleAttributes makes the attributes of the owner role of dbResource
+// match its spec.roleAttributes, only altering the ones that differ. Roles
+// created by the controller start with the least privileged attributes,
+// which unset ones are brought back to.
+func (c *Controller) syncRoleAttributes(dbResource *v1.Database, inst *instance, exec *sqlExecutor) error {
+	if !inst.dialect.roleAttributes {
+		return nil
+	}
+	attrs := dbResource.Spec.RoleAttributes
+	if attrs == nil {
+		attrs = &v1.RoleAttributes{}
+	}
+	for _, attr := range roleAttributes {
+		if !attr.desired(attrs) {
+			continue
+		}
+		if attr.privileged && !allowPrivilegedRoles {
+			return fmt.Errorf("role attribute %s requires --allow-privileged-roles", attr.keyword)
+		}
+		if attr.keyword == "BYPASSRLS" && !inst.version.supportsBypassRLS() {
+			return fmt.Errorf("role attribute BYPASSRLS requires PostgreSQL 9.5, server is %s", inst.version)
+		}
+	}
+
+	username := roleName(dbResource)
+	supported := supportedRoleAttributes(inst)
+	current, err := currentRoleAttributes(inst.DB, username, supported)
+	if err != nil {
+		return err
+	}
+	var changes []string
+	for i, attr := range supported {
+		desired := attr.desired(attrs)
+		if current == nil {
+			// the role is only planned, it is created with the defaults
+			if desired == attr.desired(&v1.RoleAttributes{}) {
+				continue
+			}
+		} else if current[i] == desired {
+			continue
+		}
+		if desired {
+			changes = append(changes, attr.keyword)
+		} else {
+			changes = append(changes, "NO"+attr.keyword)
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	stmt := fmt.Sprintf("ALTER ROLE %s %s", username, strings.Join(changes, " "))
+	if err := exec.Exec(inst.DB, stmt); err != nil {
+		return fmt.Errorf("error setting role attributes: %s", err.Error())
+	}
+	return nil
+}
+
+// currentRoleAttributes returns the values of attrs for username, nil when
+// the role does not exist yet, as happens in dry-run mode.
+func currentRoleAttributes(db *sql.DB, username string, attrs []roleAttribute) ([]bool, error) {
+	columns := make([]string, len(attrs))
+	values := make([]bool, len(attrs))
+	dest := make([]interface{}, len(attrs))
+	for i, attr := range attrs {
+		columns[i] = attr.column
+		dest[i] = &values[i]
+	}
+	query := fmt.Sprintf("SELECT %s FROM pg_roles WHERE rolname = $1", strings.Join(columns, ", "))
+	err := db.QueryRow(query, username).Scan(dest...)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+// applyRoleSettings sets the spec.roleSettings of dbResource on each of its
+// roles when they differ from the server, and resets the ones removed since
+// the status was recorded. Settings the roles got otherwise, e.g. from
+// PostgresParameters, are left alone. It returns the names of the settings
+// applied.
+func applyRoleSettings(dbResource *v1.Database, inst *instance, exec *sqlExecutor) ([]string, error) {
+	names := make([]string, 0, len(dbResource.Spec.RoleSettings))
+	for name := range dbResource.Spec.RoleSettings {
+		if !parameterNameRegexp.MatchString(name) {
+			return nil, fmt.Errorf("invalid role setting name %q", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) == 0 && len(dbResource.Status.RoleSettings) == 0 {
+		return nil, nil
+	}
+	if !inst.dialect.roleSettings {
+		if len(names) == 0 {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("spec.roleSettings is not supported by %s", inst.dialect.name)
+	}
+
+	for _, role := range ownedRoles(dbResource) {
+		t := &parameterTarget{key: parameterTargetRole, inst: inst, alter: fmt.Sprintf("ALTER ROLE %s", role), role: role}
+		current, err := t.current()
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			value := dbResource.Spec.RoleSettings[name]
+			if current[name] == normalizeParameter(value) {
+				continue
+			}
+			if err := exec.Exec(inst.DB, fmt.Sprintf("%s SET %s = %s", t.alter, name, parameterValue(value))); err != nil {
+				return nil, fmt.Errorf("error setting %s of role %s: %s", name, role, err.Error())
+			}
+		}
+		for _, name := range dbResource.Status.RoleSettings {
+			if _, ok := dbResource.Spec.RoleSettings[name]; ok {
+				continue
+			}
+			if _, ok := current[name]; !ok {
+				continue
+			}
+			if err := exec.Exec(inst.DB, fmt.Sprintf("%s RESET %s", t.alter, name)); err != nil {
+				return nil, fmt.Errorf("error resetting %s of role %s: %s", name, role, err.Error())
+			}
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	return names, nil
+}
+
+// syncRoleSettings applies the role settings of dbResource and records the
+// ones applied in its status.
+func (c *Controller) syncRoleSettings(dbResource *v1.Database, inst *instance, exec *sqlExecutor) error {
+	applied, err := applyRoleSettings(dbResource, inst, exec)
+	if err != nil {
+		return err
+	}
+	if exec.dryRun || reflect.DeepEqual(applied, dbResource.Status.RoleSettings) {
+		return nil
+	}
+	dbCopy := dbResource.DeepCopy()
+	dbCopy.Status.RoleSettings = applied
+	return c.updateStatus(dbCopy)
+}
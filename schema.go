@@ -0,0 +1,129 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+const (
+	modeDatabase = "database"
+	modeSchema   = "schema"
+)
+
+// schemaMode reports whether dbResource is provisioned as a schema of a
+// shared database rather than a database of its own.
+func schemaMode(dbResource *v1.Database) bool {
+	return dbResource.Spec.Mode == modeSchema
+}
+
+// schemaName returns the schema of dbResource in schema mode, as recorded in
+// its status.
+func schemaName(dbResource *v1.Database) string {
+	if dbResource.Status.SchemaName != "" {
+		return dbResource.Status.SchemaName
+	}
+	return roleName(dbResource)
+}
+
+// tenantSchema returns the schema the objects of dbResource live in: its own
+// in schema mode, public otherwise.
+func tenantSchema(dbResource *v1.Database) string {
+	if schemaMode(dbResource) {
+		return schemaName(dbResource)
+	}
+	return "public"
+}
+
+// schemaExists reports whether the schema name exists in db.
+func schemaExists(db *sql.DB, name string) (bool, error) {
+	var exists bool
+	err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM pg_namespace WHERE nspname = $1)", name).Scan(&exists)
+	return exists, err
+}
+
+// schemaOwnerStatements returns the statements creating schema, or handing
+// it over when it exists, so it belongs to owner.
+func (d dialect) schemaOwnerStatements(schema, owner string) []string {
+	if d.databaseOwner {
+		return []string{
+			fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s AUTHORIZATION %s", schema, owner),
+			fmt.Sprintf("ALTER SCHEMA %s OWNER TO %s", schema, owner),
+		}
+	}
+	return []string{
+		fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s", schema),
+		fmt.Sprintf("GRANT ALL ON SCHEMA %s TO %s", schema, owner),
+	}
+}
+
+// provisionSchema creates the schema of dbResource in its shared database,
+// owned by its owner role.
+func provisionSchema(dbResource *v1.Database, inst *instance, exec *sqlExecutor) error {
+	db, err := inst.openDatabase(databaseName(dbResource))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	for _, stmt := range inst.dialect.schemaOwnerStatements(schemaName(dbResource), roleName(dbResource)) {
+		if err := exec.Exec(db, stmt); err != nil {
+			return fmt.Errorf("error creating schema: %s", err.Error())
+		}
+	}
+	return nil
+}
+
+// dropSchema drops the schema of dbResource along with its objects, and
+// everything else its roles own or were granted in the shared database so
+// the roles can be dropped.
+func dropSchema(dbResource *v1.Database, inst *instance, exec *sqlExecutor) error {
+	db, err := inst.openDatabase(databaseName(dbResource))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	roles := roleName(dbResource)
+	if dbResource.Spec.ReadOnlyUser {
+		roles += ", " + readOnlyUsername(roleName(dbResource))
+	}
+	if appRole(dbResource) {
+		roles += ", " + appUsername(roleName(dbResource))
+	}
+	stmts := []string{
+		fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schemaName(dbResource)),
+		fmt.Sprintf("DROP OWNED BY %s", roles),
+	}
+	for _, stmt := range stmts {
+		if err := exec.Exec(db, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sharedSchemaExists reports whether the schema of dbResource already exists
+// in its shared database.
+func sharedSchemaExists(dbResource *v1.Database, inst *instance) (bool, error) {
+	db, err := inst.openDatabase(databaseName(dbResource))
+	if err != nil {
+		return false, err
+	}
+	defer db.Close()
+	return schemaExists(db, schemaName(dbResource))
+}
+
+// sharedSchemaOwner returns the owner of the schema of dbResource in its
+// shared database.
+func sharedSchemaOwner(dbResource *v1.Database, inst *instance) (string, error) {
+	db, err := inst.openDatabase(databaseName(dbResource))
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+	var owner string
+	err = db.QueryRow("SELECT pg_get_userbyid(nspowner) FROM pg_namespace WHERE nspname = $1", schemaName(dbResource)).Scan(&owner)
+	return owner, err
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+	"github.com/joshrendek/k8s-external-postgres/pkg/provisioner"
+)
+
+const (
+	// readOnlySecretSuffix is appended to the Database name to build the name
+	// of the Secret holding the read-only role credentials.
+	readOnlySecretSuffix = "-ro"
+
+	// databaseLabel is set to the Database name on the Secrets written for it.
+	// An orphaned Secret carrying it is adopted by the matching Database.
+	databaseLabel = "postgresql.org/database"
+)
+
+// ownerPassword returns the password of the owner role of dbResource: the
+// plaintext from its spec or from the Secret referenced by passwordSecretRef,
+// or the verifier read from the Secret referenced by passwordVerifierSecret.
+// It is empty when the role logs in without one.
+func (c *Controller) ownerPassword(dbResource *v1.Database) (string, error) {
+	if passwordless(dbResource) {
+		return "", nil
+	}
+	if ref := dbResource.Spec.PasswordSecretRef; ref != nil {
+		if dbResource.Spec.PasswordVerifierSecret != nil {
+			return "", fmt.Errorf("spec.passwordSecretRef and spec.passwordVerifierSecret can't both be set")
+		}
+		secret, err := c.SecretsLister.Secrets(dbResource.Namespace).Get(ref.Name)
+		if err != nil {
+			return "", fmt.Errorf("error reading password secret %q: %s", ref.Name, err.Error())
+		}
+		// files turned into Secrets usually end with a newline
+		password := strings.TrimRight(string(secret.Data[ref.Key]), "\r\n")
+		if password == "" {
+			return "", fmt.Errorf("key %q of secret %q is missing or empty", ref.Key, ref.Name)
+		}
+		return password, nil
+	}
+	ref := dbResource.Spec.PasswordVerifierSecret
+	if ref == nil {
+		return dbResource.Spec.Password, nil
+	}
+	secret, err := c.SecretsLister.Secrets(dbResource.Namespace).Get(ref.Name)
+	if err != nil {
+		return "", fmt.Errorf("error reading password verifier secret %q: %s", ref.Name, err.Error())
+	}
+	verifier := strings.TrimSpace(string(secret.Data[ref.Key]))
+	if !provisioner.IsPasswordVerifier(verifier) {
+		return "", fmt.Errorf("key %q of secret %q is not a SCRAM-SHA-256 or md5 password verifier", ref.Key, ref.Name)
+	}
+	return verifier, nil
+}
+
+// appliedPassword returns the password, or verifier, last written to the
+// credential store.
+func appliedPassword(credentials map[string]string) string {
+	if verifier, ok := credentials["PASSWORD_VERIFIER"]; ok {
+		return verifier
+	}
+	return credentials["PASSWORD"]
+}
+
+// storeCredentials writes the connection details for username under name, the
+// name of the Secret they are exposed as, to the credential store of
+// dbResource. When password is a verifier, only the verifier is stored as the
+// plaintext password is not known.
+func (c *Controller) storeCredentials(dbResource *v1.Database, inst *instance, name, username, password string) error {
+	host, port := inst.hostPort()
+	data := map[string]string{
+		"HOST":     host,
+		"PORT":     port,
+		"DATABASE": databaseName(dbResource),
+		"USERNAME": username,
+	}
+	if provisioner.IsPasswordVerifier(password) {
+		data["PASSWORD_VERIFIER"] = password
+	} else if password == "" {
+		// the role logs in without a password, see spec.authentication
+		dsn, err := inst.databaseURL(databaseName(dbResource), username, "")
+		if err != nil {
+			return err
+		}
+		data["DATABASE_URL"] = dsn
+	} else {
+		dsn, err := inst.databaseURL(databaseName(dbResource), username, password)
+		if err != nil {
+			return err
+		}
+		data["PASSWORD"] = password
+		data["DATABASE_URL"] = dsn
+	}
+	setReplicaCredentials(data, c.readReplicas(dbResource))
+	if mirror, err := c.mirrorInstance(dbResource); err == nil {
+		setMirrorCredentials(data, mirror)
+	}
+
+	store, err := c.credentialStore(dbResource)
+	if err != nil {
+		return err
+	}
+	return store.Put(dbResource, name, data)
+}
+
+// secretStore writes credentials to Secrets in the namespace of the Database.
+type secretStore struct {
+	c *Controller
+}
+
+func (s *secretStore) Get(dbResource *v1.Database, name string) (map[string]string, error) {
+	secret, err := s.c.SecretsLister.Secrets(dbResource.Namespace).Get(name)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	data := make(map[string]string, len(secret.Data))
+	for key, value := range secret.Data {
+		data[key] = string(value)
+	}
+	return data, nil
+}
+
+// Put creates or updates the Secret called name. The Secret is owned by
+// dbResource so deleting the Database deletes it too. Existing Secrets are
+// only overwritten when they belong to dbResource, or are orphans labelled
+// for it, in which case they are adopted.
+func (s *secretStore) Put(dbResource *v1.Database, name string, data map[string]string) (err error) {
+	span := startAPISpan(dbResource.Namespace, dbResource.Name, "Write Secret", attribute.String("k8s.secret.name", name))
+	defer func() { endSpan(span, err) }()
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: dbResource.Namespace,
+			Labels:    map[string]string{databaseLabel: dbResource.Name},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(dbResource, v1.SchemeGroupVersion.WithKind("Database")),
+			},
+		},
+		Type:       corev1.SecretTypeOpaque,
+		StringData: data,
+	}
+
+	secrets := s.c.kubeclientset.CoreV1().Secrets(dbResource.Namespace)
+	existing, err := secrets.Get(name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = secrets.Create(secret)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if !metav1.IsControlledBy(existing, dbResource) {
+		if metav1.GetControllerOf(existing) != nil || existing.Labels[databaseLabel] != dbResource.Name {
+			msg := fmt.Sprintf(MessageResourceExists, name)
+			s.c.recorder.Event(dbResource, corev1.EventTypeWarning, ErrResourceExists, msg)
+			return fmt.Errorf("%s", msg)
+		}
+		s.c.recorder.Event(dbResource, corev1.EventTypeNormal, "SecretAdopted", fmt.Sprintf("Adopted orphaned secret %q", name))
+	}
+
+	secret.ResourceVersion = existing.ResourceVersion
+	_, err = secrets.Update(secret)
+	return err
+}
+
+// Delete does nothing, the Secrets are garbage collected along with the
+// Database owning them.
+func (s *secretStore) Delete(dbResource *v1.Database, name string) error {
+	return nil
+}
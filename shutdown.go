@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// syncFunc reconciles the resource of a namespace/name key. ctx is cancelled
+// when a shutdown outlasts --shutdown-timeout.
+type syncFunc func(ctx context.Context, logger zerolog.Logger, key string) error
+
+// runQueueWorkers processes queue with threadiness workers until stopCh is
+// closed, then drains it. It returns once every worker is done.
+func runQueueWorkers(queue workqueue.RateLimitingInterface, threadiness int, syncHandler syncFunc, stopCh <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var workers sync.WaitGroup
+	for i := 0; i < threadiness; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for processNextWorkItem(ctx, queue, syncHandler) {
+			}
+		}()
+	}
+
+	<-stopCh
+	drainQueue(queue, &workers, cancel)
+}
+
+// drainQueue shuts queue down, which lets the workers process the items left
+// but no new ones, and waits for them. Past --shutdown-timeout the reconciles
+// still running are cancelled, aborting their statements.
+func drainQueue(queue workqueue.RateLimitingInterface, workers *sync.WaitGroup, cancel context.CancelFunc) {
+	queue.ShutDown()
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return
+	case <-time.After(shutdownTimeout):
+	}
+	log.Warn().Dur("timeout", shutdownTimeout).Int("queued", queue.Len()).Msg("reconciles still running at the shutdown deadline, cancelling them")
+	cancel()
+	<-done
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+const (
+	// sizeExceededCondition is True while the database of a Database is
+	// larger than its spec.maxSizeBytes.
+	sizeExceededCondition = "SizeExceeded"
+	// sizeLimitEnforcedReason is the reason of the SizeExceeded condition
+	// while CONNECT is revoked, per spec.enforceMaxSize.
+	sizeLimitEnforcedReason = "ConnectRevoked"
+)
+
+// sizeLimitRoles returns the roles CONNECT on the database of dbResource is
+// revoked from while it exceeds its enforced size limit: its application
+// role, or its owner without one, and PUBLIC unless its privileges are
+// revoked already.
+func sizeLimitRoles(dbResource *v1.Database) string {
+	role := roleName(dbResource)
+	if appRole(dbResource) {
+		role = appUsername(role)
+	}
+	if revokePublic(dbResource) {
+		return role
+	}
+	return "PUBLIC, " + role
+}
+
+// syncSizeLimit sets the SizeExceeded condition of dbCopy, the copy of
+// dbResource holding its latest usage, per spec.maxSizeBytes. With
+// spec.enforceMaxSize, CONNECT is revoked when the limit is exceeded and
+// granted back once the database is below it again, or the limit lifted.
+// Open sessions are left alone.
+func (c *Controller) syncSizeLimit(dbResource, dbCopy *v1.Database, inst *instance) error {
+	limit := dbResource.Spec.MaxSizeBytes
+	exceeded := limit != nil && dbCopy.Status.Usage.SizeBytes > *limit
+	cond := findCondition(&dbResource.Status, sizeExceededCondition)
+	wasExceeded := cond != nil && cond.Status == conditionTrue
+	enforced := wasExceeded && cond.Reason == sizeLimitEnforcedReason
+	enforce := exceeded && dbResource.Spec.EnforceMaxSize
+
+	if enforce != enforced {
+		ctx, logger := resourceContext(context.Background(), dbResource.Namespace, dbResource.Name)
+		exec := newExecutor(ctx, dbResource, inst, logger)
+		database := databaseName(dbResource)
+		stmt := fmt.Sprintf("GRANT CONNECT ON DATABASE %s TO %s", database, sizeLimitRoles(dbResource))
+		if enforce {
+			logger.Warn().Str("database", database).Msg("size limit exceeded, revoking access")
+			stmt = fmt.Sprintf("REVOKE CONNECT ON DATABASE %s FROM %s", database, sizeLimitRoles(dbResource))
+		}
+		if err := exec.Exec(inst.DB, stmt); err != nil {
+			return err
+		}
+		if exec.dryRun {
+			// the statement is only planned, access is unchanged
+			enforce = enforced
+		}
+	}
+
+	if !exceeded {
+		if cond != nil && setCondition(&dbCopy.Status, sizeExceededCondition, conditionFalse, "WithinLimit", "") && wasExceeded {
+			c.recorder.Event(dbResource, corev1.EventTypeNormal, "SizeWithinLimit", "Database is back within spec.maxSizeBytes")
+		}
+		return nil
+	}
+	reason, message := "LimitExceeded", fmt.Sprintf("Database exceeds spec.maxSizeBytes of %d bytes", *limit)
+	if enforce {
+		reason = sizeLimitEnforcedReason
+		message += fmt.Sprintf(", CONNECT revoked from %s", sizeLimitRoles(dbResource))
+	}
+	if setCondition(&dbCopy.Status, sizeExceededCondition, conditionTrue, reason, message) {
+		c.recorder.Event(dbResource, corev1.EventTypeWarning, "SizeExceeded", message)
+		if !wasExceeded {
+			databaseSizeExceeded.WithLabelValues(dbResource.Namespace, dbResource.Name).Inc()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/runtime"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+// maxSlowQueryLength truncates the statements recorded in the status, which
+// would otherwise grow the Databases past the size limit of objects.
+const maxSlowQueryLength = 1024
+
+// enableStatStatements creates the pg_stat_statements extension on the
+// admin database of inst unless it exists, which requires the library to be
+// in shared_preload_libraries. Nothing is created in dry run.
+func enableStatStatements(inst *instance) error {
+	var exists bool
+	if err := inst.DB.QueryRow(`SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_stat_statements')`).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	if dryRun {
+		return fmt.Errorf("pg_stat_statements is not installed and is not created in dry run")
+	}
+	_, err := inst.DB.Exec(`CREATE EXTENSION IF NOT EXISTS pg_stat_statements`)
+	return err
+}
+
+// collectSlowQueries returns the --slow-query-top statements with the
+// highest mean execution time of every database of the server of inst, by
+// database name.
+func collectSlowQueries(inst *instance) (map[string][]v1.SlowQuery, error) {
+	total, mean := inst.version.statStatementsTimeColumns()
+	rows, err := inst.DB.Query(fmt.Sprintf(`SELECT d.datname, s.queryid::text, s.query, s.calls, s.%[1]s, s.%[2]s
+		FROM (SELECT *, row_number() OVER (PARTITION BY dbid ORDER BY %[2]s DESC) AS rank
+			FROM pg_stat_statements WHERE calls > 0) s
+		JOIN pg_database d ON d.oid = s.dbid
+		WHERE s.rank <= $1 ORDER BY d.datname, s.rank`, total, mean), slowQueryTop)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	queries := map[string][]v1.SlowQuery{}
+	for rows.Next() {
+		var datname string
+		var query v1.SlowQuery
+		var totalMillis, meanMillis float64
+		if err := rows.Scan(&datname, &query.QueryID, &query.Query, &query.Calls, &totalMillis, &meanMillis); err != nil {
+			return nil, err
+		}
+		if len(query.Query) > maxSlowQueryLength {
+			query.Query = query.Query[:maxSlowQueryLength] + "..."
+		}
+		query.TotalTime = metav1.Duration{Duration: time.Duration(totalMillis * float64(time.Millisecond))}
+		query.MeanTime = metav1.Duration{Duration: time.Duration(meanMillis * float64(time.Millisecond))}
+		queries[datname] = append(queries[datname], query)
+	}
+	return queries, rows.Err()
+}
+
+// syncSlowQueries records the slowest statements of every provisioned
+// Database in its status, collecting pg_stat_statements once per server. With
+// --metrics-addr their mean execution time is exported too.
+func (c *Controller) syncSlowQueries() {
+	dbResources, err := c.DatabasesLister.List(labels.Everything())
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+
+	collected := map[string]map[string][]v1.SlowQuery{}
+	slowQueryMeanSeconds.Reset()
+	for _, dbResource := range dbResources {
+		// the statements of a shared database are not the ones of a tenant
+		if dbResource.Status.State != "provisioned" || schemaMode(dbResource) || paused(dbResource) {
+			continue
+		}
+		inst, err := c.instances.forDatabase(dbResource)
+		if err != nil || !inst.dialect.statistics || inst.unavailable() != nil {
+			continue
+		}
+		server := net.JoinHostPort(inst.hostPort())
+		queries, ok := collected[server]
+		if !ok {
+			if err := enableStatStatements(inst); err != nil {
+				log.Warn().Err(err).Str("instance", inst.label()).Msg("pg_stat_statements unavailable, not collecting slow queries")
+			} else if queries, err = collectSlowQueries(inst); err != nil {
+				log.Error().Err(err).Str("instance", inst.label()).Msg("error collecting slow queries")
+			}
+			collected[server] = queries
+		}
+		if queries == nil {
+			continue
+		}
+
+		report := &v1.SlowQueryReport{Queries: queries[databaseName(dbResource)], CollectedTime: metav1.Now()}
+		for _, query := range report.Queries {
+			slowQueryMeanSeconds.WithLabelValues(dbResource.Namespace, dbResource.Name, query.QueryID).Set(query.MeanTime.Seconds())
+		}
+		dbCopy := dbResource.DeepCopy()
+		dbCopy.Status.SlowQueries = report
+		if err := c.updateStatus(dbCopy); err != nil {
+			runtime.HandleError(fmt.Errorf("error updating slow queries of %s/%s: %s", dbResource.Namespace, dbResource.Name, err.Error()))
+		}
+	}
+}
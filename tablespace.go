@@ -0,0 +1,64 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+	"github.com/joshrendek/k8s-external-postgres/pkg/provisioner"
+)
+
+// databaseTablespace returns the name on the server of the tablespace of
+// spec.tablespace, empty when it isn't set. It fails until the Tablespace is
+// provisioned on the instance of dbResource.
+func (c *Controller) databaseTablespace(dbResource *v1.Database) (string, error) {
+	name := dbResource.Spec.Tablespace
+	if name == "" {
+		return "", nil
+	}
+	if schemaMode(dbResource) {
+		return "", fmt.Errorf("a Database in schema mode shares its database, it can't be placed in tablespace %q", name)
+	}
+	ts, err := c.TablespacesLister.Tablespaces(dbResource.Namespace).Get(name)
+	if errors.IsNotFound(err) {
+		return "", fmt.Errorf("tablespace %q not found", name)
+	}
+	if err != nil {
+		return "", err
+	}
+	if ts.Spec.Instance != instanceName(dbResource) || instanceNamespace(dbResource) != dbResource.Namespace {
+		return "", fmt.Errorf("tablespace %q is not on the instance of the database", name)
+	}
+	if ts.Status.State != "provisioned" {
+		return "", fmt.Errorf("tablespace %q is not provisioned yet", name)
+	}
+	return ts.Status.TablespaceName, nil
+}
+
+// syncDatabaseTablespace moves the database of dbResource to the tablespace
+// of spec.tablespace when it is elsewhere, which requires no session to be
+// connected to it. The database stays where it is once spec.tablespace is
+// unset.
+func (c *Controller) syncDatabaseTablespace(dbResource *v1.Database, inst *instance, exec *sqlExecutor) error {
+	tsName, err := c.databaseTablespace(dbResource)
+	if err != nil || tsName == "" {
+		return err
+	}
+
+	database := databaseName(dbResource)
+	var current string
+	err = inst.DB.QueryRow(`SELECT t.spcname FROM pg_database d JOIN pg_tablespace t ON t.oid = d.dattablespace
+		WHERE d.datname = $1`, database).Scan(&current)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if current == tsName {
+		return nil
+	}
+	if err := exec.Exec(inst.DB, fmt.Sprintf("ALTER DATABASE %s SET TABLESPACE %s", database, provisioner.QuoteIdentifier(tsName))); err != nil {
+		return fmt.Errorf("error moving database to tablespace %q: %s", tsName, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+	clientset "github.com/joshrendek/k8s-external-postgres/pkg/client/clientset/versioned"
+	informers "github.com/joshrendek/k8s-external-postgres/pkg/client/informers/externalversions"
+	listers "github.com/joshrendek/k8s-external-postgres/pkg/client/listers/postgresql/v1"
+	"github.com/joshrendek/k8s-external-postgres/pkg/provisioner"
+)
+
+// TablespaceController creates the tablespaces of Tablespace resources on
+// their servers.
+type TablespaceController struct {
+	kubeclientset     kubernetes.Interface
+	databaseClientset clientset.Interface
+
+	TablespacesLister listers.TablespaceLister
+	TablespacesSynced cache.InformerSynced
+
+	queue     workqueue.RateLimitingInterface
+	recorder  record.EventRecorder
+	instances *instanceRegistry
+}
+
+// NewTablespaceController returns a new tablespace controller
+func NewTablespaceController(
+	kubeclientset kubernetes.Interface,
+	databaseClientset clientset.Interface,
+	databaseInformerFactory informers.SharedInformerFactory,
+	instances *instanceRegistry) *TablespaceController {
+
+	tablespaceInformer := databaseInformerFactory.Databases().V1().Tablespaces()
+
+	controller := &TablespaceController{
+		kubeclientset:     kubeclientset,
+		databaseClientset: databaseClientset,
+		TablespacesLister: tablespaceInformer.Lister(),
+		TablespacesSynced: tablespaceInformer.Informer().HasSynced,
+		queue:             workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "Tablespaces"),
+		recorder:          newEventRecorder(kubeclientset),
+		instances:         instances,
+	}
+
+	log.Info().Msg("Setting up tablespace event handlers")
+	tablespaceInformer.Informer().AddEventHandler(instrumentHandler("tablespaces", "tablespace", cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			enqueue(controller.queue, obj)
+		},
+		UpdateFunc: func(old, new interface{}) {
+			if old.(*v1.Tablespace).ResourceVersion == new.(*v1.Tablespace).ResourceVersion {
+				return
+			}
+			enqueue(controller.queue, new)
+		},
+		// handled immediately, the resource is gone by the time the work
+		// queue would get it
+		DeleteFunc: controller.dropTablespace,
+	}))
+	return controller
+}
+
+// Run waits for the informer caches to sync and starts the workers. It blocks
+// until stopCh is closed.
+func (c *TablespaceController) Run(threadiness int, stopCh <-chan struct{}) error {
+	defer runtime.HandleCrash()
+
+	log.Info().Msg("Starting tablespace controller")
+	if ok := cache.WaitForCacheSync(stopCh, c.TablespacesSynced, c.instances.HasSynced); !ok {
+		return fmt.Errorf("failed to wait for caches to sync")
+	}
+
+	runQueueWorkers(c.queue, threadiness, c.syncTablespace, stopCh)
+	log.Info().Msg("Shutting down tablespace workers")
+	return nil
+}
+
+// tablespaceName returns the name of the tablespace of ts on the server.
+func tablespaceName(ts *v1.Tablespace) string {
+	if ts.Spec.Name != "" {
+		return ts.Spec.Name
+	}
+	return strings.Replace(ts.Name, "-", "_", -1)
+}
+
+// forTablespace returns the instance ts is created on.
+func (r *instanceRegistry) forTablespace(ts *v1.Tablespace) (*instance, error) {
+	if ts.Spec.Instance == "" {
+		return r.forNamespace(ts.Namespace)
+	}
+	return r.get(ts.Namespace, ts.Spec.Instance)
+}
+
+// syncTablespace creates the tablespace of a Tablespace resource, or checks
+// that the existing one is in the same location, and gives it to its owner.
+func (c *TablespaceController) syncTablespace(ctx context.Context, logger zerolog.Logger, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		runtime.HandleError(fmt.Errorf("invalid resource key: %s", key))
+		return nil
+	}
+
+	ts, err := c.TablespacesLister.Tablespaces(namespace).Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			runtime.HandleError(fmt.Errorf("tablespace '%s' in work queue no longer exists", key))
+			return nil
+		}
+		return err
+	}
+
+	tsName := tablespaceName(ts)
+	if !path.IsAbs(ts.Spec.Location) {
+		return c.updateTablespaceStatus(ts, "error", fmt.Sprintf("location %q must be an absolute path", ts.Spec.Location))
+	}
+	if ts.Status.TablespaceName != "" && ts.Status.TablespaceName != tsName {
+		return c.updateTablespaceStatus(ts, "error", fmt.Sprintf("the tablespace %q can't be renamed to %q", ts.Status.TablespaceName, tsName))
+	}
+
+	inst, err := c.instances.forTablespace(ts)
+	if err != nil {
+		return err
+	}
+	if !inst.dialect.tablespaces {
+		return c.updateTablespaceStatus(ts, "error", fmt.Sprintf("tablespaces are not supported by %s", inst.dialect.name))
+	}
+
+	var location, owner string
+	err = inst.DB.QueryRow("SELECT pg_tablespace_location(oid), pg_get_userbyid(spcowner) FROM pg_tablespace WHERE spcname = $1", tsName).Scan(&location, &owner)
+	exists := err == nil
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if exists && ts.Status.TablespaceName == "" && location != ts.Spec.Location {
+		return c.updateTablespaceStatus(ts, "conflict", fmt.Sprintf("Tablespace %q already exists in %s", tsName, location))
+	}
+	if exists && location != ts.Spec.Location {
+		return c.updateTablespaceStatus(ts, "error", fmt.Sprintf("the tablespace %q can't be moved from %s, recreate it", tsName, location))
+	}
+
+	exec := newResourceExecutor(ctx, "Tablespace", ts, logger)
+	var stmts []string
+	if !exists {
+		stmt := fmt.Sprintf("CREATE TABLESPACE %s", provisioner.QuoteIdentifier(tsName))
+		if ts.Spec.Owner != "" {
+			stmt += fmt.Sprintf(" OWNER %s", provisioner.QuoteIdentifier(ts.Spec.Owner))
+		}
+		stmts = append(stmts, stmt+fmt.Sprintf(" LOCATION %s", provisioner.QuoteLiteral(ts.Spec.Location)))
+	} else if ts.Spec.Owner != "" && owner != ts.Spec.Owner {
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLESPACE %s OWNER TO %s", provisioner.QuoteIdentifier(tsName), provisioner.QuoteIdentifier(ts.Spec.Owner)))
+	}
+	for _, stmt := range stmts {
+		// CREATE TABLESPACE can't run in a transaction
+		if err := exec.Exec(inst.DB, stmt); err != nil {
+			c.recorder.Event(ts, corev1.EventTypeWarning, "TablespaceFailed", err.Error())
+			if statusErr := c.updateTablespaceStatus(ts, "error", err.Error()); statusErr != nil {
+				return statusErr
+			}
+			return err
+		}
+	}
+
+	if ts.Status.State != "provisioned" {
+		c.recorder.Event(ts, corev1.EventTypeNormal, SuccessSynced, "Tablespace synced successfully")
+	}
+	tsCopy := ts.DeepCopy()
+	tsCopy.Status.TablespaceName = tsName
+	return c.setTablespaceStatus(ts, tsCopy, "provisioned", "successful")
+}
+
+// dropTablespace drops the tablespace of a deleted Tablespace. It fails,
+// leaving the tablespace in place, while databases are still stored in it.
+func (c *TablespaceController) dropTablespace(obj interface{}) {
+	ts, ok := obj.(*v1.Tablespace)
+	if !ok || ts.Status.TablespaceName == "" {
+		return
+	}
+	ctx, logger := resourceContext(context.Background(), ts.Namespace, ts.Name)
+	inst, err := c.instances.forTablespace(ts)
+	if err != nil {
+		logger.Error().Err(err).Msg("error dropping tablespace")
+		return
+	}
+	exec := newResourceExecutor(ctx, "Tablespace", ts, logger)
+	if err := exec.Exec(inst.DB, fmt.Sprintf("DROP TABLESPACE %s", provisioner.QuoteIdentifier(ts.Status.TablespaceName))); err != nil {
+		logger.Error().Err(err).Str("tablespace", ts.Status.TablespaceName).Msg("error dropping tablespace")
+	}
+}
+
+func (c *TablespaceController) updateTablespaceStatus(ts *v1.Tablespace, state, message string) error {
+	return c.setTablespaceStatus(ts, ts.DeepCopy(), state, message)
+}
+
+// setTablespaceStatus writes tsCopy with state and message unless nothing
+// changed since ts.
+func (c *TablespaceController) setTablespaceStatus(ts, tsCopy *v1.Tablespace, state, message string) error {
+	tsCopy.Status.State = state
+	tsCopy.Status.Message = message
+	if tsCopy.Status == ts.Status {
+		return nil
+	}
+	_, err := c.databaseClientset.DatabasesV1().Tablespaces(ts.Namespace).Update(tsCopy)
+	return err
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer starts the spans of the controller. It does nothing until
+// setupTracing installs the OTLP exporter.
+var tracer = otel.Tracer("github.com/joshrendek/k8s-external-postgres")
+
+// reconcileSpans holds the context of the reconcile in progress of each
+// Database, by key, so the API calls made without a context are traced as
+// part of it. keyLocks keeps a Database from being reconciled twice at once.
+var reconcileSpans sync.Map
+
+// setupTracing exports the traces of the reconciles to --otlp-endpoint over
+// gRPC, returning the function flushing the spans left on shutdown. It does
+// nothing without an endpoint.
+func setupTracing() (func(), error) {
+	if otlpEndpoint == "" {
+		return func() {}, nil
+	}
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(otlpEndpoint)}
+	if otlpInsecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(context.Background(), opts...)
+	if err != nil {
+		return nil, err
+	}
+	attrs := []attribute.KeyValue{attribute.String("service.name", "k8s-external-postgres")}
+	if controllerID != "" {
+		attrs = append(attrs, attribute.String("service.instance.id", controllerID))
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(traceSampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(attrs...)),
+	)
+	otel.SetTracerProvider(provider)
+	log.Info().Str("endpoint", otlpEndpoint).Float64("sampleRatio", traceSampleRatio).Msg("Exporting traces")
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := provider.Shutdown(ctx); err != nil {
+			log.Warn().Err(err).Msg("error flushing traces")
+		}
+	}, nil
+}
+
+// startReconcileSpan starts the trace of the reconcile of the Database
+// behind key, returning the function ending it with the outcome of the
+// reconcile.
+func startReconcileSpan(ctx context.Context, key string) (context.Context, func(error)) {
+	ctx, span := tracer.Start(ctx, "Reconcile Database", trace.WithAttributes(
+		attribute.String("k8s.database.key", key),
+		attribute.String("reconcile.id", reconcileID(ctx)),
+	))
+	reconcileSpans.Store(key, ctx)
+	return ctx, func(err error) {
+		reconcileSpans.Delete(key)
+		endSpan(span, err)
+	}
+}
+
+// startStepSpan starts the span of the provisioning step called name of the
+// Database behind key, the API calls of the step being traced under it.
+func startStepSpan(ctx context.Context, key, name string) (context.Context, func(error)) {
+	parent, tracked := reconcileSpans.Load(key)
+	ctx, span := tracer.Start(ctx, name)
+	if tracked {
+		reconcileSpans.Store(key, ctx)
+	}
+	return ctx, func(err error) {
+		if tracked {
+			reconcileSpans.Store(key, parent)
+		}
+		endSpan(span, err)
+	}
+}
+
+// startAPISpan starts the span of the Kubernetes API call operation made
+// for the Database namespace/name, as part of its reconcile in progress. The
+// calls made outside of reconciles are not traced.
+func startAPISpan(namespace, name, operation string, attrs ...attribute.KeyValue) trace.Span {
+	ctx, ok := reconcileSpans.Load(namespace + "/" + name)
+	if !ok {
+		return trace.SpanFromContext(context.Background())
+	}
+	_, span := tracer.Start(ctx.(context.Context), operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	return span
+}
+
+// startSQLSpan starts the span of the statement stmt, already redacted, run
+// on server.
+func startSQLSpan(ctx context.Context, server, stmt string) trace.Span {
+	name := "SQL"
+	if fields := strings.Fields(stmt); len(fields) > 0 {
+		name += " " + strings.ToUpper(fields[0])
+	}
+	_, span := tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("db.system", "postgresql"),
+		attribute.String("db.statement", stmt),
+		attribute.String("server.address", server),
+	))
+	return span
+}
+
+// endSpan ends span, recording err when the operation failed.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+// expiry returns when dbResource is deleted per its spec.ttl and
+// spec.expiresAt, the earliest of the two, nil when it doesn't expire.
+func expiry(dbResource *v1.Database) *time.Time {
+	var expires *time.Time
+	if ttl := dbResource.Spec.TTL; ttl != nil {
+		t := dbResource.CreationTimestamp.Add(ttl.Duration)
+		expires = &t
+	}
+	if at := dbResource.Spec.ExpiresAt; at != nil && (expires == nil || at.Time.Before(*expires)) {
+		t := at.Time
+		expires = &t
+	}
+	return expires
+}
+
+// syncExpiry deletes dbResource once it expired, the deletion dropping its
+// database as usual, and otherwise queues key again for when it expires. It
+// returns true when dbResource was deleted and must not be reconciled
+// further.
+func (c *Controller) syncExpiry(logger zerolog.Logger, key string, dbResource *v1.Database) (bool, error) {
+	expires := expiry(dbResource)
+	if expires == nil || dbResource.DeletionTimestamp != nil {
+		return false, nil
+	}
+	if remaining := time.Until(*expires); remaining > 0 {
+		c.workqueue.AddAfter(key, remaining)
+		return false, nil
+	}
+
+	logger.Info().Time("expiresAt", *expires).Msg("database expired, deleting it")
+	c.recorder.Event(dbResource, corev1.EventTypeNormal, "Expired", fmt.Sprintf("Database expired at %s, deleting it", expires.UTC().Format(time.RFC3339)))
+	err := c.databaseClientset.DatabasesV1().Databases(dbResource.Namespace).Delete(dbResource.Name, &metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return false, err
+	}
+	return true, nil
+}
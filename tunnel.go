@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx"
+	"github.com/jackc/pgx/stdlib"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	corev1 "k8s.io/api/core/v1"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+	"github.com/joshrendek/k8s-external-postgres/pkg/fakepg"
+)
+
+// knownHostsKey is the key of the SSH tunnel Secret holding the host keys of
+// the bastion.
+const knownHostsKey = "known_hosts"
+
+// sshTunnel forwards the connections to a server through an SSH bastion. A
+// single SSH connection carries them all, it is opened on the first dial and
+// again once it breaks.
+type sshTunnel struct {
+	addr   string
+	config *ssh.ClientConfig
+	// id identifies the bastion, user and keys the tunnel was opened with,
+	// so the instance is reconnected when they change.
+	id string
+
+	mu     sync.Mutex
+	client *ssh.Client
+}
+
+// newSSHTunnel returns the tunnel through the bastion of spec, logging in
+// with the private key of secret and checking the bastion against its known
+// hosts.
+func newSSHTunnel(spec *v1.SSHTunnel, secret *corev1.Secret) (*sshTunnel, error) {
+	privateKey, ok := secret.Data[corev1.SSHAuthPrivateKey]
+	if !ok {
+		return nil, fmt.Errorf("secret %q has no %s key", secret.Name, corev1.SSHAuthPrivateKey)
+	}
+	knownHosts, ok := secret.Data[knownHostsKey]
+	if !ok {
+		return nil, fmt.Errorf("secret %q has no %s key, the host key of the bastion must be known", secret.Name, knownHostsKey)
+	}
+	signer, err := ssh.ParsePrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SSH private key in secret %q: %s", secret.Name, err.Error())
+	}
+	addr := spec.Host
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "22")
+	}
+	hostKeys, err := parseKnownHosts(knownHosts, addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid known_hosts in secret %q: %s", secret.Name, err.Error())
+	}
+
+	sum := sha256.New()
+	for _, part := range [][]byte{[]byte(addr), []byte(spec.User), privateKey, knownHosts} {
+		sum.Write(part)
+		sum.Write([]byte{0})
+	}
+	return &sshTunnel{
+		addr: addr,
+		config: &ssh.ClientConfig{
+			User:            spec.User,
+			Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+			HostKeyCallback: hostKeyCallback(hostKeys),
+			Timeout:         healthCheckTimeout,
+		},
+		id: fmt.Sprintf("%x", sum.Sum(nil)),
+	}, nil
+}
+
+// parseKnownHosts returns the host keys known_hosts lists for addr.
+func parseKnownHosts(knownHosts []byte, addr string) ([]ssh.PublicKey, error) {
+	host, _, _ := net.SplitHostPort(addr)
+	patterns := map[string]bool{knownhosts.Normalize(addr): true, host: true}
+	var keys []ssh.PublicKey
+	for rest := knownHosts; len(bytes.TrimSpace(rest)) > 0; {
+		marker, hosts, key, _, next, err := ssh.ParseKnownHosts(rest)
+		if err != nil {
+			return nil, err
+		}
+		rest = next
+		if marker == "revoked" {
+			continue
+		}
+		for _, h := range hosts {
+			if patterns[h] {
+				keys = append(keys, key)
+				break
+			}
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no host key for %s", addr)
+	}
+	return keys, nil
+}
+
+// hostKeyCallback accepts the bastion when it presents one of keys.
+func hostKeyCallback(keys []ssh.PublicKey) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		for _, known := range keys {
+			if bytes.Equal(known.Marshal(), key.Marshal()) {
+				return nil
+			}
+		}
+		return fmt.Errorf("unknown host key %s for bastion %s", ssh.FingerprintSHA256(key), hostname)
+	}
+}
+
+// connect returns the SSH connection to the bastion, opening it when there
+// is none.
+func (t *sshTunnel) connect() (*ssh.Client, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.client != nil {
+		return t.client, nil
+	}
+	client, err := ssh.Dial("tcp", t.addr, t.config)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to bastion %s: %s", t.addr, err.Error())
+	}
+	t.client = client
+	return client, nil
+}
+
+// reset drops the SSH connection client when it is still the current one,
+// so the next dial opens a new one.
+func (t *sshTunnel) reset(client *ssh.Client) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.client == client {
+		t.client.Close()
+		t.client = nil
+	}
+}
+
+// DialContext opens a connection to address through the bastion. When the
+// SSH connection turns out to be broken, it is opened again once.
+func (t *sshTunnel) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		var client *ssh.Client
+		if client, err = t.connect(); err != nil {
+			return nil, err
+		}
+		var conn net.Conn
+		if conn, err = client.DialContext(ctx, network, address); err == nil {
+			return conn, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		t.reset(client)
+	}
+	return nil, err
+}
+
+func (t *sshTunnel) Dial(network, address string) (net.Conn, error) {
+	return t.DialContext(context.Background(), network, address)
+}
+
+func (t *sshTunnel) DialTimeout(network, address string, timeout time.Duration) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return t.DialContext(ctx, network, address)
+}
+
+// Close closes the SSH connection to the bastion.
+func (t *sshTunnel) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.client == nil {
+		return nil
+	}
+	err := t.client.Close()
+	t.client = nil
+	return err
+}
+
+// openDSN opens a connection pool to dsn, through tunnel when it is not nil.
+// The connections to a multi-host dsn go to the first host answering, the
+// read-write one with target_session_attrs=read-write. With azureAD they
+// log in with its access token rather than the password of dsn. With
+// --backend=fake they go to the fake server of its host.
+func openDSN(dsn string, tunnel *sshTunnel, azureAD *azureADToken) (*sql.DB, error) {
+	if backend == backendFake {
+		return fakepg.Open(dsn)
+	}
+	dsns, readWrite, err := hostDSNs(dsn)
+	if err != nil {
+		return nil, err
+	}
+	configs := make([]pgx.ConnConfig, len(dsns))
+	for i, hostDSN := range dsns {
+		if configs[i], err = hostConfig(hostDSN, readWrite, tunnel); err != nil {
+			return nil, err
+		}
+	}
+	if azureAD != nil {
+		// the token expires, every connection asks for the current one
+		return sql.OpenDB(&failoverConnector{configs: configs, password: azureAD.password}), nil
+	}
+	if len(configs) > 1 {
+		return sql.OpenDB(&failoverConnector{configs: configs}), nil
+	}
+	return stdlib.OpenDB(configs[0]), nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+// syncSpecChanges applies the edits made to a provisioned Database. A new
+// username becomes the owner of the database, or of the schema in schema
+// mode, created if needed, and a new password is set on the owner role. The
+// credentials, which hold the last applied username and password, are
+// rewritten afterwards. Previous roles are left in place as they may still
+// own objects. Owner and password changes wait for the maintenance window
+// of m.
+func (c *Controller) syncSpecChanges(dbResource *v1.Database, inst *instance, exec *sqlExecutor, m *maintenance) error {
+	username := roleName(dbResource)
+	database := databaseName(dbResource)
+	method := passwordEncryptionFor(dbResource)
+
+	password, err := c.ownerPassword(dbResource)
+	if err != nil {
+		return err
+	}
+
+	store, err := c.credentialStore(dbResource)
+	if err != nil {
+		return err
+	}
+	applied, err := store.Get(dbResource, dbResource.Name)
+	if err != nil {
+		return err
+	}
+
+	var owner string
+	if schemaMode(dbResource) && inst.dialect.databaseOwner {
+		owner, err = sharedSchemaOwner(dbResource, inst)
+		if err != nil {
+			return fmt.Errorf("error looking up owner of schema %q: %s", schemaName(dbResource), err.Error())
+		}
+	} else if inst.dialect.databaseOwner {
+		err = inst.DB.QueryRow("SELECT pg_get_userbyid(datdba) FROM pg_database WHERE datname = $1", database).Scan(&owner)
+		if err != nil {
+			return fmt.Errorf("error looking up owner of database %q: %s", database, err.Error())
+		}
+	} else if applied != nil {
+		// without database owners, the last applied one is the stored user
+		owner = applied["USERNAME"]
+	} else {
+		owner = username
+	}
+	ownerChanged := owner != username
+	passwordChanged := applied == nil || appliedPassword(applied) != password
+	if !ownerChanged && !passwordChanged {
+		return nil
+	}
+	// the first credentials written for the role disrupt nothing
+	if ownerChanged && !m.allow(actionOwnerChange) || !ownerChanged && applied != nil && !m.allow(actionPasswordChange) {
+		return nil
+	}
+
+	if ownerChanged {
+		exists, err := roleExists(inst.DB, username)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			stmt, err := rolePasswordStatement("CREATE USER", username, password, method)
+			if err != nil {
+				return err
+			}
+			roleStmts := append([]string{stmt}, inst.dialect.createRoleStatements(username)...)
+			if err := exec.ExecDDL(inst, roleStmts); err != nil {
+				return fmt.Errorf("error creating user: %s", err.Error())
+			}
+			passwordChanged = false
+		}
+		if schemaMode(dbResource) {
+			stmt := fmt.Sprintf("GRANT CONNECT ON DATABASE %s TO %s", database, username)
+			if err := exec.Exec(inst.DB, stmt); err != nil {
+				return fmt.Errorf("error granting connect to user: %s", err.Error())
+			}
+			if err := provisionSchema(dbResource, inst, exec); err != nil {
+				return err
+			}
+		} else {
+			for _, stmt := range inst.dialect.changeOwnerStatements(database, username) {
+				if err := exec.Exec(inst.DB, stmt); err != nil {
+					return fmt.Errorf("error changing database owner: %s", err.Error())
+				}
+			}
+		}
+	}
+	if passwordChanged {
+		if err := checkPasswordPolicy(dbResource, password); err != nil {
+			return err
+		}
+		stmt, err := rolePasswordStatement("ALTER ROLE", username, password, method)
+		if err != nil {
+			return err
+		}
+		if err := exec.Exec(inst.DB, stmt); err != nil {
+			return fmt.Errorf("error changing password: %s", err.Error())
+		}
+	}
+	if ownerChanged && dbResource.Spec.ReadOnlyUser {
+		// the read-only role is named after the owner
+		if err := c.provisionReadOnlyUser(dbResource, inst, exec); err != nil {
+			return err
+		}
+	}
+	if ownerChanged && appRole(dbResource) {
+		if err := c.provisionAppUser(dbResource, inst, exec); err != nil {
+			return err
+		}
+	}
+
+	if exec.dryRun {
+		return nil
+	}
+	if err := c.storeCredentials(dbResource, inst, dbResource.Name, username, password); err != nil {
+		return err
+	}
+	if passwordChanged && applied != nil {
+		notify(notifyPasswordRotated, dbResource, "Owner password changed")
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/runtime"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+// collectUsage returns the statistics of the database of dbResource on inst.
+// The last activity time of the previous collection is kept while no session
+// is connected.
+func collectUsage(dbResource *v1.Database, inst *instance) (*v1.DatabaseUsage, error) {
+	usage := &v1.DatabaseUsage{CollectedTime: metav1.Now()}
+	var lastActivity *time.Time
+	err := inst.DB.QueryRow(`SELECT pg_database_size(datname),
+		(SELECT count(*) FROM pg_stat_activity WHERE datname = $1),
+		(SELECT max(coalesce(state_change, backend_start)) FROM pg_stat_activity WHERE datname = $1)
+		FROM pg_database WHERE datname = $1`, databaseName(dbResource)).Scan(&usage.SizeBytes, &usage.Connections, &lastActivity)
+	if err != nil {
+		return nil, err
+	}
+	if lastActivity != nil {
+		t := metav1.NewTime(*lastActivity)
+		usage.LastActivityTime = &t
+	} else if previous := dbResource.Status.Usage; previous != nil {
+		usage.LastActivityTime = previous.LastActivityTime
+	}
+	return usage, nil
+}
+
+// syncUsage records the statistics of every provisioned Database in its
+// status, checking them against its size limit.
+func (c *Controller) syncUsage() {
+	dbResources, err := c.DatabasesLister.List(labels.Everything())
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	for _, dbResource := range dbResources {
+		// the statistics of a shared database are not the ones of a tenant
+		if dbResource.Status.State != "provisioned" || schemaMode(dbResource) || paused(dbResource) {
+			continue
+		}
+		inst, err := c.instances.forDatabase(dbResource)
+		if err != nil || !inst.dialect.statistics {
+			continue
+		}
+		usage, err := collectUsage(dbResource, inst)
+		if err != nil {
+			runtime.HandleError(fmt.Errorf("error collecting usage of %s/%s: %s", dbResource.Namespace, dbResource.Name, err.Error()))
+			continue
+		}
+		dbCopy := dbResource.DeepCopy()
+		dbCopy.Status.Usage = usage
+		if err := c.syncSizeLimit(dbResource, dbCopy, inst); err != nil {
+			runtime.HandleError(fmt.Errorf("error enforcing the size limit of %s/%s: %s", dbResource.Namespace, dbResource.Name, err.Error()))
+		}
+		if err := c.updateStatus(dbCopy); err != nil {
+			runtime.HandleError(fmt.Errorf("error updating usage of %s/%s: %s", dbResource.Namespace, dbResource.Name, err.Error()))
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+// serviceAccountTokenFile is the token the controller logs in to Vault with
+// through the Kubernetes auth method.
+const serviceAccountTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// vaultClient writes to the KV v2 engine of a Vault server.
+type vaultClient struct {
+	addr      string
+	mount     string
+	authMount string
+	role      string
+	client    *http.Client
+
+	// mu guards the token and the credentials last read or written, cached
+	// so reconciles don't read Vault every time.
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+	cache       map[string]map[string]string
+}
+
+// vault is configured from the --vault-* flags by setupVault, nil when
+// --vault-addr is not set.
+var vault *vaultClient
+
+// setupVault configures the Vault client of the --vault-* flags.
+func setupVault() {
+	if vaultAddr == "" {
+		return
+	}
+	vault = &vaultClient{
+		addr:      strings.TrimSuffix(vaultAddr, "/"),
+		mount:     strings.Trim(vaultMount, "/"),
+		authMount: strings.Trim(vaultAuthMount, "/"),
+		role:      vaultRole,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		cache:     map[string]map[string]string{},
+	}
+}
+
+// login returns the token requests are made with: VAULT_TOKEN without
+// --vault-role, otherwise one obtained with the service account token of the
+// controller and renewed before its lease ends. v.mu must be held.
+func (v *vaultClient) login() (string, error) {
+	if v.role == "" {
+		token := os.Getenv("VAULT_TOKEN")
+		if token == "" {
+			return "", fmt.Errorf("VAULT_TOKEN is not set and no --vault-role to log in with")
+		}
+		return token, nil
+	}
+	if v.token != "" && time.Now().Before(v.tokenExpiry) {
+		return v.token, nil
+	}
+	jwt, err := ioutil.ReadFile(serviceAccountTokenFile)
+	if err != nil {
+		return "", err
+	}
+	var resp struct {
+		Auth struct {
+			ClientToken   string `json:"client_token"`
+			LeaseDuration int    `json:"lease_duration"`
+		} `json:"auth"`
+	}
+	payload := map[string]string{"role": v.role, "jwt": strings.TrimSpace(string(jwt))}
+	if _, err := v.request("POST", fmt.Sprintf("auth/%s/login", v.authMount), "", payload, &resp); err != nil {
+		return "", fmt.Errorf("error logging in to vault: %s", err.Error())
+	}
+	v.token = resp.Auth.ClientToken
+	// renew well before the lease ends
+	v.tokenExpiry = time.Now().Add(time.Duration(resp.Auth.LeaseDuration) * time.Second * 3 / 4)
+	return v.token, nil
+}
+
+// request sends a request with a JSON payload to the Vault API and decodes
+// the response into out. It returns the status code, without error for
+// 404 Not Found.
+func (v *vaultClient) request(method, path, token string, payload, out interface{}) (int, error) {
+	var body bytes.Buffer
+	if payload != nil {
+		if err := json.NewEncoder(&body).Encode(payload); err != nil {
+			return 0, err
+		}
+	}
+	req, err := http.NewRequest(method, fmt.Sprintf("%s/v1/%s", v.addr, path), &body)
+	if err != nil {
+		return 0, err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return resp.StatusCode, nil
+	}
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("vault returned %s for %s %s", resp.Status, method, path)
+	}
+	if out != nil && resp.StatusCode != http.StatusNoContent {
+		return resp.StatusCode, json.NewDecoder(resp.Body).Decode(out)
+	}
+	return resp.StatusCode, nil
+}
+
+// do sends an authenticated request, logging in again once when the token
+// was revoked.
+func (v *vaultClient) do(method, path string, payload, out interface{}) (int, error) {
+	token, err := v.login()
+	if err != nil {
+		return 0, err
+	}
+	status, err := v.request(method, path, token, payload, out)
+	if status == http.StatusForbidden && v.role != "" {
+		v.token = ""
+		if token, err = v.login(); err != nil {
+			return 0, err
+		}
+		status, err = v.request(method, path, token, payload, out)
+	}
+	return status, err
+}
+
+// read returns the latest version of the secret at key, nil when there is
+// none.
+func (v *vaultClient) read(key string) (map[string]string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if data, ok := v.cache[key]; ok {
+		return data, nil
+	}
+	var resp struct {
+		Data struct {
+			Data map[string]string `json:"data"`
+		} `json:"data"`
+	}
+	status, err := v.do("GET", fmt.Sprintf("%s/data/%s", v.mount, key), nil, &resp)
+	if err != nil || status == http.StatusNotFound {
+		return nil, err
+	}
+	v.cache[key] = resp.Data.Data
+	return resp.Data.Data, nil
+}
+
+// write stores data as a new version of the secret at key.
+func (v *vaultClient) write(key string, data map[string]string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if _, err := v.do("POST", fmt.Sprintf("%s/data/%s", v.mount, key), map[string]interface{}{"data": data}, nil); err != nil {
+		return err
+	}
+	v.cache[key] = data
+	return nil
+}
+
+// destroy deletes every version of the secret at key.
+func (v *vaultClient) destroy(key string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.cache, key)
+	_, err := v.do("DELETE", fmt.Sprintf("%s/metadata/%s", v.mount, key), nil, nil)
+	return err
+}
+
+// vaultKey returns the Vault key the credentials stored under name, the
+// Secret name, are written to: spec.credentialStore.path, defaulting to
+// <namespace>/<name>, plus the suffix of name, -ro for the read-only role.
+func vaultKey(dbResource *v1.Database, name string) string {
+	base := dbResource.Namespace + "/" + dbResource.Name
+	if spec := dbResource.Spec.CredentialStore; spec != nil && spec.Path != "" {
+		base = strings.Trim(spec.Path, "/")
+	}
+	return base + strings.TrimPrefix(name, dbResource.Name)
+}
+
+// vaultStore writes credentials to Vault only, no Secret holds them.
+type vaultStore struct {
+	vault *vaultClient
+}
+
+func (s *vaultStore) Get(dbResource *v1.Database, name string) (map[string]string, error) {
+	return s.vault.read(vaultKey(dbResource, name))
+}
+
+func (s *vaultStore) Put(dbResource *v1.Database, name string, data map[string]string) error {
+	return s.vault.write(vaultKey(dbResource, name), data)
+}
+
+func (s *vaultStore) Delete(dbResource *v1.Database, name string) error {
+	return s.vault.destroy(vaultKey(dbResource, name))
+}
+
+// externalSecretStore writes credentials to Vault and creates an External
+// Secrets ExternalSecret syncing them into the Secret called name.
+type externalSecretStore struct {
+	vaultStore
+	kubeclientset kubernetes.Interface
+}
+
+// externalSecret is the subset of the ExternalSecret resource of External
+// Secrets the controller writes.
+type externalSecret struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              externalSecretSpec `json:"spec"`
+}
+
+type externalSecretSpec struct {
+	RefreshInterval string                     `json:"refreshInterval"`
+	SecretStoreRef  v1.SecretStoreRef          `json:"secretStoreRef"`
+	Target          externalSecretTarget       `json:"target"`
+	DataFrom        []externalSecretDataSource `json:"dataFrom"`
+}
+
+type externalSecretTarget struct {
+	Name           string `json:"name"`
+	CreationPolicy string `json:"creationPolicy"`
+}
+
+type externalSecretDataSource struct {
+	Extract externalSecretExtract `json:"extract"`
+}
+
+type externalSecretExtract struct {
+	Key string `json:"key"`
+}
+
+func (s *externalSecretStore) Put(dbResource *v1.Database, name string, data map[string]string) error {
+	if err := s.vaultStore.Put(dbResource, name, data); err != nil {
+		return err
+	}
+
+	ref := *dbResource.Spec.CredentialStore.SecretStoreRef
+	if ref.Kind == "" {
+		ref.Kind = "SecretStore"
+	}
+	obj := externalSecret{
+		TypeMeta: metav1.TypeMeta{APIVersion: "external-secrets.io/v1beta1", Kind: "ExternalSecret"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: dbResource.Namespace,
+			Labels:    map[string]string{databaseLabel: dbResource.Name},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(dbResource, v1.SchemeGroupVersion.WithKind("Database")),
+			},
+		},
+		Spec: externalSecretSpec{
+			RefreshInterval: "1m",
+			SecretStoreRef:  ref,
+			// the Secret belongs to the ExternalSecret, itself owned by
+			// the Database
+			Target:   externalSecretTarget{Name: name, CreationPolicy: "Owner"},
+			DataFrom: []externalSecretDataSource{{Extract: externalSecretExtract{Key: vaultKey(dbResource, name)}}},
+		},
+	}
+	body, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	// External Secrets isn't part of the vendored clients, the ExternalSecret
+	// is created through the raw REST client. Its spec only depends on the
+	// immutable credentialStore, an existing one is left as is.
+	err = s.kubeclientset.CoreV1().RESTClient().Post().
+		AbsPath("/apis/external-secrets.io/v1beta1/namespaces", dbResource.Namespace, "externalsecrets").
+		Body(body).
+		Do().
+		Error()
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return fmt.Errorf("error creating external secret %q: %s", name, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// serverVersion is a PostgreSQL server_version_num, e.g. 150002 for 15.2. Its
+// methods describe the capabilities statements adapt to.
+type serverVersion int
+
+// detectServerVersion returns the version of the server db is connected to.
+func detectServerVersion(db *sql.DB) (serverVersion, error) {
+	var num string
+	if err := db.QueryRow("SHOW server_version_num").Scan(&num); err != nil {
+		return 0, err
+	}
+	v, err := strconv.Atoi(strings.TrimSpace(num))
+	if err != nil {
+		return 0, fmt.Errorf("unexpected server_version_num %q", num)
+	}
+	return serverVersion(v), nil
+}
+
+func (v serverVersion) String() string {
+	if v >= 100000 {
+		return fmt.Sprintf("%d.%d", v/10000, v%10000)
+	}
+	return fmt.Sprintf("%d.%d.%d", v/10000, v/100%100, v%100)
+}
+
+// supportsScram reports whether passwords can be stored as SCRAM-SHA-256.
+func (v serverVersion) supportsScram() bool {
+	return v >= 100000
+}
+
+// supportsBypassRLS reports whether roles take the BYPASSRLS attribute.
+func (v serverVersion) supportsBypassRLS() bool {
+	return v >= 90500
+}
+
+// supportsDropForce reports whether DROP DATABASE ... WITH (FORCE) is
+// available.
+func (v serverVersion) supportsDropForce() bool {
+	return v >= 130000
+}
+
+// supportsLogicalReplication reports whether publications and subscriptions
+// are available.
+func (v serverVersion) supportsLogicalReplication() bool {
+	return v >= 100000
+}
+
+// statStatementsTimeColumns returns the total and mean execution time columns
+// of pg_stat_statements, renamed when planning time was added to it.
+func (v serverVersion) statStatementsTimeColumns() (total, mean string) {
+	if v >= 130000 {
+		return "total_exec_time", "mean_exec_time"
+	}
+	return "total_time", "mean_time"
+}
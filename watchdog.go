@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+const (
+	// serverUnreachableCondition is set on the Databases of an instance the
+	// watchdog found unreachable, whose reconciles are paused meanwhile.
+	serverUnreachableCondition = "ServerUnreachable"
+
+	// healthCheckTimeout bounds a single health check.
+	healthCheckTimeout = 5 * time.Second
+	// maxHealthCheckBackoff caps the delay between the health checks of an
+	// unreachable instance.
+	maxHealthCheckBackoff = 2 * time.Minute
+)
+
+// instanceHealth is the state of the admin connection of an instance, as
+// last checked by the watchdog.
+type instanceHealth struct {
+	mu        sync.Mutex
+	err       error
+	failures  uint
+	nextCheck time.Time
+}
+
+// label returns the name of inst in logs and metrics.
+func (i *instance) label() string {
+	if i.name == "" {
+		return "default"
+	}
+	return i.name
+}
+
+// unavailable returns the error of the last health check of inst while it is
+// unreachable, nil otherwise.
+func (i *instance) unavailable() error {
+	i.health.mu.Lock()
+	defer i.health.mu.Unlock()
+	return i.health.err
+}
+
+// retryAfter returns how long until the next health check of inst.
+func (i *instance) retryAfter() time.Duration {
+	i.health.mu.Lock()
+	defer i.health.mu.Unlock()
+	if d := time.Until(i.health.nextCheck); d > 0 {
+		return d
+	}
+	return healthCheckInterval
+}
+
+// check pings inst when its next health check is due.
+func (i *instance) check() {
+	i.health.mu.Lock()
+	due := !time.Now().Before(i.health.nextCheck)
+	i.health.mu.Unlock()
+	if due {
+		i.checkNow()
+	}
+}
+
+// checkNow pings inst and records the result, returning the error while it
+// is unreachable. Unreachable instances are checked again with an
+// exponential backoff. Once it answers again, the idle connections of the
+// pool, likely broken by the outage, are dropped so reconciles don't fail on
+// them. Those of an admin URI asking for the read-write host are dropped as
+// well once it turns read-only, the pool failing over to the new primary;
+// the instance is unreachable until one answers.
+func (i *instance) checkNow() error {
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	err := i.DB.PingContext(ctx)
+	if err == nil && i.readWrite {
+		var standby bool
+		if standby, err = readOnly(ctx, i.DB); err == nil && standby {
+			// the primary moved, the pool reconnects to the new one
+			log.Info().Str("instance", i.label()).Msg("Instance demoted to read-only, failing over")
+			i.DB.SetMaxIdleConns(0)
+			i.DB.SetMaxIdleConns(2)
+			instanceReconnects.WithLabelValues(i.label()).Inc()
+			if standby, err = readOnly(ctx, i.DB); err == nil && standby {
+				err = fmt.Errorf("no read-write host answered")
+			}
+		}
+	}
+	cancel()
+
+	i.health.mu.Lock()
+	defer i.health.mu.Unlock()
+	if err != nil {
+		if i.health.err == nil {
+			log.Error().Err(err).Str("instance", i.label()).Msg("Instance unreachable, pausing its Databases")
+		}
+		i.health.err = err
+		i.health.failures++
+		backoff := maxHealthCheckBackoff
+		if i.health.failures < 8 {
+			backoff = healthCheckInterval << (i.health.failures - 1)
+		}
+		if backoff > maxHealthCheckBackoff {
+			backoff = maxHealthCheckBackoff
+		}
+		i.health.nextCheck = time.Now().Add(backoff)
+		instanceUp.WithLabelValues(i.label()).Set(0)
+		return err
+	}
+
+	if i.health.err != nil {
+		i.DB.SetMaxIdleConns(0)
+		i.DB.SetMaxIdleConns(2)
+		instanceReconnects.WithLabelValues(i.label()).Inc()
+		log.Info().Str("instance", i.label()).Msg("Instance reachable again, resuming its Databases")
+	}
+	i.health.err = nil
+	i.health.failures = 0
+	i.health.nextCheck = time.Now().Add(healthCheckInterval)
+	instanceUp.WithLabelValues(i.label()).Set(1)
+	return nil
+}
+
+// all returns the default instance and every instance connected to.
+func (r *instanceRegistry) all() []*instance {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	instances := []*instance{r.defaultInstance}
+	for _, inst := range r.instances {
+		instances = append(instances, inst)
+	}
+	return instances
+}
+
+// checkInstances runs the health checks that are due.
+func (r *instanceRegistry) checkInstances() {
+	for _, inst := range r.all() {
+		inst.check()
+	}
+}
+
+// updateServerReachability records on the ServerUnreachable condition of
+// dbResource whether its server is unreachable, along with Ready while it is.
+// Nothing is written while the condition is already as recorded.
+func (c *Controller) updateServerReachability(dbResource *v1.Database, unreachable error) error {
+	cond := findCondition(&dbResource.Status, serverUnreachableCondition)
+	if unreachable == nil && (cond == nil || cond.Status == conditionFalse) {
+		return nil
+	}
+	if unreachable != nil && cond != nil && cond.Status == conditionTrue {
+		return nil
+	}
+	dbCopy := dbResource.DeepCopy()
+	if unreachable != nil {
+		setCondition(&dbCopy.Status, serverUnreachableCondition, conditionTrue, "ServerUnreachable", unreachable.Error())
+		setCondition(&dbCopy.Status, readyCondition, conditionFalse, "ServerUnreachable", unreachable.Error())
+	} else {
+		setCondition(&dbCopy.Status, serverUnreachableCondition, conditionFalse, "ServerReachable", "")
+	}
+	return c.updateStatus(dbCopy)
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/rs/zerolog/log"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	v1 "github.com/joshrendek/k8s-external-postgres/pkg/apis/postgresql/v1"
+)
+
+// webhookPermissions are needed on top of requiredPermissions by
+// --webhook-addr, to check the users may use the PostgresInstances of other
+// namespaces.
+var webhookPermissions = []permission{
+	{"authorization.k8s.io", "subjectaccessreviews", []string{"create"}},
+}
+
+// runWebhookServer serves the validating admission webhook on webhookAddr
+// until stopCh is closed.
+func runWebhookServer(kubeClient kubernetes.Interface, stopCh <-chan struct{}) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/validate-database", func(w http.ResponseWriter, r *http.Request) {
+		serveValidateDatabase(kubeClient, w, r)
+	})
+	server := &http.Server{Addr: webhookAddr, Handler: mux}
+
+	go func() {
+		<-stopCh
+		server.Close()
+	}()
+
+	log.Info().Str("addr", webhookAddr).Msg("Starting admission webhook")
+	err := server.ListenAndServeTLS(webhookCertFile, webhookKeyFile)
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// serveValidateDatabase answers an AdmissionReview for a Database.
+func serveValidateDatabase(kubeClient kubernetes.Interface, w http.ResponseWriter, r *http.Request) {
+	review := admissionv1beta1.AdmissionReview{}
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil || review.Request == nil {
+		http.Error(w, "invalid AdmissionReview", http.StatusBadRequest)
+		return
+	}
+
+	response := &admissionv1beta1.AdmissionResponse{UID: review.Request.UID, Allowed: true}
+	err := validateDatabaseUpdate(review.Request)
+	if err == nil {
+		err = validateDatabasePassword(review.Request)
+	}
+	if err == nil {
+		err = validateInstanceAccess(kubeClient, review.Request)
+	}
+	if err != nil {
+		response.Allowed = false
+		response.Result = &metav1.Status{Message: err.Error()}
+	}
+	review.Response = response
+	review.Request = nil
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(review); err != nil {
+		log.Error().Err(err).Msg("error writing admission response")
+	}
+}
+
+// validateDatabaseUpdate rejects updates changing the immutable fields of a
+// Database.
+func validateDatabaseUpdate(req *admissionv1beta1.AdmissionRequest) error {
+	if req.Operation != admissionv1beta1.Update {
+		return nil
+	}
+	oldDB, newDB := &v1.Database{}, &v1.Database{}
+	if err := json.Unmarshal(req.OldObject.Raw, oldDB); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(req.Object.Raw, newDB); err != nil {
+		return err
+	}
+	if oldDB.Spec.Database != newDB.Spec.Database && schemaMode(newDB) {
+		return fmt.Errorf("spec.database is immutable in schema mode, create a new Database instead")
+	}
+	if schemaMode(oldDB) != schemaMode(newDB) {
+		return fmt.Errorf("spec.mode is immutable, create a new Database instead")
+	}
+	if oldDB.Spec.CloneFrom != newDB.Spec.CloneFrom {
+		return fmt.Errorf("spec.cloneFrom is immutable, create a new Database instead")
+	}
+	if !reflect.DeepEqual(oldDB.Spec.CredentialStore, newDB.Spec.CredentialStore) {
+		return fmt.Errorf("spec.credentialStore is immutable, create a new Database instead")
+	}
+	return nil
+}
+
+// validateDatabasePassword rejects Databases created, or updated, with a
+// password breaking the password policy. The passwords of Secrets are
+// checked by the controller when it reads them.
+func validateDatabasePassword(req *admissionv1beta1.AdmissionRequest) error {
+	if req.Operation != admissionv1beta1.Create && req.Operation != admissionv1beta1.Update {
+		return nil
+	}
+	newDB := &v1.Database{}
+	if err := json.Unmarshal(req.Object.Raw, newDB); err != nil {
+		return err
+	}
+	if newDB.Spec.PasswordSecretRef != nil {
+		return nil
+	}
+	if req.Operation == admissionv1beta1.Update {
+		oldDB := &v1.Database{}
+		if err := json.Unmarshal(req.OldObject.Raw, oldDB); err != nil {
+			return err
+		}
+		if oldDB.Spec.Password == newDB.Spec.Password {
+			return nil
+		}
+	}
+	if err := checkPasswordPolicy(newDB, newDB.Spec.Password); err != nil {
+		return fmt.Errorf("spec.password: %s", err.Error())
+	}
+	return nil
+}
+
+// validateInstanceAccess rejects Databases created, or updated, to be
+// provisioned on a PostgresInstance of another namespace by a user who may
+// not use it: the use verb on the postgresinstances of that namespace is
+// checked with a SubjectAccessReview. The controller checks on top that the
+// instance allows the namespace of the Database.
+func validateInstanceAccess(kubeClient kubernetes.Interface, req *admissionv1beta1.AdmissionRequest) error {
+	if req.Operation != admissionv1beta1.Create && req.Operation != admissionv1beta1.Update {
+		return nil
+	}
+	newDB := &v1.Database{}
+	if err := json.Unmarshal(req.Object.Raw, newDB); err != nil {
+		return err
+	}
+	namespace, name := newDB.Spec.InstanceNamespace, newDB.Spec.Instance
+	if namespace == "" || namespace == req.Namespace || name == "" {
+		return nil
+	}
+	if req.Operation == admissionv1beta1.Update {
+		oldDB := &v1.Database{}
+		if err := json.Unmarshal(req.OldObject.Raw, oldDB); err != nil {
+			return err
+		}
+		if oldDB.Spec.InstanceNamespace == namespace && oldDB.Spec.Instance == name {
+			// checked when it was set
+			return nil
+		}
+	}
+
+	extra := map[string]authorizationv1.ExtraValue{}
+	for key, values := range req.UserInfo.Extra {
+		extra[key] = authorizationv1.ExtraValue(values)
+	}
+	access, err := kubeClient.AuthorizationV1().SubjectAccessReviews().Create(&authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Group:     v1.CRDGroup,
+				Resource:  v1.InstanceCRDPlural,
+				Name:      name,
+				Verb:      "use",
+			},
+			User:   req.UserInfo.Username,
+			Groups: req.UserInfo.Groups,
+			UID:    req.UserInfo.UID,
+			Extra:  extra,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error checking access to instance %s/%s: %s", namespace, name, err.Error())
+	}
+	if !access.Status.Allowed {
+		return fmt.Errorf("%s can't use instance %s/%s", req.UserInfo.Username, namespace, name)
+	}
+	return nil
+}